@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
 	"imgGeneratePrompts/routes"
 	"io"
 	"log"
@@ -41,6 +42,8 @@ func setup() {
 	if err := config.LoadConfig(); err != nil {
 		log.Fatalf("加载配置失败: %v", err)
 	}
+	// 这些测试以匿名身份创建数据，匿名角色需要编辑权限
+	config.AppConfig.Auth.AnonymousRole = models.RoleEditor
 
 	// 初始化数据库
 	if err := config.InitDBWithoutMigration(); err != nil {
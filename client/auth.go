@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"io"
+	"net/http"
+)
+
+// Register 注册用户，成功后客户端使用返回的访问令牌
+func (c *Client) Register(ctx context.Context, req *models.RegisterRequest) (*models.TokenResponse, error) {
+	return c.authenticate(ctx, "/api/v1/auth/register", req)
+}
+
+// Login 登录，成功后客户端使用返回的访问令牌
+func (c *Client) Login(ctx context.Context, username, password string) (*models.TokenResponse, error) {
+	return c.authenticate(ctx, "/api/v1/auth/login", &models.LoginRequest{Username: username, Password: password})
+}
+
+// RefreshToken 使用刷新令牌获取新的令牌，成功后客户端使用新的访问令牌
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*models.TokenResponse, error) {
+	return c.authenticate(ctx, "/api/v1/auth/refresh", &models.RefreshTokenRequest{RefreshToken: refreshToken})
+}
+
+// authenticate 调用返回令牌的认证接口并更换访问令牌
+func (c *Client) authenticate(ctx context.Context, path string, in interface{}) (*models.TokenResponse, error) {
+	var tokens models.TokenResponse
+	if err := c.call(ctx, http.MethodPost, path, nil, in, &tokens); err != nil {
+		return nil, err
+	}
+	c.SetToken(tokens.AccessToken)
+	return &tokens, nil
+}
+
+// Me 获取当前用户
+func (c *Client) Me(ctx context.Context) (*models.UserResponse, error) {
+	var user models.UserResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/auth/me", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Health 检查服务是否正常运行
+func (c *Client) Health(ctx context.Context) error {
+	resp, err := c.send(ctx, &request{method: http.MethodGet, path: "/health"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp.StatusCode, body)
+	}
+
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &health); err != nil || health.Status != "ok" {
+		return fmt.Errorf("服务状态异常: %s", body)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+// Package client 是图像生成提示词管理系统 API 的 Go 客户端
+//
+// 客户端解析统一的 ResponseData 响应：成功时将 data 解码为对应的类型，
+// 失败时返回带有服务端 code 和 message 的 *Error。
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 默认的请求超时时间
+const DefaultTimeout = 60 * time.Second
+
+// Client API 客户端，可以在多个 goroutine 中同时使用
+type Client struct {
+	baseURL    string
+	mu         sync.RWMutex
+	token      string
+	userAgent  string
+	httpClient *http.Client
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithToken 设置访问令牌，可以是登录获得的 JWT，也可以是以 igp_ 开头的个人API密钥
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient 使用自定义的 http.Client（如测试服务器的客户端）
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent 设置 User-Agent 请求头
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New 创建客户端，baseURL 为服务地址，如 http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		userAgent:  "imgGeneratePrompts-client/1.0",
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetToken 更换访问令牌（如登录或刷新令牌之后）
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// Token 返回当前的访问令牌
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// Error 服务端返回的错误
+type Error struct {
+	StatusCode int    // HTTP状态码
+	Code       int    // 响应中的 code
+	Message    string // 响应中的 message
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return fmt.Sprintf("请求失败（%d）: %s", e.Code, e.Message)
+}
+
+// IsNotFound 判断错误是否为资源不存在（404）
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized 判断错误是否为未登录或令牌无效（401）
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden 判断错误是否为没有权限（403）
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsConflict 判断错误是否为版本冲突（412 或 409）
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusPreconditionFailed) || hasStatus(err, http.StatusConflict)
+}
+
+// hasStatus 判断错误是否为指定状态码的 *Error
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// envelope 统一响应结构，data 延迟解码为调用方需要的类型
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// request 一个待发送的请求
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        io.Reader
+	contentType string
+	header      http.Header
+}
+
+// jsonRequest 创建 JSON 请求体的请求，in 为 nil 时没有请求体
+func jsonRequest(method, path string, in interface{}) (*request, error) {
+	req := &request{method: method, path: path}
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("编码请求失败: %v", err)
+		}
+		req.body = bytes.NewReader(data)
+		req.contentType = "application/json"
+	}
+	return req, nil
+}
+
+// call 发送 JSON 请求并将响应的 data 解码到 out（out 为 nil 时忽略 data）
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	req, err := jsonRequest(method, path, in)
+	if err != nil {
+		return err
+	}
+	req.query = query
+	return c.do(ctx, req, out)
+}
+
+// do 发送请求并解析统一响应
+func (c *Client) do(ctx context.Context, req *request, out interface{}) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return decodeError(resp.StatusCode, body)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if out == nil || len(env.Data) == 0 || string(env.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("解析响应数据失败: %v", err)
+	}
+	return nil
+}
+
+// send 发送请求，返回原始响应，调用方负责关闭响应体
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	target := c.baseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, req.body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		httpReq.Header.Set("User-Agent", c.userAgent)
+	}
+	if token := c.Token(); token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	return resp, nil
+}
+
+// decodeError 将错误响应转换为 *Error，响应不是统一格式时使用响应内容作为 message
+func decodeError(statusCode int, body []byte) error {
+	var env envelope
+	if err := json.Unmarshal(body, &env); err == nil && env.Message != "" {
+		return &Error{StatusCode: statusCode, Code: env.Code, Message: env.Message}
+	}
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return &Error{StatusCode: statusCode, Code: statusCode, Message: message}
+}
+
+// Page 分页响应
+type Page[T any] struct {
+	Items      []T   `json:"items"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// Iterator 逐条遍历分页接口的结果，需要时才请求下一页
+//
+//	it := c.Prompts(&models.PromptQuery{Keyword: "cat"})
+//	for it.Next(ctx) {
+//		prompt := it.Item()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+	fetch    func(ctx context.Context, page int) (*Page[T], error)
+	page     int
+	items    []T
+	index    int
+	item     T
+	total    int64
+	finished bool
+	err      error
+}
+
+// newIterator 创建从 firstPage 开始的迭代器
+func newIterator[T any](firstPage int, fetch func(ctx context.Context, page int) (*Page[T], error)) *Iterator[T] {
+	if firstPage < 1 {
+		firstPage = 1
+	}
+	return &Iterator[T]{fetch: fetch, page: firstPage - 1}
+}
+
+// Next 前进到下一条，没有更多结果或出错时返回 false
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for it.index >= len(it.items) {
+		if it.finished || it.err != nil {
+			return false
+		}
+		page, err := it.fetch(ctx, it.page+1)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = page.Page
+		it.items = page.Items
+		it.index = 0
+		it.total = page.Total
+		it.finished = len(page.Items) == 0 || page.Page >= page.TotalPages
+	}
+	it.item = it.items[it.index]
+	it.index++
+	return true
+}
+
+// Item 返回当前的结果
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Total 返回结果总数（至少调用一次 Next 之后有效）
+func (it *Iterator[T]) Total() int64 {
+	return it.total
+}
+
+// Err 返回遍历过程中的错误
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All 读取剩余的全部结果
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for it.Next(ctx) {
+		items = append(items, it.Item())
+	}
+	return items, it.Err()
+}
@@ -0,0 +1,296 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"imgGeneratePrompts/client"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/routes"
+	"imgGeneratePrompts/services"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ClientTestSuite 在 httptest 服务器上运行 routes.SetupRoutes，通过客户端调用接口
+type ClientTestSuite struct {
+	suite.Suite
+	db     *gorm.DB
+	cfg    *config.Config
+	server *httptest.Server
+	client *client.Client
+	ctx    context.Context
+}
+
+// SetupSuite 创建专用的测试数据库并启动测试服务器
+func (s *ClientTestSuite) SetupSuite() {
+	gin.SetMode(gin.TestMode)
+	if err := config.LoadConfig(); err != nil {
+		log.Fatalf("无法加载配置: %v", err)
+	}
+	s.cfg = config.AppConfig
+	s.cfg.Database.DBName = s.cfg.Database.DBName + "_clienttest"
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=%s&parseTime=true&loc=Local",
+		s.cfg.Database.User, s.cfg.Database.Password, s.cfg.Database.Host, s.cfg.Database.Port, s.cfg.Database.Charset)
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	s.Require().NoError(err, "无法连接到MySQL服务器")
+	db.Exec("DROP DATABASE IF EXISTS " + s.cfg.Database.DBName)
+	s.Require().NoError(db.Exec("CREATE DATABASE " + s.cfg.Database.DBName).Error)
+
+	s.Require().NoError(config.InitDBWithMigration())
+	s.db = config.GetDB()
+	os.MkdirAll(s.cfg.Server.UploadPath, os.ModePerm)
+
+	s.server = httptest.NewServer(routes.SetupRoutes())
+	s.ctx = context.Background()
+}
+
+// TearDownSuite 关闭服务器并删除测试数据库
+func (s *ClientTestSuite) TearDownSuite() {
+	s.server.Close()
+	if dbName := s.cfg.Database.DBName; dbName != "" && dbName != "img_generate_prompts" {
+		s.db.Exec("DROP DATABASE " + dbName)
+	}
+	config.CloseDB()
+	os.RemoveAll(s.cfg.Server.UploadPath)
+}
+
+// SetupTest 清理数据并以新注册的用户登录
+func (s *ClientTestSuite) SetupTest() {
+	s.db.Exec("DELETE FROM webhook_deliveries")
+	s.db.Exec("DELETE FROM webhooks")
+	s.db.Exec("DELETE FROM jobs")
+	s.db.Exec("DELETE FROM share_links")
+	s.db.Exec("DELETE FROM collection_items")
+	s.db.Exec("DELETE FROM collections")
+	s.db.Exec("DELETE FROM prompt_images")
+	s.db.Exec("DELETE FROM prompt_tags")
+	s.db.Exec("DELETE FROM prompts")
+	s.db.Exec("DELETE FROM tags")
+	s.db.Exec("DELETE FROM wildcards")
+	s.db.Exec("DELETE FROM api_keys")
+	s.db.Exec("DELETE FROM users")
+
+	s.client = client.New(s.server.URL, client.WithHTTPClient(s.server.Client()))
+	tokens, err := s.client.Register(s.ctx, &models.RegisterRequest{Username: "alice", Password: "password123"})
+	s.Require().NoError(err)
+	s.Equal(tokens.AccessToken, s.client.Token(), "注册后使用返回的访问令牌")
+}
+
+// TestPrompts 测试提示词的创建、查询、更新和删除
+func (s *ClientTestSuite) TestPrompts() {
+	created, err := s.client.CreatePrompt(s.ctx, &models.CreatePromptRequest{
+		PromptText: "a cat in the rain",
+		ModelName:  "sdxl",
+		TagNames:   []string{"猫", "雨"},
+	})
+	s.Require().NoError(err)
+	s.Equal("a cat in the rain", created.PromptText)
+	s.Len(created.Tags, 2)
+
+	prompt, err := s.client.GetPrompt(s.ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal(created.ID, prompt.ID)
+
+	// 过时的版本返回冲突
+	text := "a cat in the snow"
+	updated, err := s.client.UpdatePromptIfMatch(s.ctx, prompt.ID, prompt.Version, &models.UpdatePromptRequest{PromptText: &text})
+	s.Require().NoError(err)
+	s.Equal(text, updated.PromptText)
+	_, err = s.client.UpdatePromptIfMatch(s.ctx, prompt.ID, prompt.Version, &models.UpdatePromptRequest{PromptText: &text})
+	s.True(client.IsConflict(err), "旧版本应返回412: %v", err)
+
+	patched, err := s.client.PatchPrompt(s.ctx, prompt.ID, updated.Version, map[string]interface{}{"model_name": "flux"})
+	s.Require().NoError(err)
+	s.Equal("flux", patched.ModelName)
+
+	duplicate, err := s.client.CheckDuplicate(s.ctx, text)
+	s.Require().NoError(err)
+	s.True(duplicate.IsDuplicate)
+
+	recent, err := s.client.RecentPrompts(s.ctx, 5)
+	s.Require().NoError(err)
+	s.Len(recent, 1)
+
+	stats, err := s.client.PromptStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(int64(1), stats.TotalPrompts)
+
+	var export bytes.Buffer
+	s.Require().NoError(s.client.ExportPrompts(s.ctx, &models.ExportQuery{Format: models.ExportFormatJSONL}, &export))
+	s.Contains(export.String(), text)
+
+	s.Require().NoError(s.client.DeletePrompt(s.ctx, prompt.ID))
+	_, err = s.client.GetPrompt(s.ctx, prompt.ID)
+	s.True(client.IsNotFound(err), "删除后应返回404: %v", err)
+}
+
+// TestPromptIterator 测试分页迭代器按需请求每一页
+func (s *ClientTestSuite) TestPromptIterator() {
+	for i := 0; i < 7; i++ {
+		_, err := s.client.CreatePrompt(s.ctx, &models.CreatePromptRequest{
+			PromptText: fmt.Sprintf("迭代提示词 %d", i),
+			IsPublic:   i%2 == 0,
+			TagNames:   []string{"迭代"},
+		})
+		s.Require().NoError(err)
+	}
+
+	page, err := s.client.ListPrompts(s.ctx, &models.PromptQuery{Page: 2, PageSize: 3})
+	s.Require().NoError(err)
+	s.Equal(2, page.Page)
+	s.Equal(int64(7), page.Total)
+	s.Equal(3, page.TotalPages)
+	s.Len(page.Items, 3)
+
+	it := s.client.Prompts(&models.PromptQuery{PageSize: 3, TagNames: []string{"迭代"}})
+	prompts, err := it.All(s.ctx)
+	s.Require().NoError(err)
+	s.Len(prompts, 7)
+	s.Equal(int64(7), it.Total())
+	seen := make(map[uint]bool)
+	for _, prompt := range prompts {
+		s.False(seen[prompt.ID], "迭代结果不应重复")
+		seen[prompt.ID] = true
+	}
+
+	public, err := s.client.PublicPrompts(2).All(s.ctx)
+	s.Require().NoError(err)
+	s.Len(public, 4)
+
+	byTags, err := s.client.PromptsByTags([]string{"迭代"}, 5).All(s.ctx)
+	s.Require().NoError(err)
+	s.Len(byTags, 7)
+
+	// 空结果
+	empty, err := s.client.Prompts(&models.PromptQuery{Keyword: "不存在的关键词"}).All(s.ctx)
+	s.Require().NoError(err)
+	s.Empty(empty)
+}
+
+// TestUploadAndAnalyze 测试 multipart 上传图片
+func (s *ClientTestSuite) TestUploadAndAnalyze() {
+	var img bytes.Buffer
+	s.Require().NoError(png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	prompt, err := s.client.UploadPrompt(s.ctx, &models.CreatePromptRequest{
+		PromptText: "上传的提示词",
+		TagNames:   []string{"上传", "测试"},
+	}, &client.PromptFiles{
+		OutputImage: &client.File{Name: "output.png", Reader: bytes.NewReader(img.Bytes())},
+		InputImages: []client.File{{Name: "input.png", Reader: bytes.NewReader(img.Bytes())}},
+	})
+	s.Require().NoError(err)
+	s.NotEmpty(prompt.OutputImageURL)
+	s.Len(prompt.InputImageURLs, 1)
+	s.Len(prompt.Tags, 2)
+
+	// 缺少图片时返回服务端的错误信息
+	_, err = s.client.Analyze(s.ctx, &models.AnalyzePromptRequest{PromptText: "没有图片"}, nil)
+	var apiErr *client.Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusBadRequest, apiErr.StatusCode)
+	s.NotEmpty(apiErr.Message)
+}
+
+// TestTags 测试标签接口
+func (s *ClientTestSuite) TestTags() {
+	cat, err := s.client.CreateTag(s.ctx, "猫")
+	s.Require().NoError(err)
+	kitten, err := s.client.CreateTag(s.ctx, "小猫")
+	s.Require().NoError(err)
+	_, err = s.client.CreatePrompt(s.ctx, &models.CreatePromptRequest{PromptText: "小猫", TagNames: []string{"小猫"}})
+	s.Require().NoError(err)
+
+	tags, err := s.client.ListTags(s.ctx)
+	s.Require().NoError(err)
+	s.Len(tags, 2)
+
+	found, err := s.client.SearchTags(s.ctx, "小")
+	s.Require().NoError(err)
+	s.Require().Len(found, 1)
+	s.Equal(kitten.ID, found[0].ID)
+
+	stats, err := s.client.TagStats(s.ctx)
+	s.Require().NoError(err)
+	s.Equal(int64(2), stats.TotalTags)
+
+	// 合并标签需要管理员权限，管理员只能通过 db-manager 创建
+	_, err = services.NewAuthService().CreateAdmin("alice", "")
+	s.Require().NoError(err)
+	merged, err := s.client.MergeTag(s.ctx, kitten.ID, cat.ID)
+	s.Require().NoError(err)
+	s.Equal(cat.ID, merged.Tag.ID)
+	s.Equal(int64(1), merged.MergedPrompts)
+
+	_, err = s.client.GetTag(s.ctx, kitten.ID)
+	s.True(client.IsNotFound(err))
+}
+
+// TestAuth 测试登录和未授权的错误
+func (s *ClientTestSuite) TestAuth() {
+	anonymous := client.New(s.server.URL)
+	_, err := anonymous.Me(s.ctx)
+	s.True(client.IsUnauthorized(err), "未登录应返回401: %v", err)
+
+	_, err = anonymous.Login(s.ctx, "alice", "wrong-password")
+	var apiErr *client.Error
+	s.Require().ErrorAs(err, &apiErr)
+	s.Equal(http.StatusUnauthorized, apiErr.Code)
+	s.Empty(anonymous.Token(), "登录失败时不更换令牌")
+
+	tokens, err := anonymous.Login(s.ctx, "alice", "password123")
+	s.Require().NoError(err)
+	s.Equal(tokens.AccessToken, anonymous.Token())
+	me, err := anonymous.Me(s.ctx)
+	s.Require().NoError(err)
+	s.Equal("alice", me.Username)
+}
+
+// TestClientSuite 运行客户端测试套件
+func TestClientSuite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("在短模式下跳过客户端测试")
+	}
+	suite.Run(t, new(ClientTestSuite))
+}
+
+// TestErrorDecoding 测试不需要数据库的错误解析
+func TestErrorDecoding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(routes.SetupRoutes())
+	defer server.Close()
+	c := client.New(server.URL, client.WithHTTPClient(server.Client()))
+	ctx := context.Background()
+
+	require.NoError(t, c.Health(ctx))
+
+	// 参数验证在访问数据库之前失败，返回统一格式的错误
+	_, err := c.Login(ctx, "", "")
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+	assert.True(t, strings.HasPrefix(apiErr.Message, "参数验证失败"), apiErr.Message)
+	assert.Contains(t, err.Error(), apiErr.Message)
+
+	// 非统一格式的响应（如路由不存在）使用响应内容作为错误信息
+	notFound := client.New(server.URL+"/missing", client.WithHTTPClient(server.Client()))
+	err = notFound.Health(ctx)
+	assert.True(t, client.IsNotFound(err), "%v", err)
+}
@@ -0,0 +1,408 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// File 上传的文件
+type File struct {
+	Name   string    // 文件名，服务端根据扩展名判断类型
+	Reader io.Reader // 文件内容，为 nil 时读取 Name 指定的本地文件
+}
+
+// PromptFiles 上传的图片，字段对应上传接口的文件字段
+type PromptFiles struct {
+	OutputImage       *File  // output_image
+	InputImages       []File // input_images
+	ReferenceImages   []File // reference_images
+	ExtraOutputImages []File // extra_output_images
+	MaskImages        []File // mask_images
+	ControlNetImages  []File // controlnet_images
+}
+
+// PromptStats 提示词统计信息
+type PromptStats struct {
+	TotalPrompts   int64 `json:"total_prompts"`
+	PublicPrompts  int64 `json:"public_prompts"`
+	PrivatePrompts int64 `json:"private_prompts"`
+	ModelStats     []struct {
+		ModelName string `json:"model_name"`
+		Count     int64  `json:"count"`
+	} `json:"model_stats"`
+}
+
+// DuplicateResult 重复检查结果
+type DuplicateResult struct {
+	IsDuplicate bool                    `json:"is_duplicate"`
+	Count       int                     `json:"count"`
+	Prompts     []models.PromptResponse `json:"prompts"`
+}
+
+// CreatePrompt 创建提示词（图片使用URL）
+func (c *Client) CreatePrompt(ctx context.Context, req *models.CreatePromptRequest) (*models.PromptResponse, error) {
+	var prompt models.PromptResponse
+	if err := c.call(ctx, http.MethodPost, "/api/v1/prompts/", nil, req, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// UploadPrompt 上传图片并创建提示词（multipart/form-data）
+func (c *Client) UploadPrompt(ctx context.Context, req *models.CreatePromptRequest, files *PromptFiles) (*models.PromptResponse, error) {
+	fields := promptFormFields(req)
+	body, contentType, err := multipartBody(fields, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var query url.Values
+	if req.FetchRemote {
+		query = url.Values{"fetch_remote": {"true"}}
+	}
+	var prompt models.PromptResponse
+	err = c.do(ctx, &request{method: http.MethodPost, path: "/api/v1/prompts/upload", query: query, body: body, contentType: contentType}, &prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// GetPrompt 获取单个提示词
+func (c *Client) GetPrompt(ctx context.Context, id uint) (*models.PromptResponse, error) {
+	var prompt models.PromptResponse
+	if err := c.call(ctx, http.MethodGet, promptPath(id), nil, nil, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// ListPrompts 获取一页提示词，query 为 nil 时使用服务端的默认分页
+func (c *Client) ListPrompts(ctx context.Context, query *models.PromptQuery) (*Page[models.PromptResponse], error) {
+	var page Page[models.PromptResponse]
+	if err := c.call(ctx, http.MethodGet, "/api/v1/prompts/", promptQueryValues(query), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Prompts 遍历符合条件的全部提示词，从 query.Page 开始（为0时从第一页开始）
+func (c *Client) Prompts(query *models.PromptQuery) *Iterator[models.PromptResponse] {
+	q := models.PromptQuery{}
+	if query != nil {
+		q = *query
+	}
+	return newIterator(q.Page, func(ctx context.Context, page int) (*Page[models.PromptResponse], error) {
+		q.Page = page
+		return c.ListPrompts(ctx, &q)
+	})
+}
+
+// ListPublicPrompts 获取一页公开提示词
+func (c *Client) ListPublicPrompts(ctx context.Context, page, pageSize int) (*Page[models.PromptResponse], error) {
+	var result Page[models.PromptResponse]
+	if err := c.call(ctx, http.MethodGet, "/api/v1/prompts/public", pageValues(page, pageSize), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PublicPrompts 遍历全部公开提示词
+func (c *Client) PublicPrompts(pageSize int) *Iterator[models.PromptResponse] {
+	return newIterator(1, func(ctx context.Context, page int) (*Page[models.PromptResponse], error) {
+		return c.ListPublicPrompts(ctx, page, pageSize)
+	})
+}
+
+// SearchPromptsByTags 获取一页包含指定标签的提示词
+func (c *Client) SearchPromptsByTags(ctx context.Context, tagNames []string, page, pageSize int) (*Page[models.PromptResponse], error) {
+	query := pageValues(page, pageSize)
+	query.Set("tags", strings.Join(tagNames, ","))
+	var result Page[models.PromptResponse]
+	if err := c.call(ctx, http.MethodGet, "/api/v1/prompts/search/tags", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PromptsByTags 遍历包含指定标签的全部提示词
+func (c *Client) PromptsByTags(tagNames []string, pageSize int) *Iterator[models.PromptResponse] {
+	return newIterator(1, func(ctx context.Context, page int) (*Page[models.PromptResponse], error) {
+		return c.SearchPromptsByTags(ctx, tagNames, page, pageSize)
+	})
+}
+
+// RecentPrompts 获取最近创建的提示词
+func (c *Client) RecentPrompts(ctx context.Context, limit int) ([]models.PromptResponse, error) {
+	var query url.Values
+	if limit > 0 {
+		query = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+	var prompts []models.PromptResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/prompts/recent", query, nil, &prompts); err != nil {
+		return nil, err
+	}
+	return prompts, nil
+}
+
+// UpdatePrompt 更新提示词，只更新 req 中非 nil 的字段
+func (c *Client) UpdatePrompt(ctx context.Context, id uint, req *models.UpdatePromptRequest) (*models.PromptResponse, error) {
+	return c.UpdatePromptIfMatch(ctx, id, 0, req)
+}
+
+// UpdatePromptIfMatch 仅当提示词的当前版本为 version 时更新，否则返回 IsConflict 为 true 的错误
+// version 为0时不检查版本
+func (c *Client) UpdatePromptIfMatch(ctx context.Context, id, version uint, req *models.UpdatePromptRequest) (*models.PromptResponse, error) {
+	r, err := jsonRequest(http.MethodPut, promptPath(id), req)
+	if err != nil {
+		return nil, err
+	}
+	r.header = ifMatchHeader(id, version)
+	var prompt models.PromptResponse
+	if err := c.do(ctx, r, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// PatchPrompt 以 JSON Merge Patch 部分更新提示词，patch 中为 null 的字段被清除
+// version 不为0时仅当当前版本为 version 时更新
+func (c *Client) PatchPrompt(ctx context.Context, id, version uint, patch map[string]interface{}) (*models.PromptResponse, error) {
+	r, err := jsonRequest(http.MethodPatch, promptPath(id), patch)
+	if err != nil {
+		return nil, err
+	}
+	r.contentType = utils.MergePatchContentType
+	r.header = ifMatchHeader(id, version)
+	var prompt models.PromptResponse
+	if err := c.do(ctx, r, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// DeletePrompt 删除提示词
+func (c *Client) DeletePrompt(ctx context.Context, id uint) error {
+	return c.call(ctx, http.MethodDelete, promptPath(id), nil, nil, nil)
+}
+
+// ForkPrompt 分叉提示词，返回新的提示词
+func (c *Client) ForkPrompt(ctx context.Context, id uint) (*models.PromptResponse, error) {
+	var prompt models.PromptResponse
+	if err := c.call(ctx, http.MethodPost, promptPath(id)+"/fork", nil, nil, &prompt); err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// PromptStats 获取提示词统计信息
+func (c *Client) PromptStats(ctx context.Context) (*PromptStats, error) {
+	var stats PromptStats
+	if err := c.call(ctx, http.MethodGet, "/api/v1/prompts/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// CheckDuplicate 检查是否已有相同文本的提示词
+func (c *Client) CheckDuplicate(ctx context.Context, promptText string) (*DuplicateResult, error) {
+	var result DuplicateResult
+	query := url.Values{"prompt_text": {promptText}}
+	if err := c.call(ctx, http.MethodGet, "/api/v1/prompts/check-duplicate", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Analyze 由AI分析图片和提示词，files.OutputImage 必填
+func (c *Client) Analyze(ctx context.Context, req *models.AnalyzePromptRequest, files *PromptFiles) (*models.AnalyzePromptResponse, error) {
+	fields := url.Values{"prompt_text": {req.PromptText}}
+	if req.ModelName != "" {
+		fields.Set("model_name", req.ModelName)
+	}
+	body, contentType, err := multipartBody(fields, files)
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.AnalyzePromptResponse
+	err = c.do(ctx, &request{method: http.MethodPost, path: "/api/v1/prompts/analyze", body: body, contentType: contentType}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportPrompts 按过滤条件导出提示词，将导出的文件写入 w
+func (c *Client) ExportPrompts(ctx context.Context, query *models.ExportQuery, w io.Writer) error {
+	values := url.Values{}
+	if query != nil {
+		setNonEmpty(values, "format", query.Format)
+		if query.Zip {
+			values.Set("zip", "true")
+		}
+		setFilterValues(values, query.ModelName, query.IsPublic, query.Keyword, query.TagNames, query.SortBy, query.SortOrder)
+	}
+
+	resp, err := c.send(ctx, &request{method: http.MethodGet, path: "/api/v1/prompts/export", query: values})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return decodeError(resp.StatusCode, body)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("读取导出内容失败: %v", err)
+	}
+	return nil
+}
+
+// promptPath 提示词的接口路径
+func promptPath(id uint) string {
+	return "/api/v1/prompts/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// ifMatchHeader 生成 If-Match 请求头，version 为0时不设置
+func ifMatchHeader(id, version uint) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {utils.PromptETag(id, version)}}
+}
+
+// pageValues 分页查询参数，为0的参数使用服务端的默认值
+func pageValues(page, pageSize int) url.Values {
+	values := url.Values{}
+	if page > 0 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		values.Set("page_size", strconv.Itoa(pageSize))
+	}
+	return values
+}
+
+// promptQueryValues 将 PromptQuery 转换为查询参数，标签名称以逗号分隔
+func promptQueryValues(query *models.PromptQuery) url.Values {
+	if query == nil {
+		return nil
+	}
+	values := pageValues(query.Page, query.PageSize)
+	setFilterValues(values, query.ModelName, query.IsPublic, query.Keyword, query.TagNames, query.SortBy, query.SortOrder)
+	return values
+}
+
+// setFilterValues 设置列表和导出共用的过滤条件
+func setFilterValues(values url.Values, modelName string, isPublic *bool, keyword string, tagNames []string, sortBy, sortOrder string) {
+	setNonEmpty(values, "model_name", modelName)
+	if isPublic != nil {
+		values.Set("is_public", strconv.FormatBool(*isPublic))
+	}
+	setNonEmpty(values, "keyword", keyword)
+	setNonEmpty(values, "tag_names", strings.Join(tagNames, ","))
+	setNonEmpty(values, "sort_by", sortBy)
+	setNonEmpty(values, "sort_order", sortOrder)
+}
+
+// setNonEmpty 值不为空时设置参数
+func setNonEmpty(values url.Values, key, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
+
+// promptFormFields 将创建请求转换为表单字段，标签名称以逗号分隔（与服务端的表单解析一致）
+func promptFormFields(req *models.CreatePromptRequest) url.Values {
+	fields := url.Values{"prompt_text": {req.PromptText}}
+	setNonEmpty(fields, "negative_prompt", req.NegativePrompt)
+	setNonEmpty(fields, "model_name", req.ModelName)
+	if req.IsPublic {
+		fields.Set("is_public", "true")
+	}
+	setNonEmpty(fields, "style_description", req.StyleDescription)
+	setNonEmpty(fields, "usage_scenario", req.UsageScenario)
+	setNonEmpty(fields, "atmosphere_description", req.AtmosphereDescription)
+	setNonEmpty(fields, "expressive_intent", req.ExpressiveIntent)
+	setNonEmpty(fields, "structure_analysis", req.StructureAnalysis)
+	setNonEmpty(fields, "generation_params", req.GenerationParams)
+	for _, u := range req.InputImageURLs {
+		fields.Add("input_image_urls", u)
+	}
+	setNonEmpty(fields, "output_image_url", req.OutputImageURL)
+	setNonEmpty(fields, "tag_names", strings.Join(req.TagNames, ","))
+	return fields
+}
+
+// multipartBody 生成 multipart/form-data 请求体
+func multipartBody(fields url.Values, files *PromptFiles) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for key, values := range fields {
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	if files != nil {
+		if files.OutputImage != nil {
+			if err := writeFile(writer, "output_image", *files.OutputImage); err != nil {
+				return nil, "", err
+			}
+		}
+		for field, list := range map[string][]File{
+			"input_images":        files.InputImages,
+			"reference_images":    files.ReferenceImages,
+			"extra_output_images": files.ExtraOutputImages,
+			"mask_images":         files.MaskImages,
+			"controlnet_images":   files.ControlNetImages,
+		} {
+			for _, file := range list {
+				if err := writeFile(writer, field, file); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, writer.FormDataContentType(), nil
+}
+
+// writeFile 写入一个文件字段
+func writeFile(writer *multipart.Writer, field string, file File) error {
+	reader := file.Reader
+	if reader == nil {
+		f, err := os.Open(file.Name)
+		if err != nil {
+			return fmt.Errorf("打开文件失败: %v", err)
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	part, err := writer.CreateFormFile(field, filepath.Base(file.Name))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, reader); err != nil {
+		return fmt.Errorf("读取文件 %s 失败: %v", file.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"imgGeneratePrompts/models"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// TagStats 标签统计信息
+type TagStats struct {
+	TotalTags   int64 `json:"total_tags"`
+	PopularTags []struct {
+		TagID    uint   `json:"tag_id"`
+		TagName  string `json:"tag_name"`
+		UseCount int64  `json:"use_count"`
+	} `json:"popular_tags"`
+}
+
+// MergeTagResult 合并标签的结果
+type MergeTagResult struct {
+	Tag           models.TagResponse `json:"tag"`
+	MergedPrompts int64              `json:"merged_prompts"`
+}
+
+// CreateTag 创建标签
+func (c *Client) CreateTag(ctx context.Context, name string) (*models.TagResponse, error) {
+	var tag models.TagResponse
+	if err := c.call(ctx, http.MethodPost, "/api/v1/tags/", nil, &models.CreateTagRequest{Name: name}, &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// ListTags 获取所有标签
+func (c *Client) ListTags(ctx context.Context) ([]models.TagResponse, error) {
+	var tags []models.TagResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/tags/", nil, nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// SearchTags 按关键词搜索标签
+func (c *Client) SearchTags(ctx context.Context, keyword string) ([]models.TagResponse, error) {
+	var tags []models.TagResponse
+	if err := c.call(ctx, http.MethodGet, "/api/v1/tags/search", url.Values{"keyword": {keyword}}, nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetTag 获取单个标签
+func (c *Client) GetTag(ctx context.Context, id uint) (*models.TagResponse, error) {
+	var tag models.TagResponse
+	if err := c.call(ctx, http.MethodGet, tagPath(id), nil, nil, &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag 删除标签
+func (c *Client) DeleteTag(ctx context.Context, id uint) error {
+	return c.call(ctx, http.MethodDelete, tagPath(id), nil, nil, nil)
+}
+
+// MergeTag 将标签 id 合并到 targetID，返回目标标签和移动的提示词数量
+func (c *Client) MergeTag(ctx context.Context, id, targetID uint) (*MergeTagResult, error) {
+	var result MergeTagResult
+	if err := c.call(ctx, http.MethodPost, tagPath(id)+"/merge", nil, &models.MergeTagRequest{TargetID: targetID}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TagStats 获取标签统计信息
+func (c *Client) TagStats(ctx context.Context) (*TagStats, error) {
+	var stats TagStats
+	if err := c.call(ctx, http.MethodGet, "/api/v1/tags/stats", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// tagPath 标签的接口路径
+func tagPath(id uint) string {
+	return "/api/v1/tags/" + strconv.FormatUint(uint64(id), 10)
+}
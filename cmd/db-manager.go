@@ -4,9 +4,14 @@ import (
 	"flag"
 	"fmt"
 	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/migrations"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
 	"imgGeneratePrompts/utils"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func main() {
@@ -18,8 +23,36 @@ func main() {
 		showStats    = flag.Bool("stats", false, "显示数据库统计信息")
 		validate     = flag.Bool("validate", false, "验证数据完整性")
 		writeDB      = flag.Bool("write", false, "完整写入数据库（初始化+示例数据）")
+		migrateCmd   = flag.String("migrate", "", "版本化迁移：up | down | status | to N")
+		migrateSteps = flag.Int("steps", 1, "-migrate down 回滚的迁移数量")
+		migrateTo    = flag.Int("to", -1, "同 -migrate to N：升级或回滚到指定的迁移版本（0 表示回滚全部）")
+		backupFile   = flag.String("backup", "", "备份数据到指定的 tar.gz 文件")
+		restoreFile  = flag.String("restore", "", "从指定的 tar.gz 备份文件恢复数据")
+		remapIDs     = flag.Bool("remap", false, "恢复时重新分配ID（恢复到已有数据的数据库时使用）")
+		restoreOwner = flag.String("restore-owner", "", "恢复时所有者在当前数据库中不存在（按用户名匹配）的提示词改为归属该管理员")
+		assignOwner  = flag.String("assign-ownerless", "", "将无所有者的私有提示词交给指定的管理员")
+		importFile   = flag.String("import", "", "从 JSONL、CSV 或 Civitai JSON 文件批量导入提示词")
+		importFormat = flag.String("format", "", "导入文件格式：jsonl | csv | civitai（默认根据扩展名判断）")
+		onDuplicate  = flag.String("on-duplicate", models.ImportOnDuplicateSkip, "导入时遇到重复提示词的处理方式：skip | upsert")
+		importMap    = flag.String("mapping", "", "导入列映射，如 prompt=prompt_text,tags=tag_names 或JSON对象")
+		chunkSize    = flag.Int("chunk-size", models.DefaultImportChunkSize, "导入时每个事务处理的行数")
+		adminUser    = flag.String("user", "", "执行危险操作（如-reset、-backup、-restore、-assign-ownerless）的管理员用户名，密码从 DB_MANAGER_PASSWORD 环境变量或标准输入读取")
+		createAdmin  = flag.String("create-admin", "", "创建管理员账号（用户已存在时提升为管理员），新账号密码从 ADMIN_PASSWORD 环境变量或标准输入读取")
 	)
 	flag.Parse()
+	// -migrate to N 的版本号是位置参数，取出后继续解析其后的选项（如 -user）
+	if *migrateCmd == "to" && flag.NArg() > 0 {
+		version, err := strconv.Atoi(flag.Arg(0))
+		if err != nil || version < 0 {
+			log.Fatalf("无效的迁移版本: %s", flag.Arg(0))
+		}
+		*migrateTo = version
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	// flag 在第一个非选项参数处停止解析，其后的 -user 等选项会被忽略，因此不接受位置参数
+	if flag.NArg() > 0 {
+		log.Fatalf("无法识别的参数: %s（请使用 -steps 等选项传入）", strings.Join(flag.Args(), " "))
+	}
 
 	// 加载配置
 	if err := config.LoadConfig(); err != nil {
@@ -52,13 +85,13 @@ func main() {
 		var confirm string
 		fmt.Scanln(&confirm)
 		if confirm == "y" || confirm == "Y" {
-			fmt.Println("🚀 开始重置数据库...")
-			if err := config.LoadConfig(); err != nil {
-				log.Fatalf("加载配置失败: %v", err)
-			}
 			if err := config.InitDB(); err != nil {
 				log.Fatalf("连接数据库失败: %v", err)
 			}
+			if err := authorizeAdmin(*adminUser, models.PermDatabaseReset); err != nil {
+				log.Fatalf("❌ 权限校验失败: %v", err)
+			}
+			fmt.Println("🚀 开始重置数据库...")
 			if err := dbManager.ResetDatabase(); err != nil {
 				log.Fatalf("❌ 重置数据库失败: %v", err)
 			}
@@ -67,6 +100,94 @@ func main() {
 			fmt.Println("❌ 操作已取消")
 		}
 
+	case *createAdmin != "":
+		// 创建管理员（不插入初始标签，空数据库创建管理员后仍可按原ID恢复备份）
+		if err := config.InitDBWithoutSeed(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := runCreateAdmin(*createAdmin, *adminUser); err != nil {
+			log.Fatalf("❌ 创建管理员失败: %v", err)
+		}
+
+	case *migrateTo >= 0:
+		// 迁移到指定版本（-migrate to N 或 -to N）
+		if err := config.InitDBWithoutMigration(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := runMigrateTo(*migrateTo, *adminUser); err != nil {
+			log.Fatalf("❌ 迁移失败: %v", err)
+		}
+
+	case *migrateCmd != "":
+		// 版本化迁移
+		if err := config.InitDBWithoutMigration(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := runMigrate(*migrateCmd, *migrateSteps, *adminUser); err != nil {
+			log.Fatalf("❌ 迁移失败: %v", err)
+		}
+
+	case *backupFile != "":
+		// 备份数据
+		if err := config.InitDB(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := authorizeAdmin(*adminUser, models.PermDatabaseReset); err != nil {
+			log.Fatalf("❌ 权限校验失败: %v", err)
+		}
+		fmt.Printf("🚀 开始备份数据到 %s...\n", *backupFile)
+		manifest, err := dbManager.BackupData(*backupFile)
+		if err != nil {
+			log.Fatalf("❌ 备份失败: %v", err)
+		}
+		fmt.Printf("✅ 备份完成！提示词 %d 条，标签 %d 个，标签关联 %d 条，文件 %d 个\n",
+			manifest.Prompts, manifest.Tags, manifest.PromptTags, manifest.Files)
+		if len(manifest.MissingFiles) > 0 {
+			fmt.Printf("⚠️  %d 个被引用的文件不存在，未包含在备份中\n", len(manifest.MissingFiles))
+		}
+
+	case *restoreFile != "":
+		// 恢复数据（不插入初始标签，以免与备份中的标签ID冲突）
+		if err := config.InitDBWithoutSeed(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := authorizeAdmin(*adminUser, models.PermDatabaseReset); err != nil {
+			log.Fatalf("❌ 权限校验失败: %v", err)
+		}
+		fmt.Printf("🚀 开始从 %s 恢复数据...\n", *restoreFile)
+		result, err := dbManager.RestoreData(*restoreFile, utils.RestoreOptions{RemapIDs: *remapIDs, OwnerFallback: *restoreOwner})
+		if err != nil {
+			log.Fatalf("❌ 恢复失败: %v", err)
+		}
+		fmt.Printf("✅ 恢复完成！提示词 %d 条，新标签 %d 个（合并 %d 个），标签关联 %d 条，文件 %d 个\n",
+			result.Prompts, result.Tags, result.MergedTags, result.PromptTags, result.Files)
+		if result.ReassignedOwners > 0 {
+			fmt.Printf("⚠️  %d 条提示词的所有者在当前数据库中不存在，已改为归属管理员 %s\n", result.ReassignedOwners, *restoreOwner)
+		}
+
+	case *assignOwner != "":
+		// 为用户功能上线前的私有提示词指定所有者
+		if err := config.InitDB(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := authorizeAdmin(*adminUser, models.PermDatabaseReset); err != nil {
+			log.Fatalf("❌ 权限校验失败: %v", err)
+		}
+		count, err := dbManager.AssignOwnerlessPrompts(*assignOwner)
+		if err != nil {
+			log.Fatalf("❌ 指定所有者失败: %v", err)
+		}
+		fmt.Printf("✅ %d 条无所有者的私有提示词已交给管理员 %s\n", count, *assignOwner)
+
+	case *importFile != "":
+		// 批量导入
+		if err := config.InitDB(); err != nil {
+			log.Fatalf("连接数据库失败: %v", err)
+		}
+		if err := runImport(*importFile, *importFormat, *onDuplicate, *importMap, *chunkSize, *adminUser); err != nil {
+			log.Fatalf("❌ 导入失败: %v", err)
+		}
+
 	case *createSample:
 		// 创建示例数据
 		fmt.Println("🚀 开始创建示例数据...")
@@ -116,14 +237,208 @@ func main() {
 		fmt.Println("  -reset     重置数据库（危险操作）")
 		fmt.Println("  -stats     显示数据库统计信息")
 		fmt.Println("  -validate  验证数据完整性")
+		fmt.Println("  -migrate   版本化迁移：up | down（配合 -steps N）| status | to N（升级或回滚到指定版本）")
+		fmt.Println("  -to        同 -migrate to N")
+		fmt.Println("  -backup    备份数据到 tar.gz 文件（含软删除的提示词和上传的图片）")
+		fmt.Println("  -restore   从 tar.gz 备份文件恢复数据")
+		fmt.Println("  -remap     恢复时重新分配ID，用于恢复到已有数据的数据库")
+		fmt.Println("  -restore-owner  恢复时所有者按用户名匹配，不存在的所有者改为归属该管理员（不指定时恢复失败）")
+		fmt.Println("  -assign-ownerless  将无所有者的私有提示词（用户功能上线前创建）交给指定的管理员")
+		fmt.Println("  -import    从 JSONL、CSV 或 Civitai JSON 文件批量导入提示词（配合 -format、-on-duplicate、-mapping、-chunk-size）")
+		fmt.Println("  -create-admin  创建管理员账号；已有管理员时需配合 -user 以管理员身份执行")
+		fmt.Println("  -user      管理员用户名（-reset、-backup、-restore、-assign-ownerless 需要admin角色）；-import 时导入的提示词归属该用户")
 		fmt.Println("")
 		fmt.Println("示例:")
 		fmt.Printf("  %s -write    # 完整初始化数据库\n", os.Args[0])
 		fmt.Printf("  %s -stats    # 查看统计信息\n", os.Args[0])
 		fmt.Printf("  %s -sample   # 只创建示例数据\n", os.Args[0])
+		fmt.Printf("  %s -migrate status   # 查看迁移状态\n", os.Args[0])
+		fmt.Printf("  %s -migrate down -steps 2 -user admin   # 回滚最近两个迁移\n", os.Args[0])
+		fmt.Printf("  %s -migrate to 5 -user admin   # 升级或回滚到第5个迁移\n", os.Args[0])
+		fmt.Printf("  %s -create-admin admin   # 创建第一个管理员\n", os.Args[0])
+		fmt.Printf("  %s -reset -user admin   # 以管理员身份重置数据库\n", os.Args[0])
+		fmt.Printf("  %s -backup out.tar.gz -user admin   # 备份数据\n", os.Args[0])
+		fmt.Printf("  %s -restore out.tar.gz -remap -user admin   # 恢复到已有数据的数据库\n", os.Args[0])
+		fmt.Printf("  %s -assign-ownerless admin -user admin   # 旧的私有提示词交给管理员 admin\n", os.Args[0])
+		fmt.Printf("  %s -import prompts.csv -mapping prompt=prompt_text,tags=tag_names -on-duplicate upsert   # 批量导入\n", os.Args[0])
 	}
 }
 
+// runMigrate 执行 -migrate 子命令，回滚操作需要管理员身份
+func runMigrate(command string, steps int, adminUser string) error {
+	migrator := migrations.NewMigrator(config.GetDB())
+
+	switch command {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Println("📋 迁移状态:")
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("  ✅ %04d_%s  (%s)\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("  ⏳ %04d_%s\n", status.Version, status.Name)
+			}
+		}
+		return nil
+
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ 执行了 %d 个迁移，当前版本 %d\n", count, migrations.Latest())
+		return nil
+
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("无效的回滚数量: %d", steps)
+		}
+		if err := authorizeAdmin(adminUser, models.PermDatabaseReset); err != nil {
+			return err
+		}
+		count, err := migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ 回滚了 %d 个迁移\n", count)
+		return nil
+
+	case "to":
+		return fmt.Errorf("请指定目标版本，如 -migrate to 5")
+
+	default:
+		return fmt.Errorf("未知的迁移命令: %s（可用: up, down, status, to N）", command)
+	}
+}
+
+// runMigrateTo 执行 -migrate to N（或 -to N）：升级或回滚到指定版本，回滚需要管理员身份
+func runMigrateTo(version int, adminUser string) error {
+	migrator := migrations.NewMigrator(config.GetDB())
+	current, err := migrator.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	if version < current {
+		if err := authorizeAdmin(adminUser, models.PermDatabaseReset); err != nil {
+			return err
+		}
+	}
+	count, err := migrator.To(version)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ 执行或回滚了 %d 个迁移，当前版本 %d\n", count, version)
+	return nil
+}
+
+// runImport 执行 -import：指定 -user 时导入的提示词归属该用户（需校验密码），否则为无所有者的共享提示词
+func runImport(path, format, onDuplicate, mappingSpec string, chunkSize int, username string) error {
+	if format == "" {
+		format = utils.DetectImportFormat(path)
+		if format == "" {
+			return fmt.Errorf("无法根据文件扩展名判断格式，请使用 -format 指定 jsonl、csv 或 civitai")
+		}
+	}
+	mapping, err := utils.ParseImportMapping(mappingSpec)
+	if err != nil {
+		return err
+	}
+
+	opts := &models.ImportOptions{
+		Format:      format,
+		OnDuplicate: onDuplicate,
+		ChunkSize:   chunkSize,
+		Mapping:     mapping,
+	}
+	if username != "" {
+		password := os.Getenv("DB_MANAGER_PASSWORD")
+		if password == "" {
+			fmt.Printf("请输入用户 %s 的密码: ", username)
+			fmt.Scanln(&password)
+		}
+		user, err := services.NewAuthService().VerifyPermission(username, password, models.PermPromptWrite)
+		if err != nil {
+			return err
+		}
+		opts.OwnerID = &user.ID
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开导入文件失败: %v", err)
+	}
+	defer file.Close()
+
+	fmt.Printf("🚀 开始从 %s 导入提示词...\n", path)
+	result, err := services.NewImportService().ImportPrompts(file, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ 导入完成！共 %d 行，新建 %d 条，更新 %d 条，跳过 %d 条，失败 %d 条\n",
+		result.Total, result.Created, result.Updated, result.Skipped, result.Failed)
+	for _, rowErr := range result.Errors {
+		fmt.Printf("  ❌ 第 %d 行: %s\n", rowErr.Line, rowErr.Message)
+	}
+	return nil
+}
+
+// runCreateAdmin 执行 -create-admin：数据库中还没有管理员时直接创建（首次部署），否则需要 -user 指定的管理员授权
+func runCreateAdmin(username, adminUser string) error {
+	var adminCount int64
+	if err := config.GetDB().Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount).Error; err != nil {
+		return fmt.Errorf("统计管理员失败: %v", err)
+	}
+	if adminCount > 0 {
+		if err := authorizeAdmin(adminUser, models.PermUserManage); err != nil {
+			return err
+		}
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Printf("请输入管理员 %s 的密码（用户已存在时不会修改密码）: ", username)
+		fmt.Scanln(&password)
+	}
+	user, err := services.NewAuthService().CreateAdmin(username, password)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ 用户 %s 已成为管理员（ID %d）\n", user.Username, user.ID)
+	return nil
+}
+
+// authorizeAdmin 校验命令行操作者拥有指定权限
+// 数据库中没有用户表或还没有管理员时拒绝执行，需要先使用 -create-admin 创建管理员
+func authorizeAdmin(username string, perm models.Permission) error {
+	db := config.GetDB()
+	if !db.Migrator().HasTable(&models.User{}) {
+		return fmt.Errorf("用户表不存在，请先执行迁移并使用 -create-admin 创建管理员")
+	}
+	var adminCount int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount).Error; err != nil {
+		return fmt.Errorf("统计管理员失败: %v", err)
+	}
+	if adminCount == 0 {
+		return fmt.Errorf("数据库中尚无管理员账号，请先使用 -create-admin 创建管理员")
+	}
+
+	if username == "" {
+		return fmt.Errorf("请使用 -user 指定管理员用户名")
+	}
+	password := os.Getenv("DB_MANAGER_PASSWORD")
+	if password == "" {
+		fmt.Printf("请输入用户 %s 的密码: ", username)
+		fmt.Scanln(&password)
+	}
+
+	_, err := services.NewAuthService().VerifyPermission(username, password, perm)
+	return err
+}
+
 // printStats 打印统计信息
 func printStats(stats map[string]interface{}) {
 	if tables, ok := stats["tables"].(map[string]int64); ok {
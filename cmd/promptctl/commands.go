@@ -0,0 +1,363 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/client"
+	"imgGeneratePrompts/models"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stringList 可重复指定的选项，也支持逗号分隔
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// runAdd 创建提示词：指定图片时通过 multipart 上传，否则以 JSON 创建
+func runAdd(a *app, args []string) error {
+	fs := newFlagSet("add")
+	var inputs, references, tags stringList
+	image := fs.String("image", "", "输出图片（生成结果）文件")
+	fs.Var(&inputs, "input", "输入图片文件，可重复指定")
+	fs.Var(&references, "ref", "参考图片文件，可重复指定")
+	fs.Var(&tags, "tags", "标签，逗号分隔或重复指定")
+	model := fs.String("model", "", "模型名称")
+	negative := fs.String("negative", "", "负面提示词")
+	public := fs.Bool("public", false, "公开提示词")
+	params := fs.String("params", "", "生成参数（JSON对象），如 {\"steps\":30}")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	text, err := promptText(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	req := &models.CreatePromptRequest{
+		PromptText:       text,
+		NegativePrompt:   *negative,
+		ModelName:        *model,
+		IsPublic:         *public,
+		GenerationParams: *params,
+		TagNames:         tags,
+	}
+	var prompt *models.PromptResponse
+	if *image == "" && len(inputs) == 0 && len(references) == 0 {
+		prompt, err = a.client.CreatePrompt(a.ctx, req)
+	} else {
+		prompt, err = a.client.UploadPrompt(a.ctx, req, promptFiles(*image, inputs, references))
+	}
+	if err != nil {
+		return err
+	}
+	return a.out.prompt(prompt)
+}
+
+// runSearch 搜索提示词，默认显示前 limit 条
+func runSearch(a *app, args []string) error {
+	fs := newFlagSet("search")
+	var tags stringList
+	fs.Var(&tags, "tags", "标签，逗号分隔或重复指定（需包含全部标签）")
+	model := fs.String("model", "", "模型名称")
+	public := fs.Bool("public", false, "只显示公开的提示词")
+	private := fs.Bool("private", false, "只显示私有的提示词")
+	sortBy := fs.String("sort", "", "排序字段，如 created_at")
+	order := fs.String("order", "", "排序方向：asc | desc")
+	limit := fs.Int("limit", 20, "最多显示的条数，0 表示全部")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *public && *private {
+		return errors.New("-public 和 -private 不能同时指定")
+	}
+
+	pageSize := 100
+	if *limit > 0 && *limit < pageSize {
+		pageSize = *limit
+	}
+	query := &models.PromptQuery{
+		PageSize:  pageSize,
+		ModelName: *model,
+		Keyword:   strings.Join(fs.Args(), " "),
+		TagNames:  tags,
+		SortBy:    *sortBy,
+		SortOrder: *order,
+	}
+	if *public || *private {
+		query.IsPublic = public
+	}
+
+	it := a.client.Prompts(query)
+	var prompts []models.PromptResponse
+	for (*limit <= 0 || len(prompts) < *limit) && it.Next(a.ctx) {
+		prompts = append(prompts, it.Item())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return a.out.prompts(prompts, it.Total())
+}
+
+// runShow 查看提示词详情
+func runShow(a *app, args []string) error {
+	id, err := promptID(args)
+	if err != nil {
+		return err
+	}
+	prompt, err := a.client.GetPrompt(a.ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.out.prompt(prompt)
+}
+
+// runEdit 在编辑器中修改提示词，以 JSON Merge Patch 提交修改的字段
+// 提交时带上编辑前的版本号，编辑期间提示词被其他人修改时不会覆盖对方的修改
+func runEdit(a *app, args []string) error {
+	id, err := promptID(args)
+	if err != nil {
+		return err
+	}
+	prompt, err := a.client.GetPrompt(a.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	before := editableDocument(prompt)
+	after, err := editInEditor(before)
+	if err != nil {
+		return err
+	}
+	patch, err := mergePatch(before, after)
+	if err != nil {
+		return err
+	}
+	if len(patch) == 0 {
+		fmt.Fprintln(os.Stderr, "未修改")
+		return nil
+	}
+
+	updated, err := a.client.PatchPrompt(a.ctx, id, prompt.Version, patch)
+	if client.IsConflict(err) {
+		return fmt.Errorf("提示词在编辑期间已被修改（编辑时的版本为 %d），请重新编辑: %w", prompt.Version, err)
+	}
+	if err != nil {
+		return err
+	}
+	return a.out.prompt(updated)
+}
+
+// runTag 不带参数时列出所有标签；带ID时添加（+名称 或 名称）或移除（-名称）提示词的标签
+func runTag(a *app, args []string) error {
+	if len(args) == 0 {
+		tags, err := a.client.ListTags(a.ctx)
+		if err != nil {
+			return err
+		}
+		return a.out.tags(tags)
+	}
+
+	id, err := promptID(args[:1])
+	if err != nil {
+		return err
+	}
+	prompt, err := a.client.GetPrompt(a.ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		tags := make([]models.TagResponse, len(prompt.Tags))
+		for i, tag := range prompt.Tags {
+			tags[i] = tag.ToResponse()
+		}
+		return a.out.tags(tags)
+	}
+
+	names := applyTagChanges(tagNames(prompt.Tags), args[1:])
+	updated, err := a.client.PatchPrompt(a.ctx, id, prompt.Version, map[string]interface{}{"tag_names": names})
+	if err != nil {
+		return err
+	}
+	return a.out.prompt(updated)
+}
+
+// applyTagChanges 按 +名称 / -名称 修改标签列表，保持原有顺序
+func applyTagChanges(current []string, changes []string) []string {
+	names := append([]string{}, current...)
+	for _, change := range changes {
+		switch {
+		case strings.HasPrefix(change, "-"):
+			name := strings.TrimSpace(change[1:])
+			kept := names[:0]
+			for _, existing := range names {
+				if existing != name {
+					kept = append(kept, existing)
+				}
+			}
+			names = kept
+		default:
+			name := strings.TrimSpace(strings.TrimPrefix(change, "+"))
+			if name != "" && !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// runExport 导出提示词到文件或标准输出
+func runExport(a *app, args []string) error {
+	fs := newFlagSet("export")
+	var tags stringList
+	format := fs.String("format", models.ExportFormatJSONL, "导出格式：jsonl | csv | markdown | txt | civitai")
+	zip := fs.Bool("zip", false, "打包为zip并附带引用的本地图片")
+	file := fs.String("file", "", "输出文件，默认写到标准输出")
+	keyword := fs.String("keyword", "", "关键词")
+	fs.Var(&tags, "tags", "标签，逗号分隔或重复指定")
+	model := fs.String("model", "", "模型名称")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = a.out.w
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("创建文件失败: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	query := &models.ExportQuery{Format: *format, Zip: *zip, Keyword: *keyword, TagNames: tags, ModelName: *model}
+	if err := a.client.ExportPrompts(a.ctx, query, w); err != nil {
+		if *file != "" {
+			os.Remove(*file)
+		}
+		return err
+	}
+	if *file != "" {
+		fmt.Fprintf(os.Stderr, "✅ 已导出到 %s\n", *file)
+	}
+	return nil
+}
+
+// runAnalyze 由AI分析图片和提示词，指定 -apply 时将结果写入已有的提示词
+func runAnalyze(a *app, args []string) error {
+	fs := newFlagSet("analyze")
+	var inputs, references stringList
+	image := fs.String("image", "", "输出图片（生成结果）文件，必填")
+	fs.Var(&inputs, "input", "输入图片文件，可重复指定")
+	fs.Var(&references, "ref", "参考图片文件，可重复指定")
+	model := fs.String("model", "", "模型名称")
+	apply := fs.Uint("apply", 0, "将分析结果写入该ID的提示词（追加标签）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *image == "" {
+		return errors.New("请使用 -image 指定输出图片")
+	}
+	text, err := promptText(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	result, err := a.client.Analyze(a.ctx, &models.AnalyzePromptRequest{PromptText: text, ModelName: *model},
+		promptFiles(*image, inputs, references))
+	if err != nil {
+		return err
+	}
+	if *apply == 0 {
+		return a.out.analysis(result)
+	}
+
+	prompt, err := a.client.GetPrompt(a.ctx, uint(*apply))
+	if err != nil {
+		return err
+	}
+	names := tagNames(prompt.Tags)
+	for _, name := range result.TagNames {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	patch := map[string]interface{}{
+		"negative_prompt":        result.NegativePrompt,
+		"style_description":      result.StyleDescription,
+		"usage_scenario":         result.UsageScenario,
+		"atmosphere_description": result.AtmosphereDescription,
+		"expressive_intent":      result.ExpressiveIntent,
+		"tag_names":              names,
+	}
+	updated, err := a.client.PatchPrompt(a.ctx, prompt.ID, prompt.Version, patch)
+	if err != nil {
+		return err
+	}
+	return a.out.prompt(updated)
+}
+
+// promptFiles 由命令行指定的路径生成上传的文件
+func promptFiles(image string, inputs, references []string) *client.PromptFiles {
+	files := &client.PromptFiles{}
+	if image != "" {
+		files.OutputImage = &client.File{Name: image}
+	}
+	for _, path := range inputs {
+		files.InputImages = append(files.InputImages, client.File{Name: path})
+	}
+	for _, path := range references {
+		files.ReferenceImages = append(files.ReferenceImages, client.File{Name: path})
+	}
+	return files
+}
+
+// promptText 由参数拼接提示词，参数为 - 时从标准输入读取
+func promptText(args []string) (string, error) {
+	if len(args) == 1 && args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("读取标准输入失败: %v", err)
+		}
+		args = []string{string(data)}
+	}
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" {
+		return "", errors.New("请提供提示词（使用 - 从标准输入读取）")
+	}
+	return text, nil
+}
+
+// promptID 解析唯一的ID参数
+func promptID(args []string) (uint, error) {
+	if len(args) != 1 {
+		return 0, errors.New("请提供一个提示词ID")
+	}
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("无效的ID: %s", args[0])
+	}
+	return uint(id), nil
+}
+
+// containsString 判断列表中是否包含 s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
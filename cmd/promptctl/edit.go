@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// editableDocument 在编辑器中修改的字段，与服务端 JSON Merge Patch 支持的字段一致
+func editableDocument(prompt *models.PromptResponse) map[string]interface{} {
+	doc := map[string]interface{}{
+		"prompt_text":            prompt.PromptText,
+		"negative_prompt":        prompt.NegativePrompt,
+		"model_name":             prompt.ModelName,
+		"is_public":              prompt.IsPublic,
+		"style_description":      prompt.StyleDescription,
+		"usage_scenario":         prompt.UsageScenario,
+		"atmosphere_description": prompt.AtmosphereDescription,
+		"expressive_intent":      prompt.ExpressiveIntent,
+		"tag_names":              tagNames(prompt.Tags),
+		"structure_analysis":     rawOrEmpty(prompt.StructureAnalysis),
+		"generation_params":      rawOrEmpty(prompt.GenerationParams),
+	}
+	return doc
+}
+
+// rawOrEmpty 空的JSON字段显示为 {}
+func rawOrEmpty(data json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(data)) == 0 || string(data) == "null" {
+		return json.RawMessage("{}")
+	}
+	return data
+}
+
+// mergePatch 比较编辑前后的文档，生成 JSON Merge Patch：修改的字段为新值，删除的字段为 null
+// 未修改时返回空的补丁
+func mergePatch(before, after map[string]interface{}) (map[string]interface{}, error) {
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			return nil, fmt.Errorf("不支持修改字段 %s", key)
+		}
+	}
+	b, err := normalizeJSON(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := normalizeJSON(after)
+	if err != nil {
+		return nil, err
+	}
+	return diffObjects(b.(map[string]interface{}), a.(map[string]interface{})), nil
+}
+
+// diffObjects 生成从 before 到 after 的合并补丁
+// 服务端会递归合并对象，所以前后都是对象的字段（如 generation_params）也要递归比较，
+// 否则嵌套对象中删除的键不会被删除
+func diffObjects(before, after map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, value := range after {
+		old, ok := before[key]
+		if ok && reflect.DeepEqual(old, value) {
+			continue
+		}
+		oldObject, oldIsObject := old.(map[string]interface{})
+		newObject, newIsObject := value.(map[string]interface{})
+		if ok && oldIsObject && newIsObject {
+			patch[key] = diffObjects(oldObject, newObject)
+			continue
+		}
+		patch[key] = value
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// normalizeJSON 经过一次JSON编码和解码，使值只包含 map、[]interface{} 和基本类型，比较时与格式和键顺序无关
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// editInEditor 将文档写入临时文件并用编辑器打开，返回修改后的文档
+// 编辑器依次取 $VISUAL、$EDITOR，都未设置时使用 vi（Windows 为 notepad）
+func editInEditor(doc map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "promptctl-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	file.Close()
+
+	for {
+		if err := runEditor(file.Name()); err != nil {
+			return nil, err
+		}
+		edited, err := os.ReadFile(file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("读取临时文件失败: %v", err)
+		}
+		var after map[string]interface{}
+		if err := json.Unmarshal(edited, &after); err == nil {
+			return after, nil
+		} else if !confirm(fmt.Sprintf("JSON格式错误: %v，重新编辑？(Y/n): ", err), true) {
+			return nil, fmt.Errorf("JSON格式错误: %v", err)
+		}
+	}
+}
+
+// runEditor 运行编辑器，编辑器命令可以带参数（如 "code --wait"）
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("运行编辑器 %s 失败: %v", editor, err)
+	}
+	return nil
+}
+
+// confirm 询问用户，直接回车时返回 defaultYes
+func confirm(question string, defaultYes bool) bool {
+	fmt.Fprint(os.Stderr, question)
+	var answer string
+	fmt.Scanln(&answer)
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "":
+		return defaultYes
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,163 @@
+// promptctl 是图像生成提示词管理系统的命令行客户端，通过 HTTP API 操作运行中的服务
+//
+// 连接配置从 ~/.promptctl（或 $PROMPTCTL_CONFIG）读取，详见 loadProfile。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"imgGeneratePrompts/client"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+// app 一次命令执行的上下文
+type app struct {
+	ctx     context.Context
+	client  *client.Client
+	out     *printer
+	profile *profile
+}
+
+// command 子命令
+type command struct {
+	usage       string
+	description string
+	run         func(a *app, args []string) error
+}
+
+// commands 所有子命令（在 init 中初始化，避免与使用它的 newFlagSet 形成初始化循环）
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"add":     {"add [选项] <提示词>", "创建提示词，可上传输出图片和参考图片", runAdd},
+		"search":  {"search [选项] [关键词]", "按关键词、标签、模型搜索提示词", runSearch},
+		"show":    {"show <ID>", "查看提示词详情", runShow},
+		"edit":    {"edit <ID>", "在 $EDITOR 中编辑提示词", runEdit},
+		"tag":     {"tag [<ID> [+标签|-标签|标签]...]", "不带参数时列出所有标签；否则查看、添加(+)或移除(-)提示词的标签", runTag},
+		"export":  {"export [选项]", "按过滤条件导出提示词（jsonl、csv、markdown、txt、civitai）", runExport},
+		"analyze": {"analyze [选项] <提示词>", "由AI分析图片和提示词", runAnalyze},
+	}
+}
+
+func main() {
+	var (
+		configPath  = flag.String("config", defaultConfigPath(), "配置文件路径（也可用 $"+configEnv+" 指定）")
+		profileName = flag.String("profile", "", "使用的配置名称（默认 $"+profileEnv+" 或 default）")
+		server      = flag.String("server", "", "服务地址，覆盖配置文件")
+		token       = flag.String("token", "", "访问令牌（JWT 或 igp_ 开头的API密钥），覆盖配置文件")
+		output      = flag.String("o", "", "输出格式：table | json（默认使用配置文件中的 output，否则为 table）")
+	)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	p, err := loadProfile(*configPath, *profileName)
+	if err != nil {
+		fatal(err)
+	}
+	if *server != "" {
+		p.Server = *server
+	}
+	if *token != "" {
+		p.Token = *token
+	}
+	format := *output
+	if format == "" {
+		format = p.Output
+	}
+	if format == "" {
+		format = outputTable
+	}
+	if format != outputTable && format != outputJSON {
+		fatal(fmt.Errorf("输出格式应为 table 或 json"))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	a := &app{
+		ctx:     ctx,
+		client:  client.New(p.Server, client.WithToken(p.Token), client.WithUserAgent("promptctl/1.0")),
+		out:     &printer{w: os.Stdout, format: format},
+		profile: p,
+	}
+	if err := cmd.run(a, flag.Args()[1:]); err != nil {
+		fatal(err)
+	}
+}
+
+// usage 打印帮助信息
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "promptctl - 图像生成提示词管理命令行工具")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "用法:")
+	fmt.Fprintf(w, "  %s [全局选项] <命令> [参数]\n", os.Args[0])
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "命令:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-40s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "全局选项:")
+	flag.PrintDefaults()
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "配置文件示例（~/.promptctl）:")
+	fmt.Fprintln(w, "  [default]")
+	fmt.Fprintln(w, "  server = http://localhost:8080")
+	fmt.Fprintln(w, "  token = igp_xxxxxxxx")
+	fmt.Fprintln(w, "  output = table")
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "使用 %s <命令> -h 查看命令的选项\n", os.Args[0])
+}
+
+// newFlagSet 创建子命令的参数解析器
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		cmd := commands[name]
+		fmt.Fprintf(fs.Output(), "用法: promptctl %s\n\n%s\n", cmd.usage, cmd.description)
+		if hasFlags(fs) {
+			fmt.Fprintln(fs.Output(), "\n选项:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// hasFlags 判断参数解析器是否定义了选项
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// fatal 打印错误并退出
+func fatal(err error) {
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	if client.IsUnauthorized(err) {
+		fmt.Fprintln(os.Stderr, "请在配置文件中设置 token，或使用 -token 指定访问令牌")
+	}
+	os.Exit(1)
+}
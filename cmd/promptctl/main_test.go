@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseProfiles 测试配置文件解析
+func TestParseProfiles(t *testing.T) {
+	profiles, err := parseProfiles(strings.NewReader(`
+# 段之前的配置属于 default
+server = http://localhost:8080
+
+[prod]
+server = "https://prompts.example.com"
+token = igp_abc ; 不是注释
+output = json
+
+[prod]
+; 重复的段合并
+output = table
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", profiles["default"].Server)
+	assert.Equal(t, "https://prompts.example.com", profiles["prod"].Server, "值两侧的引号应被去掉")
+	assert.Equal(t, "igp_abc ; 不是注释", profiles["prod"].Token)
+	assert.Equal(t, "table", profiles["prod"].Output)
+
+	_, err = parseProfiles(strings.NewReader("[default]\nuser = x\n"))
+	assert.ErrorContains(t, err, "第 2 行: 未知的配置项 user")
+	_, err = parseProfiles(strings.NewReader("output = yaml\n"))
+	assert.ErrorContains(t, err, "output 应为 table 或 json")
+	_, err = parseProfiles(strings.NewReader("[ ]\n"))
+	assert.ErrorContains(t, err, "配置名称为空")
+	_, err = parseProfiles(strings.NewReader("server\n"))
+	assert.ErrorContains(t, err, "key = value")
+}
+
+// TestMergePatch 测试编辑前后文档的差异
+func TestMergePatch(t *testing.T) {
+	before := map[string]interface{}{
+		"prompt_text":       "a cat",
+		"tag_names":         []string{"猫"},
+		"is_public":         false,
+		"generation_params": json.RawMessage(`{"steps": 30, "cfg": 7}`),
+	}
+	after := map[string]interface{}{
+		"prompt_text":       "a cat",
+		"tag_names":         []interface{}{"猫", "动物"},
+		"generation_params": map[string]interface{}{"cfg": 7.0, "steps": 30.0},
+	}
+	patch, err := mergePatch(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tag_names": []interface{}{"猫", "动物"},
+		"is_public": nil,
+	}, patch, "未修改的字段（与格式和键顺序无关）不提交，删除的字段为 null")
+
+	// 嵌套对象中删除的键为 null，未修改的键不提交
+	after = map[string]interface{}{
+		"prompt_text":       "a cat",
+		"tag_names":         []interface{}{"猫"},
+		"is_public":         false,
+		"generation_params": map[string]interface{}{"steps": 20.0},
+	}
+	patch, err = mergePatch(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"generation_params": map[string]interface{}{"steps": 20.0, "cfg": nil},
+	}, patch)
+
+	patch, err = mergePatch(before, before)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	_, err = mergePatch(before, map[string]interface{}{"id": 1})
+	assert.ErrorContains(t, err, "不支持修改字段 id")
+}
+
+// TestApplyTagChanges 测试 +标签 / -标签 参数
+func TestApplyTagChanges(t *testing.T) {
+	current := []string{"猫", "风景"}
+	assert.Equal(t, []string{"风景", "动物", "夜晚"}, applyTagChanges(current, []string{"-猫", "+动物", "夜晚", "动物"}))
+	assert.Equal(t, []string{"猫", "风景"}, current, "不修改原列表")
+	assert.Equal(t, []string{"猫", "风景"}, applyTagChanges(current, []string{"-不存在", "+"}))
+}
+
+// TestTruncate 测试单行截断
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "a b", truncate("a\n  b", 10))
+	assert.Equal(t, "一只猫…", truncate("一只猫在屋顶上", 4))
+	assert.Equal(t, "一只猫在", truncate("一只猫在", 4))
+}
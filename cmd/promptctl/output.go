@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// 输出格式
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer 按输出格式打印结果
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// json 以缩进的JSON打印
+func (p *printer) json(v interface{}) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// table 打印表格，第一行为表头
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// fields 打印 名称: 值 形式的详情，值为空的行省略
+func (p *printer) fields(pairs [][2]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	for _, pair := range pairs {
+		if pair[1] != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", pair[0], pair[1])
+		}
+	}
+	return tw.Flush()
+}
+
+// prompts 打印提示词列表
+func (p *printer) prompts(prompts []models.PromptResponse, total int64) error {
+	if p.format == outputJSON {
+		return p.json(map[string]interface{}{"items": prompts, "total": total})
+	}
+	rows := make([][]string, len(prompts))
+	for i, prompt := range prompts {
+		rows[i] = []string{
+			fmt.Sprint(prompt.ID),
+			prompt.ModelName,
+			yesNo(prompt.IsPublic),
+			strings.Join(tagNames(prompt.Tags), ","),
+			truncate(prompt.PromptText, 60),
+		}
+	}
+	if err := p.table([]string{"ID", "模型", "公开", "标签", "提示词"}, rows); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(p.w, "\n共 %d 条，显示 %d 条\n", total, len(prompts))
+	return err
+}
+
+// prompt 打印提示词详情
+func (p *printer) prompt(prompt *models.PromptResponse) error {
+	if p.format == outputJSON {
+		return p.json(prompt)
+	}
+	var images []string
+	for _, image := range prompt.Images {
+		images = append(images, image.Role+" "+image.URL)
+	}
+	return p.fields([][2]string{
+		{"ID", fmt.Sprint(prompt.ID)},
+		{"版本", fmt.Sprint(prompt.Version)},
+		{"提示词", prompt.PromptText},
+		{"负面提示词", prompt.NegativePrompt},
+		{"模型", prompt.ModelName},
+		{"公开", yesNo(prompt.IsPublic)},
+		{"标签", strings.Join(tagNames(prompt.Tags), ", ")},
+		{"风格", prompt.StyleDescription},
+		{"使用场景", prompt.UsageScenario},
+		{"氛围", prompt.AtmosphereDescription},
+		{"表达意图", prompt.ExpressiveIntent},
+		{"结构分析", compactJSON(prompt.StructureAnalysis)},
+		{"生成参数", compactJSON(prompt.GenerationParams)},
+		{"图片", strings.Join(images, "\n\t")},
+		{"创建时间", prompt.CreatedAt.Format("2006-01-02 15:04:05")},
+	})
+}
+
+// tags 打印标签列表
+func (p *printer) tags(tags []models.TagResponse) error {
+	if p.format == outputJSON {
+		return p.json(tags)
+	}
+	rows := make([][]string, len(tags))
+	for i, tag := range tags {
+		rows[i] = []string{fmt.Sprint(tag.ID), tag.Name, tag.CreatedAt.Format("2006-01-02")}
+	}
+	return p.table([]string{"ID", "名称", "创建时间"}, rows)
+}
+
+// analysis 打印AI分析结果
+func (p *printer) analysis(result *models.AnalyzePromptResponse) error {
+	if p.format == outputJSON {
+		return p.json(result)
+	}
+	return p.fields([][2]string{
+		{"负面提示词", result.NegativePrompt},
+		{"风格", result.StyleDescription},
+		{"使用场景", result.UsageScenario},
+		{"氛围", result.AtmosphereDescription},
+		{"表达意图", result.ExpressiveIntent},
+		{"结构分析", result.StructureAnalysis},
+		{"标签", strings.Join(result.TagNames, ", ")},
+	})
+}
+
+// tagNames 标签名称列表
+func tagNames(tags []*models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// truncate 将单行显示的文本截断为最多 n 个字符
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// compactJSON 单行显示JSON，空对象不显示
+func compactJSON(data json.RawMessage) string {
+	s := strings.TrimSpace(string(data))
+	if s == "" || s == "null" || s == "{}" {
+		return ""
+	}
+	return s
+}
+
+// yesNo 布尔值显示为 是/否
+func yesNo(b bool) string {
+	if b {
+		return "是"
+	}
+	return "否"
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 配置文件和环境变量
+const (
+	defaultProfile = "default"
+	defaultServer  = "http://localhost:8080"
+	configEnv      = "PROMPTCTL_CONFIG"  // 配置文件路径，默认 ~/.promptctl
+	profileEnv     = "PROMPTCTL_PROFILE" // 使用的配置名称
+	serverEnv      = "PROMPTCTL_SERVER"  // 覆盖配置中的服务地址
+	tokenEnv       = "PROMPTCTL_TOKEN"   // 覆盖配置中的访问令牌
+)
+
+// profile 一组连接配置，对应配置文件中的一个 [名称] 段
+type profile struct {
+	Name   string
+	Server string // 服务地址
+	Token  string // JWT 访问令牌或以 igp_ 开头的API密钥
+	Output string // 默认输出格式：table 或 json
+}
+
+// defaultConfigPath 默认的配置文件路径
+func defaultConfigPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".promptctl"
+	}
+	return filepath.Join(home, ".promptctl")
+}
+
+// loadProfile 从配置文件读取指定的配置，文件不存在时使用默认值；环境变量优先于配置文件
+//
+// 配置文件格式：
+//
+//	[default]
+//	server = http://localhost:8080
+//	token = igp_xxx
+//	output = table
+func loadProfile(path, name string) (*profile, error) {
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+
+	p := &profile{Name: name}
+	file, err := os.Open(path)
+	switch {
+	case err == nil:
+		defer file.Close()
+		profiles, err := parseProfiles(file)
+		if err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+		}
+		found, ok := profiles[name]
+		if !ok && name != defaultProfile {
+			return nil, fmt.Errorf("配置文件 %s 中没有配置 [%s]", path, name)
+		}
+		if ok {
+			p = found
+		}
+		if info, err := file.Stat(); err == nil && p.Token != "" && info.Mode().Perm()&0o077 != 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  配置文件 %s 包含访问令牌，建议执行 chmod 600 %s\n", path, path)
+		}
+	case os.IsNotExist(err):
+		if name != defaultProfile {
+			return nil, fmt.Errorf("配置文件 %s 不存在，无法使用配置 [%s]", path, name)
+		}
+	default:
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	if server := os.Getenv(serverEnv); server != "" {
+		p.Server = server
+	}
+	if token := os.Getenv(tokenEnv); token != "" {
+		p.Token = token
+	}
+	if p.Server == "" {
+		p.Server = defaultServer
+	}
+	return p, nil
+}
+
+// parseProfiles 解析配置文件，# 或 ; 开头的行为注释，段之前的配置属于 [default]
+func parseProfiles(r io.Reader) (map[string]*profile, error) {
+	profiles := make(map[string]*profile)
+	current := &profile{Name: defaultProfile}
+	profiles[defaultProfile] = current
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if name == "" {
+				return nil, fmt.Errorf("第 %d 行: 配置名称为空", lineNo)
+			}
+			if profiles[name] == nil {
+				profiles[name] = &profile{Name: name}
+			}
+			current = profiles[name]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("第 %d 行: 应为 key = value 格式", lineNo)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "server":
+			current.Server = value
+		case "token":
+			current.Token = value
+		case "output":
+			if value != outputTable && value != outputJSON {
+				return nil, fmt.Errorf("第 %d 行: output 应为 table 或 json", lineNo)
+			}
+			current.Output = value
+		default:
+			return nil, fmt.Errorf("第 %d 行: 未知的配置项 %s", lineNo, strings.TrimSpace(key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
@@ -2,15 +2,23 @@ package config
 
 import (
 	"bufio"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config 应用配置结构
 type Config struct {
 	Database DatabaseConfig
 	Server   ServerConfig
+	Auth     AuthConfig
+	Jobs     JobConfig
+	Webhooks WebhookConfig
 }
 
 // DatabaseConfig 数据库配置
@@ -28,6 +36,42 @@ type ServerConfig struct {
 	Port        string
 	UploadPath  string
 	MaxFileSize int64 // 最大文件大小（字节）
+
+	// 下载远程图片（fetch_remote）的限制，大小限制与 MaxFileSize 相同
+	RemoteFetchTimeout      time.Duration // 单张图片的超时
+	RemoteFetchMaxRedirects int           // 最大重定向次数
+	RemoteFetchAllowPrivate bool          // 允许访问内网地址（REMOTE_FETCH_ALLOW_PRIVATE=true），默认禁止以防SSRF
+	RemoteFetchMaxImages    int           // 单个请求最多下载的远程图片数量（相同URL只计一次）
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	JWTSecret       string        // JWT签名密钥
+	URLSigningKey   string        // 分享图片签名URL的密钥，与JWT密钥相互独立
+	AccessTokenTTL  time.Duration // 访问令牌有效期
+	RefreshTokenTTL time.Duration // 刷新令牌有效期
+	AnonymousRole   string        // 未登录请求视为的角色，为空表示匿名请求只读
+}
+
+// JobConfig 后台任务配置
+type JobConfig struct {
+	Workers      int           // 并发执行任务的worker数量，0表示不启动
+	MaxAttempts  int           // 默认最大尝试次数
+	RetryBackoff time.Duration // 首次重试的等待时间，之后每次翻倍
+	MaxBackoff   time.Duration // 重试等待时间上限
+	PollInterval time.Duration // 没有可执行任务时的轮询间隔
+	Timeout      time.Duration // 单次执行的超时，超时未完成的任务会被其他worker重新领取
+}
+
+// WebhookConfig 事件推送配置
+type WebhookConfig struct {
+	Workers      int           // 并发发送的worker数量，0表示不发送（推送记录仍会保存）
+	MaxAttempts  int           // 每条推送的最大尝试次数
+	RetryBackoff time.Duration // 首次重试的等待时间，之后每次翻倍
+	MaxBackoff   time.Duration // 重试等待时间上限
+	PollInterval time.Duration // 没有待发送推送时的轮询间隔
+	Timeout      time.Duration // 单次请求的超时
+	AllowPrivate bool          // 允许推送到内网地址（WEBHOOK_ALLOW_PRIVATE=true），默认禁止以防SSRF
 }
 
 var AppConfig *Config
@@ -48,12 +92,85 @@ func LoadConfig() error {
 		Port:        ":8080",
 		UploadPath:  "./uploads",
 		MaxFileSize: 10 << 20, // 10MB
+
+		RemoteFetchTimeout:      15 * time.Second,
+		RemoteFetchMaxRedirects: 3,
+		RemoteFetchAllowPrivate: strings.EqualFold(strings.TrimSpace(os.Getenv("REMOTE_FETCH_ALLOW_PRIVATE")), "true"),
+		RemoteFetchMaxImages:    10,
+	}
+
+	// 设置认证配置
+	config.Auth = AuthConfig{
+		JWTSecret:       loadSecret("JWT_SECRET", "已签发的令牌"),
+		URLSigningKey:   loadSecret("URL_SIGNING_SECRET", "已生成的图片签名URL"),
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 7 * 24 * time.Hour,
+		AnonymousRole:   loadAnonymousRole(),
+	}
+
+	// 设置后台任务配置
+	config.Jobs = JobConfig{
+		Workers:      loadJobWorkers(),
+		MaxAttempts:  3,
+		RetryBackoff: 5 * time.Second,
+		MaxBackoff:   5 * time.Minute,
+		PollInterval: time.Second,
+		Timeout:      2 * time.Minute,
+	}
+
+	// 设置事件推送配置
+	config.Webhooks = WebhookConfig{
+		Workers:      2,
+		MaxAttempts:  5,
+		RetryBackoff: 10 * time.Second,
+		MaxBackoff:   time.Hour,
+		PollInterval: time.Second,
+		Timeout:      10 * time.Second,
+		AllowPrivate: strings.EqualFold(strings.TrimSpace(os.Getenv("WEBHOOK_ALLOW_PRIVATE")), "true"),
 	}
 
 	AppConfig = config
 	return nil
 }
 
+// loadJobWorkers 从环境变量 JOB_WORKERS 读取后台任务的worker数量，默认2
+func loadJobWorkers() int {
+	value := strings.TrimSpace(os.Getenv("JOB_WORKERS"))
+	if value == "" {
+		return 2
+	}
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers < 0 {
+		log.Printf("警告：JOB_WORKERS=%q 无效，使用默认值2", value)
+		return 2
+	}
+	return workers
+}
+
+// loadSecret 从环境变量读取签名密钥
+// 未设置时随机生成，服务重启后用旧密钥签名的内容（usage）将全部失效
+func loadSecret(env, usage string) string {
+	if secret := strings.TrimSpace(os.Getenv(env)); secret != "" {
+		return secret
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("生成 %s 密钥失败: %v", env, err)
+	}
+	log.Printf("警告：未设置 %s 环境变量，使用随机生成的密钥，重启后%s将失效", env, usage)
+	return hex.EncodeToString(buf)
+}
+
+// loadAnonymousRole 从环境变量 ANONYMOUS_ROLE 读取匿名请求的角色
+// 默认为viewer，未登录只能浏览；设为editor可恢复未登录即可创建和编辑的旧行为
+func loadAnonymousRole() string {
+	if role := strings.TrimSpace(os.Getenv("ANONYMOUS_ROLE")); role != "" {
+		return role
+	}
+	return "viewer"
+}
+
 // loadDatabaseConfig 从apikey目录加载数据库配置 (已优化)
 func loadDatabaseConfig() (*DatabaseConfig, error) {
 	// 定义可能的配置文件路径
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"imgGeneratePrompts/migrations"
 	"imgGeneratePrompts/models"
 	"log"
 	"time"
@@ -24,14 +25,26 @@ func InitDBWithMigration() error {
 		return err
 	}
 
-	// 自动迁移数据表
-	if err := autoMigrate(); err != nil {
+	// 执行版本化迁移
+	if err := migrate(); err != nil {
 		return fmt.Errorf("数据表迁移失败: %v", err)
 	}
 
 	return nil
 }
 
+// InitDBWithoutSeed 初始化数据库连接并执行迁移，但不插入初始标签
+// 用于把备份恢复到空数据库，初始标签会与备份中的标签ID冲突
+func InitDBWithoutSeed() error {
+	if err := connectToDatabase(); err != nil {
+		return err
+	}
+	if err := runMigrations(); err != nil {
+		return fmt.Errorf("数据表迁移失败: %v", err)
+	}
+	return nil
+}
+
 // InitDBWithoutMigration 初始化数据库连接但不执行迁移
 func InitDBWithoutMigration() error {
 	return connectToDatabase()
@@ -72,20 +85,12 @@ func connectToDatabase() error {
 	return nil
 }
 
-// autoMigrate 自动迁移数据表
-func autoMigrate() error {
-	// 按顺序迁移所有模型
-	err := DB.AutoMigrate(
-		&models.Tag{},    // 先迁移标签表
-		&models.Prompt{}, // 再迁移提示词表（包含外键关系）
-	)
-
-	if err != nil {
-		return fmt.Errorf("自动迁移失败: %v", err)
+// migrate 执行所有未执行的版本化迁移（见 migrations 包），然后插入初始数据
+func migrate() error {
+	if err := runMigrations(); err != nil {
+		return err
 	}
 
-	log.Println("数据表迁移完成")
-
 	// 插入初始数据
 	if err := insertInitialData(); err != nil {
 		log.Printf("插入初始数据失败: %v", err)
@@ -95,6 +100,17 @@ func autoMigrate() error {
 	return nil
 }
 
+// runMigrations 执行所有未执行的版本化迁移
+func runMigrations() error {
+	applied, err := migrations.NewMigrator(DB).Up()
+	if err != nil {
+		return fmt.Errorf("执行迁移失败: %v", err)
+	}
+
+	log.Printf("数据表迁移完成，本次执行 %d 个迁移，当前版本 %d", applied, migrations.Latest())
+	return nil
+}
+
 // insertInitialData 插入初始数据
 func insertInitialData() error {
 	// 检查是否已有数据
@@ -191,12 +207,12 @@ func ResetDatabase() error {
 	log.Println("警告：正在重置数据库，所有数据将被删除！")
 
 	// 删除所有表
-	if err := DB.Migrator().DropTable(&models.Prompt{}, &models.Tag{}); err != nil {
+	if err := DB.Migrator().DropTable(&models.WebhookDelivery{}, &models.Webhook{}, &models.Job{}, &models.ShareLink{}, &models.PromptImage{}, &models.CollectionItem{}, &models.Collection{}, "prompt_tags", &models.Prompt{}, &models.Tag{}, &models.Wildcard{}, &models.APIKey{}, &models.User{}, &migrations.SchemaMigration{}); err != nil {
 		return fmt.Errorf("删除表失败: %v", err)
 	}
 
-	// 重新创建表
-	if err := autoMigrate(); err != nil {
+	// 重新执行全部迁移
+	if err := migrate(); err != nil {
 		return fmt.Errorf("重新创建表失败: %v", err)
 	}
 
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController 个人API密钥控制器
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyController 创建API密钥控制器实例
+func NewAPIKeyController() *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: services.NewAPIKeyService(),
+	}
+}
+
+// CreateAPIKey 创建API密钥，明文密钥只在响应中出现这一次
+func (ac *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	apiKey, plainKey, err := ac.apiKeyService.CreateAPIKey(user.ID, &req)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "创建成功，请妥善保存密钥，它不会再次显示", models.CreateAPIKeyResponse{
+		APIKeyResponse: apiKey.ToResponse(),
+		Key:            plainKey,
+	})
+}
+
+// GetAPIKeys 获取当前用户的API密钥列表
+func (ac *APIKeyController) GetAPIKeys(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	keys, err := ac.apiKeyService.GetUserAPIKeys(user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	responses := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = keys[i].ToResponse()
+	}
+	utils.SuccessResponse(c, responses)
+}
+
+// RevokeAPIKey 吊销API密钥
+func (ac *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的API密钥ID")
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	apiKey, err := ac.apiKeyService.RevokeAPIKey(user.ID, uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "吊销成功", apiKey.ToResponse())
+}
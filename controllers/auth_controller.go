@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthController 用户认证控制器
+type AuthController struct {
+	authService *services.AuthService
+}
+
+// NewAuthController 创建用户认证控制器实例
+func NewAuthController() *AuthController {
+	return &AuthController{
+		authService: services.NewAuthService(),
+	}
+}
+
+// Register 注册新用户并直接返回令牌
+func (ac *AuthController) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	user, err := ac.authService.Register(&req)
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	tokens, err := ac.authService.IssueTokens(user)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "注册成功", tokens)
+}
+
+// Login 用户登录
+func (ac *AuthController) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	tokens, err := ac.authService.Login(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			utils.UnauthorizedResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "登录成功", tokens)
+}
+
+// RefreshToken 使用刷新令牌换取新的令牌对
+func (ac *AuthController) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	tokens, err := ac.authService.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "无效的刷新令牌: "+err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "刷新成功", tokens)
+}
+
+// Me 获取当前登录用户信息
+func (ac *AuthController) Me(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	utils.SuccessResponse(c, user.ToResponse())
+}
+
+// GetUsers 获取所有用户（需要用户管理权限）
+func (ac *AuthController) GetUsers(c *gin.Context) {
+	users, err := ac.authService.GetUsers()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i := range users {
+		responses[i] = users[i].ToResponse()
+	}
+	utils.SuccessResponse(c, responses)
+}
+
+// UpdateUserRole 修改用户角色（需要用户管理权限）
+func (ac *AuthController) UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的用户ID")
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	user, err := ac.authService.UpdateUserRole(uint(id), req.Role)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "角色修改成功", user.ToResponse())
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BulkController 提示词批量操作控制器
+type BulkController struct {
+	bulkService *services.BulkService
+}
+
+// NewBulkController 创建批量操作控制器实例
+func NewBulkController() *BulkController {
+	return &BulkController{
+		bulkService: services.NewBulkService(),
+	}
+}
+
+// BulkPrompts 对按ID列表或过滤条件选择的提示词执行批量操作
+// 支持添加/移除标签、设置公开状态、设置模型名称、删除、恢复和加入收藏集，dry_run=true 时只返回逐项结果
+func (bc *BulkController) BulkPrompts(c *gin.Context) {
+	var req models.BulkPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+	req.ViewerID = middleware.CurrentUserID(c)
+
+	result, err := bc.bulkService.BulkPrompts(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBulk):
+			utils.BadRequestResponse(c, err.Error())
+		case errors.Is(err, services.ErrCollectionNotFound):
+			utils.NotFoundResponse(c, err.Error())
+		case errors.Is(err, services.ErrCollectionForbidden):
+			utils.ForbiddenResponse(c, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c, err.Error())
+		}
+		return
+	}
+
+	message := "批量操作完成"
+	if req.DryRun {
+		message = "预演完成，未修改任何数据"
+	}
+	utils.SuccessWithMessage(c, message, result)
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CollectionController 收藏集控制器
+type CollectionController struct {
+	collectionService *services.CollectionService
+}
+
+// NewCollectionController 创建收藏集控制器实例
+func NewCollectionController() *CollectionController {
+	return &CollectionController{
+		collectionService: services.NewCollectionService(),
+	}
+}
+
+// CreateCollection 创建收藏集
+func (cc *CollectionController) CreateCollection(c *gin.Context) {
+	var req models.CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+	req.OwnerID = middleware.CurrentUserID(c)
+
+	collection, err := cc.collectionService.CreateCollection(&req)
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	detail, err := cc.collectionService.GetCollectionDetail(collection.ID, middleware.CurrentUserID(c))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "创建成功", detail)
+}
+
+// GetCollections 获取收藏集列表
+func (cc *CollectionController) GetCollections(c *gin.Context) {
+	var query models.CollectionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+	query.ViewerID = middleware.CurrentUserID(c)
+
+	collections, err := cc.collectionService.GetCollections(&query)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, collections)
+}
+
+// GetCollection 获取收藏集详情（包含有序条目）
+func (cc *CollectionController) GetCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := cc.collectionService.GetCollectionDetail(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, detail)
+}
+
+// UpdateCollection 更新收藏集
+func (cc *CollectionController) UpdateCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	if _, err := cc.collectionService.UpdateCollection(id, &req, middleware.CurrentUserID(c)); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	detail, err := cc.collectionService.GetCollectionDetail(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "更新成功", detail)
+}
+
+// DeleteCollection 删除收藏集
+func (cc *CollectionController) DeleteCollection(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	if err := cc.collectionService.DeleteCollection(id, middleware.CurrentUserID(c)); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// AddItems 向收藏集添加提示词
+func (cc *CollectionController) AddItems(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CollectionItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	if err := cc.collectionService.AddItems(id, req.PromptIDs, middleware.CurrentUserID(c)); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	detail, err := cc.collectionService.GetCollectionDetail(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "添加成功", detail)
+}
+
+// RemoveItem 从收藏集移除提示词
+func (cc *CollectionController) RemoveItem(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	promptID, err := strconv.ParseUint(c.Param("prompt_id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的提示词ID")
+		return
+	}
+
+	if err := cc.collectionService.RemoveItem(id, uint(promptID), middleware.CurrentUserID(c)); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "移除成功", nil)
+}
+
+// ReorderItems 重新排列收藏集条目
+func (cc *CollectionController) ReorderItems(c *gin.Context) {
+	id, ok := parseCollectionID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CollectionItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	if err := cc.collectionService.ReorderItems(id, req.PromptIDs, middleware.CurrentUserID(c)); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	detail, err := cc.collectionService.GetCollectionDetail(id, middleware.CurrentUserID(c))
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "排序成功", detail)
+}
+
+// GetPromptCollections 获取包含指定提示词的收藏集
+func (cc *CollectionController) GetPromptCollections(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	collections, err := cc.collectionService.GetCollectionsByPrompt(uint(id), middleware.CurrentUserID(c))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, collections)
+}
+
+// parseCollectionID 解析路径中的收藏集ID，失败时直接写入错误响应
+func parseCollectionID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondCollectionError 根据错误类型返回404、403、400或500
+func respondCollectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCollectionNotFound):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrCollectionForbidden):
+		utils.ForbiddenResponse(c, err.Error())
+	case errors.Is(err, services.ErrInvalidCollection),
+		errors.Is(err, services.ErrCollectionPromptNotFound),
+		errors.Is(err, services.ErrCollectionItemNotFound):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, err.Error())
+	}
+}
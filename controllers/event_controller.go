@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventHeartbeatInterval 没有事件时发送心跳注释的间隔，避免代理关闭空闲连接
+	eventHeartbeatInterval = 15 * time.Second
+	// eventRetryMillis 建议客户端断线后的重连间隔
+	eventRetryMillis = 3000
+)
+
+// EventController 资料库变更事件流控制器
+type EventController struct {
+	bus *services.EventBus
+}
+
+// NewEventController 创建事件流控制器实例
+func NewEventController() *EventController {
+	return &EventController{
+		bus: services.Events,
+	}
+}
+
+// Stream 以 Server-Sent Events 推送资料库变更事件，只推送当前用户可见的事件
+// 断线重连时根据 Last-Event-ID 请求头（或 last_event_id 查询参数）补发错过的事件；
+// 错过的事件已不在缓冲区中时先发送 stream.reset，客户端应重新拉取数据
+func (ec *EventController) Stream(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub, replay, complete := ec.bus.Subscribe(lastEventID)
+	defer sub.Close()
+
+	userID := middleware.CurrentUserID(c)
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // 关闭 Nginx 缓冲
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event models.Event) error {
+		if !event.IsVisibleTo(userID) {
+			return nil
+		}
+		return utils.WriteSSE(w, event.ID, event.Type, event)
+	}
+
+	if err := utils.WriteSSERetry(w, eventRetryMillis); err != nil {
+		return
+	}
+	if !complete {
+		reset := models.Event{ID: ec.bus.LastEventID(), Type: models.EventStreamReset, Time: time.Now()}
+		if err := utils.WriteSSE(w, reset.ID, reset.Type, reset); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// 订阅者过慢被断开，客户端重连后从 Last-Event-ID 继续
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := utils.WriteSSEComment(w, "ping"); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,586 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// GraphQL 错误码（errors[].extensions.code）
+const (
+	graphQLBadUserInput    = "BAD_USER_INPUT"
+	graphQLForbidden       = "FORBIDDEN"
+	graphQLNotFound        = "NOT_FOUND"
+	graphQLVersionConflict = "VERSION_CONFLICT"
+	graphQLInternalError   = "INTERNAL_SERVER_ERROR"
+)
+
+// GraphQLController GraphQL 控制器
+// 一次请求可以同时获取提示词、标签、图片和收藏集；关联数据通过按请求创建的 GraphQLLoader 批量加载，
+// 列表中的每个提示词不会各自查询一次
+type GraphQLController struct {
+	promptService     *services.PromptService
+	tagService        *services.TagService
+	collectionService *services.CollectionService
+	schema            graphql.Schema
+	sdl               string
+}
+
+// NewGraphQLController 创建 GraphQL 控制器实例
+func NewGraphQLController() *GraphQLController {
+	gc := &GraphQLController{
+		promptService:     services.NewPromptService(),
+		tagService:        services.NewTagService(),
+		collectionService: services.NewCollectionService(),
+	}
+	schema, err := gc.newGraphQLSchema()
+	if err != nil {
+		panic(fmt.Sprintf("创建 GraphQL schema 失败: %v", err))
+	}
+	gc.schema = schema
+	gc.sdl = utils.GraphQLSDL(&schema)
+	return gc
+}
+
+// graphQLState 一次 GraphQL 请求的状态：当前用户和批量加载器
+type graphQLState struct {
+	c           *gin.Context
+	viewerID    *uint
+	parents     *utils.GraphQLLoader[uint, *models.Prompt]
+	tags        *utils.GraphQLLoader[uint, []*models.Tag]
+	images      *utils.GraphQLLoader[uint, []*models.PromptImage]
+	collections *utils.GraphQLLoader[uint, []models.CollectionResponse]
+	forkCounts  *utils.GraphQLLoader[uint, int64]
+	tagCounts   *utils.GraphQLLoader[uint, int64]
+}
+
+// graphQLStateKey graphQLState 在 context 中的键
+type graphQLStateKey struct{}
+
+// newGraphQLState 创建请求状态，加载器只在本次请求内缓存
+func (gc *GraphQLController) newGraphQLState(c *gin.Context) *graphQLState {
+	viewerID := middleware.CurrentUserID(c)
+	return &graphQLState{
+		c:        c,
+		viewerID: viewerID,
+		parents: utils.NewGraphQLLoader(func(ids []uint) (map[uint]*models.Prompt, error) {
+			return gc.promptService.GetVisiblePromptsByIDs(ids, viewerID)
+		}),
+		tags:   utils.NewGraphQLLoader(gc.tagService.GetTagsByPromptIDs),
+		images: utils.NewGraphQLLoader(gc.promptService.GetImagesByPromptIDs),
+		collections: utils.NewGraphQLLoader(func(ids []uint) (map[uint][]models.CollectionResponse, error) {
+			return gc.collectionService.GetCollectionsByPromptIDs(ids, viewerID)
+		}),
+		forkCounts: utils.NewGraphQLLoader(func(ids []uint) (map[uint]int64, error) {
+			return gc.promptService.CountVisibleForksByPromptIDs(ids, viewerID)
+		}),
+		tagCounts: utils.NewGraphQLLoader(func(ids []uint) (map[uint]int64, error) {
+			return gc.tagService.CountVisiblePromptsByTagIDs(ids, viewerID)
+		}),
+	}
+}
+
+// maxGraphQLBodySize GraphQL 请求体的最大字节数
+const maxGraphQLBodySize = 64 << 10
+
+// Query 执行 GraphQL 请求
+// POST 请求体为 {"query", "operationName", "variables"}；GET 通过同名查询参数传入（variables 为 JSON 字符串），只能执行查询
+// 语法错误、校验失败和超出 utils.DefaultGraphQLLimits 的请求在执行前以400拒绝
+func (gc *GraphQLController) Query(c *gin.Context) {
+	var req utils.GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, graphQLErrorResponse("variables 不是有效的 JSON 对象"))
+				return
+			}
+		}
+	} else {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxGraphQLBodySize+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, graphQLErrorResponse("读取请求体失败"))
+			return
+		}
+		if len(body) > maxGraphQLBodySize {
+			c.JSON(http.StatusRequestEntityTooLarge, graphQLErrorResponse(fmt.Sprintf("请求体超过 %d 字节", maxGraphQLBodySize)))
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			c.JSON(http.StatusBadRequest, graphQLErrorResponse("请求数据格式错误: "+err.Error()))
+			return
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		c.JSON(http.StatusBadRequest, graphQLErrorResponse("缺少 query"))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": gqlerrors.FormatErrors(err)})
+		return
+	}
+	if result := graphql.ValidateDocument(&gc.schema, doc, nil); !result.IsValid {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": result.Errors})
+		return
+	}
+	operation, err := utils.GraphQLOperation(doc, req.OperationName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, graphQLErrorResponse(err.Error()))
+		return
+	}
+	if operation.Operation != ast.OperationTypeQuery && c.Request.Method == http.MethodGet {
+		c.Header("Allow", http.MethodPost)
+		c.JSON(http.StatusMethodNotAllowed, graphQLErrorResponse("变更操作只能通过 POST 请求执行"))
+		return
+	}
+	if err := utils.CheckGraphQLLimits(&gc.schema, doc, operation, req.Variables, utils.DefaultGraphQLLimits); err != nil {
+		c.JSON(http.StatusBadRequest, graphQLErrorResponse(err.Error()))
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphQLStateKey{}, gc.newGraphQLState(c))
+	c.JSON(http.StatusOK, graphql.Execute(graphql.ExecuteParams{
+		Schema:        gc.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	}))
+}
+
+// Schema 以 SDL 格式返回 GraphQL schema
+func (gc *GraphQLController) Schema(c *gin.Context) {
+	c.String(http.StatusOK, gc.sdl)
+}
+
+// ---------- 查询 ----------
+
+// resolvePrompt Query.prompt
+func (gc *GraphQLController) resolvePrompt(p graphql.ResolveParams) (interface{}, error) {
+	state := graphQLStateFrom(p)
+	if err := state.authorize(models.PermPromptRead); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, err := gc.promptService.GetVisiblePrompt(id, state.viewerID)
+	if errors.Is(err, services.ErrPromptNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, graphQLInternal(err)
+	}
+	state.prime(prompt)
+	return prompt, nil
+}
+
+// resolvePrompts Query.prompts
+func (gc *GraphQLController) resolvePrompts(p graphql.ResolveParams) (interface{}, error) {
+	state := graphQLStateFrom(p)
+	if err := state.authorize(models.PermPromptRead); err != nil {
+		return nil, err
+	}
+
+	page, _ := p.Args["page"].(int)
+	pageSize, _ := p.Args["page_size"].(int)
+	query := &models.PromptQuery{
+		Page:      page,
+		PageSize:  pageSize,
+		TagNames:  graphQLStrings(p.Args["tag_names"]),
+		ViewerID:  state.viewerID,
+		ModelName: graphQLString(p.Args["model_name"]),
+		Keyword:   graphQLString(p.Args["keyword"]),
+		SortBy:    graphQLString(p.Args["sort_by"]),
+		SortOrder: graphQLString(p.Args["sort_order"]),
+	}
+	if isPublic, ok := p.Args["is_public"].(bool); ok {
+		query.IsPublic = &isPublic
+	}
+	if query.Page < 1 {
+		return nil, utils.NewGraphQLError(graphQLBadUserInput, "page 必须大于0")
+	}
+	if query.PageSize < 1 || query.PageSize > 100 {
+		return nil, utils.NewGraphQLError(graphQLBadUserInput, "page_size 应在 1 到 100 之间")
+	}
+
+	// 只查询提示词本身，标签和图片等关联数据在客户端选择时批量加载
+	prompts, total, err := gc.promptService.GetPromptRows(query)
+	if err != nil {
+		return nil, graphQLInternal(err)
+	}
+	items := make([]*models.Prompt, len(prompts))
+	for i := range prompts {
+		items[i] = &prompts[i]
+	}
+	return utils.PaginationData{
+		Items:      items,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		Total:      total,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
+
+// resolveTag Query.tag
+func (gc *GraphQLController) resolveTag(p graphql.ResolveParams) (interface{}, error) {
+	if err := graphQLStateFrom(p).authorize(models.PermTagRead); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	tag, err := gc.tagService.GetTagByID(id)
+	if err != nil {
+		// GetTagByID 不区分不存在和查询失败，与 REST 接口一样视为不存在
+		return nil, nil
+	}
+	return tag, nil
+}
+
+// resolveTags Query.tags
+func (gc *GraphQLController) resolveTags(p graphql.ResolveParams) (interface{}, error) {
+	if err := graphQLStateFrom(p).authorize(models.PermTagRead); err != nil {
+		return nil, err
+	}
+	tags, err := gc.tagService.SearchTags(graphQLString(p.Args["keyword"]))
+	if err != nil {
+		return nil, graphQLInternal(err)
+	}
+	result := make([]*models.Tag, len(tags))
+	for i := range tags {
+		result[i] = &tags[i]
+	}
+	return result, nil
+}
+
+// ---------- 关联字段（批量加载） ----------
+
+// resolvePromptTags Prompt.tags
+func (gc *GraphQLController) resolvePromptTags(p graphql.ResolveParams) (interface{}, error) {
+	return graphQLStateFrom(p).tags.Load(p.Source.(*models.Prompt).ID), nil
+}
+
+// resolvePromptImages Prompt.images
+func (gc *GraphQLController) resolvePromptImages(p graphql.ResolveParams) (interface{}, error) {
+	return graphQLStateFrom(p).images.Load(p.Source.(*models.Prompt).ID), nil
+}
+
+// resolvePromptInputImageURLs Prompt.input_image_urls，由图片列表计算
+func (gc *GraphQLController) resolvePromptInputImageURLs(p graphql.ResolveParams) (interface{}, error) {
+	return gc.withImages(p, func(prompt *models.Prompt) interface{} {
+		return prompt.GetInputImageURLs()
+	}), nil
+}
+
+// resolvePromptOutputImageURL Prompt.output_image_url，由图片列表计算，没有输出图片时为 null
+func (gc *GraphQLController) resolvePromptOutputImageURL(p graphql.ResolveParams) (interface{}, error) {
+	return gc.withImages(p, func(prompt *models.Prompt) interface{} {
+		if url := prompt.GetOutputImageURL(); url != "" {
+			return url
+		}
+		return nil
+	}), nil
+}
+
+// withImages 批量加载图片后对带图片的提示词副本调用 fn
+func (gc *GraphQLController) withImages(p graphql.ResolveParams, fn func(prompt *models.Prompt) interface{}) utils.GraphQLThunk {
+	source := p.Source.(*models.Prompt)
+	load := graphQLStateFrom(p).images.Load(source.ID)
+	return func() (interface{}, error) {
+		images, err := load()
+		if err != nil {
+			return nil, err
+		}
+		prompt := *source
+		prompt.Images = images.([]*models.PromptImage)
+		return fn(&prompt), nil
+	}
+}
+
+// resolvePromptCollections Prompt.collections
+func (gc *GraphQLController) resolvePromptCollections(p graphql.ResolveParams) (interface{}, error) {
+	state := graphQLStateFrom(p)
+	if err := state.authorize(models.PermCollectionRead); err != nil {
+		return nil, err
+	}
+	return state.collections.Load(p.Source.(*models.Prompt).ID), nil
+}
+
+// resolvePromptParent Prompt.parent
+func (gc *GraphQLController) resolvePromptParent(p graphql.ResolveParams) (interface{}, error) {
+	prompt := p.Source.(*models.Prompt)
+	if prompt.ParentID == nil {
+		return nil, nil
+	}
+	return graphQLStateFrom(p).parents.Load(*prompt.ParentID), nil
+}
+
+// resolvePromptForkCount Prompt.fork_count
+func (gc *GraphQLController) resolvePromptForkCount(p graphql.ResolveParams) (interface{}, error) {
+	return graphQLStateFrom(p).forkCounts.Load(p.Source.(*models.Prompt).ID), nil
+}
+
+// resolveTagPromptCount Tag.prompt_count
+func (gc *GraphQLController) resolveTagPromptCount(p graphql.ResolveParams) (interface{}, error) {
+	return graphQLStateFrom(p).tagCounts.Load(p.Source.(*models.Tag).ID), nil
+}
+
+// ---------- 变更 ----------
+
+// resolveCreatePrompt Mutation.create_prompt，与 POST /api/v1/prompts 一致
+func (gc *GraphQLController) resolveCreatePrompt(p graphql.ResolveParams) (interface{}, error) {
+	state := graphQLStateFrom(p)
+	if err := state.authorizeWrite(models.PermPromptWrite); err != nil {
+		return nil, err
+	}
+	var req models.CreatePromptRequest
+	if err := decodeGraphQLInput(p.Args["input"], &req); err != nil {
+		return nil, err
+	}
+
+	// 登录用户创建的提示词归属于该用户
+	req.OwnerID = state.viewerID
+	req.Context = p.Context
+	prompt, err := gc.promptService.CreatePrompt(&req, "/uploads/placeholder.jpg")
+	if err != nil {
+		if errors.Is(err, utils.ErrRemoteImage) {
+			return nil, utils.NewGraphQLError(graphQLBadUserInput, err.Error())
+		}
+		return nil, graphQLInternal(err)
+	}
+	state.prime(prompt)
+	return prompt, nil
+}
+
+// resolveUpdatePrompt Mutation.update_prompt，与 PUT /api/v1/prompts/{id} 一致，if_version 对应 If-Match
+func (gc *GraphQLController) resolveUpdatePrompt(p graphql.ResolveParams) (interface{}, error) {
+	state := graphQLStateFrom(p)
+	if err := state.authorizeWrite(models.PermPromptWrite); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := gc.checkPromptModifiable(state, id); err != nil {
+		return nil, err
+	}
+
+	var req models.UpdatePromptRequest
+	if err := decodeGraphQLInput(p.Args["input"], &req); err != nil {
+		return nil, err
+	}
+	if version, ok := p.Args["if_version"].(int); ok {
+		if version < 1 {
+			return nil, utils.NewGraphQLError(graphQLBadUserInput, "if_version 必须大于0")
+		}
+		req.IfMatch = []uint{uint(version)}
+	}
+
+	prompt, err := gc.promptService.UpdatePrompt(id, &req)
+	if err != nil {
+		return nil, graphQLPromptWriteError(err)
+	}
+	state.prime(prompt)
+	return prompt, nil
+}
+
+// resolveCreateTag Mutation.create_tag
+func (gc *GraphQLController) resolveCreateTag(p graphql.ResolveParams) (interface{}, error) {
+	if err := graphQLStateFrom(p).authorizeWrite(models.PermTagWrite); err != nil {
+		return nil, err
+	}
+	name, _ := p.Args["name"].(string)
+	req := models.CreateTagRequest{Name: name}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, utils.NewGraphQLError(graphQLBadUserInput, "参数验证失败: "+err.Error())
+	}
+	tag, err := gc.tagService.CreateTag(&req)
+	if err != nil {
+		return nil, graphQLInternal(err)
+	}
+	return tag, nil
+}
+
+// resolveDeleteTag Mutation.delete_tag
+func (gc *GraphQLController) resolveDeleteTag(p graphql.ResolveParams) (interface{}, error) {
+	if err := graphQLStateFrom(p).authorizeWrite(models.PermTagDelete); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := gc.tagService.DeleteTag(id); err != nil {
+		// 与 REST 接口一样直接返回服务层的错误信息（标签不存在或仍在使用）
+		return nil, utils.NewGraphQLError(graphQLBadUserInput, err.Error())
+	}
+	return true, nil
+}
+
+// resolveMergeTag Mutation.merge_tag
+func (gc *GraphQLController) resolveMergeTag(p graphql.ResolveParams) (interface{}, error) {
+	if err := graphQLStateFrom(p).authorizeWrite(models.PermTagMerge); err != nil {
+		return nil, err
+	}
+	id, err := graphQLID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	targetID, err := graphQLID(p.Args["target_id"])
+	if err != nil {
+		return nil, err
+	}
+	target, moved, err := gc.tagService.MergeTags(id, targetID)
+	if err != nil {
+		return nil, utils.NewGraphQLError(graphQLBadUserInput, err.Error())
+	}
+	return map[string]interface{}{"tag": target, "merged_prompts": moved}, nil
+}
+
+// checkPromptModifiable 检查当前用户是否可以修改提示词，与 PromptController.checkPromptModifiable 一致
+func (gc *GraphQLController) checkPromptModifiable(state *graphQLState, id uint) error {
+	prompt, err := gc.promptService.GetVisiblePrompt(id, state.viewerID)
+	if err != nil {
+		return graphQLPromptWriteError(err)
+	}
+	if !prompt.CanBeModifiedBy(state.viewerID) {
+		return utils.NewGraphQLError(graphQLForbidden, "只有提示词的所有者可以修改或删除")
+	}
+	return nil
+}
+
+// ---------- 辅助函数 ----------
+
+// graphQLStateFrom 从解析参数的 context 中取出请求状态
+func graphQLStateFrom(p graphql.ResolveParams) *graphQLState {
+	return p.Context.Value(graphQLStateKey{}).(*graphQLState)
+}
+
+// authorize 检查当前角色的权限，与 middleware.Authorize 的提示一致
+func (s *graphQLState) authorize(perm models.Permission) error {
+	if models.RoleHasPermission(middleware.CurrentRole(s.c), perm) {
+		return nil
+	}
+	if middleware.CurrentUser(s.c) == nil {
+		return utils.NewGraphQLError(graphQLForbidden, "未登录用户没有该权限，请先登录: "+string(perm))
+	}
+	return utils.NewGraphQLError(graphQLForbidden, "当前角色没有该权限: "+string(perm))
+}
+
+// authorizeWrite 检查变更操作的权限；使用API密钥时还需要 write 权限范围
+// /graphql 的 POST 请求在认证时只要求 read 权限范围，查询和变更在这里区分
+func (s *graphQLState) authorizeWrite(perm models.Permission) error {
+	if apiKey := middleware.CurrentAPIKey(s.c); apiKey != nil && !apiKey.HasScope(models.ScopeWrite) {
+		return utils.NewGraphQLError(graphQLForbidden, "API密钥缺少所需权限: "+models.ScopeWrite)
+	}
+	return s.authorize(perm)
+}
+
+// prime 将已加载的标签和图片写入加载器，避免再次查询
+func (s *graphQLState) prime(prompt *models.Prompt) {
+	tags := prompt.Tags
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+	images := prompt.Images
+	if images == nil {
+		images = []*models.PromptImage{}
+	}
+	s.tags.Prime(prompt.ID, tags)
+	s.images.Prime(prompt.ID, images)
+}
+
+// graphQLID 解析 ID 参数
+func graphQLID(value interface{}) (uint, error) {
+	s, _ := value.(string)
+	id, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil || id == 0 {
+		return 0, utils.NewGraphQLError(graphQLBadUserInput, "无效的ID")
+	}
+	return uint(id), nil
+}
+
+// graphQLString 可选的字符串参数，未提供时为空字符串
+func graphQLString(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// graphQLStrings 可选的字符串列表参数
+func graphQLStrings(value interface{}) []string {
+	list, _ := value.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+			result = append(result, strings.TrimSpace(s))
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// decodeGraphQLInput 将输入对象转换为 REST 接口的请求结构体并按 binding 标签校验
+// 输入对象的字段名与请求结构体的 JSON 字段名一致
+func decodeGraphQLInput(input interface{}, req interface{}) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return utils.NewGraphQLError(graphQLBadUserInput, "参数格式错误: "+err.Error())
+	}
+	if err := json.Unmarshal(data, req); err != nil {
+		return utils.NewGraphQLError(graphQLBadUserInput, "参数格式错误: "+err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return utils.NewGraphQLError(graphQLBadUserInput, "参数验证失败: "+err.Error())
+	}
+	return nil
+}
+
+// graphQLPromptWriteError 将修改提示词时的错误转换为 GraphQL 错误，与 writePromptWriteError 对应
+func graphQLPromptWriteError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrVersionConflict):
+		return utils.NewGraphQLError(graphQLVersionConflict, err.Error())
+	case errors.Is(err, services.ErrPromptNotFound):
+		return utils.NewGraphQLError(graphQLNotFound, err.Error())
+	case errors.Is(err, services.ErrOwnerlessPrivate):
+		return utils.NewGraphQLError(graphQLBadUserInput, err.Error())
+	default:
+		return graphQLInternal(err)
+	}
+}
+
+// graphQLErrorResponse 执行前失败的响应，只有 errors
+func graphQLErrorResponse(message string) gin.H {
+	return gin.H{"errors": gqlerrors.FormatErrors(errors.New(message))}
+}
+
+// graphQLInternal 服务层的内部错误
+func graphQLInternal(err error) error {
+	return utils.NewGraphQLError(graphQLInternalError, err.Error())
+}
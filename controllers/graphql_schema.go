@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GraphQL 自定义标量
+var (
+	graphQLTime = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "Time",
+		Description: "RFC 3339 格式的时间",
+		Serialize: func(v interface{}) interface{} {
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil
+			}
+			return t.Format(time.RFC3339)
+		},
+		ParseValue: func(v interface{}) interface{} {
+			s, _ := v.(string)
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t
+			}
+			return nil
+		},
+		ParseLiteral: func(v ast.Value) interface{} {
+			s, ok := v.(*ast.StringValue)
+			if !ok {
+				return nil
+			}
+			if t, err := time.Parse(time.RFC3339, s.Value); err == nil {
+				return t
+			}
+			return nil
+		},
+	})
+	graphQLJSON = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "JSON",
+		Description: "任意 JSON 值，原样输出",
+		Serialize: func(v interface{}) interface{} {
+			raw, ok := v.(json.RawMessage)
+			if !ok || len(raw) == 0 || !json.Valid(raw) {
+				return nil
+			}
+			return raw
+		},
+		ParseValue: func(v interface{}) interface{} {
+			return v
+		},
+		ParseLiteral: func(v ast.Value) interface{} {
+			return v.GetValue()
+		},
+	})
+)
+
+// graphQLOptionalID 可为空的ID字段（*uint），默认解析函数会把指针本身格式化为字符串
+func graphQLOptionalID(description string) *graphql.Field {
+	return &graphql.Field{Type: graphql.ID, Description: description, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		value, err := graphql.DefaultResolveFn(p)
+		if id, ok := value.(*uint); ok {
+			if id == nil {
+				return nil, err
+			}
+			return *id, err
+		}
+		return value, err
+	}}
+}
+
+// newGraphQLSchema 创建 GraphQL schema，解析函数由 gc 提供
+// 字段名与 REST 接口的 JSON 字段一致（snake_case），列表查询的参数与 PromptQuery 一致
+func (gc *GraphQLController) newGraphQLSchema() (graphql.Schema, error) {
+	nonNull := graphql.NewNonNull
+	list := func(t graphql.Type) graphql.Type { return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t))) }
+
+	tagType := graphql.NewObject(graphql.ObjectConfig{Name: "Tag", Description: "标签", Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: nonNull(graphql.ID)},
+		"name":         &graphql.Field{Type: nonNull(graphql.String)},
+		"created_at":   &graphql.Field{Type: nonNull(graphQLTime)},
+		"prompt_count": &graphql.Field{Type: nonNull(graphql.Int), Description: "使用该标签、且对当前用户可见的提示词数量", Resolve: gc.resolveTagPromptCount},
+	}})
+	imageType := graphql.NewObject(graphql.ObjectConfig{Name: "PromptImage", Description: "提示词的图片", Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: nonNull(graphql.ID)},
+		"role":     &graphql.Field{Type: nonNull(graphql.String), Description: "input | output | mask | controlnet"},
+		"position": &graphql.Field{Type: nonNull(graphql.Int)},
+		"url":      &graphql.Field{Type: nonNull(graphql.String)},
+		"caption":  &graphql.Field{Type: nonNull(graphql.String)},
+		"width":    &graphql.Field{Type: nonNull(graphql.Int)},
+		"height":   &graphql.Field{Type: nonNull(graphql.Int)},
+		"hash":     &graphql.Field{Type: nonNull(graphql.String)},
+	}})
+	collectionType := graphql.NewObject(graphql.ObjectConfig{Name: "Collection", Description: "收藏集", Fields: graphql.Fields{
+		"id":              &graphql.Field{Type: nonNull(graphql.ID)},
+		"created_at":      &graphql.Field{Type: nonNull(graphQLTime)},
+		"updated_at":      &graphql.Field{Type: nonNull(graphQLTime)},
+		"name":            &graphql.Field{Type: nonNull(graphql.String)},
+		"description":     &graphql.Field{Type: nonNull(graphql.String)},
+		"cover_image_url": &graphql.Field{Type: nonNull(graphql.String)},
+		"visibility":      &graphql.Field{Type: nonNull(graphql.String)},
+		"owner_id":        graphQLOptionalID(""),
+		"item_count":      &graphql.Field{Type: nonNull(graphql.Int)},
+	}})
+
+	// Prompt.parent 引用 Prompt 自身，字段通过函数延迟定义
+	var promptType *graphql.Object
+	promptType = graphql.NewObject(graphql.ObjectConfig{Name: "Prompt", Description: "提示词", Fields: graphql.FieldsThunk(func() graphql.Fields {
+		return graphql.Fields{
+			"id":                     &graphql.Field{Type: nonNull(graphql.ID)},
+			"created_at":             &graphql.Field{Type: nonNull(graphQLTime)},
+			"updated_at":             &graphql.Field{Type: nonNull(graphQLTime)},
+			"prompt_text":            &graphql.Field{Type: nonNull(graphql.String), Description: "正面提示词"},
+			"negative_prompt":        &graphql.Field{Type: nonNull(graphql.String), Description: "负面提示词"},
+			"model_name":             &graphql.Field{Type: nonNull(graphql.String)},
+			"is_public":              &graphql.Field{Type: nonNull(graphql.Boolean)},
+			"style_description":      &graphql.Field{Type: nonNull(graphql.String)},
+			"usage_scenario":         &graphql.Field{Type: nonNull(graphql.String)},
+			"atmosphere_description": &graphql.Field{Type: nonNull(graphql.String)},
+			"expressive_intent":      &graphql.Field{Type: nonNull(graphql.String)},
+			"structure_analysis":     &graphql.Field{Type: graphQLJSON},
+			"generation_params":      &graphql.Field{Type: graphQLJSON, Description: "生成参数（Civitai meta 格式）"},
+			"owner_id":               graphQLOptionalID(""),
+			"parent_id":              graphQLOptionalID("分叉来源的提示词ID"),
+			"version":                &graphql.Field{Type: nonNull(graphql.Int), Description: "版本号，每次修改（包括标签）时递增，可作为 update_prompt 的 if_version"},
+			"fork_count":             &graphql.Field{Type: nonNull(graphql.Int), Description: "可见的分叉数量", Resolve: gc.resolvePromptForkCount},
+			"parent":                 &graphql.Field{Type: promptType, Description: "分叉来源的提示词，不可见时为 null", Resolve: gc.resolvePromptParent},
+			"tags":                   &graphql.Field{Type: list(tagType), Resolve: gc.resolvePromptTags},
+			"images":                 &graphql.Field{Type: list(imageType), Resolve: gc.resolvePromptImages},
+			"input_image_urls":       &graphql.Field{Type: list(graphql.String), Resolve: gc.resolvePromptInputImageURLs},
+			"output_image_url":       &graphql.Field{Type: graphql.String, Resolve: gc.resolvePromptOutputImageURL},
+			"collections": &graphql.Field{Type: graphql.NewList(nonNull(collectionType)), Description: "包含该提示词的收藏集，没有收藏集读取权限时为 null",
+				Resolve: gc.resolvePromptCollections},
+		}
+	})})
+	pageType := graphql.NewObject(graphql.ObjectConfig{Name: "PromptPage", Description: "分页的提示词列表", Fields: graphql.Fields{
+		"items":       &graphql.Field{Type: list(promptType)},
+		"page":        &graphql.Field{Type: nonNull(graphql.Int)},
+		"page_size":   &graphql.Field{Type: nonNull(graphql.Int)},
+		"total":       &graphql.Field{Type: nonNull(graphql.Int)},
+		"total_pages": &graphql.Field{Type: nonNull(graphql.Int)},
+	}})
+	mergeResultType := graphql.NewObject(graphql.ObjectConfig{Name: "MergeTagResult", Fields: graphql.Fields{
+		"tag":            &graphql.Field{Type: nonNull(tagType), Description: "目标标签"},
+		"merged_prompts": &graphql.Field{Type: nonNull(graphql.Int), Description: "新增关联的提示词数量"},
+	}})
+
+	imageInput := graphql.NewInputObject(graphql.InputObjectConfig{Name: "PromptImageInput", Fields: graphql.InputObjectConfigFieldMap{
+		"role":    &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "input | output | mask | controlnet，为空时视为 input"},
+		"url":     &graphql.InputObjectFieldConfig{Type: nonNull(graphql.String)},
+		"caption": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"width":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"height":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"hash":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+	}})
+	stringList := graphql.NewList(nonNull(graphql.String))
+	imageList := graphql.NewList(nonNull(imageInput))
+	createInput := graphql.NewInputObject(graphql.InputObjectConfig{Name: "CreatePromptInput", Description: "与 POST /api/v1/prompts 的请求体一致", Fields: graphql.InputObjectConfigFieldMap{
+		"prompt_text":            &graphql.InputObjectFieldConfig{Type: nonNull(graphql.String)},
+		"negative_prompt":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"model_name":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"is_public":              &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"style_description":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"usage_scenario":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"atmosphere_description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"expressive_intent":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"structure_analysis":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"generation_params":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "JSON 对象字符串"},
+		"input_image_urls":       &graphql.InputObjectFieldConfig{Type: stringList},
+		"output_image_url":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"images":                 &graphql.InputObjectFieldConfig{Type: imageList},
+		"tag_names":              &graphql.InputObjectFieldConfig{Type: stringList},
+		"fetch_remote":           &graphql.InputObjectFieldConfig{Type: graphql.Boolean, Description: "将 http/https 图片下载到上传目录"},
+	}})
+	updateInput := graphql.NewInputObject(graphql.InputObjectConfig{Name: "UpdatePromptInput", Description: "与 PUT /api/v1/prompts/{id} 的请求体一致，未提供或为 null 的字段不修改", Fields: graphql.InputObjectConfigFieldMap{
+		"prompt_text":            &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"negative_prompt":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"model_name":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"is_public":              &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"style_description":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"usage_scenario":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"atmosphere_description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"expressive_intent":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"structure_analysis":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"generation_params":      &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "JSON 对象字符串，空字符串表示清除"},
+		"input_image_urls":       &graphql.InputObjectFieldConfig{Type: stringList},
+		"output_image_url":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"images":                 &graphql.InputObjectFieldConfig{Type: imageList, Description: "提供时替换全部图片"},
+		"tag_names":              &graphql.InputObjectFieldConfig{Type: stringList, Description: "提供时替换全部标签，空列表表示清除"},
+	}})
+
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"prompt": &graphql.Field{Type: promptType, Description: "获取单个提示词，不存在或不可见时为 null",
+			Args: graphql.FieldConfigArgument{"id": {Type: nonNull(graphql.ID)}}, Resolve: gc.resolvePrompt},
+		"prompts": &graphql.Field{Type: nonNull(pageType), Description: "获取提示词列表，过滤条件与 GET /api/v1/prompts 一致",
+			Args: graphql.FieldConfigArgument{
+				"page":       {Type: graphql.Int, DefaultValue: 1},
+				"page_size":  {Type: graphql.Int, DefaultValue: 20, Description: "1 到 100"},
+				"model_name": {Type: graphql.String},
+				"is_public":  {Type: graphql.Boolean},
+				"keyword":    {Type: graphql.String},
+				"tag_names":  {Type: stringList},
+				"sort_by":    {Type: graphql.String, Description: "created_at"},
+				"sort_order": {Type: graphql.String, Description: "asc | desc"},
+			}, Resolve: gc.resolvePrompts},
+		"tag": &graphql.Field{Type: tagType, Description: "获取单个标签，不存在时为 null",
+			Args: graphql.FieldConfigArgument{"id": {Type: nonNull(graphql.ID)}}, Resolve: gc.resolveTag},
+		"tags": &graphql.Field{Type: list(tagType), Description: "获取标签，指定 keyword 时按名称搜索",
+			Args: graphql.FieldConfigArgument{"keyword": {Type: graphql.String}}, Resolve: gc.resolveTags},
+	}})
+	mutation := graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: graphql.Fields{
+		"create_prompt": &graphql.Field{Type: nonNull(promptType), Description: "创建提示词",
+			Args: graphql.FieldConfigArgument{"input": {Type: nonNull(createInput)}}, Resolve: gc.resolveCreatePrompt},
+		"update_prompt": &graphql.Field{Type: nonNull(promptType), Description: "更新提示词，指定 if_version 时当前版本不一致则失败（VERSION_CONFLICT）",
+			Args: graphql.FieldConfigArgument{
+				"id":         {Type: nonNull(graphql.ID)},
+				"input":      {Type: nonNull(updateInput)},
+				"if_version": {Type: graphql.Int},
+			}, Resolve: gc.resolveUpdatePrompt},
+		"create_tag": &graphql.Field{Type: nonNull(tagType), Description: "创建标签，已存在时返回现有标签",
+			Args: graphql.FieldConfigArgument{"name": {Type: nonNull(graphql.String)}}, Resolve: gc.resolveCreateTag},
+		"delete_tag": &graphql.Field{Type: nonNull(graphql.Boolean), Description: "删除未被使用的标签",
+			Args: graphql.FieldConfigArgument{"id": {Type: nonNull(graphql.ID)}}, Resolve: gc.resolveDeleteTag},
+		"merge_tag": &graphql.Field{Type: nonNull(mergeResultType), Description: "将标签合并到目标标签",
+			Args: graphql.FieldConfigArgument{
+				"id":        {Type: nonNull(graphql.ID)},
+				"target_id": {Type: nonNull(graphql.ID)},
+			}, Resolve: gc.resolveMergeTag},
+	}})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
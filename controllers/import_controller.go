@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportController 批量导入控制器
+type ImportController struct {
+	importService *services.ImportService
+}
+
+// NewImportController 创建批量导入控制器实例
+func NewImportController() *ImportController {
+	return &ImportController{
+		importService: services.NewImportService(),
+	}
+}
+
+// ImportPrompts 从上传的 JSONL、CSV 或 Civitai JSON 文件批量导入提示词
+// 表单字段：file（必填）、format、on_duplicate、chunk_size、mapping（JSON对象或 源列=目标字段 列表）
+func (ic *ImportController) ImportPrompts(c *gin.Context) {
+	var opts models.ImportOptions
+	if err := c.ShouldBind(&opts); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequestResponse(c, "请上传导入文件（字段名 file）")
+		return
+	}
+	if opts.Format == "" {
+		opts.Format = utils.DetectImportFormat(fileHeader.Filename)
+		if opts.Format == "" {
+			utils.BadRequestResponse(c, "无法根据文件扩展名判断格式，请指定 format 为 jsonl、csv 或 civitai")
+			return
+		}
+	}
+
+	mapping, err := utils.ParseImportMapping(c.PostForm("mapping"))
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	opts.Mapping = mapping
+
+	// 导入的提示词归属于当前用户
+	opts.OwnerID = middleware.CurrentUserID(c)
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "读取导入文件失败")
+		return
+	}
+	defer file.Close()
+
+	result, err := ic.importService.ImportPrompts(file, &opts)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidImport) {
+			utils.BadRequestResponse(c, err.Error())
+		} else {
+			utils.InternalServerErrorResponse(c, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "导入完成", result)
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobController 后台任务控制器
+type JobController struct {
+	jobService    *services.JobService
+	promptService *services.PromptService
+}
+
+// NewJobController 创建后台任务控制器实例
+func NewJobController() *JobController {
+	return &JobController{
+		jobService:    services.NewJobService(),
+		promptService: services.NewPromptService(),
+	}
+}
+
+// CreateAnalyzeJob 创建异步分析任务，立即返回任务，通过 GET /jobs/:id 查询进度
+// 图片字段与 /prompts/analyze 相同；指定 prompt_id 且未上传输出图片时使用该提示词的本地图片
+func (jc *JobController) CreateAnalyzeJob(c *gin.Context) {
+	var req models.CreateAnalyzeJobRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+	if req.AutoApply && req.PromptID == nil {
+		utils.BadRequestResponse(c, "auto_apply 需要同时指定 prompt_id")
+		return
+	}
+
+	userID := middleware.CurrentUserID(c)
+	var prompt *models.Prompt
+	if req.PromptID != nil {
+		var err error
+		prompt, err = jc.promptService.GetVisiblePrompt(*req.PromptID, userID)
+		if err != nil {
+			if errors.Is(err, services.ErrPromptNotFound) {
+				utils.NotFoundResponse(c, err.Error())
+			} else {
+				utils.InternalServerErrorResponse(c, err.Error())
+			}
+			return
+		}
+		if req.AutoApply && !prompt.CanBeModifiedBy(userID) {
+			utils.ForbiddenResponse(c, "只有提示词的所有者可以修改或删除")
+			return
+		}
+	}
+
+	payload := models.AnalyzeJobPayload{PromptText: req.PromptText, ModelName: req.ModelName}
+	var saved []string
+	cleanup := func() {
+		for _, filename := range saved {
+			utils.DeleteFile(filepath.Join(config.AppConfig.Server.UploadPath, filename))
+		}
+	}
+	save := func(file *multipart.FileHeader) (string, bool) {
+		if file.Size > config.AppConfig.Server.MaxFileSize {
+			cleanup()
+			utils.BadRequestResponse(c, "文件大小超出限制: "+file.Filename)
+			return "", false
+		}
+		filename, err := utils.SaveUploadedFile(file, config.AppConfig.Server.UploadPath)
+		if err != nil {
+			cleanup()
+			utils.BadRequestResponse(c, err.Error())
+			return "", false
+		}
+		saved = append(saved, filename)
+		return utils.GetFileURL(c, filename), true
+	}
+	// 上传的图片保存到上传目录，由worker执行时读取，只供本任务使用，任务结束后由 JobService 删除
+	if form, err := c.MultipartForm(); err == nil && form != nil {
+		if files := form.File["output_image"]; len(files) > 0 {
+			url, ok := save(files[0])
+			if !ok {
+				return
+			}
+			payload.OutputImageURL = url
+		}
+		for _, key := range []string{"input_images", "reference_images"} {
+			for _, file := range form.File[key] {
+				url, ok := save(file)
+				if !ok {
+					return
+				}
+				payload.InputImageURLs = append(payload.InputImageURLs, url)
+			}
+		}
+	}
+
+	// 未上传的内容使用提示词中已有的数据
+	if prompt != nil {
+		if payload.PromptText == "" {
+			payload.PromptText = prompt.PromptText
+		}
+		if payload.ModelName == "" {
+			payload.ModelName = prompt.ModelName
+		}
+		if payload.OutputImageURL == "" {
+			payload.OutputImageURL = prompt.GetOutputImageURL()
+			for _, url := range prompt.GetInputImageURLs() {
+				if strings.HasPrefix(url, "/uploads/") {
+					payload.InputImageURLs = append(payload.InputImageURLs, url)
+				}
+			}
+		}
+	}
+
+	if strings.TrimSpace(payload.PromptText) == "" {
+		cleanup()
+		utils.BadRequestResponse(c, "请提供提示词内容")
+		return
+	}
+	if !strings.HasPrefix(payload.OutputImageURL, "/uploads/") {
+		cleanup()
+		utils.BadRequestResponse(c, "请提供输出图片")
+		return
+	}
+
+	payload.UploadedFiles = saved
+	job, err := jc.jobService.EnqueueAnalyzeJob(&payload, req.PromptID, req.AutoApply, userID, req.MaxAttempts)
+	if err != nil {
+		cleanup()
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	c.Header("Location", "/api/v1/jobs/"+strconv.FormatUint(uint64(job.ID), 10))
+	utils.AcceptedResponse(c, "任务已创建", job)
+}
+
+// GetJob 获取任务状态和结果，匿名创建的任务需要通过 X-Job-Token 请求头提供访问令牌
+func (jc *JobController) GetJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	job, err := jc.jobService.GetJob(uint(id), middleware.CurrentUserID(c), c.GetHeader("X-Job-Token"))
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, job)
+}
+
+// CancelJob 取消等待中或执行中的任务
+func (jc *JobController) CancelJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	job, err := jc.jobService.CancelJob(uint(id), middleware.CurrentUserID(c), c.GetHeader("X-Job-Token"))
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	utils.SuccessWithMessage(c, "已请求取消", job)
+}
+
+// respondJobError 将任务相关的错误写入响应
+func respondJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrJobFinished):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, err.Error())
+	}
+}
@@ -3,14 +3,19 @@ package controllers
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/middleware"
 	"imgGeneratePrompts/models"
 	"imgGeneratePrompts/services"
 	"imgGeneratePrompts/utils"
+	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -54,6 +59,13 @@ func (pc *PromptController) CreatePrompt(c *gin.Context) {
 		}
 	}
 
+	// 登录用户创建的提示词归属于该用户
+	req.OwnerID = middleware.CurrentUserID(c)
+	req.Context = c.Request.Context()
+	if c.Query("fetch_remote") == "true" {
+		req.FetchRemote = true
+	}
+
 	// TODO: 这里应该处理图片生成逻辑
 	// 现在先用一个占位符URL
 	imageURL := "/uploads/placeholder.jpg"
@@ -61,7 +73,7 @@ func (pc *PromptController) CreatePrompt(c *gin.Context) {
 	// 创建提示词
 	prompt, err := pc.promptService.CreatePrompt(&req, imageURL)
 	if err != nil {
-		utils.InternalServerErrorResponse(c, err.Error())
+		writeCreatePromptError(c, err)
 		return
 	}
 
@@ -173,19 +185,55 @@ func (pc *PromptController) UploadAndCreatePrompt(c *gin.Context) {
 				req.OutputImageURL = utils.GetFileURL(c, filename)
 			}
 		}
+
+		// 处理其他输出图片、蒙版和 ControlNet 控制图（支持多个）
+		for key, role := range map[string]string{
+			"extra_output_images": models.ImageRoleOutput,
+			"mask_images":         models.ImageRoleMask,
+			"controlnet_images":   models.ImageRoleControlNet,
+		} {
+			for _, file := range form.File[key] {
+				if file.Size > config.AppConfig.Server.MaxFileSize {
+					utils.BadRequestResponse(c, "文件大小超出限制: "+file.Filename)
+					return
+				}
+				filename, err := utils.SaveUploadedFile(file, config.AppConfig.Server.UploadPath)
+				if err != nil {
+					utils.InternalServerErrorResponse(c, err.Error())
+					return
+				}
+				req.Images = append(req.Images, models.PromptImageInput{Role: role, URL: utils.GetFileURL(c, filename)})
+			}
+		}
+	}
+
+	// 登录用户创建的提示词归属于该用户
+	req.OwnerID = middleware.CurrentUserID(c)
+	req.Context = c.Request.Context()
+	if c.Query("fetch_remote") == "true" {
+		req.FetchRemote = true
 	}
 
 	log.Printf("--- DEBUG: Request object before saving to DB: %+v", req)
 	// 创建提示词
 	prompt, err := pc.promptService.CreatePromptWithImages(&req)
 	if err != nil {
-		utils.InternalServerErrorResponse(c, err.Error())
+		writeCreatePromptError(c, err)
 		return
 	}
 
 	utils.SuccessWithMessage(c, "创建成功", prompt.ToResponse())
 }
 
+// writeCreatePromptError 返回创建提示词失败的响应，远程图片无法下载或不符合限制时返回400
+func writeCreatePromptError(c *gin.Context, err error) {
+	if errors.Is(err, utils.ErrRemoteImage) {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	utils.InternalServerErrorResponse(c, err.Error())
+}
+
 // AnalyzePrompt 智能生成接口 - 分析图片并返回建议内容
 func (pc *PromptController) AnalyzePrompt(c *gin.Context) {
 	var req models.AnalyzePromptRequest
@@ -296,12 +344,16 @@ func (pc *PromptController) GetPrompt(c *gin.Context) {
 		return
 	}
 
-	prompt, err := pc.promptService.GetPromptByID(uint(id))
+	prompt, err := pc.promptService.GetVisiblePrompt(uint(id), middleware.CurrentUserID(c))
 	if err != nil {
 		utils.NotFoundResponse(c, err.Error())
 		return
 	}
 
+	// ETag 由版本号生成，客户端可通过 If-None-Match 轮询、通过 If-Match 防止覆盖他人的修改
+	if utils.NotModified(c, utils.PromptETag(prompt.ID, prompt.Version)) {
+		return
+	}
 	utils.SuccessResponse(c, prompt.ToResponse())
 }
 
@@ -314,6 +366,14 @@ func (pc *PromptController) UpdatePrompt(c *gin.Context) {
 		return
 	}
 
+	if !pc.checkPromptModifiable(c, uint(id)) {
+		return
+	}
+	versions, ok := ifMatchVersions(c, uint(id))
+	if !ok {
+		return
+	}
+
 	var req models.UpdatePromptRequest
 	contentType := c.GetHeader("Content-Type")
 	if strings.Contains(contentType, "application/json") {
@@ -336,12 +396,73 @@ func (pc *PromptController) UpdatePrompt(c *gin.Context) {
 		}
 	}
 
+	req.IfMatch = versions
+
 	prompt, err := pc.promptService.UpdatePrompt(uint(id), &req)
 	if err != nil {
-		utils.InternalServerErrorResponse(c, err.Error())
+		writePromptWriteError(c, err)
+		return
+	}
+
+	c.Header("ETag", utils.PromptETag(prompt.ID, prompt.Version))
+	utils.SuccessWithMessage(c, "更新成功", prompt.ToResponse())
+}
+
+// maxPatchBodySize PATCH 请求体的最大字节数
+const maxPatchBodySize = 1 << 20
+
+// PatchPrompt 部分更新提示词
+// Content-Type 为 application/json-patch+json 时按 JSON Patch 处理，
+// 为 application/merge-patch+json 或 application/json 时按 JSON Merge Patch 处理
+func (pc *PromptController) PatchPrompt(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	contentType := c.ContentType()
+	switch contentType {
+	case utils.JSONPatchContentType, utils.MergePatchContentType, "application/json":
+	default:
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "不支持的 Content-Type，请使用 "+
+			utils.MergePatchContentType+" 或 "+utils.JSONPatchContentType)
+		return
+	}
+
+	if !pc.checkPromptModifiable(c, uint(id)) {
+		return
+	}
+	versions, ok := ifMatchVersions(c, uint(id))
+	if !ok {
+		return
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPatchBodySize+1))
+	if err != nil {
+		utils.BadRequestResponse(c, "读取请求体失败")
+		return
+	}
+	if len(patch) > maxPatchBodySize {
+		utils.BadRequestResponse(c, "补丁过大")
 		return
 	}
 
+	prompt, err := pc.promptService.PatchPrompt(uint(id), contentType, patch, versions)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidPatch):
+			utils.BadRequestResponse(c, err.Error())
+		case errors.Is(err, utils.ErrPatchTestFailed):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		default:
+			writePromptWriteError(c, err)
+		}
+		return
+	}
+
+	c.Header("ETag", utils.PromptETag(prompt.ID, prompt.Version))
 	utils.SuccessWithMessage(c, "更新成功", prompt.ToResponse())
 }
 
@@ -354,14 +475,37 @@ func (pc *PromptController) DeletePrompt(c *gin.Context) {
 		return
 	}
 
-	if err := pc.promptService.DeletePrompt(uint(id)); err != nil {
-		utils.InternalServerErrorResponse(c, err.Error())
+	if !pc.checkPromptModifiable(c, uint(id)) {
+		return
+	}
+	versions, ok := ifMatchVersions(c, uint(id))
+	if !ok {
+		return
+	}
+
+	if err := pc.promptService.DeletePromptIfMatch(uint(id), versions); err != nil {
+		writePromptWriteError(c, err)
 		return
 	}
 
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
 
+// GetUploadedFile 获取上传的图片，只能访问当前用户可见的提示词或收藏集引用的文件
+func (pc *PromptController) GetUploadedFile(c *gin.Context) {
+	path, err := pc.promptService.ResolveUploadedFile(c.Param("filename"), middleware.CurrentUserID(c))
+	if err != nil {
+		if errors.Is(err, services.ErrUploadNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	c.File(path)
+}
+
 // GetPrompts 获取提示词列表
 func (pc *PromptController) GetPrompts(c *gin.Context) {
 	var query models.PromptQuery
@@ -387,6 +531,7 @@ func (pc *PromptController) GetPrompts(c *gin.Context) {
 	if query.PageSize == 0 {
 		query.PageSize = 10
 	}
+	query.ViewerID = middleware.CurrentUserID(c)
 
 	prompts, total, err := pc.promptService.GetPrompts(&query)
 	if err != nil {
@@ -420,6 +565,90 @@ func (pc *PromptController) GetPrompts(c *gin.Context) {
 	utils.PaginationResponse(c, responses, query.Page, query.PageSize, total)
 }
 
+// ExportPrompts 按过滤条件流式导出提示词（JSONL/CSV/Markdown/A1111文本/Civitai，可选打包图片为zip）
+func (pc *PromptController) ExportPrompts(c *gin.Context) {
+	var query models.ExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	// 处理标签查询（支持逗号分隔的标签名）
+	if tagNamesStr := c.Query("tag_names"); tagNamesStr != "" {
+		query.TagNames = strings.Split(tagNamesStr, ",")
+		for i := range query.TagNames {
+			query.TagNames[i] = strings.TrimSpace(query.TagNames[i])
+		}
+	}
+	if query.Format == "" {
+		query.Format = models.ExportFormatJSONL
+	}
+
+	filename := "prompts-" + time.Now().Format("20060102-150405")
+	ext := utils.ExportFileExtension(query.Format)
+
+	var out io.Writer = c.Writer
+	var bundle *utils.ExportBundle
+	var rewriteImageURL func(string) string
+	if query.Zip {
+		bundle = utils.NewExportBundle(c.Writer, config.AppConfig.Server.UploadPath)
+		var err error
+		out, rewriteImageURL, err = bundle.Create("prompts" + ext)
+		if err != nil {
+			utils.InternalServerErrorResponse(c, err.Error())
+			return
+		}
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	} else {
+		c.Header("Content-Type", utils.ExportContentType(query.Format))
+		c.Header("Content-Disposition", `attachment; filename="`+filename+ext+`"`)
+	}
+
+	exporter, err := utils.NewPromptExporter(out, query.Format)
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+	exporter.ImageURL = rewriteImageURL
+
+	// 分批读取并写出，每批写完后刷新到客户端
+	c.Status(http.StatusOK)
+	err = pc.promptService.ExportPrompts(query.ToPromptQuery(middleware.CurrentUserID(c)), func(batch []models.Prompt) error {
+		for i := range batch {
+			if err := exporter.Write(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if err := exporter.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err == nil {
+		err = exporter.Close()
+	}
+	if err == nil && bundle != nil {
+		var missing []string
+		missing, err = bundle.Close()
+		if len(missing) > 0 {
+			log.Printf("导出时有 %d 个图片文件不存在: %v", len(missing), missing)
+		}
+	}
+	if err != nil {
+		// 已经开始写出内容时无法再返回JSON错误，只能中断下载
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			utils.InternalServerErrorResponse(c, err.Error())
+			return
+		}
+		// 正常结束响应会让客户端得到截断但看似完整的文件，因此由 net/http 中断响应（关闭连接或重置 HTTP/2 流）
+		log.Printf("导出提示词失败: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
 // GetPublicPrompts 获取公开的提示词列表
 func (pc *PromptController) GetPublicPrompts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -456,7 +685,7 @@ func (pc *PromptController) SearchPromptsByTags(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	prompts, total, err := pc.promptService.SearchPromptsByTags(tagNames, page, pageSize)
+	prompts, total, err := pc.promptService.SearchPromptsByTags(tagNames, page, pageSize, middleware.CurrentUserID(c))
 	if err != nil {
 		utils.InternalServerErrorResponse(c, err.Error())
 		return
@@ -471,9 +700,9 @@ func (pc *PromptController) SearchPromptsByTags(c *gin.Context) {
 	utils.PaginationResponse(c, responses, page, pageSize, total)
 }
 
-// GetPromptStats 获取提示词统计信息
+// GetPromptStats 获取当前用户可见的提示词统计信息
 func (pc *PromptController) GetPromptStats(c *gin.Context) {
-	stats, err := pc.promptService.GetPromptStats()
+	stats, err := pc.promptService.GetPromptStats(middleware.CurrentUserID(c))
 	if err != nil {
 		utils.InternalServerErrorResponse(c, err.Error())
 		return
@@ -512,7 +741,7 @@ func (pc *PromptController) CheckDuplicate(c *gin.Context) {
 		return
 	}
 
-	prompts, err := pc.promptService.DuplicateCheck(promptText)
+	prompts, err := pc.promptService.DuplicateCheck(promptText, middleware.CurrentUserID(c))
 	if err != nil {
 		utils.InternalServerErrorResponse(c, err.Error())
 		return
@@ -532,3 +761,120 @@ func (pc *PromptController) CheckDuplicate(c *gin.Context) {
 
 	utils.SuccessResponse(c, result)
 }
+
+// ExpandPrompt 展开动态提示词，返回全部组合或随机采样结果
+func (pc *PromptController) ExpandPrompt(c *gin.Context) {
+	var req models.ExpandPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+	req.ViewerID = middleware.CurrentUserID(c)
+
+	response, err := pc.promptService.ExpandPrompt(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrPromptNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, response)
+}
+
+// ForkPrompt 分叉提示词，复制为新的可编辑记录
+func (pc *PromptController) ForkPrompt(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	prompt, err := pc.promptService.ForkPrompt(uint(id), middleware.CurrentUserID(c))
+	if err != nil {
+		if errors.Is(err, services.ErrPromptNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "分叉成功", prompt.ToResponse())
+}
+
+// GetPromptLineage 获取提示词的祖先链和子孙树
+func (pc *PromptController) GetPromptLineage(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	lineage, err := pc.promptService.GetPromptLineage(uint(id), middleware.CurrentUserID(c))
+	if err != nil {
+		if errors.Is(err, services.ErrPromptNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, lineage)
+}
+
+// checkPromptModifiable 检查当前用户是否可以修改提示词，不可修改时直接写入错误响应
+func (pc *PromptController) checkPromptModifiable(c *gin.Context, id uint) bool {
+	userID := middleware.CurrentUserID(c)
+	prompt, err := pc.promptService.GetVisiblePrompt(id, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrPromptNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+		} else {
+			utils.InternalServerErrorResponse(c, err.Error())
+		}
+		return false
+	}
+	if !prompt.CanBeModifiedBy(userID) {
+		utils.ForbiddenResponse(c, "只有提示词的所有者可以修改或删除")
+		return false
+	}
+	return true
+}
+
+// ifMatchVersions 解析 If-Match 请求头中属于该提示词的版本号
+// 没有请求头或为 "*" 时不检查版本；请求头中没有该提示词的有效 ETag 时直接返回412
+func ifMatchVersions(c *gin.Context, id uint) ([]uint, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil, true
+	}
+	versions, any := utils.ParseIfMatchVersions(header, id)
+	if any {
+		return nil, true
+	}
+	if len(versions) == 0 {
+		utils.PreconditionFailedResponse(c, services.ErrVersionConflict.Error())
+		return nil, false
+	}
+	return versions, true
+}
+
+// writePromptWriteError 将修改提示词时的错误写入响应
+func writePromptWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrVersionConflict):
+		utils.PreconditionFailedResponse(c, err.Error())
+	case errors.Is(err, services.ErrPromptNotFound):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrOwnerlessPrivate):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, err.Error())
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareController 提示词分享链接控制器
+type ShareController struct {
+	shareService *services.ShareService
+}
+
+// NewShareController 创建分享链接控制器实例
+func NewShareController() *ShareController {
+	return &ShareController{
+		shareService: services.NewShareService(),
+	}
+}
+
+// CreateShareLink 为提示词创建分享链接
+func (sc *ShareController) CreateShareLink(c *gin.Context) {
+	promptID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ValidationErrorResponse(c, err)
+			return
+		}
+	}
+
+	link, token, err := sc.shareService.CreateShareLink(uint(promptID), middleware.CurrentUserID(c), &req)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "分享链接创建成功", models.CreateShareLinkResponse{
+		ShareLinkResponse: link.ToResponse(),
+		Token:             token,
+		URL:               "/api/v1/shared/" + token,
+	})
+}
+
+// GetShareLinks 获取提示词的分享链接列表
+func (sc *ShareController) GetShareLinks(c *gin.Context) {
+	promptID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	links, err := sc.shareService.GetShareLinks(uint(promptID), middleware.CurrentUserID(c))
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	responses := make([]models.ShareLinkResponse, len(links))
+	for i := range links {
+		responses[i] = links[i].ToResponse()
+	}
+	utils.SuccessResponse(c, responses)
+}
+
+// RevokeShareLink 吊销分享链接
+func (sc *ShareController) RevokeShareLink(c *gin.Context) {
+	promptID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+	shareID, err := strconv.ParseUint(c.Param("share_id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的分享链接ID")
+		return
+	}
+
+	link, err := sc.shareService.RevokeShareLink(uint(promptID), uint(shareID), middleware.CurrentUserID(c))
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "吊销成功", link.ToResponse())
+}
+
+// GetSharedPrompt 通过分享令牌查看提示词（无需登录）
+func (sc *ShareController) GetSharedPrompt(c *gin.Context) {
+	shared, err := sc.shareService.GetSharedPrompt(c.Param("token"))
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, shared)
+}
+
+// GetSharedFile 通过限时签名URL获取分享提示词的图片
+func (sc *ShareController) GetSharedFile(c *gin.Context) {
+	path, err := sc.shareService.ResolveSharedFile(c.Param("token"), c.Param("image_id"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	c.File(path)
+}
+
+// respondShareError 将分享相关的错误转换为对应的HTTP响应
+func respondShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPromptNotFound),
+		errors.Is(err, services.ErrShareLinkNotFound),
+		errors.Is(err, services.ErrShareLinkInvalid):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrPermissionDenied):
+		utils.ForbiddenResponse(c, "只有提示词的所有者可以管理分享链接")
+	case errors.Is(err, utils.ErrInvalidSignature), errors.Is(err, utils.ErrExpiredSignature):
+		utils.ForbiddenResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, err.Error())
+	}
+}
@@ -121,6 +121,33 @@ func (tc *TagController) DeleteTag(c *gin.Context) {
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
 
+// MergeTag 将标签合并到目标标签
+func (tc *TagController) MergeTag(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req models.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	target, moved, err := tc.tagService.MergeTags(uint(id), req.TargetID)
+	if err != nil {
+		utils.BadRequestResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessWithMessage(c, "合并成功", gin.H{
+		"tag":            target.ToResponse(),
+		"merged_prompts": moved,
+	})
+}
+
 // GetTagStats 获取标签统计信息
 func (tc *TagController) GetTagStats(c *gin.Context) {
 	stats, err := tc.tagService.GetTagStats()
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController 事件推送订阅控制器
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookController 创建事件推送订阅控制器实例
+func NewWebhookController() *WebhookController {
+	return &WebhookController{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+// CreateWebhook 创建订阅，签名密钥只在响应中返回这一次
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	webhook, secret, err := wc.webhookService.CreateWebhook(user.ID, &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "创建成功，请妥善保存签名密钥，它不会再次显示", models.WebhookSecretResponse{
+		WebhookResponse: webhook.ToResponse(),
+		Secret:          secret,
+	})
+}
+
+// GetWebhooks 获取当前用户的订阅列表
+func (wc *WebhookController) GetWebhooks(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	webhooks, err := wc.webhookService.GetWebhooks(user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	responses := make([]models.WebhookResponse, len(webhooks))
+	for i := range webhooks {
+		responses[i] = webhooks[i].ToResponse()
+	}
+	utils.SuccessResponse(c, responses)
+}
+
+// GetWebhook 获取订阅详情
+func (wc *WebhookController) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	webhook, err := wc.webhookService.GetWebhook(id, middleware.CurrentUser(c).ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, webhook.ToResponse())
+}
+
+// UpdateWebhook 更新订阅，rotate_secret=true 时返回新的签名密钥
+func (wc *WebhookController) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	webhook, secret, err := wc.webhookService.UpdateWebhook(id, middleware.CurrentUser(c).ID, &req)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	if secret != "" {
+		utils.SuccessWithMessage(c, "更新成功，请妥善保存新的签名密钥", models.WebhookSecretResponse{
+			WebhookResponse: webhook.ToResponse(),
+			Secret:          secret,
+		})
+		return
+	}
+	utils.SuccessWithMessage(c, "更新成功", webhook.ToResponse())
+}
+
+// DeleteWebhook 删除订阅及其推送记录
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := wc.webhookService.DeleteWebhook(id, middleware.CurrentUser(c).ID); err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// GetDeliveries 分页获取推送记录，可按 status 过滤
+func (wc *WebhookController) GetDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+	var query models.WebhookDeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.PageSize == 0 {
+		query.PageSize = 20
+	}
+
+	deliveries, total, err := wc.webhookService.GetDeliveries(id, middleware.CurrentUser(c).ID, &query)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	utils.PaginationResponse(c, deliveries, query.Page, query.PageSize, total)
+}
+
+// GetDelivery 获取一条推送记录（包括推送内容和最近一次的响应）
+func (wc *WebhookController) GetDelivery(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseWebhookID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := wc.webhookService.GetDelivery(id, deliveryID, middleware.CurrentUser(c).ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, delivery)
+}
+
+// Redeliver 以相同的内容重新推送，返回新的推送记录
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseWebhookID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := wc.webhookService.Redeliver(id, deliveryID, middleware.CurrentUser(c).ID)
+	if err != nil {
+		respondWebhookError(c, err)
+		return
+	}
+	utils.AcceptedResponse(c, "已加入推送队列", delivery)
+}
+
+// parseWebhookID 解析路径中的ID参数，无效时写入400响应
+func parseWebhookID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondWebhookError 将订阅相关的错误写入响应
+func respondWebhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrInvalidWebhook):
+		utils.BadRequestResponse(c, err.Error())
+	case errors.Is(err, services.ErrWebhookInactive):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, err.Error())
+	}
+}
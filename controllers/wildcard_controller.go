@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"errors"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WildcardController 通配符控制器
+type WildcardController struct {
+	wildcardService *services.WildcardService
+}
+
+// NewWildcardController 创建通配符控制器实例
+func NewWildcardController() *WildcardController {
+	return &WildcardController{
+		wildcardService: services.NewWildcardService(),
+	}
+}
+
+// CreateWildcard 创建通配符
+func (wc *WildcardController) CreateWildcard(c *gin.Context) {
+	var req models.CreateWildcardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	wildcard, err := wc.wildcardService.CreateWildcard(&req)
+	if err != nil {
+		respondWildcardError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "创建成功", wildcard.ToResponse())
+}
+
+// GetWildcard 获取单个通配符
+func (wc *WildcardController) GetWildcard(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	wildcard, err := wc.wildcardService.GetWildcardByID(uint(id))
+	if err != nil {
+		utils.NotFoundResponse(c, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, wildcard.ToResponse())
+}
+
+// GetAllWildcards 获取所有通配符
+func (wc *WildcardController) GetAllWildcards(c *gin.Context) {
+	wildcards, err := wc.wildcardService.GetAllWildcards()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, err.Error())
+		return
+	}
+
+	// 转换为响应格式
+	responses := make([]models.WildcardResponse, len(wildcards))
+	for i, wildcard := range wildcards {
+		responses[i] = wildcard.ToResponse()
+	}
+
+	utils.SuccessResponse(c, responses)
+}
+
+// UpdateWildcard 更新通配符
+func (wc *WildcardController) UpdateWildcard(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	var req models.UpdateWildcardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	wildcard, err := wc.wildcardService.UpdateWildcard(uint(id), &req)
+	if err != nil {
+		respondWildcardError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "更新成功", wildcard.ToResponse())
+}
+
+// DeleteWildcard 删除通配符
+func (wc *WildcardController) DeleteWildcard(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID")
+		return
+	}
+
+	if err := wc.wildcardService.DeleteWildcard(uint(id)); err != nil {
+		respondWildcardError(c, err)
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// respondWildcardError 根据错误类型返回404、409、400或500
+func respondWildcardError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWildcardNotFound):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, services.ErrWildcardExists):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidWildcard):
+		utils.BadRequestResponse(c, err.Error())
+	default:
+		utils.InternalServerErrorResponse(c, err.Error())
+	}
+}
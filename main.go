@@ -4,6 +4,7 @@ import (
 	"flag"
 	"imgGeneratePrompts/config"
 	"imgGeneratePrompts/routes"
+	"imgGeneratePrompts/services"
 	"log"
 	"os"
 	"os/signal"
@@ -12,7 +13,7 @@ import (
 
 func main() {
 	// 定义命令行参数
-	migrate := flag.Bool("migrate", false, "执行数据库迁移")
+	migrate := flag.Bool("migrate", false, "执行所有未执行的版本化数据库迁移（更多操作见 db-manager -migrate）")
 	flag.Parse()
 
 	// 初始化配置
@@ -43,6 +44,15 @@ func main() {
 		log.Fatalf("创建上传目录失败: %v", err)
 	}
 
+	// 启动后台任务worker（JOB_WORKERS=0 时不启动，由其他进程执行任务）
+	jobRunner := services.NewJobRunner()
+	jobRunner.Start()
+	log.Printf("后台任务worker数量: %d", config.AppConfig.Jobs.Workers)
+
+	// 启动事件推送（webhook）
+	webhookDispatcher := services.NewWebhookDispatcher()
+	webhookDispatcher.Start()
+
 	// 设置路由
 	router := routes.SetupRoutes()
 
@@ -64,6 +74,10 @@ func main() {
 	<-quit
 	log.Println("正在关闭服务器...")
 
+	// 停止后台任务，执行中的任务归还后由下次启动重新执行
+	jobRunner.Stop()
+	webhookDispatcher.Stop()
+
 	// 关闭数据库连接
 	config.CloseDB()
 	log.Println("服务器已关闭")
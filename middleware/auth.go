@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"errors"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上下文中的键
+const (
+	contextUserKey   = "current_user"    // 当前用户
+	contextAPIKeyKey = "current_api_key" // 当前请求使用的API密钥
+)
+
+// Authenticate 解析 Authorization: Bearer <token> 并将当前用户放入上下文
+// 令牌可以是JWT访问令牌，也可以是以 igp_ 开头的个人API密钥（此时用户为密钥所有者）
+// 未携带令牌的请求以匿名身份继续；携带了无效令牌的请求直接返回401
+func Authenticate() gin.HandlerFunc {
+	authService := services.NewAuthService()
+	apiKeyService := services.NewAPIKeyService()
+
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if strings.HasPrefix(token, models.APIKeyPrefix) {
+			apiKey, err := apiKeyService.AuthenticateAPIKey(token)
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					utils.UnauthorizedResponse(c, err.Error())
+				} else {
+					utils.InternalServerErrorResponse(c, err.Error())
+				}
+				c.Abort()
+				return
+			}
+			if !apiKey.HasScope(requiredScope(c)) {
+				utils.ForbiddenResponse(c, "API密钥缺少所需权限: "+requiredScope(c))
+				c.Abort()
+				return
+			}
+
+			c.Set(contextUserKey, apiKey.User)
+			c.Set(contextAPIKeyKey, apiKey)
+			c.Next()
+			return
+		}
+
+		user, err := authService.AuthenticateAccessToken(token)
+		if err != nil {
+			if errors.Is(err, utils.ErrExpiredToken) {
+				utils.UnauthorizedResponse(c, "访问令牌已过期，请刷新令牌")
+			} else {
+				utils.UnauthorizedResponse(c, "无效的访问令牌")
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set(contextUserKey, user)
+		c.Next()
+	}
+}
+
+// RequireAuth 要求请求必须已登录
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentUser(c) == nil {
+			utils.UnauthorizedResponse(c, "请先登录")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope 要求使用API密钥的请求拥有指定权限（JWT登录的请求不受限制）
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey := CurrentAPIKey(c); apiKey != nil && !apiKey.HasScope(scope) {
+			utils.ForbiddenResponse(c, "API密钥缺少所需权限: "+scope)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentAPIKey 获取当前请求使用的API密钥，非API密钥认证时返回nil
+func CurrentAPIKey(c *gin.Context) *models.APIKey {
+	value, exists := c.Get(contextAPIKeyKey)
+	if !exists {
+		return nil
+	}
+	apiKey, _ := value.(*models.APIKey)
+	return apiKey
+}
+
+// CurrentUser 获取当前登录用户，匿名请求返回nil
+func CurrentUser(c *gin.Context) *models.User {
+	value, exists := c.Get(contextUserKey)
+	if !exists {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
+
+// CurrentUserID 获取当前登录用户的ID，匿名请求返回nil
+func CurrentUserID(c *gin.Context) *uint {
+	user := CurrentUser(c)
+	if user == nil {
+		return nil
+	}
+	id := user.ID
+	return &id
+}
+
+// bearerToken 从Authorization头中提取Bearer令牌
+func bearerToken(c *gin.Context) (string, bool) {
+	header := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
+// requiredScope 根据请求推断API密钥所需的基础权限：分析接口需要analyze，读请求需要read，其余需要write
+// GraphQL 的查询和动态提示词展开也通过 POST 提交，只要求read，GraphQL 的变更操作由 GraphQLController 检查write
+func requiredScope(c *gin.Context) string {
+	if strings.HasSuffix(c.FullPath(), "/analyze") {
+		return models.ScopeAnalyze
+	}
+	if strings.HasSuffix(c.FullPath(), "/graphql") || strings.HasSuffix(c.FullPath(), "/prompts/expand") {
+		return models.ScopeRead
+	}
+	switch c.Request.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return models.ScopeRead
+	default:
+		return models.ScopeWrite
+	}
+}
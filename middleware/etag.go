@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"imgGeneratePrompts/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bufferedWriter 缓存响应体，以便在写出前根据内容计算 ETag
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write 写入缓存而不是直接写出
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString 写入缓存而不是直接写出
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// ConditionalGET 为 GET 列表接口根据响应内容生成 ETag，If-None-Match 匹配时返回 304 且不返回响应体
+// 数据库查询照常执行，节省的是轮询时重复传输的带宽；不能用于流式响应（如导出）
+func ConditionalGET() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if original.Status() != http.StatusOK {
+			original.Write(writer.body.Bytes())
+			return
+		}
+
+		etag := utils.ContentETag(writer.body.Bytes())
+		if utils.NotModified(c, etag) {
+			return
+		}
+		original.Write(writer.body.Bytes())
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authorize 路由组级别的权限校验：读请求需要read权限，其余请求需要write权限
+func Authorize(read, write models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		perm := write
+		switch c.Request.Method {
+		case "GET", "HEAD", "OPTIONS":
+			perm = read
+		}
+		checkPermission(c, perm)
+	}
+}
+
+// RequirePermission 要求当前用户拥有指定权限
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checkPermission(c, perm)
+	}
+}
+
+// CurrentRole 获取当前请求的角色，未登录时使用配置的匿名角色
+func CurrentRole(c *gin.Context) string {
+	if user := CurrentUser(c); user != nil {
+		return user.Role
+	}
+	if config.AppConfig != nil && models.IsValidRole(config.AppConfig.Auth.AnonymousRole) {
+		return config.AppConfig.Auth.AnonymousRole
+	}
+	return models.RoleViewer
+}
+
+// checkPermission 校验权限，没有权限时返回403并中止请求
+func checkPermission(c *gin.Context, perm models.Permission) {
+	if models.RoleHasPermission(CurrentRole(c), perm) {
+		c.Next()
+		return
+	}
+
+	if CurrentUser(c) == nil {
+		utils.ForbiddenResponse(c, "未登录用户没有该权限，请先登录: "+string(perm))
+	} else {
+		utils.ForbiddenResponse(c, "当前角色没有该权限: "+string(perm))
+	}
+	c.Abort()
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery 与 gin.Recovery 相同，但 http.ErrAbortHandler 继续向上抛出，由 net/http 中断响应
+// gin 的 Recovery 会把它当作普通 panic 并正常结束响应，流式下载中途失败时客户端会得到截断但看似完整的文件
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err interface{}) {
+		if err == http.ErrAbortHandler {
+			panic(err)
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
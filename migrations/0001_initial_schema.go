@@ -0,0 +1,53 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0001 V3.0 的初始表结构：标签、提示词及其多对多关联
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, "tags", "CREATE TABLE `tags` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`name` varchar(100) NOT NULL COMMENT '标签名称',"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"PRIMARY KEY (`id`),"+
+				"UNIQUE KEY `name` (`name`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"); err != nil {
+				return err
+			}
+			if err := createTable(tx, "prompts", "CREATE TABLE `prompts` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"`deleted_at` datetime(3) NULL COMMENT '软删除时间',"+
+				"`prompt_text` text NOT NULL COMMENT '正面提示词',"+
+				"`negative_prompt` text COMMENT '负面提示词',"+
+				"`model_name` varchar(100) COMMENT '使用的AI模型名称',"+
+				"`reference_images` varchar(500) COMMENT '参考图片',"+
+				"`output_image` varchar(500) COMMENT '输出图片',"+
+				"`is_public` boolean DEFAULT false COMMENT '是否公开',"+
+				"`style_description` varchar(500) COMMENT '风格描述',"+
+				"`usage_scenario` varchar(500) COMMENT '适用场景描述',"+
+				"`atmosphere_description` varchar(500) COMMENT '氛围描述',"+
+				"`expressive_intent` varchar(500) COMMENT '表现意图描述',"+
+				"`structure_analysis` json COMMENT '提示词结构分析',"+
+				"PRIMARY KEY (`id`),"+
+				"INDEX `idx_prompts_deleted_at` (`deleted_at`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"); err != nil {
+				return err
+			}
+			return createTable(tx, "prompt_tags", "CREATE TABLE `prompt_tags` ("+
+				"`prompt_id` bigint unsigned NOT NULL,"+
+				"`tag_id` bigint unsigned NOT NULL,"+
+				"PRIMARY KEY (`prompt_id`, `tag_id`),"+
+				"CONSTRAINT `fk_prompt_tags_prompt` FOREIGN KEY (`prompt_id`) REFERENCES `prompts` (`id`),"+
+				"CONSTRAINT `fk_prompt_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags` (`id`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "prompt_tags", "prompts", "tags")
+		},
+	})
+}
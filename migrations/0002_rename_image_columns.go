@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0002 V3.1 字段重命名：reference_images → input_image_url，output_image → output_image_url
+// 以前需要按README手动执行，已手动改名的数据库会被跳过
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "rename_image_columns",
+		Up: func(tx *gorm.DB) error {
+			if err := renameColumn(tx, "prompts", "reference_images", "input_image_url",
+				"varchar(500) COMMENT '输入的参照图片的存储路径或URL；可能多个图片'"); err != nil {
+				return err
+			}
+			return renameColumn(tx, "prompts", "output_image", "output_image_url",
+				"varchar(500) COMMENT '输出的参照图片的存储路径或URL'")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := renameColumn(tx, "prompts", "input_image_url", "reference_images",
+				"varchar(500) COMMENT '参考图片'"); err != nil {
+				return err
+			}
+			return renameColumn(tx, "prompts", "output_image_url", "output_image",
+				"varchar(500) COMMENT '输出图片'")
+		},
+	})
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0003 动态提示词的通配符表
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "create_wildcards",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, "wildcards", "CREATE TABLE `wildcards` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`name` varchar(100) NOT NULL COMMENT '通配符名称',"+
+				"`content` text NOT NULL COMMENT '通配符选项，每行一个',"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"PRIMARY KEY (`id`),"+
+				"UNIQUE KEY `name` (`name`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "wildcards")
+		},
+	})
+}
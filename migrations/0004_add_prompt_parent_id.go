@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0004 提示词分叉的衍生关系
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "add_prompt_parent_id",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumn(tx, "prompts", "parent_id", "bigint unsigned NULL COMMENT '父提示词ID（分叉来源）'"); err != nil {
+				return err
+			}
+			return createIndex(tx, "prompts", "idx_prompts_parent_id", "`parent_id`")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndex(tx, "prompts", "idx_prompts_parent_id"); err != nil {
+				return err
+			}
+			return dropColumn(tx, "prompts", "parent_id")
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0005 收藏集及其有序条目
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "create_collections",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, "collections", "CREATE TABLE `collections` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"`name` varchar(100) NOT NULL COMMENT '收藏集名称',"+
+				"`description` text COMMENT '收藏集描述',"+
+				"`cover_image_url` varchar(500) COMMENT '封面图片URL',"+
+				"`visibility` varchar(20) NOT NULL DEFAULT 'private' COMMENT '可见性(private/public)',"+
+				"PRIMARY KEY (`id`),"+
+				"INDEX `idx_collections_visibility` (`visibility`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"); err != nil {
+				return err
+			}
+			return createTable(tx, "collection_items", "CREATE TABLE `collection_items` ("+
+				"`collection_id` bigint unsigned NOT NULL COMMENT '收藏集ID',"+
+				"`prompt_id` bigint unsigned NOT NULL COMMENT '提示词ID',"+
+				"`position` bigint NOT NULL DEFAULT 0 COMMENT '排序位置',"+
+				"`created_at` datetime(3) NULL COMMENT '加入时间',"+
+				"PRIMARY KEY (`collection_id`, `prompt_id`),"+
+				"INDEX `idx_collection_items_prompt_id` (`prompt_id`),"+
+				"CONSTRAINT `fk_collections_items` FOREIGN KEY (`collection_id`) REFERENCES `collections` (`id`) ON DELETE CASCADE,"+
+				"CONSTRAINT `fk_collection_items_prompt` FOREIGN KEY (`prompt_id`) REFERENCES `prompts` (`id`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "collection_items", "collections")
+		},
+	})
+}
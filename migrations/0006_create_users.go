@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0006 用户账号以及提示词所有者
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "create_users",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, "users", "CREATE TABLE `users` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"`username` varchar(50) NOT NULL COMMENT '用户名',"+
+				"`email` varchar(255) NULL COMMENT '邮箱',"+
+				"`password_hash` varchar(100) NOT NULL COMMENT 'bcrypt密码哈希',"+
+				"PRIMARY KEY (`id`),"+
+				"UNIQUE KEY `username` (`username`),"+
+				"UNIQUE KEY `email` (`email`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"); err != nil {
+				return err
+			}
+			if err := addColumn(tx, "prompts", "owner_id", "bigint unsigned NULL COMMENT '所有者用户ID'"); err != nil {
+				return err
+			}
+			return createIndex(tx, "prompts", "idx_prompts_owner_id", "`owner_id`")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndex(tx, "prompts", "idx_prompts_owner_id"); err != nil {
+				return err
+			}
+			if err := dropColumn(tx, "prompts", "owner_id"); err != nil {
+				return err
+			}
+			return dropTables(tx, "users")
+		},
+	})
+}
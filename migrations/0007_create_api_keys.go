@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0007 个人API密钥
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "create_api_keys",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, "api_keys", "CREATE TABLE `api_keys` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`user_id` bigint unsigned NOT NULL COMMENT '所属用户ID',"+
+				"`name` varchar(100) NOT NULL COMMENT '密钥名称',"+
+				"`prefix` varchar(20) NOT NULL COMMENT '密钥前缀（用于识别）',"+
+				"`key_hash` char(64) NOT NULL COMMENT '密钥SHA-256哈希',"+
+				"`scopes` varchar(100) NOT NULL COMMENT '权限范围，逗号分隔',"+
+				"`expires_at` datetime(3) NULL COMMENT '过期时间，为空表示永不过期',"+
+				"`last_used_at` datetime(3) NULL COMMENT '最后使用时间',"+
+				"`revoked_at` datetime(3) NULL COMMENT '吊销时间',"+
+				"PRIMARY KEY (`id`),"+
+				"UNIQUE KEY `key_hash` (`key_hash`),"+
+				"INDEX `idx_api_keys_user_id` (`user_id`),"+
+				"CONSTRAINT `fk_api_keys_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "api_keys")
+		},
+	})
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0008 用户角色（viewer/editor/curator/admin）
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "add_user_role",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, "users", "role", "varchar(20) NOT NULL DEFAULT 'editor' COMMENT '角色(viewer/editor/curator/admin)'")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, "users", "role")
+		},
+	})
+}
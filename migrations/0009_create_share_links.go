@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0009 提示词分享链接
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "create_share_links",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, "share_links", "CREATE TABLE `share_links` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`prompt_id` bigint unsigned NOT NULL COMMENT '提示词ID',"+
+				"`created_by` bigint unsigned NULL COMMENT '创建者用户ID',"+
+				"`token_hash` char(64) NOT NULL COMMENT '令牌SHA-256哈希',"+
+				"`expires_at` datetime(3) NOT NULL COMMENT '过期时间',"+
+				"`revoked_at` datetime(3) NULL COMMENT '吊销时间',"+
+				"`access_count` bigint NOT NULL DEFAULT 0 COMMENT '访问次数',"+
+				"`last_accessed_at` datetime(3) NULL COMMENT '最后访问时间',"+
+				"PRIMARY KEY (`id`),"+
+				"UNIQUE KEY `token_hash` (`token_hash`),"+
+				"INDEX `idx_share_links_prompt_id` (`prompt_id`),"+
+				"INDEX `idx_share_links_created_by` (`created_by`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "share_links")
+		},
+	})
+}
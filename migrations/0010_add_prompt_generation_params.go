@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0010 提示词生成参数（seed、采样器、CFG、模型资源等，Civitai meta 格式）
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "add_prompt_generation_params",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, "prompts", "generation_params", "json NULL COMMENT '生成参数（Civitai meta 格式）'")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, "prompts", "generation_params")
+		},
+	})
+}
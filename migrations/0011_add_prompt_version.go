@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0011 提示词版本号，用于乐观并发控制（ETag / If-Match）
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "add_prompt_version",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, "prompts", "version", "bigint unsigned NOT NULL DEFAULT 1 COMMENT '版本号（每次修改递增）'")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, "prompts", "version")
+		},
+	})
+}
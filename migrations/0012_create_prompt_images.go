@@ -0,0 +1,172 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// legacyImageURLLength 旧的 input_image_url / output_image_url 字段长度
+const legacyImageURLLength = 500
+
+// legacyPromptImages 旧字段中的图片
+type legacyPromptImages struct {
+	ID             uint
+	CreatedAt      *time.Time
+	InputImageURL  string
+	OutputImageURL string
+}
+
+// 0012 提示词图片表，取代逗号分隔的 input_image_url 和单个 output_image_url
+// 升级时将旧字段中的图片拆分写入新表后删除旧字段
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "create_prompt_images",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, "prompt_images", "CREATE TABLE `prompt_images` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`prompt_id` bigint unsigned NOT NULL COMMENT '提示词ID',"+
+				"`role` varchar(20) NOT NULL COMMENT '图片角色(input/output/mask/controlnet)',"+
+				"`position` bigint NOT NULL DEFAULT 0 COMMENT '同一角色内的排序位置',"+
+				"`url` varchar(2048) NOT NULL COMMENT '图片的存储路径或URL',"+
+				"`caption` varchar(500) COMMENT '图片说明',"+
+				"`width` bigint NOT NULL DEFAULT 0 COMMENT '宽度（像素，0表示未知）',"+
+				"`height` bigint NOT NULL DEFAULT 0 COMMENT '高度（像素，0表示未知）',"+
+				"`hash` varchar(64) COMMENT '文件内容的SHA-256（本地文件）',"+
+				"PRIMARY KEY (`id`),"+
+				"INDEX `idx_prompt_images_prompt_id` (`prompt_id`),"+
+				"INDEX `idx_prompt_images_hash` (`hash`),"+
+				"CONSTRAINT `fk_prompts_images` FOREIGN KEY (`prompt_id`) REFERENCES `prompts` (`id`) ON DELETE CASCADE"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"); err != nil {
+				return err
+			}
+			if !tx.Migrator().HasColumn("prompts", "input_image_url") {
+				return nil
+			}
+
+			var rows []legacyPromptImages
+			err := tx.Table("prompts").
+				Select("id, created_at, input_image_url, output_image_url").
+				Where("(input_image_url <> '' OR output_image_url <> '')").
+				FindInBatches(&rows, 500, func(batch *gorm.DB, _ int) error {
+					return copyLegacyImages(tx, rows)
+				}).Error
+			if err != nil {
+				return fmt.Errorf("迁移提示词图片失败: %v", err)
+			}
+
+			if err := dropColumn(tx, "prompts", "input_image_url"); err != nil {
+				return err
+			}
+			return dropColumn(tx, "prompts", "output_image_url")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := addColumn(tx, "prompts", "input_image_url",
+				"varchar(500) COMMENT '输入的参照图片的存储路径或URL；可能多个图片'"); err != nil {
+				return err
+			}
+			if err := addColumn(tx, "prompts", "output_image_url",
+				"varchar(500) COMMENT '输出的参照图片的存储路径或URL'"); err != nil {
+				return err
+			}
+			if tx.Migrator().HasTable("prompt_images") {
+				if err := restoreLegacyImages(tx); err != nil {
+					return fmt.Errorf("回写提示词图片失败: %v", err)
+				}
+			}
+			return dropTables(tx, "prompt_images")
+		},
+	})
+}
+
+// copyLegacyImages 将旧字段中的图片写入 prompt_images 表
+func copyLegacyImages(tx *gorm.DB, rows []legacyPromptImages) error {
+	var images []map[string]interface{}
+	for _, row := range rows {
+		position := 0
+		for _, url := range strings.Split(row.InputImageURL, ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				images = append(images, legacyImageRow(row, "input", position, url))
+				position++
+			}
+		}
+		if url := strings.TrimSpace(row.OutputImageURL); url != "" {
+			images = append(images, legacyImageRow(row, "output", 0, url))
+		}
+	}
+	if len(images) == 0 {
+		return nil
+	}
+	return tx.Table("prompt_images").Create(&images).Error
+}
+
+// legacyImageRow 构造一条 prompt_images 记录
+func legacyImageRow(row legacyPromptImages, role string, position int, url string) map[string]interface{} {
+	return map[string]interface{}{
+		"created_at": row.CreatedAt,
+		"prompt_id":  row.ID,
+		"role":       role,
+		"position":   position,
+		"url":        url,
+	}
+}
+
+// restoreLegacyImages 将输入图片和第一张输出图片写回旧字段
+// 旧字段长度有限，放不下的输入图片以及蒙版、ControlNet 等其他角色的图片会丢失
+func restoreLegacyImages(tx *gorm.DB) error {
+	var images []struct {
+		PromptID uint
+		Role     string
+		URL      string
+	}
+	err := tx.Table("prompt_images").
+		Select("prompt_id, role, url").
+		Where("role IN ?", []string{"input", "output"}).
+		Order("prompt_id ASC, role ASC, position ASC, id ASC").
+		Find(&images).Error
+	if err != nil {
+		return err
+	}
+
+	legacy := make(map[uint]*legacyPromptImages)
+	var order []uint
+	for _, image := range images {
+		row := legacy[image.PromptID]
+		if row == nil {
+			row = &legacyPromptImages{ID: image.PromptID}
+			legacy[image.PromptID] = row
+			order = append(order, image.PromptID)
+		}
+		if len(image.URL) > legacyImageURLLength || strings.Contains(image.URL, ",") {
+			continue
+		}
+		switch {
+		case image.Role == "output" && row.OutputImageURL == "":
+			row.OutputImageURL = image.URL
+		case image.Role == "input":
+			joined := image.URL
+			if row.InputImageURL != "" {
+				joined = row.InputImageURL + "," + image.URL
+			}
+			if len(joined) <= legacyImageURLLength {
+				row.InputImageURL = joined
+			}
+		}
+	}
+
+	for _, id := range order {
+		row := legacy[id]
+		err := tx.Table("prompts").Where("id = ?", id).Updates(map[string]interface{}{
+			"input_image_url":  row.InputImageURL,
+			"output_image_url": row.OutputImageURL,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0013 后台任务表（异步分析等）
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "create_jobs",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, "jobs", "CREATE TABLE `jobs` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"`type` varchar(32) NOT NULL COMMENT '任务类型',"+
+				"`status` varchar(20) NOT NULL COMMENT '任务状态',"+
+				"`owner_id` bigint unsigned NULL COMMENT '创建者用户ID',"+
+				"`prompt_id` bigint unsigned NULL COMMENT '关联的提示词ID',"+
+				"`auto_apply` boolean NOT NULL DEFAULT false COMMENT '成功后是否将结果应用到提示词',"+
+				"`payload` json COMMENT '任务参数',"+
+				"`result` json COMMENT '执行结果',"+
+				"`error` text COMMENT '最近一次失败的原因',"+
+				"`attempts` bigint NOT NULL DEFAULT 0 COMMENT '已尝试次数',"+
+				"`max_attempts` bigint NOT NULL DEFAULT 3 COMMENT '最大尝试次数',"+
+				"`run_at` datetime(3) NOT NULL COMMENT '最早可执行时间',"+
+				"`locked_until` datetime(3) NULL COMMENT '执行租约到期时间',"+
+				"`started_at` datetime(3) NULL COMMENT '最近一次开始执行时间',"+
+				"`finished_at` datetime(3) NULL COMMENT '完成时间',"+
+				"`applied_at` datetime(3) NULL COMMENT '结果应用到提示词的时间',"+
+				"`cancel_requested` boolean NOT NULL DEFAULT false COMMENT '是否已请求取消',"+
+				"PRIMARY KEY (`id`),"+
+				"INDEX `idx_jobs_status_run_at` (`status`, `run_at`),"+
+				"INDEX `idx_jobs_owner_id` (`owner_id`),"+
+				"INDEX `idx_jobs_prompt_id` (`prompt_id`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "jobs")
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0014 事件推送订阅表和推送记录表
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "create_webhooks",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, "webhooks", "CREATE TABLE `webhooks` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"`owner_id` bigint unsigned NOT NULL COMMENT '所属用户ID',"+
+				"`url` varchar(2048) NOT NULL COMMENT '接收地址',"+
+				"`events` varchar(255) NOT NULL COMMENT '订阅的事件类型，逗号分隔',"+
+				"`secret` varchar(128) NOT NULL COMMENT '签名密钥',"+
+				"`description` varchar(255) COMMENT '说明',"+
+				"`active` boolean NOT NULL DEFAULT true COMMENT '是否启用',"+
+				"PRIMARY KEY (`id`),"+
+				"INDEX `idx_webhooks_owner_id` (`owner_id`)"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"); err != nil {
+				return err
+			}
+			return createTable(tx, "webhook_deliveries", "CREATE TABLE `webhook_deliveries` ("+
+				"`id` bigint unsigned NOT NULL AUTO_INCREMENT,"+
+				"`created_at` datetime(3) NULL COMMENT '创建时间',"+
+				"`updated_at` datetime(3) NULL COMMENT '更新时间',"+
+				"`webhook_id` bigint unsigned NOT NULL COMMENT '订阅ID',"+
+				"`event_id` varchar(64) NOT NULL COMMENT '事件ID',"+
+				"`event_type` varchar(32) NOT NULL COMMENT '事件类型',"+
+				"`payload` json COMMENT '推送内容',"+
+				"`status` varchar(20) NOT NULL COMMENT '推送状态',"+
+				"`attempts` bigint NOT NULL DEFAULT 0 COMMENT '已尝试次数',"+
+				"`max_attempts` bigint NOT NULL DEFAULT 5 COMMENT '最大尝试次数',"+
+				"`next_attempt_at` datetime(3) NOT NULL COMMENT '下次尝试时间',"+
+				"`locked_until` datetime(3) NULL COMMENT '发送租约到期时间',"+
+				"`response_status` bigint NOT NULL DEFAULT 0 COMMENT '最近一次响应状态码',"+
+				"`response_body` text COMMENT '最近一次响应内容（截断）',"+
+				"`error` text COMMENT '最近一次失败的原因',"+
+				"`duration_ms` bigint NOT NULL DEFAULT 0 COMMENT '最近一次请求耗时（毫秒）',"+
+				"`delivered_at` datetime(3) NULL COMMENT '推送成功时间',"+
+				"`redelivery_of` bigint unsigned NULL COMMENT '手动重新推送的原记录ID',"+
+				"PRIMARY KEY (`id`),"+
+				"INDEX `idx_webhook_deliveries_webhook_id` (`webhook_id`),"+
+				"INDEX `idx_webhook_deliveries_status_next` (`status`, `next_attempt_at`),"+
+				"CONSTRAINT `fk_webhook_deliveries_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks` (`id`) ON DELETE CASCADE"+
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropTables(tx, "webhook_deliveries", "webhooks")
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0015 收藏集所有者
+// 此前的收藏集没有所有者，private 实际上对所有人可见；迁移时将其标记为公开以反映真实的访问范围
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "add_collection_owner",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumn(tx, "collections", "owner_id", "bigint unsigned NULL COMMENT '所有者用户ID'"); err != nil {
+				return err
+			}
+			if err := createIndex(tx, "collections", "idx_collections_owner_id", "`owner_id`"); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE `collections` SET `visibility` = 'public' WHERE `owner_id` IS NULL").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndex(tx, "collections", "idx_collections_owner_id"); err != nil {
+				return err
+			}
+			return dropColumn(tx, "collections", "owner_id")
+		},
+	})
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0016 无所有者的私有提示词保持私有
+// 早期版本在此将它们改为公开，回滚时无法还原；现在保留私有标记，迁移后这些提示词对所有人不可见，
+// 需要使用 db-manager -assign-ownerless 将其交给管理员。保留本版本号以维持迁移版本连续
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "publish_ownerless_prompts",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0017 匿名任务的访问令牌
+// 此前匿名创建的任务对所有人可见；没有令牌的旧任务迁移后只能由后台继续执行，无法再被查询或取消
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "add_job_access_token",
+		Up: func(tx *gorm.DB) error {
+			return addColumn(tx, "jobs", "access_token_hash", "varchar(64) NOT NULL DEFAULT '' COMMENT '匿名任务访问令牌的SHA-256'")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumn(tx, "jobs", "access_token_hash")
+		},
+	})
+}
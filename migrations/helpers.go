@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// createTable 表不存在时执行建表语句
+func createTable(tx *gorm.DB, table, ddl string) error {
+	if tx.Migrator().HasTable(table) {
+		return nil
+	}
+	if err := tx.Exec(ddl).Error; err != nil {
+		return fmt.Errorf("创建表 %s 失败: %v", table, err)
+	}
+	return nil
+}
+
+// dropTables 按顺序删除表（不存在时跳过）
+func dropTables(tx *gorm.DB, tables ...string) error {
+	for _, table := range tables {
+		if err := tx.Exec("DROP TABLE IF EXISTS `" + table + "`").Error; err != nil {
+			return fmt.Errorf("删除表 %s 失败: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// addColumn 字段不存在时添加字段
+func addColumn(tx *gorm.DB, table, column, definition string) error {
+	if tx.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	sql := fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` %s", table, column, definition)
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("为表 %s 添加字段 %s 失败: %v", table, column, err)
+	}
+	return nil
+}
+
+// dropColumn 字段存在时删除字段
+func dropColumn(tx *gorm.DB, table, column string) error {
+	if !tx.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	sql := fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`", table, column)
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("删除表 %s 的字段 %s 失败: %v", table, column, err)
+	}
+	return nil
+}
+
+// renameColumn 旧字段存在且新字段不存在时重命名字段
+func renameColumn(tx *gorm.DB, table, from, to, definition string) error {
+	if !tx.Migrator().HasColumn(table, from) || tx.Migrator().HasColumn(table, to) {
+		return nil
+	}
+	sql := fmt.Sprintf("ALTER TABLE `%s` CHANGE COLUMN `%s` `%s` %s", table, from, to, definition)
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("重命名表 %s 的字段 %s 失败: %v", table, from, err)
+	}
+	return nil
+}
+
+// createIndex 索引不存在时创建索引
+func createIndex(tx *gorm.DB, table, name, columns string) error {
+	if tx.Migrator().HasIndex(table, name) {
+		return nil
+	}
+	sql := fmt.Sprintf("CREATE INDEX `%s` ON `%s` (%s)", name, table, columns)
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("创建索引 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// dropIndex 索引存在时删除索引
+func dropIndex(tx *gorm.DB, table, name string) error {
+	if !tx.Migrator().HasIndex(table, name) {
+		return nil
+	}
+	if err := tx.Exec(fmt.Sprintf("DROP INDEX `%s` ON `%s`", name, table)).Error; err != nil {
+		return fmt.Errorf("删除索引 %s 失败: %v", name, err)
+	}
+	return nil
+}
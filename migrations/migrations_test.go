@@ -0,0 +1,22 @@
+package migrations_test
+
+import (
+	"imgGeneratePrompts/migrations"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRegistry 测试迁移编号连续且都有升级和回滚
+func TestRegistry(t *testing.T) {
+	all := migrations.All()
+	assert.NotEmpty(t, all)
+
+	for i, migration := range all {
+		assert.Equal(t, i+1, migration.Version, "迁移版本号应从1开始连续递增")
+		assert.NotEmpty(t, migration.Name)
+		assert.NotNil(t, migration.Up, "迁移 %d 缺少 Up", migration.Version)
+		assert.NotNil(t, migration.Down, "迁移 %d 缺少 Down", migration.Version)
+	}
+	assert.Equal(t, len(all), migrations.Latest())
+}
@@ -0,0 +1,217 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一个带编号的数据库迁移，Up 升级、Down 回滚
+// 迁移应尽量幂等：已通过 AutoMigrate 建好表的旧数据库第一次执行时，已存在的表和字段会被跳过
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移记录 - 对应 schema_migrations 表
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// registry 所有已注册的迁移，由各迁移文件的 init 函数注册
+var registry []Migration
+
+// register 注册迁移
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All 返回按版本号排序的全部迁移
+func All() []Migration {
+	all := make([]Migration, len(registry))
+	copy(all, registry)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Latest 返回最新的迁移版本号
+func Latest() int {
+	all := All()
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator 创建迁移执行器
+func NewMigrator(db *gorm.DB) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: All(),
+	}
+}
+
+// Status 返回每个迁移的执行状态
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// CurrentVersion 返回已执行的最高版本号，没有执行过任何迁移时返回0
+func (m *Migrator) CurrentVersion() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// Up 执行所有未执行的迁移，返回本次执行的数量
+func (m *Migrator) Up() (int, error) {
+	return m.To(Latest())
+}
+
+// Down 按版本从高到低回滚指定数量的迁移，返回本次回滚的数量
+func (m *Migrator) Down(steps int) (int, error) {
+	if steps <= 0 {
+		return 0, nil
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.run(migration, false); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// To 升级或回滚到指定版本，返回本次执行或回滚的数量
+func (m *Migrator) To(version int) (int, error) {
+	if version < 0 || version > Latest() {
+		return 0, fmt.Errorf("无效的迁移版本 %d（可用范围 0-%d）", version, Latest())
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	// 先回滚高于目标版本的迁移
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok && migration.Version > version {
+			if err := m.run(migration, false); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	// 再执行不高于目标版本的未执行迁移
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= version {
+			if err := m.run(migration, true); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// run 执行单个迁移并更新 schema_migrations
+// 注意：MySQL 的 DDL 会隐式提交事务，因此迁移本身需要保证可重复执行
+func (m *Migrator) run(migration Migration, up bool) error {
+	direction, fn := "回滚", migration.Down
+	if up {
+		direction, fn = "执行", migration.Up
+	}
+	log.Printf("%s迁移 %04d_%s", direction, migration.Version, migration.Name)
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if up {
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		}
+		return tx.Delete(&SchemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("%s迁移 %04d_%s 失败: %v", direction, migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// applied 读取已执行的迁移记录，必要时创建 schema_migrations 表
+func (m *Migrator) applied() (map[int]SchemaMigration, error) {
+	if !m.db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := m.db.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+			return nil, fmt.Errorf("创建 schema_migrations 表失败: %v", err)
+		}
+	}
+
+	var records []SchemaMigration
+	if err := m.db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("读取迁移记录失败: %v", err)
+	}
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API密钥权限范围
+const (
+	ScopeRead    = "read"    // 读取提示词、标签等
+	ScopeWrite   = "write"   // 创建、修改、删除
+	ScopeAnalyze = "analyze" // 调用AI分析接口
+	ScopeAdmin   = "admin"   // 全部权限，包括管理API密钥
+)
+
+// APIKeyPrefix API密钥的固定前缀，用于区分JWT
+const APIKeyPrefix = "igp_"
+
+// ValidScopes 所有合法的权限范围
+var ValidScopes = []string{ScopeRead, ScopeWrite, ScopeAnalyze, ScopeAdmin}
+
+// APIKey 个人API密钥模型 - 对应 api_keys 表
+// 只保存密钥的SHA-256哈希，明文仅在创建时返回一次
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UserID     uint       `json:"user_id" gorm:"not null;index;comment:所属用户ID"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null;comment:密钥名称"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(20);not null;comment:密钥前缀（用于识别）"`
+	KeyHash    string     `json:"-" gorm:"type:char(64);unique;not null;comment:密钥SHA-256哈希"`
+	Scopes     string     `json:"-" gorm:"type:varchar(100);not null;comment:权限范围，逗号分隔"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"comment:过期时间，为空表示永不过期"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"comment:最后使用时间"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"comment:吊销时间"`
+	User       *User      `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// GetScopes 获取权限范围列表
+func (k *APIKey) GetScopes() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope 判断密钥是否拥有指定权限，admin拥有全部权限
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.GetScopes() {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive 判断密钥当前是否可用（未吊销且未过期）
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// APIKeyResponse API密钥响应结构体
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Active     bool       `json:"active"`
+}
+
+// ToResponse 转换为响应结构体
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		CreatedAt:  k.CreatedAt,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.GetScopes(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		Active:     k.IsActive(),
+	}
+}
+
+// CreateAPIKeyRequest 创建API密钥的请求结构体
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=read write analyze admin"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"` // 为空表示永不过期
+}
+
+// CreateAPIKeyResponse 创建API密钥的响应结构体（明文密钥仅返回这一次）
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
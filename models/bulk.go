@@ -0,0 +1,86 @@
+package models
+
+// 批量操作类型
+const (
+	BulkOpAddTags         = "add_tags"          // 添加标签
+	BulkOpRemoveTags      = "remove_tags"       // 移除标签
+	BulkOpSetPublic       = "set_public"        // 设置公开状态
+	BulkOpSetModelName    = "set_model_name"    // 设置模型名称
+	BulkOpDelete          = "delete"            // 删除（软删除）
+	BulkOpRestore         = "restore"           // 恢复已删除的提示词
+	BulkOpAddToCollection = "add_to_collection" // 加入收藏集
+)
+
+// 单个提示词的批量操作结果
+const (
+	BulkStatusSuccess = "success" // 已执行（预演时表示将会执行）
+	BulkStatusSkipped = "skipped" // 无需修改，如标签已存在、已是目标状态
+	BulkStatusFailed  = "failed"  // 不存在、无权限修改或无法执行
+)
+
+// MaxBulkPrompts 单次批量操作最多处理的提示词数量
+const MaxBulkPrompts = 1000
+
+// BulkPromptFilter 批量操作的过滤条件，与 PromptQuery 的过滤字段相同
+type BulkPromptFilter struct {
+	ModelName string   `json:"model_name"`
+	IsPublic  *bool    `json:"is_public"`
+	Keyword   string   `json:"keyword"`
+	TagNames  []string `json:"tag_names"`
+}
+
+// ToPromptQuery 转换为不分页的 PromptQuery
+func (f *BulkPromptFilter) ToPromptQuery(viewerID *uint) *PromptQuery {
+	return &PromptQuery{
+		ModelName: f.ModelName,
+		IsPublic:  f.IsPublic,
+		Keyword:   f.Keyword,
+		TagNames:  f.TagNames,
+		ViewerID:  viewerID,
+	}
+}
+
+// BulkPromptRequest 批量操作请求：通过 ids 或 filter 之一选择提示词
+type BulkPromptRequest struct {
+	IDs          []uint            `json:"ids"`
+	Filter       *BulkPromptFilter `json:"filter"`
+	Operation    string            `json:"operation" binding:"required,oneof=add_tags remove_tags set_public set_model_name delete restore add_to_collection"`
+	TagNames     []string          `json:"tag_names"`     // add_tags / remove_tags
+	IsPublic     *bool             `json:"is_public"`     // set_public
+	ModelName    *string           `json:"model_name"`    // set_model_name
+	CollectionID uint              `json:"collection_id"` // add_to_collection
+	DryRun       bool              `json:"dry_run"`       // 只返回将要执行的结果，不修改数据
+	ViewerID     *uint             `json:"-"`             // 当前用户，不接受客户端传入
+}
+
+// BulkItemResult 单个提示词的操作结果
+type BulkItemResult struct {
+	ID      uint   `json:"id"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// BulkPromptResult 批量操作结果
+type BulkPromptResult struct {
+	Operation string           `json:"operation"`
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Skipped   int              `json:"skipped"`
+	Failed    int              `json:"failed"`
+	Items     []BulkItemResult `json:"items"`
+}
+
+// Add 记录单个提示词的结果并更新计数
+func (r *BulkPromptResult) Add(id uint, status, message string) {
+	r.Items = append(r.Items, BulkItemResult{ID: id, Status: status, Message: message})
+	r.Total++
+	switch status {
+	case BulkStatusSuccess:
+		r.Succeeded++
+	case BulkStatusSkipped:
+		r.Skipped++
+	case BulkStatusFailed:
+		r.Failed++
+	}
+}
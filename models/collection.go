@@ -0,0 +1,131 @@
+package models
+
+import (
+	"time"
+)
+
+// 收藏集可见性
+const (
+	CollectionVisibilityPrivate = "private"
+	CollectionVisibilityPublic  = "public"
+)
+
+// Collection 收藏集模型 - 对应 collections 表
+// 用于人工整理的提示词集合（如"客户X情绪板"），与描述内容的标签互补
+type Collection struct {
+	ID            uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt     time.Time         `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt     time.Time         `json:"updated_at" gorm:"autoUpdateTime;comment:更新时间"`
+	Name          string            `json:"name" gorm:"type:varchar(100);not null;comment:收藏集名称"`
+	Description   string            `json:"description" gorm:"type:text;comment:收藏集描述"`
+	CoverImageURL string            `json:"cover_image_url" gorm:"type:varchar(500);comment:封面图片URL"`
+	Visibility    string            `json:"visibility" gorm:"type:varchar(20);not null;default:private;index;comment:可见性(private/public)"`
+	OwnerID       *uint             `json:"owner_id" gorm:"index;comment:所有者用户ID"`
+	Items         []*CollectionItem `json:"-" gorm:"foreignKey:CollectionID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName 指定表名
+func (Collection) TableName() string {
+	return "collections"
+}
+
+// IsVisibleTo 判断收藏集对指定用户是否可见：公开的收藏集所有人可见，私有收藏集仅所有者可见
+func (c *Collection) IsVisibleTo(userID *uint) bool {
+	return c.Visibility == CollectionVisibilityPublic || c.isOwnedBy(userID)
+}
+
+// CanBeModifiedBy 判断指定用户是否可以修改或删除收藏集
+// 无所有者的收藏集（未登录时创建，总是公开）保持开放，有所有者的收藏集仅所有者可修改
+func (c *Collection) CanBeModifiedBy(userID *uint) bool {
+	return c.OwnerID == nil || c.isOwnedBy(userID)
+}
+
+// isOwnedBy 判断收藏集是否属于指定用户
+func (c *Collection) isOwnedBy(userID *uint) bool {
+	return c.OwnerID != nil && userID != nil && *c.OwnerID == *userID
+}
+
+// CollectionItem 收藏集条目 - 对应 collection_items 表（带排序的多对多关联）
+type CollectionItem struct {
+	CollectionID uint      `json:"collection_id" gorm:"primaryKey;comment:收藏集ID"`
+	PromptID     uint      `json:"prompt_id" gorm:"primaryKey;index;comment:提示词ID"`
+	Position     int       `json:"position" gorm:"not null;default:0;comment:排序位置"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime;comment:加入时间"`
+	Prompt       *Prompt   `json:"-" gorm:"foreignKey:PromptID"`
+}
+
+// TableName 指定表名
+func (CollectionItem) TableName() string {
+	return "collection_items"
+}
+
+// CollectionResponse 收藏集响应结构体
+type CollectionResponse struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	CoverImageURL string    `json:"cover_image_url"`
+	Visibility    string    `json:"visibility"`
+	OwnerID       *uint     `json:"owner_id"`
+	ItemCount     int64     `json:"item_count"`
+}
+
+// ToResponse 转换为响应结构体
+func (c *Collection) ToResponse(itemCount int64) CollectionResponse {
+	return CollectionResponse{
+		ID:            c.ID,
+		CreatedAt:     c.CreatedAt,
+		UpdatedAt:     c.UpdatedAt,
+		Name:          c.Name,
+		Description:   c.Description,
+		CoverImageURL: c.CoverImageURL,
+		Visibility:    c.Visibility,
+		OwnerID:       c.OwnerID,
+		ItemCount:     itemCount,
+	}
+}
+
+// CollectionItemResponse 收藏集条目响应结构体
+type CollectionItemResponse struct {
+	Position int            `json:"position"`
+	AddedAt  time.Time      `json:"added_at"`
+	Prompt   PromptResponse `json:"prompt"`
+}
+
+// CollectionDetailResponse 收藏集详情响应结构体（包含有序条目）
+type CollectionDetailResponse struct {
+	CollectionResponse
+	Items []CollectionItemResponse `json:"items"`
+}
+
+// CreateCollectionRequest 创建收藏集的请求结构体
+type CreateCollectionRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	Description   string `json:"description"`
+	CoverImageURL string `json:"cover_image_url" binding:"max=500"`
+	Visibility    string `json:"visibility" binding:"omitempty,oneof=private public"` // 登录时默认为private，未登录时只能为public
+	PromptIDs     []uint `json:"prompt_ids"`                                          // 创建时可直接加入的提示词（按顺序）
+	OwnerID       *uint  `json:"-"`                                                   // 由当前登录用户决定，不接受客户端传入
+}
+
+// UpdateCollectionRequest 更新收藏集的请求结构体
+type UpdateCollectionRequest struct {
+	Name          *string `json:"name" binding:"omitempty,max=100"`
+	Description   *string `json:"description"`
+	CoverImageURL *string `json:"cover_image_url" binding:"omitempty,max=500"`
+	Visibility    *string `json:"visibility" binding:"omitempty,oneof=private public"`
+}
+
+// CollectionItemsRequest 添加条目或重新排序的请求结构体
+type CollectionItemsRequest struct {
+	PromptIDs []uint `json:"prompt_ids" binding:"required,min=1"`
+}
+
+// CollectionQuery 收藏集列表查询参数
+type CollectionQuery struct {
+	Visibility string `form:"visibility" binding:"omitempty,oneof=private public"`
+	Keyword    string `form:"keyword"`
+	ViewerID   *uint  `form:"-"` // 当前用户，只返回对其可见的收藏集
+}
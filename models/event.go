@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 事件类型
+const (
+	EventPromptCreated = "prompt.created" // 数据为 PromptResponse
+	EventPromptUpdated = "prompt.updated" // 数据为 PromptResponse
+	EventPromptDeleted = "prompt.deleted" // 数据为 PromptDeletedEvent
+	EventTagCreated    = "tag.created"    // 数据为 Tag
+	EventTagMerged     = "tag.merged"     // 数据为 TagMergedEvent
+	EventJobFinished   = "job.finished"   // 数据为 Job（状态为 succeeded、failed 或 canceled）
+	// EventStreamReset 客户端的 Last-Event-ID 已超出重放缓冲区（或来自服务重启之前），中间的事件已丢失，需要重新拉取数据
+	EventStreamReset = "stream.reset"
+)
+
+// Event 资料库变更事件
+type Event struct {
+	ID   string      `json:"id"` // 格式为 "<进程启动时间>-<序号>"，用于 Last-Event-ID 续传
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+
+	Seq     uint64 `json:"-"` // 本进程内的递增序号
+	OwnerID *uint  `json:"-"` // 事件所属用户，非公开事件只发送给该用户
+	Public  bool   `json:"-"` // 是否对所有人可见
+}
+
+// IsVisibleTo 判断事件是否可以发送给指定用户，规则与提示词的可见性一致：公开的事件对所有人可见，其他事件只发送给所有者
+func (e *Event) IsVisibleTo(userID *uint) bool {
+	if e.Public {
+		return true
+	}
+	return e.OwnerID != nil && userID != nil && *e.OwnerID == *userID
+}
+
+// PromptDeletedEvent prompt.deleted 事件的数据
+type PromptDeletedEvent struct {
+	ID uint `json:"id"`
+}
+
+// TagMergedEvent tag.merged 事件的数据，合并后相关提示词的版本号已加一
+type TagMergedEvent struct {
+	SourceID  uint   `json:"source_id"`
+	Target    *Tag   `json:"target"`
+	PromptIDs []uint `json:"prompt_ids"` // 标签发生变化的提示词
+}
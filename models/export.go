@@ -0,0 +1,35 @@
+package models
+
+// 导出支持的文件格式
+const (
+	ExportFormatJSONL    = "jsonl"
+	ExportFormatCSV      = "csv"
+	ExportFormatMarkdown = "markdown" // 带图片的 Markdown 画廊
+	ExportFormatText     = "txt"      // 每行一个提示词，可直接用于 A1111 的 "Prompts from file or textbox" 脚本
+	ExportFormatCivitai  = "civitai"  // 与 Civitai /api/v1/images 响应相同结构的 {"items": [...]}
+)
+
+// ExportQuery 导出查询参数：过滤和排序条件与 PromptQuery 相同，但不分页
+type ExportQuery struct {
+	Format    string   `form:"format" binding:"omitempty,oneof=jsonl csv markdown txt civitai"` // 默认 jsonl
+	Zip       bool     `form:"zip"`                                                             // 打包为zip并附带引用的本地图片
+	ModelName string   `form:"model_name"`
+	IsPublic  *bool    `form:"is_public"`
+	Keyword   string   `form:"keyword"`
+	TagNames  []string `form:"tag_names"`
+	SortBy    string   `form:"sort_by"`
+	SortOrder string   `form:"sort_order"`
+}
+
+// ToPromptQuery 转换为不分页的 PromptQuery
+func (q *ExportQuery) ToPromptQuery(viewerID *uint) *PromptQuery {
+	return &PromptQuery{
+		ModelName: q.ModelName,
+		IsPublic:  q.IsPublic,
+		Keyword:   q.Keyword,
+		TagNames:  q.TagNames,
+		SortBy:    q.SortBy,
+		SortOrder: q.SortOrder,
+		ViewerID:  viewerID,
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+// 批量导入支持的文件格式
+const (
+	ImportFormatJSONL   = "jsonl"
+	ImportFormatCSV     = "csv"
+	ImportFormatCivitai = "civitai" // Civitai 图片元数据（接口响应或保存的条目），不使用列映射
+)
+
+// 导入时遇到重复提示词（提示词文本相同）的处理方式
+const (
+	ImportOnDuplicateSkip   = "skip"   // 跳过该行
+	ImportOnDuplicateUpsert = "upsert" // 用该行中出现的字段更新已有提示词
+)
+
+const (
+	// DefaultImportChunkSize 默认每个事务处理的行数
+	DefaultImportChunkSize = 100
+	// MaxImportChunkSize 每个事务允许处理的最大行数
+	MaxImportChunkSize = 1000
+)
+
+// ImportOptions 批量导入选项
+type ImportOptions struct {
+	Format      string            `form:"format" binding:"omitempty,oneof=jsonl csv civitai"` // 为空时根据文件扩展名判断
+	OnDuplicate string            `form:"on_duplicate" binding:"omitempty,oneof=skip upsert"` // 默认 skip
+	ChunkSize   int               `form:"chunk_size" binding:"omitempty,min=1,max=1000"`      // 默认100
+	Mapping     map[string]string `form:"-"`                                                  // 源列名 -> CreatePromptRequest 字段名
+	OwnerID     *uint             `form:"-"`                                                  // 导入的提示词归属的用户
+}
+
+// ImportRowError 导入失败的行
+type ImportRowError struct {
+	Line    int    `json:"line"` // 文件中的行号（从1开始，CSV表头为第1行；Civitai 格式为条目序号）
+	Message string `json:"message"`
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	Total   int              `json:"total"`   // 读取到的数据行数
+	Created int              `json:"created"` // 新建的提示词数量
+	Updated int              `json:"updated"` // upsert 模式下更新的提示词数量
+	Skipped int              `json:"skipped"` // skip 模式下跳过的重复行数量
+	Failed  int              `json:"failed"`  // 失败的行数量
+	Errors  []ImportRowError `json:"errors"`  // 每个失败行的错误信息
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 后台任务类型
+const (
+	JobTypeAnalyze = "analyze" // AI分析图片和提示词
+)
+
+// 后台任务状态
+const (
+	JobStatusPending   = "pending"   // 等待执行（包括等待重试）
+	JobStatusRunning   = "running"   // 执行中
+	JobStatusSucceeded = "succeeded" // 执行成功
+	JobStatusFailed    = "failed"    // 重试次数用尽或出现不可重试的错误
+	JobStatusCanceled  = "canceled"  // 已取消
+)
+
+// Job 后台任务模型 - 对应 jobs 表
+// worker 通过条件更新领取任务，locked_until 过期的运行中任务视为执行者已退出，可以被重新领取
+type Job struct {
+	ID              uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt       time.Time       `json:"updated_at" gorm:"autoUpdateTime;comment:更新时间"`
+	Type            string          `json:"type" gorm:"type:varchar(32);not null;comment:任务类型"`
+	Status          string          `json:"status" gorm:"type:varchar(20);not null;index:idx_jobs_status_run_at,priority:1;comment:任务状态"`
+	OwnerID         *uint           `json:"owner_id" gorm:"index;comment:创建者用户ID"`
+	AccessTokenHash string          `json:"-" gorm:"type:varchar(64);not null;default:'';comment:匿名任务访问令牌的SHA-256"`
+	AccessToken     string          `json:"access_token,omitempty" gorm:"-"` // 匿名任务的访问令牌，只在创建时返回一次
+	PromptID        *uint           `json:"prompt_id" gorm:"index;comment:关联的提示词ID"`
+	AutoApply       bool            `json:"auto_apply" gorm:"not null;default:false;comment:成功后是否将结果应用到提示词"`
+	Payload         json.RawMessage `json:"payload" gorm:"type:json;comment:任务参数"`
+	Result          json.RawMessage `json:"result" gorm:"type:json;comment:执行结果"`
+	Error           string          `json:"error" gorm:"type:text;comment:最近一次失败的原因"`
+	Attempts        int             `json:"attempts" gorm:"not null;default:0;comment:已尝试次数"`
+	MaxAttempts     int             `json:"max_attempts" gorm:"not null;default:3;comment:最大尝试次数"`
+	RunAt           time.Time       `json:"run_at" gorm:"not null;index:idx_jobs_status_run_at,priority:2;comment:最早可执行时间"`
+	LockedUntil     *time.Time      `json:"-" gorm:"comment:执行租约到期时间"`
+	StartedAt       *time.Time      `json:"started_at" gorm:"comment:最近一次开始执行时间"`
+	FinishedAt      *time.Time      `json:"finished_at" gorm:"comment:完成时间"`
+	AppliedAt       *time.Time      `json:"applied_at" gorm:"comment:结果应用到提示词的时间"`
+	CancelRequested bool            `json:"cancel_requested" gorm:"not null;default:false;comment:是否已请求取消"`
+}
+
+// TableName 指定表名
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// IsFinished 判断任务是否已结束（成功、失败或取消）
+func (j *Job) IsFinished() bool {
+	switch j.Status {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCanceled:
+		return true
+	}
+	return false
+}
+
+// IsVisibleTo 判断指定用户是否可以查看或取消该任务
+// 有创建者的任务仅创建者可见；匿名创建的任务只能凭创建时返回的访问令牌访问（见 JobService.GetJob）
+func (j *Job) IsVisibleTo(userID *uint) bool {
+	return j.OwnerID != nil && userID != nil && *j.OwnerID == *userID
+}
+
+// AnalyzeJobPayload 分析任务的参数，图片保存在上传目录中，执行时再读取
+type AnalyzeJobPayload struct {
+	PromptText     string   `json:"prompt_text"`
+	ModelName      string   `json:"model_name"`
+	OutputImageURL string   `json:"output_image_url"`
+	InputImageURLs []string `json:"input_image_urls"`
+	UploadedFiles  []string `json:"uploaded_files,omitempty"` // 为任务上传的文件名，任务结束后删除（提示词已有的图片不在其中）
+}
+
+// CreateAnalyzeJobRequest 创建分析任务的请求结构体（图片通过 output_image / input_images 文件字段上传）
+// 指定 prompt_id 且未上传图片时，使用该提示词的文本、模型和本地图片
+type CreateAnalyzeJobRequest struct {
+	PromptText  string `form:"prompt_text"`
+	ModelName   string `form:"model_name"`
+	PromptID    *uint  `form:"prompt_id"`
+	AutoApply   bool   `form:"auto_apply"` // 成功后将结果应用到 prompt_id 指定的提示词
+	MaxAttempts int    `form:"max_attempts" binding:"omitempty,min=1,max=10"`
+}
@@ -1,8 +1,8 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
-	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -30,8 +30,6 @@ type Prompt struct {
 	PromptText            string         `json:"prompt_text" gorm:"type:text;not null;comment:正面提示词"`
 	NegativePrompt        string         `json:"negative_prompt" gorm:"type:text;comment:负面提示词"`
 	ModelName             string         `json:"model_name" gorm:"type:varchar(100);comment:使用的AI模型名称"`
-	InputImageURL         string         `json:"input_image_url" gorm:"type:varchar(500);comment:输入的参照图片的存储路径或URL；可能多个图片"`
-	OutputImageURL        string         `json:"output_image_url" gorm:"type:varchar(500);comment:输出的参照图片的存储路径或URL"`
 	IsPublic              bool           `json:"is_public" gorm:"default:false;comment:是否公开"`
 	StyleDescription      string         `json:"style_description" gorm:"type:varchar(500);comment:风格描述"`
 	UsageScenario         string         `json:"usage_scenario" gorm:"type:varchar(500);comment:适用场景描述"`
@@ -42,8 +40,23 @@ type Prompt struct {
 	// and embed it directly, avoiding double-encoding issues.
 	StructureAnalysis json.RawMessage `json:"structure_analysis" gorm:"type:json;comment:提示词结构分析"`
 
+	// 生成参数：seed、采样器、CFG、步数、模型资源等，使用 Civitai meta 的字段命名，为空表示未记录
+	GenerationParams json.RawMessage `json:"generation_params" gorm:"type:json;comment:生成参数（Civitai meta 格式）"`
+
+	// 衍生关系：从哪个提示词分叉（fork）而来
+	ParentID *uint `json:"parent_id" gorm:"index;comment:父提示词ID（分叉来源）"`
+
+	// 所有者：为空表示历史数据或匿名创建的共享提示词
+	OwnerID *uint `json:"owner_id" gorm:"index;comment:所有者用户ID"`
+
+	// 版本号：每次修改提示词（包括标签）时递增，作为 ETag 用于乐观并发控制
+	Version uint `json:"version" gorm:"not null;default:1;comment:版本号（每次修改递增）"`
+
 	// 多对多关系字段
 	Tags []*Tag `json:"tags" gorm:"many2many:prompt_tags;"`
+
+	// 图片：输入、输出、蒙版和 ControlNet 控制图
+	Images []*PromptImage `json:"images" gorm:"foreignKey:PromptID;constraint:OnDelete:CASCADE"`
 }
 
 // TableName 指定表名
@@ -51,35 +64,19 @@ func (Prompt) TableName() string {
 	return "prompts"
 }
 
-// GetInputImageURLs 获取输入图片URL列表
-func (p *Prompt) GetInputImageURLs() []string {
-	if p.InputImageURL == "" {
-		return []string{}
-	}
-	urls := strings.Split(p.InputImageURL, ",")
-	result := make([]string, 0, len(urls))
-	for _, url := range urls {
-		if trimmed := strings.TrimSpace(url); trimmed != "" {
-			result = append(result, trimmed)
-		}
-	}
-	return result
+// IsVisibleTo 判断提示词对指定用户是否可见
+// 公开的以及用户自己的提示词可见；私有提示词仅所有者可见（没有所有者的提示词总是公开的）
+func (p *Prompt) IsVisibleTo(userID *uint) bool {
+	return p.IsPublic || (p.OwnerID != nil && userID != nil && *p.OwnerID == *userID)
 }
 
-// SetInputImageURLs 设置输入图片URL列表
-func (p *Prompt) SetInputImageURLs(urls []string) {
-	if len(urls) == 0 {
-		p.InputImageURL = ""
-		return
-	}
-	// 过滤空字符串并组合
-	filteredURLs := make([]string, 0, len(urls))
-	for _, url := range urls {
-		if trimmed := strings.TrimSpace(url); trimmed != "" {
-			filteredURLs = append(filteredURLs, trimmed)
-		}
+// CanBeModifiedBy 判断指定用户是否可以修改或删除该提示词
+// 无所有者的提示词保持原有的开放行为，有所有者的提示词仅所有者可修改
+func (p *Prompt) CanBeModifiedBy(userID *uint) bool {
+	if p.OwnerID == nil {
+		return true
 	}
-	p.InputImageURL = strings.Join(filteredURLs, ",")
+	return userID != nil && *p.OwnerID == *userID
 }
 
 // BeforeSave 在保存（创建或更新）前的钩子
@@ -95,6 +92,16 @@ func (p *Prompt) BeforeSave(tx *gorm.DB) (err error) {
 			p.StructureAnalysis = json.RawMessage("{}")
 		}
 	}
+
+	if p.Version == 0 {
+		p.Version = 1
+	}
+
+	// 生成参数为空或不是合法的JSON对象时存为 NULL
+	var params map[string]json.RawMessage
+	if len(p.GenerationParams) == 0 || json.Unmarshal(p.GenerationParams, &params) != nil || params == nil {
+		p.GenerationParams = nil
+	}
 	return nil
 }
 
@@ -115,11 +122,20 @@ type PromptResponse struct {
 	// --- FIX: Changed type to json.RawMessage to match the model ---
 	// This ensures the raw JSON is passed through to the final response correctly.
 	StructureAnalysis json.RawMessage `json:"structure_analysis"`
+	GenerationParams  json.RawMessage `json:"generation_params,omitempty"`
+	ParentID          *uint           `json:"parent_id"`
+	OwnerID           *uint           `json:"owner_id"`
+	Version           uint            `json:"version"`
 	Tags              []*Tag          `json:"tags"`
+	Images            []*PromptImage  `json:"images"` // 完整的图片记录，input_image_urls 和 output_image_url 由其生成
 }
 
 // ToResponse 转换为响应结构体
 func (p *Prompt) ToResponse() PromptResponse {
+	images := p.Images
+	if images == nil {
+		images = []*PromptImage{}
+	}
 	return PromptResponse{
 		ID:                    p.ID,
 		CreatedAt:             p.CreatedAt,
@@ -127,47 +143,90 @@ func (p *Prompt) ToResponse() PromptResponse {
 		NegativePrompt:        p.NegativePrompt,
 		ModelName:             p.ModelName,
 		InputImageURLs:        p.GetInputImageURLs(),
-		OutputImageURL:        p.OutputImageURL,
+		OutputImageURL:        p.GetOutputImageURL(),
 		IsPublic:              p.IsPublic,
 		StyleDescription:      p.StyleDescription,
 		UsageScenario:         p.UsageScenario,
 		AtmosphereDescription: p.AtmosphereDescription,
 		ExpressiveIntent:      p.ExpressiveIntent,
 		StructureAnalysis:     p.StructureAnalysis,
+		GenerationParams:      p.GenerationParams,
+		ParentID:              p.ParentID,
+		OwnerID:               p.OwnerID,
+		Version:               p.Version,
 		Tags:                  p.Tags,
+		Images:                images,
+	}
+}
+
+// ToLineageNode 转换为衍生关系节点
+func (p *Prompt) ToLineageNode() *PromptLineageNode {
+	return &PromptLineageNode{
+		ID:             p.ID,
+		ParentID:       p.ParentID,
+		CreatedAt:      p.CreatedAt,
+		PromptText:     p.PromptText,
+		ModelName:      p.ModelName,
+		OutputImageURL: p.GetOutputImageURL(),
 	}
 }
 
+// PromptLineageNode 衍生关系中的提示词节点
+type PromptLineageNode struct {
+	ID             uint                 `json:"id"`
+	ParentID       *uint                `json:"parent_id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	PromptText     string               `json:"prompt_text"`
+	ModelName      string               `json:"model_name"`
+	OutputImageURL string               `json:"output_image_url"`
+	Children       []*PromptLineageNode `json:"children,omitempty"`
+}
+
+// PromptLineageResponse 提示词衍生关系响应结构体
+type PromptLineageResponse struct {
+	Prompt      *PromptLineageNode   `json:"prompt"`
+	Ancestors   []*PromptLineageNode `json:"ancestors"`   // 从根节点到直接父节点
+	Descendants []*PromptLineageNode `json:"descendants"` // 子孙节点树
+}
+
 // CreatePromptRequest 创建提示词的请求结构体
 type CreatePromptRequest struct {
-	PromptText            string   `form:"prompt_text" json:"prompt_text" binding:"required"`
-	NegativePrompt        string   `form:"negative_prompt" json:"negative_prompt"`
-	ModelName             string   `form:"model_name" json:"model_name"`
-	IsPublic              bool     `form:"is_public" json:"is_public"`
-	StyleDescription      string   `form:"style_description" json:"style_description"`
-	UsageScenario         string   `form:"usage_scenario" json:"usage_scenario"`
-	AtmosphereDescription string   `form:"atmosphere_description" json:"atmosphere_description"`
-	ExpressiveIntent      string   `form:"expressive_intent" json:"expressive_intent"`
-	StructureAnalysis     string   `form:"structure_analysis" json:"structure_analysis"`
-	InputImageURLs        []string `form:"input_image_urls" json:"input_image_urls"`
-	OutputImageURL        string   `form:"output_image_url" json:"output_image_url"`
-	TagNames              []string `form:"tag_names" json:"tag_names"`
+	PromptText            string             `form:"prompt_text" json:"prompt_text" binding:"required"`
+	NegativePrompt        string             `form:"negative_prompt" json:"negative_prompt"`
+	ModelName             string             `form:"model_name" json:"model_name"`
+	IsPublic              bool               `form:"is_public" json:"is_public"`
+	StyleDescription      string             `form:"style_description" json:"style_description"`
+	UsageScenario         string             `form:"usage_scenario" json:"usage_scenario"`
+	AtmosphereDescription string             `form:"atmosphere_description" json:"atmosphere_description"`
+	ExpressiveIntent      string             `form:"expressive_intent" json:"expressive_intent"`
+	StructureAnalysis     string             `form:"structure_analysis" json:"structure_analysis"`
+	GenerationParams      string             `form:"generation_params" json:"generation_params"` // JSON对象字符串
+	InputImageURLs        []string           `form:"input_image_urls" json:"input_image_urls"`
+	OutputImageURL        string             `form:"output_image_url" json:"output_image_url"`
+	Images                []PromptImageInput `form:"-" json:"images" binding:"omitempty,dive"` // 其他图片（如蒙版、ControlNet 控制图），角色为空时视为输入图片
+	TagNames              []string           `form:"tag_names" json:"tag_names"`
+	FetchRemote           bool               `form:"fetch_remote" json:"fetch_remote"` // 将 http/https 图片下载到上传目录并改写为本地URL
+	OwnerID               *uint              `form:"-" json:"-"`                       // 由当前登录用户决定，不接受客户端传入
+	Context               context.Context    `form:"-" json:"-"`                       // 请求的上下文，客户端断开时取消远程图片下载；为空时不取消
 }
 
 // UpdatePromptRequest 更新提示词的请求结构体
 type UpdatePromptRequest struct {
-	PromptText            *string  `form:"prompt_text" json:"prompt_text"`
-	NegativePrompt        *string  `form:"negative_prompt" json:"negative_prompt"`
-	ModelName             *string  `form:"model_name" json:"model_name"`
-	IsPublic              *bool    `form:"is_public" json:"is_public"`
-	StyleDescription      *string  `form:"style_description" json:"style_description"`
-	UsageScenario         *string  `form:"usage_scenario" json:"usage_scenario"`
-	AtmosphereDescription *string  `form:"atmosphere_description" json:"atmosphere_description"`
-	ExpressiveIntent      *string  `form:"expressive_intent" json:"expressive_intent"`
-	StructureAnalysis     *string  `form:"structure_analysis" json:"structure_analysis"`
-	InputImageURLs        []string `form:"input_image_urls" json:"input_image_urls"`
-	OutputImageURL        *string  `form:"output_image_url" json:"output_image_url"`
-	TagNames              []string `form:"tag_names" json:"tag_names"`
+	PromptText            *string            `form:"prompt_text" json:"prompt_text"`
+	NegativePrompt        *string            `form:"negative_prompt" json:"negative_prompt"`
+	ModelName             *string            `form:"model_name" json:"model_name"`
+	IsPublic              *bool              `form:"is_public" json:"is_public"`
+	StyleDescription      *string            `form:"style_description" json:"style_description"`
+	UsageScenario         *string            `form:"usage_scenario" json:"usage_scenario"`
+	AtmosphereDescription *string            `form:"atmosphere_description" json:"atmosphere_description"`
+	ExpressiveIntent      *string            `form:"expressive_intent" json:"expressive_intent"`
+	StructureAnalysis     *string            `form:"structure_analysis" json:"structure_analysis"`
+	GenerationParams      *string            `form:"generation_params" json:"generation_params"` // JSON对象字符串，空字符串表示清除
+	InputImageURLs        []string           `form:"input_image_urls" json:"input_image_urls"`
+	OutputImageURL        *string            `form:"output_image_url" json:"output_image_url"`
+	Images                []PromptImageInput `form:"-" json:"images" binding:"omitempty,dive"` // 非 nil 时替换全部图片（优先于 input_image_urls 和 output_image_url）
+	TagNames              []string           `form:"tag_names" json:"tag_names"`
+	IfMatch               []uint             `form:"-" json:"-"` // If-Match 请求头中的版本号，非空时当前版本必须是其中之一
 }
 
 // AnalyzePromptRequest 分析请求结构体
@@ -197,6 +256,7 @@ type PromptQuery struct {
 	TagNames  []string `form:"tag_names"`  // 标签名称过滤
 	SortBy    string   `form:"sort_by"`    // created_at
 	SortOrder string   `form:"sort_order"` // asc, desc
+	ViewerID  *uint    `form:"-"`          // 当前用户，用于过滤他人的私有提示词
 }
 
 // CreateTagRequest 创建标签的请求结构体
@@ -204,6 +264,11 @@ type CreateTagRequest struct {
 	Name string `form:"name" json:"name" binding:"required,max=100"`
 }
 
+// MergeTagRequest 合并标签的请求结构体（将路径中的标签合并到目标标签）
+type MergeTagRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
 // TagResponse 标签响应结构体
 type TagResponse struct {
 	ID        uint      `json:"id"`
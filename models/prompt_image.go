@@ -0,0 +1,171 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 提示词图片的角色
+const (
+	ImageRoleInput      = "input"      // 输入的参照图片
+	ImageRoleOutput     = "output"     // 生成的输出图片
+	ImageRoleMask       = "mask"       // 局部重绘蒙版
+	ImageRoleControlNet = "controlnet" // ControlNet 控制图
+)
+
+// IsValidImageRole 判断图片角色是否有效
+func IsValidImageRole(role string) bool {
+	switch role {
+	case ImageRoleInput, ImageRoleOutput, ImageRoleMask, ImageRoleControlNet:
+		return true
+	}
+	return false
+}
+
+// PromptImage 提示词图片模型 - 对应 prompt_images 表
+// 取代 prompts 表中逗号分隔的 input_image_url 和单个 output_image_url
+type PromptImage struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	PromptID  uint      `json:"prompt_id" gorm:"not null;index;comment:提示词ID"`
+	Role      string    `json:"role" gorm:"type:varchar(20);not null;comment:图片角色(input/output/mask/controlnet)"`
+	Position  int       `json:"position" gorm:"not null;default:0;comment:同一角色内的排序位置"`
+	URL       string    `json:"url" gorm:"type:varchar(2048);not null;comment:图片的存储路径或URL"`
+	Caption   string    `json:"caption" gorm:"type:varchar(500);comment:图片说明"`
+	Width     int       `json:"width" gorm:"not null;default:0;comment:宽度（像素，0表示未知）"`
+	Height    int       `json:"height" gorm:"not null;default:0;comment:高度（像素，0表示未知）"`
+	Hash      string    `json:"hash" gorm:"type:varchar(64);index;comment:文件内容的SHA-256（本地文件）"`
+}
+
+// TableName 指定表名
+func (PromptImage) TableName() string {
+	return "prompt_images"
+}
+
+// OrderedImages 预加载图片时使用的排序，如 Preload("Images", models.OrderedImages)
+func OrderedImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+// PromptImageInput 创建或更新提示词时传入的图片
+type PromptImageInput struct {
+	Role    string `json:"role" binding:"omitempty,oneof=input output mask controlnet"`
+	URL     string `json:"url" binding:"required,max=2048"`
+	Caption string `json:"caption" binding:"max=500"`
+	Width   int    `json:"width" binding:"min=0"`
+	Height  int    `json:"height" binding:"min=0"`
+	Hash    string `json:"hash" binding:"max=64"`
+}
+
+// NewPromptImages 将请求中的图片转换为模型，忽略URL为空的图片，同一角色内按出现顺序编号
+// 角色为空时视为输入图片
+func NewPromptImages(inputs []PromptImageInput) []*PromptImage {
+	images := make([]*PromptImage, 0, len(inputs))
+	positions := make(map[string]int)
+	for _, input := range inputs {
+		url := strings.TrimSpace(input.URL)
+		if url == "" {
+			continue
+		}
+		role := input.Role
+		if role == "" {
+			role = ImageRoleInput
+		}
+		images = append(images, &PromptImage{
+			Role:     role,
+			Position: positions[role],
+			URL:      url,
+			Caption:  input.Caption,
+			Width:    input.Width,
+			Height:   input.Height,
+			Hash:     input.Hash,
+		})
+		positions[role]++
+	}
+	return images
+}
+
+// ImagesByRole 获取指定角色的图片，按位置排序
+func (p *Prompt) ImagesByRole(role string) []*PromptImage {
+	images := make([]*PromptImage, 0, len(p.Images))
+	for _, image := range p.Images {
+		if image.Role == role {
+			images = append(images, image)
+		}
+	}
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Position < images[j].Position })
+	return images
+}
+
+// GetInputImageURLs 获取输入图片URL列表
+func (p *Prompt) GetInputImageURLs() []string {
+	images := p.ImagesByRole(ImageRoleInput)
+	urls := make([]string, len(images))
+	for i, image := range images {
+		urls[i] = image.URL
+	}
+	return urls
+}
+
+// GetOutputImageURL 获取第一张输出图片的URL，没有输出图片时返回空字符串
+func (p *Prompt) GetOutputImageURL() string {
+	if images := p.ImagesByRole(ImageRoleOutput); len(images) > 0 {
+		return images[0].URL
+	}
+	return ""
+}
+
+// SetInputImageURLs 设置输入图片URL列表（替换内存中已有的输入图片，忽略空URL）
+func (p *Prompt) SetInputImageURLs(urls []string) {
+	inputs := make([]PromptImageInput, len(urls))
+	for i, url := range urls {
+		inputs[i] = PromptImageInput{Role: ImageRoleInput, URL: url}
+	}
+	p.replaceImages(ImageRoleInput, NewPromptImages(inputs))
+}
+
+// SetOutputImageURL 设置输出图片（替换内存中已有的输出图片，空URL表示清除）
+func (p *Prompt) SetOutputImageURL(url string) {
+	p.replaceImages(ImageRoleOutput, NewPromptImages([]PromptImageInput{{Role: ImageRoleOutput, URL: url}}))
+}
+
+// ImageURLs 获取所有图片的URL
+func (p *Prompt) ImageURLs() []string {
+	urls := make([]string, len(p.Images))
+	for i, image := range p.Images {
+		urls[i] = image.URL
+	}
+	return urls
+}
+
+// replaceImages 替换内存中指定角色的图片
+func (p *Prompt) replaceImages(role string, images []*PromptImage) {
+	kept := make([]*PromptImage, 0, len(p.Images)+len(images))
+	for _, image := range p.Images {
+		if image.Role != role {
+			kept = append(kept, image)
+		}
+	}
+	p.Images = append(kept, images...)
+}
+
+// MapImageURLs 改写响应中的所有图片URL（如签名URL或绝对地址），图片记录会被复制，不影响原提示词
+func (r *PromptResponse) MapImageURLs(fn func(string) string) {
+	inputImageURLs := make([]string, len(r.InputImageURLs))
+	for i, url := range r.InputImageURLs {
+		inputImageURLs[i] = fn(url)
+	}
+	r.InputImageURLs = inputImageURLs
+	r.OutputImageURL = fn(r.OutputImageURL)
+
+	images := make([]*PromptImage, len(r.Images))
+	for i, image := range r.Images {
+		copied := *image
+		copied.URL = fn(image.URL)
+		images[i] = &copied
+	}
+	r.Images = images
+}
@@ -0,0 +1,79 @@
+package models
+
+// 用户角色
+const (
+	RoleViewer  = "viewer"  // 只读
+	RoleEditor  = "editor"  // 可以创建和修改内容
+	RoleCurator = "curator" // 可以整理标签（删除、合并）
+	RoleAdmin   = "admin"   // 全部权限，包括用户管理和数据库维护
+)
+
+// Permission 操作权限
+type Permission string
+
+// 权限列表
+const (
+	PermPromptRead      Permission = "prompts:read"
+	PermPromptWrite     Permission = "prompts:write"
+	PermTagRead         Permission = "tags:read"
+	PermTagWrite        Permission = "tags:write"
+	PermTagDelete       Permission = "tags:delete"
+	PermTagMerge        Permission = "tags:merge"
+	PermCollectionRead  Permission = "collections:read"
+	PermCollectionWrite Permission = "collections:write"
+	PermWildcardRead    Permission = "wildcards:read"
+	PermWildcardWrite   Permission = "wildcards:write"
+	PermUserManage      Permission = "users:manage"
+	PermDatabaseReset   Permission = "database:reset"
+)
+
+// ValidRoles 所有合法的角色（按权限从低到高）
+var ValidRoles = []string{RoleViewer, RoleEditor, RoleCurator, RoleAdmin}
+
+// rolePermissions 角色权限矩阵，高级角色包含低级角色的全部权限
+var rolePermissions = func() map[string]map[Permission]bool {
+	viewer := []Permission{PermPromptRead, PermTagRead, PermCollectionRead, PermWildcardRead}
+	editor := append(append([]Permission{}, viewer...), PermPromptWrite, PermTagWrite, PermCollectionWrite, PermWildcardWrite)
+	curator := append(append([]Permission{}, editor...), PermTagDelete, PermTagMerge)
+	admin := append(append([]Permission{}, curator...), PermUserManage, PermDatabaseReset)
+
+	matrix := make(map[string]map[Permission]bool)
+	for role, perms := range map[string][]Permission{
+		RoleViewer:  viewer,
+		RoleEditor:  editor,
+		RoleCurator: curator,
+		RoleAdmin:   admin,
+	} {
+		matrix[role] = make(map[Permission]bool, len(perms))
+		for _, perm := range perms {
+			matrix[role][perm] = true
+		}
+	}
+	return matrix
+}()
+
+// IsValidRole 判断角色是否合法
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission 判断角色是否拥有指定权限
+func RoleHasPermission(role string, perm Permission) bool {
+	return rolePermissions[role][perm]
+}
+
+// RolePermissions 获取角色拥有的全部权限
+func RolePermissions(role string) []Permission {
+	perms := make([]Permission, 0, len(rolePermissions[role]))
+	for _, perm := range []Permission{
+		PermPromptRead, PermPromptWrite, PermTagRead, PermTagWrite, PermTagDelete, PermTagMerge,
+		PermCollectionRead, PermCollectionWrite, PermWildcardRead, PermWildcardWrite,
+		PermUserManage, PermDatabaseReset,
+	} {
+		if rolePermissions[role][perm] {
+			perms = append(perms, perm)
+		}
+	}
+	return perms
+}
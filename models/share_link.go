@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// ShareLink 提示词分享链接 - 对应 share_links 表
+// 持有令牌的任何人都可以在过期或吊销前查看提示词（包括私有提示词）
+type ShareLink struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	PromptID       uint       `json:"prompt_id" gorm:"not null;index;comment:提示词ID"`
+	CreatedBy      *uint      `json:"created_by" gorm:"index;comment:创建者用户ID"`
+	TokenHash      string     `json:"-" gorm:"type:char(64);unique;not null;comment:令牌SHA-256哈希"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null;comment:过期时间"`
+	RevokedAt      *time.Time `json:"revoked_at" gorm:"comment:吊销时间"`
+	AccessCount    int64      `json:"access_count" gorm:"not null;default:0;comment:访问次数"`
+	LastAccessedAt *time.Time `json:"last_accessed_at" gorm:"comment:最后访问时间"`
+}
+
+// TableName 指定表名
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// IsActive 判断分享链接当前是否可用（未吊销且未过期）
+func (l *ShareLink) IsActive() bool {
+	return l.RevokedAt == nil && time.Now().Before(l.ExpiresAt)
+}
+
+// ShareLinkResponse 分享链接响应结构体
+type ShareLinkResponse struct {
+	ID             uint       `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PromptID       uint       `json:"prompt_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	AccessCount    int64      `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at"`
+	Active         bool       `json:"active"`
+}
+
+// ToResponse 转换为响应结构体
+func (l *ShareLink) ToResponse() ShareLinkResponse {
+	return ShareLinkResponse{
+		ID:             l.ID,
+		CreatedAt:      l.CreatedAt,
+		PromptID:       l.PromptID,
+		ExpiresAt:      l.ExpiresAt,
+		RevokedAt:      l.RevokedAt,
+		AccessCount:    l.AccessCount,
+		LastAccessedAt: l.LastAccessedAt,
+		Active:         l.IsActive(),
+	}
+}
+
+// CreateShareLinkRequest 创建分享链接的请求结构体
+type CreateShareLinkRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"` // 默认72小时
+}
+
+// CreateShareLinkResponse 创建分享链接的响应结构体（令牌仅返回这一次）
+type CreateShareLinkResponse struct {
+	ShareLinkResponse
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// SharedPromptResponse 通过分享链接访问时的响应结构体
+// 本地图片URL被替换为带签名的限时URL
+type SharedPromptResponse struct {
+	Prompt            PromptResponse `json:"prompt"`
+	ExpiresAt         time.Time      `json:"expires_at"`           // 分享链接的过期时间
+	ImageURLExpiresAt time.Time      `json:"image_url_expires_at"` // 图片URL的过期时间
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+)
+
+// User 用户模型 - 对应 users 表
+type User struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime;comment:更新时间"`
+	Username     string    `json:"username" gorm:"type:varchar(50);unique;not null;comment:用户名"`
+	Email        *string   `json:"email" gorm:"type:varchar(255);unique;comment:邮箱"`
+	PasswordHash string    `json:"-" gorm:"type:varchar(100);not null;comment:bcrypt密码哈希"`
+	Role         string    `json:"role" gorm:"type:varchar(20);not null;default:editor;comment:角色(viewer/editor/curator/admin)"`
+}
+
+// TableName 指定表名
+func (User) TableName() string {
+	return "users"
+}
+
+// HasPermission 判断用户是否拥有指定权限
+func (u *User) HasPermission(perm Permission) bool {
+	return RoleHasPermission(u.Role, perm)
+}
+
+// UserResponse 用户响应结构体
+type UserResponse struct {
+	ID          uint         `json:"id"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Username    string       `json:"username"`
+	Email       string       `json:"email"`
+	Role        string       `json:"role"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// ToResponse 转换为响应结构体
+func (u *User) ToResponse() UserResponse {
+	email := ""
+	if u.Email != nil {
+		email = *u.Email
+	}
+	return UserResponse{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		Username:    u.Username,
+		Email:       email,
+		Role:        u.Role,
+		Permissions: RolePermissions(u.Role),
+	}
+}
+
+// RegisterRequest 注册请求结构体
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"omitempty,email,max=255"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// LoginRequest 登录请求结构体
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshTokenRequest 刷新令牌请求结构体
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse 令牌响应结构体
+type TokenResponse struct {
+	AccessToken      string       `json:"access_token"`
+	RefreshToken     string       `json:"refresh_token"`
+	TokenType        string       `json:"token_type"`
+	ExpiresIn        int64        `json:"expires_in"`         // 访问令牌有效期（秒）
+	RefreshExpiresIn int64        `json:"refresh_expires_in"` // 刷新令牌有效期（秒）
+	User             UserResponse `json:"user"`
+}
+
+// UpdateUserRoleRequest 修改用户角色的请求结构体
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor curator admin"`
+}
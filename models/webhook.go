@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// WebhookEventTypes 可以订阅的事件类型，与 GET /api/v1/events 推送的事件相同
+var WebhookEventTypes = []string{
+	EventPromptCreated, EventPromptUpdated, EventPromptDeleted,
+	EventTagCreated, EventTagMerged, EventJobFinished,
+}
+
+// 推送记录状态
+const (
+	DeliveryStatusPending   = "pending"   // 等待发送（包括等待重试）
+	DeliveryStatusSending   = "sending"   // 发送中
+	DeliveryStatusSucceeded = "succeeded" // 接收方返回 2xx
+	DeliveryStatusFailed    = "failed"    // 重试次数用尽
+)
+
+// Webhook 事件推送订阅模型 - 对应 webhooks 表
+// 订阅的事件发生时向 URL 发送带 HMAC-SHA256 签名的 JSON，只推送所有者可见的事件
+type Webhook struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime;comment:更新时间"`
+	OwnerID     uint      `json:"owner_id" gorm:"not null;index;comment:所属用户ID"`
+	URL         string    `json:"url" gorm:"type:varchar(2048);not null;comment:接收地址"`
+	Events      string    `json:"-" gorm:"type:varchar(255);not null;comment:订阅的事件类型，逗号分隔"`
+	Secret      string    `json:"-" gorm:"type:varchar(128);not null;comment:签名密钥"`
+	Description string    `json:"description" gorm:"type:varchar(255);comment:说明"`
+	Active      bool      `json:"active" gorm:"not null;comment:是否启用"`
+}
+
+// TableName 指定表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// GetEvents 获取订阅的事件类型列表
+func (w *Webhook) GetEvents() []string {
+	if w.Events == "" {
+		return []string{}
+	}
+	return strings.Split(w.Events, ",")
+}
+
+// Subscribes 判断是否订阅了指定类型的事件
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, event := range w.GetEvents() {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookResponse 订阅响应结构体（不含签名密钥）
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+}
+
+// ToResponse 转换为响应结构体
+func (w *Webhook) ToResponse() WebhookResponse {
+	return WebhookResponse{
+		ID:          w.ID,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+		URL:         w.URL,
+		Events:      w.GetEvents(),
+		Description: w.Description,
+		Active:      w.Active,
+	}
+}
+
+// WebhookSecretResponse 创建订阅或重新生成密钥时的响应，签名密钥只返回这一次
+type WebhookSecretResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// CreateWebhookRequest 创建订阅的请求结构体
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=prompt.created prompt.updated prompt.deleted tag.created tag.merged job.finished"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=128"` // 为空时自动生成
+	Description string   `json:"description" binding:"max=255"`
+	Active      *bool    `json:"active"` // 为空表示启用
+}
+
+// UpdateWebhookRequest 更新订阅的请求结构体，字段为空表示不修改
+type UpdateWebhookRequest struct {
+	URL          *string  `json:"url" binding:"omitempty,url,max=2048"`
+	Events       []string `json:"events" binding:"omitempty,min=1,dive,oneof=prompt.created prompt.updated prompt.deleted tag.created tag.merged job.finished"`
+	Description  *string  `json:"description" binding:"omitempty,max=255"`
+	Active       *bool    `json:"active"`
+	RotateSecret bool     `json:"rotate_secret"` // 重新生成签名密钥，新密钥在响应中返回
+}
+
+// WebhookDelivery 推送记录模型 - 对应 webhook_deliveries 表
+// 每个事件对每个订阅生成一条记录，失败时按指数退避重试；手动重新推送会生成新的记录
+type WebhookDelivery struct {
+	ID             uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt      time.Time       `json:"updated_at" gorm:"autoUpdateTime;comment:更新时间"`
+	WebhookID      uint            `json:"webhook_id" gorm:"not null;index;comment:订阅ID"`
+	EventID        string          `json:"event_id" gorm:"type:varchar(64);not null;comment:事件ID"`
+	EventType      string          `json:"event_type" gorm:"type:varchar(32);not null;comment:事件类型"`
+	Payload        json.RawMessage `json:"payload" gorm:"type:json;comment:推送内容"`
+	Status         string          `json:"status" gorm:"type:varchar(20);not null;index:idx_webhook_deliveries_status_next,priority:1;comment:推送状态"`
+	Attempts       int             `json:"attempts" gorm:"not null;default:0;comment:已尝试次数"`
+	MaxAttempts    int             `json:"max_attempts" gorm:"not null;default:5;comment:最大尝试次数"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" gorm:"not null;index:idx_webhook_deliveries_status_next,priority:2;comment:下次尝试时间"`
+	LockedUntil    *time.Time      `json:"-" gorm:"comment:发送租约到期时间"`
+	ResponseStatus int             `json:"response_status" gorm:"not null;default:0;comment:最近一次响应状态码"`
+	ResponseBody   string          `json:"response_body" gorm:"type:text;comment:最近一次响应内容（截断）"`
+	Error          string          `json:"error" gorm:"type:text;comment:最近一次失败的原因"`
+	DurationMs     int64           `json:"duration_ms" gorm:"not null;default:0;comment:最近一次请求耗时（毫秒）"`
+	DeliveredAt    *time.Time      `json:"delivered_at" gorm:"comment:推送成功时间"`
+	RedeliveryOf   *uint           `json:"redelivery_of" gorm:"comment:手动重新推送的原记录ID"`
+}
+
+// TableName 指定表名
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryQuery 推送记录查询参数
+type WebhookDeliveryQuery struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending sending succeeded failed"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Wildcard 通配符模型 - 对应 wildcards 表
+// 在提示词中以 __name__ 的形式引用，内容每行一个选项
+type Wildcard struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name      string    `json:"name" gorm:"type:varchar(100);unique;not null;comment:通配符名称"`
+	Content   string    `json:"-" gorm:"type:text;not null;comment:通配符选项，每行一个"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime;comment:更新时间"`
+}
+
+// TableName 指定表名
+func (Wildcard) TableName() string {
+	return "wildcards"
+}
+
+// GetValues 获取通配符选项列表（忽略空行和以#开头的注释行）
+func (w *Wildcard) GetValues() []string {
+	lines := strings.Split(w.Content, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		result = append(result, trimmed)
+	}
+	return result
+}
+
+// SetValues 设置通配符选项列表
+func (w *Wildcard) SetValues(values []string) {
+	filtered := make([]string, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			filtered = append(filtered, trimmed)
+		}
+	}
+	w.Content = strings.Join(filtered, "\n")
+}
+
+// WildcardResponse 通配符响应结构体
+type WildcardResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Values    []string  `json:"values"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ToResponse 转换为响应结构体
+func (w *Wildcard) ToResponse() WildcardResponse {
+	return WildcardResponse{
+		ID:        w.ID,
+		Name:      w.Name,
+		Values:    w.GetValues(),
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+// CreateWildcardRequest 创建通配符的请求结构体
+type CreateWildcardRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Values []string `json:"values" binding:"required,min=1"`
+}
+
+// UpdateWildcardRequest 更新通配符的请求结构体
+type UpdateWildcardRequest struct {
+	Name   *string  `json:"name" binding:"omitempty,max=100"`
+	Values []string `json:"values"`
+}
+
+// ExpandPromptRequest 展开动态提示词的请求结构体
+type ExpandPromptRequest struct {
+	PromptID   uint   `json:"prompt_id"`   // 使用已有提示词的文本（优先于prompt_text）
+	PromptText string `json:"prompt_text"` // 直接提供的提示词文本
+	Mode       string `json:"mode"`        // all（全部组合，默认）或 random（随机采样）
+	Limit      int    `json:"limit" binding:"omitempty,min=1,max=1000"`
+	Count      int    `json:"count" binding:"omitempty,min=1,max=1000"`
+	Seed       *int64 `json:"seed"`
+	ViewerID   *uint  `json:"-"` // 当前用户，用于校验prompt_id的可见性
+}
+
+// ExpandPromptResponse 展开动态提示词的响应结构体
+type ExpandPromptResponse struct {
+	Mode      string   `json:"mode"`
+	Prompts   []string `json:"prompts"`
+	Count     int      `json:"count"`
+	Total     int64    `json:"total"`
+	Truncated bool     `json:"truncated"`
+	Seed      *int64   `json:"seed,omitempty"`
+}
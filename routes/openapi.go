@@ -0,0 +1,256 @@
+package routes
+
+import (
+	_ "embed"
+	"encoding/json"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// swaggerUIPage 接口文档页面，Swagger UI 的脚本和样式从 CDN 加载
+//
+//go:embed swagger.html
+var swaggerUIPage []byte
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument []byte
+)
+
+// OpenAPISpec 返回 OpenAPI 3.1 文档（JSON），只生成一次
+func OpenAPISpec() []byte {
+	openAPIOnce.Do(func() {
+		builder := utils.NewOpenAPIBuilder("Image Generate Prompts API", "v2.0.0")
+		builder.Add(apiOperations()...)
+		data, err := json.Marshal(builder.Document())
+		if err != nil {
+			panic("生成 OpenAPI 文档失败: " + err.Error())
+		}
+		openAPIDocument = data
+	})
+	return openAPIDocument
+}
+
+// serveOpenAPISpec 返回 OpenAPI 文档
+func serveOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", OpenAPISpec())
+}
+
+// serveSwaggerUI 返回 Swagger UI 页面
+func serveSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUIPage)
+}
+
+// 响应中使用 map 构造的数据结构
+type (
+	duplicateCheckResponse struct {
+		IsDuplicate bool                    `json:"is_duplicate"`
+		Count       int                     `json:"count"`
+		Prompts     []models.PromptResponse `json:"prompts"`
+	}
+	promptStatsResponse struct {
+		TotalPrompts   int64 `json:"total_prompts"`
+		PublicPrompts  int64 `json:"public_prompts"`
+		PrivatePrompts int64 `json:"private_prompts"`
+		ModelStats     []struct {
+			ModelName string `json:"model_name"`
+			Count     int64  `json:"count"`
+		} `json:"model_stats"`
+	}
+	tagStatsResponse struct {
+		TotalTags   int64 `json:"total_tags"`
+		PopularTags []struct {
+			TagID    uint   `json:"tag_id"`
+			TagName  string `json:"tag_name"`
+			UseCount int64  `json:"use_count"`
+		} `json:"popular_tags"`
+	}
+	mergeTagResponse struct {
+		Tag           models.TagResponse `json:"tag"`
+		MergedPrompts int64              `json:"merged_prompts"`
+	}
+	healthResponse struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	pageQuery struct {
+		Page     int `form:"page" binding:"omitempty,min=1"`
+		PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+	}
+	tagSearchQuery struct {
+		Tags     string `form:"tags" binding:"required"` // 逗号分隔的标签名称
+		Page     int    `form:"page" binding:"omitempty,min=1"`
+		PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	}
+	recentQuery struct {
+		Limit int `form:"limit" binding:"omitempty,min=1"`
+	}
+	duplicateQuery struct {
+		PromptText string `form:"prompt_text" binding:"required"`
+	}
+	fetchRemoteQuery struct {
+		FetchRemote bool `form:"fetch_remote"` // 将 http/https 图片下载到上传目录
+	}
+	keywordQuery struct {
+		Keyword string `form:"keyword" binding:"required"`
+	}
+	eventStreamQuery struct {
+		LastEventID string `form:"last_event_id"` // 浏览器无法设置 Last-Event-ID 时使用
+	}
+	importForm struct {
+		models.ImportOptions
+		Mapping string `form:"mapping"` // JSON对象或 源列=目标字段 列表
+	}
+	signedFileQuery struct {
+		Expires   string `form:"expires" binding:"required"`
+		Signature string `form:"signature" binding:"required"`
+	}
+	graphQLQuery struct {
+		Query         string `form:"query" binding:"required"`
+		OperationName string `form:"operationName"`
+		Variables     string `form:"variables"` // JSON对象字符串
+	}
+	graphQLResponse struct {
+		Data   map[string]interface{}     `json:"data"`
+		Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+	}
+)
+
+// jsonPatchSchema RFC 6902 JSON Patch 请求体
+var jsonPatchSchema = map[string]interface{}{
+	"type": "array",
+	"items": map[string]interface{}{
+		"type":     "object",
+		"required": []string{"op", "path"},
+		"properties": map[string]interface{}{
+			"op":    map[string]interface{}{"enum": []string{"add", "remove", "replace", "move", "copy", "test"}},
+			"path":  map[string]interface{}{"type": "string"},
+			"from":  map[string]interface{}{"type": "string"},
+			"value": map[string]interface{}{},
+		},
+	},
+}
+
+// apiOperations 所有接口的文档描述，新增路由时需要同步添加（由 TestOpenAPICoversRoutes 检查）
+func apiOperations() []utils.OpenAPIOperation {
+	type op = utils.OpenAPIOperation
+	const (
+		get   = http.MethodGet
+		post  = http.MethodPost
+		put   = http.MethodPut
+		patch = http.MethodPatch
+		del   = http.MethodDelete
+	)
+
+	uploadFiles := []string{"input_images[]", "reference_images[]", "output_image", "image",
+		"extra_output_images[]", "mask_images[]", "controlnet_images[]"}
+
+	return []op{
+		// 系统
+		{Method: get, Path: "/", Tag: "系统", Summary: "API 信息", ContentType: "application/json", Public: true},
+		{Method: get, Path: "/health", Tag: "系统", Summary: "健康检查", Data: healthResponse{}, ContentType: "application/json", Public: true},
+		{Method: get, Path: "/db-status", Tag: "系统", Summary: "数据库状态", ContentType: "application/json", Public: true},
+		{Method: get, Path: "/openapi.json", Tag: "系统", Summary: "OpenAPI 文档", ContentType: "application/json", Public: true},
+		{Method: get, Path: "/docs", Tag: "系统", Summary: "Swagger UI 接口文档页面", ContentType: "text/html", Public: true},
+		{Method: get, Path: "/uploads/:filename", Tag: "系统", Summary: "获取上传的图片（需要能查看引用它的提示词或收藏集）", ContentType: "application/octet-stream", Public: true},
+		{Method: http.MethodHead, Path: "/uploads/:filename", Tag: "系统", Summary: "获取上传图片的元数据", ContentType: "application/octet-stream", Public: true},
+
+		// 认证
+		{Method: post, Path: "/api/v1/auth/register", Tag: "认证", Summary: "注册", Body: models.RegisterRequest{}, Data: models.TokenResponse{}, Public: true},
+		{Method: post, Path: "/api/v1/auth/login", Tag: "认证", Summary: "登录", Body: models.LoginRequest{}, Data: models.TokenResponse{}, Public: true},
+		{Method: post, Path: "/api/v1/auth/refresh", Tag: "认证", Summary: "刷新令牌", Body: models.RefreshTokenRequest{}, Data: models.TokenResponse{}, Public: true},
+		{Method: get, Path: "/api/v1/auth/me", Tag: "认证", Summary: "获取当前用户", Data: models.UserResponse{}, AuthRequired: true},
+
+		// 用户管理
+		{Method: get, Path: "/api/v1/users/", Tag: "用户管理", Summary: "获取用户列表（管理员）", Data: []models.UserResponse{}, AuthRequired: true},
+		{Method: put, Path: "/api/v1/users/:id/role", Tag: "用户管理", Summary: "修改用户角色（管理员）", Body: models.UpdateUserRoleRequest{}, Data: models.UserResponse{}, AuthRequired: true},
+
+		// API密钥
+		{Method: post, Path: "/api/v1/me/api-keys/", Tag: "API密钥", Summary: "创建API密钥（密钥只返回一次）", Body: models.CreateAPIKeyRequest{}, Data: models.CreateAPIKeyResponse{}, AuthRequired: true},
+		{Method: get, Path: "/api/v1/me/api-keys/", Tag: "API密钥", Summary: "获取API密钥列表", Data: []models.APIKeyResponse{}, AuthRequired: true},
+		{Method: del, Path: "/api/v1/me/api-keys/:id", Tag: "API密钥", Summary: "吊销API密钥", Data: models.APIKeyResponse{}, AuthRequired: true},
+
+		// 提示词
+		{Method: post, Path: "/api/v1/prompts/", Tag: "提示词", Summary: "创建提示词", Query: fetchRemoteQuery{}, Body: models.CreatePromptRequest{}, Form: models.CreatePromptRequest{}, Data: models.PromptResponse{}},
+		{Method: post, Path: "/api/v1/prompts/upload", Tag: "提示词", Summary: "上传图片并创建提示词", Query: fetchRemoteQuery{}, Form: models.CreatePromptRequest{}, Files: uploadFiles, Data: models.PromptResponse{}},
+		{Method: post, Path: "/api/v1/prompts/analyze", Tag: "提示词", Summary: "AI 分析图片和提示词", Form: models.AnalyzePromptRequest{}, Files: []string{"output_image", "input_images[]", "reference_images[]"}, Data: models.AnalyzePromptResponse{}},
+		{Method: post, Path: "/api/v1/prompts/expand", Tag: "提示词", Summary: "展开动态提示词", Body: models.ExpandPromptRequest{}, Data: models.ExpandPromptResponse{}},
+		{Method: post, Path: "/api/v1/prompts/import", Tag: "提示词", Summary: "从 JSONL / CSV / Civitai 文件批量导入", Form: importForm{}, Files: []string{"file"}, Data: models.ImportResult{}},
+		{Method: post, Path: "/api/v1/prompts/bulk", Tag: "提示词", Summary: "批量操作", Body: models.BulkPromptRequest{}, Data: models.BulkPromptResult{}},
+		{Method: get, Path: "/api/v1/prompts/", Tag: "提示词", Summary: "获取提示词列表", Query: models.PromptQuery{}, Data: models.PromptResponse{}, Paginated: true, Headers: []string{"If-None-Match"}},
+		{Method: get, Path: "/api/v1/prompts/public", Tag: "提示词", Summary: "获取公开提示词列表", Query: pageQuery{}, Data: models.PromptResponse{}, Paginated: true, Headers: []string{"If-None-Match"}},
+		{Method: get, Path: "/api/v1/prompts/recent", Tag: "提示词", Summary: "获取最近的提示词", Query: recentQuery{}, Data: []models.PromptResponse{}, Headers: []string{"If-None-Match"}},
+		{Method: get, Path: "/api/v1/prompts/stats", Tag: "提示词", Summary: "提示词统计信息", Data: promptStatsResponse{}},
+		{Method: get, Path: "/api/v1/prompts/export", Tag: "提示词", Summary: "按过滤条件导出", Query: models.ExportQuery{}, ContentType: "application/octet-stream"},
+		{Method: get, Path: "/api/v1/prompts/search/tags", Tag: "提示词", Summary: "根据标签搜索提示词", Query: tagSearchQuery{}, Data: models.PromptResponse{}, Paginated: true, Headers: []string{"If-None-Match"}},
+		{Method: get, Path: "/api/v1/prompts/check-duplicate", Tag: "提示词", Summary: "检查重复提示词", Query: duplicateQuery{}, Data: duplicateCheckResponse{}},
+		{Method: get, Path: "/api/v1/prompts/:id", Tag: "提示词", Summary: "获取单个提示词（ETag 为版本号）", Data: models.PromptResponse{}, Headers: []string{"If-None-Match"}},
+		{Method: put, Path: "/api/v1/prompts/:id", Tag: "提示词", Summary: "更新提示词", Body: models.UpdatePromptRequest{}, Form: models.UpdatePromptRequest{}, Data: models.PromptResponse{}, Headers: []string{"If-Match"}},
+		{Method: patch, Path: "/api/v1/prompts/:id", Tag: "提示词", Summary: "部分更新提示词（JSON Merge Patch / JSON Patch）", Body: models.UpdatePromptRequest{},
+			Bodies: map[string]interface{}{utils.MergePatchContentType: models.UpdatePromptRequest{}, utils.JSONPatchContentType: jsonPatchSchema},
+			Data:   models.PromptResponse{}, Headers: []string{"If-Match"}},
+		{Method: del, Path: "/api/v1/prompts/:id", Tag: "提示词", Summary: "删除提示词", Headers: []string{"If-Match"}},
+		{Method: post, Path: "/api/v1/prompts/:id/fork", Tag: "提示词", Summary: "分叉提示词", Data: models.PromptResponse{}},
+		{Method: get, Path: "/api/v1/prompts/:id/lineage", Tag: "提示词", Summary: "获取衍生关系", Data: models.PromptLineageResponse{}},
+		{Method: get, Path: "/api/v1/prompts/:id/collections", Tag: "提示词", Summary: "获取包含该提示词的收藏集", Data: []models.CollectionResponse{}},
+		{Method: post, Path: "/api/v1/prompts/:id/share", Tag: "分享", Summary: "创建分享链接（令牌只返回一次）", Body: models.CreateShareLinkRequest{}, Data: models.CreateShareLinkResponse{}},
+		{Method: get, Path: "/api/v1/prompts/:id/shares", Tag: "分享", Summary: "获取分享链接列表", Data: []models.ShareLinkResponse{}},
+		{Method: del, Path: "/api/v1/prompts/:id/shares/:share_id", Tag: "分享", Summary: "吊销分享链接", Data: models.ShareLinkResponse{}},
+
+		// 后台任务
+		{Method: post, Path: "/api/v1/jobs/analyze", Tag: "后台任务", Summary: "创建异步分析任务", Form: models.CreateAnalyzeJobRequest{}, Files: []string{"output_image", "input_images[]", "reference_images[]"}, Data: models.Job{}, Status: http.StatusAccepted},
+		{Method: get, Path: "/api/v1/jobs/:id", Tag: "后台任务", Summary: "查询任务状态和结果", Data: models.Job{}, Headers: []string{"X-Job-Token"}},
+		{Method: post, Path: "/api/v1/jobs/:id/cancel", Tag: "后台任务", Summary: "取消任务", Data: models.Job{}, Headers: []string{"X-Job-Token"}},
+
+		// 事件
+		{Method: get, Path: "/api/v1/events", Tag: "事件", Summary: "资料库变更事件流（Server-Sent Events）", Query: eventStreamQuery{}, Headers: []string{"Last-Event-ID"}, ContentType: "text/event-stream"},
+		{Method: post, Path: "/api/v1/webhooks/", Tag: "事件", Summary: "创建事件推送订阅（签名密钥只返回一次）", Body: models.CreateWebhookRequest{}, Data: models.WebhookSecretResponse{}, AuthRequired: true},
+		{Method: get, Path: "/api/v1/webhooks/", Tag: "事件", Summary: "获取订阅列表", Data: []models.WebhookResponse{}, AuthRequired: true},
+		{Method: get, Path: "/api/v1/webhooks/:id", Tag: "事件", Summary: "获取订阅详情", Data: models.WebhookResponse{}, AuthRequired: true},
+		{Method: put, Path: "/api/v1/webhooks/:id", Tag: "事件", Summary: "更新订阅（rotate_secret 时返回新的签名密钥）", Body: models.UpdateWebhookRequest{}, Data: models.WebhookSecretResponse{}, AuthRequired: true},
+		{Method: del, Path: "/api/v1/webhooks/:id", Tag: "事件", Summary: "删除订阅", AuthRequired: true},
+		{Method: get, Path: "/api/v1/webhooks/:id/deliveries", Tag: "事件", Summary: "推送记录", Query: models.WebhookDeliveryQuery{}, Data: models.WebhookDelivery{}, Paginated: true, AuthRequired: true},
+		{Method: get, Path: "/api/v1/webhooks/:id/deliveries/:delivery_id", Tag: "事件", Summary: "推送记录详情", Data: models.WebhookDelivery{}, AuthRequired: true},
+		{Method: post, Path: "/api/v1/webhooks/:id/deliveries/:delivery_id/redeliver", Tag: "事件", Summary: "重新推送", Data: models.WebhookDelivery{}, Status: http.StatusAccepted, AuthRequired: true},
+
+		// 分享链接访问
+		{Method: get, Path: "/api/v1/shared/:token", Tag: "分享", Summary: "查看分享的提示词", Data: models.SharedPromptResponse{}, Public: true},
+		{Method: get, Path: "/api/v1/shared/:token/images/:image_id", Tag: "分享", Summary: "通过签名URL获取图片", Query: signedFileQuery{}, ContentType: "application/octet-stream", Public: true},
+
+		// 标签
+		{Method: post, Path: "/api/v1/tags/", Tag: "标签", Summary: "创建标签", Body: models.CreateTagRequest{}, Form: models.CreateTagRequest{}, Data: models.TagResponse{}},
+		{Method: get, Path: "/api/v1/tags/", Tag: "标签", Summary: "获取所有标签", Data: []models.TagResponse{}},
+		{Method: get, Path: "/api/v1/tags/search", Tag: "标签", Summary: "搜索标签", Query: keywordQuery{}, Data: []models.TagResponse{}},
+		{Method: get, Path: "/api/v1/tags/stats", Tag: "标签", Summary: "标签统计信息", Data: tagStatsResponse{}},
+		{Method: get, Path: "/api/v1/tags/:id", Tag: "标签", Summary: "获取单个标签", Data: models.TagResponse{}},
+		{Method: del, Path: "/api/v1/tags/:id", Tag: "标签", Summary: "删除标签"},
+		{Method: post, Path: "/api/v1/tags/:id/merge", Tag: "标签", Summary: "合并到目标标签", Body: models.MergeTagRequest{}, Data: mergeTagResponse{}},
+
+		// 收藏集
+		{Method: post, Path: "/api/v1/collections/", Tag: "收藏集", Summary: "创建收藏集", Body: models.CreateCollectionRequest{}, Data: models.CollectionDetailResponse{}},
+		{Method: get, Path: "/api/v1/collections/", Tag: "收藏集", Summary: "获取收藏集列表", Query: models.CollectionQuery{}, Data: []models.CollectionResponse{}},
+		{Method: get, Path: "/api/v1/collections/:id", Tag: "收藏集", Summary: "获取收藏集详情", Data: models.CollectionDetailResponse{}},
+		{Method: put, Path: "/api/v1/collections/:id", Tag: "收藏集", Summary: "更新收藏集", Body: models.UpdateCollectionRequest{}, Data: models.CollectionDetailResponse{}},
+		{Method: del, Path: "/api/v1/collections/:id", Tag: "收藏集", Summary: "删除收藏集"},
+		{Method: post, Path: "/api/v1/collections/:id/items", Tag: "收藏集", Summary: "添加提示词", Body: models.CollectionItemsRequest{}, Data: models.CollectionDetailResponse{}},
+		{Method: put, Path: "/api/v1/collections/:id/items/order", Tag: "收藏集", Summary: "重新排序", Body: models.CollectionItemsRequest{}, Data: models.CollectionDetailResponse{}},
+		{Method: del, Path: "/api/v1/collections/:id/items/:prompt_id", Tag: "收藏集", Summary: "移除提示词"},
+
+		// GraphQL
+		{Method: post, Path: "/api/v1/graphql", Tag: "GraphQL", Summary: "执行 GraphQL 查询或变更", Body: utils.GraphQLRequest{}, Data: graphQLResponse{}, ContentType: "application/json"},
+		{Method: get, Path: "/api/v1/graphql", Tag: "GraphQL", Summary: "通过查询参数执行 GraphQL 查询", Query: graphQLQuery{}, Data: graphQLResponse{}, ContentType: "application/json"},
+		{Method: get, Path: "/api/v1/graphql/schema", Tag: "GraphQL", Summary: "获取 GraphQL schema（SDL）", ContentType: "text/plain"},
+
+		// 通配符
+		{Method: post, Path: "/api/v1/wildcards/", Tag: "通配符", Summary: "创建通配符", Body: models.CreateWildcardRequest{}, Data: models.WildcardResponse{}},
+		{Method: get, Path: "/api/v1/wildcards/", Tag: "通配符", Summary: "获取所有通配符", Data: []models.WildcardResponse{}},
+		{Method: get, Path: "/api/v1/wildcards/:id", Tag: "通配符", Summary: "获取单个通配符", Data: models.WildcardResponse{}},
+		{Method: put, Path: "/api/v1/wildcards/:id", Tag: "通配符", Summary: "更新通配符", Body: models.UpdateWildcardRequest{}, Data: models.WildcardResponse{}},
+		{Method: del, Path: "/api/v1/wildcards/:id", Tag: "通配符", Summary: "删除通配符"},
+	}
+}
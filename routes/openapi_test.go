@@ -0,0 +1,119 @@
+package routes_test
+
+import (
+	"encoding/json"
+	"imgGeneratePrompts/routes"
+	"imgGeneratePrompts/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument 解析后的 OpenAPI 文档中测试用到的部分
+type openAPIDocument struct {
+	OpenAPI    string                                       `json:"openapi"`
+	Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+	Components struct {
+		Schemas map[string]map[string]interface{} `json:"schemas"`
+	} `json:"components"`
+}
+
+// loadOpenAPIDocument 通过 /openapi.json 获取文档
+func loadOpenAPIDocument(t *testing.T, router *gin.Engine) openAPIDocument {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	return doc
+}
+
+// TestOpenAPICoversRoutes 测试每个注册的路由都在文档中，文档中也没有不存在的接口
+func TestOpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := routes.SetupRoutes()
+	doc := loadOpenAPIDocument(t, router)
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		path := utils.OpenAPIPath(route.Path)
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		assert.Contains(t, doc.Paths[path], method, "路由 %s %s 没有写入 OpenAPI 文档", route.Method, route.Path)
+	}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			assert.True(t, registered[method+" "+path], "文档中的接口 %s %s 没有注册", strings.ToUpper(method), path)
+		}
+	}
+}
+
+// TestOpenAPISchemas 测试请求和响应的 schema
+func TestOpenAPISchemas(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	doc := loadOpenAPIDocument(t, routes.SetupRoutes())
+
+	// 响应封装
+	require.Contains(t, doc.Components.Schemas, "ResponseData")
+	require.Contains(t, doc.Components.Schemas, "PaginationData")
+	assert.Contains(t, doc.Components.Schemas["PaginationData"]["properties"], "total_pages")
+
+	// binding 标签转换为约束
+	createPrompt := doc.Components.Schemas["CreatePromptRequest"]
+	require.NotNil(t, createPrompt)
+	assert.Equal(t, []interface{}{"prompt_text"}, createPrompt["required"])
+	assert.NotContains(t, createPrompt["properties"], "OwnerID", "json:\"-\" 的字段不应出现")
+
+	webhook := doc.Components.Schemas["CreateWebhookRequest"]["properties"].(map[string]interface{})
+	events := webhook["events"].(map[string]interface{})
+	assert.Equal(t, "array", events["type"])
+	assert.NotEmpty(t, events["items"].(map[string]interface{})["enum"], "dive 之后的 oneof 作用于数组元素")
+
+	// 嵌入的结构体展开到外层
+	secret := doc.Components.Schemas["WebhookSecretResponse"]["properties"].(map[string]interface{})
+	assert.Contains(t, secret, "secret")
+	assert.Contains(t, secret, "url")
+
+	// 查询参数和路径参数
+	list := doc.Paths["/api/v1/prompts/"]["get"]
+	var names []string
+	for _, parameter := range list["parameters"].([]interface{}) {
+		names = append(names, parameter.(map[string]interface{})["name"].(string))
+	}
+	assert.Contains(t, names, "page_size")
+	assert.Contains(t, names, "tag_names")
+	assert.NotContains(t, names, "ViewerID")
+
+	get := doc.Paths["/api/v1/prompts/{id}"]["get"]
+	parameter := get["parameters"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "id", parameter["name"])
+	assert.Equal(t, "path", parameter["in"])
+
+	// 分页响应
+	body, _ := json.Marshal(list["responses"])
+	assert.Contains(t, string(body), "#/components/schemas/PaginationData")
+	assert.Contains(t, string(body), "#/components/schemas/PromptResponse")
+
+	// 上传接口为 multipart
+	upload, _ := json.Marshal(doc.Paths["/api/v1/prompts/upload"]["post"]["requestBody"])
+	assert.Contains(t, string(upload), "multipart/form-data")
+	assert.Contains(t, string(upload), "output_image")
+}
+
+// TestSwaggerUI 测试接口文档页面
+func TestSwaggerUI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	routes.SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "/openapi.json")
+}
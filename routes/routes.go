@@ -2,52 +2,173 @@ package routes
 
 import (
 	"imgGeneratePrompts/controllers"
+	"imgGeneratePrompts/middleware"
+	"imgGeneratePrompts/models"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 设置路由
 func SetupRoutes() *gin.Engine {
-	// 创建Gin引擎
-	r := gin.Default()
-
-	// 设置静态文件服务
-	r.Static("/uploads", "./uploads")
+	// 创建Gin引擎（与 gin.Default 相同，但使用放行 http.ErrAbortHandler 的 Recovery）
+	r := gin.New()
+	r.Use(gin.Logger(), middleware.Recovery())
 
 	// 创建控制器实例
 	promptController := controllers.NewPromptController()
 	tagController := controllers.NewTagController()
+	wildcardController := controllers.NewWildcardController()
+	collectionController := controllers.NewCollectionController()
+	authController := controllers.NewAuthController()
+	apiKeyController := controllers.NewAPIKeyController()
+	shareController := controllers.NewShareController()
+	importController := controllers.NewImportController()
+	bulkController := controllers.NewBulkController()
+	jobController := controllers.NewJobController()
+	eventController := controllers.NewEventController()
+	webhookController := controllers.NewWebhookController()
+	graphQLController := controllers.NewGraphQLController()
+
+	// 上传的图片与引用它的提示词使用相同的可见性规则，私有提示词的图片只有所有者能访问
+	uploads := r.Group("/uploads", middleware.Authenticate())
+	{
+		uploads.GET("/:filename", promptController.GetUploadedFile)
+		uploads.HEAD("/:filename", promptController.GetUploadedFile)
+	}
+
+	// 列表接口根据响应内容生成 ETag，支持 If-None-Match 返回 304
+	conditionalGET := middleware.ConditionalGET()
 
-	// API v1 路由组
-	v1 := r.Group("/api/v1")
+	// API v1 路由组（解析可选的访问令牌，将当前用户放入上下文）
+	v1 := r.Group("/api/v1", middleware.Authenticate())
 	{
+		// 用户认证相关路由
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/register", authController.Register)              // 注册
+			auth.POST("/login", authController.Login)                    // 登录
+			auth.POST("/refresh", authController.RefreshToken)           // 刷新令牌
+			auth.GET("/me", middleware.RequireAuth(), authController.Me) // 获取当前用户
+		}
+
+		// 用户管理（仅管理员）
+		users := v1.Group("/users", middleware.RequireAuth(), middleware.RequirePermission(models.PermUserManage))
+		{
+			users.GET("/", authController.GetUsers)               // 获取用户列表
+			users.PUT("/:id/role", authController.UpdateUserRole) // 修改用户角色
+		}
+
+		// 个人API密钥（通过API密钥管理密钥需要admin权限）
+		apiKeys := v1.Group("/me/api-keys", middleware.RequireAuth(), middleware.RequireScope(models.ScopeAdmin))
+		{
+			apiKeys.POST("/", apiKeyController.CreateAPIKey)      // 创建API密钥
+			apiKeys.GET("/", apiKeyController.GetAPIKeys)         // 获取API密钥列表
+			apiKeys.DELETE("/:id", apiKeyController.RevokeAPIKey) // 吊销API密钥
+		}
+
 		// 提示词相关路由
-		prompts := v1.Group("/prompts")
+		prompts := v1.Group("/prompts", middleware.Authorize(models.PermPromptRead, models.PermPromptWrite))
 		{
 			// 基础CRUD操作
-			prompts.POST("/", promptController.CreatePrompt)                  // 创建提示词
-			prompts.POST("/upload", promptController.UploadAndCreatePrompt)   // 上传图片并创建提示词
-			prompts.POST("/analyze", promptController.AnalyzePrompt)          // 智能生成：AI分析图片和提示词
-			prompts.GET("/", promptController.GetPrompts)                     // 获取提示词列表
-			prompts.GET("/public", promptController.GetPublicPrompts)         // 获取公开提示词列表
-			prompts.GET("/recent", promptController.GetRecentPrompts)         // 获取最近的提示词
-			prompts.GET("/stats", promptController.GetPromptStats)            // 获取提示词统计信息
-			prompts.GET("/search/tags", promptController.SearchPromptsByTags) // 根据标签搜索提示词
-			prompts.GET("/check-duplicate", promptController.CheckDuplicate)  // 检查重复提示词
-			prompts.GET("/:id", promptController.GetPrompt)                   // 获取单个提示词
-			prompts.PUT("/:id", promptController.UpdatePrompt)                // 更新提示词
-			prompts.DELETE("/:id", promptController.DeletePrompt)             // 删除提示词
+			prompts.POST("/", promptController.CreatePrompt)                                  // 创建提示词
+			prompts.POST("/upload", promptController.UploadAndCreatePrompt)                   // 上传图片并创建提示词
+			prompts.POST("/analyze", promptController.AnalyzePrompt)                          // 智能生成：AI分析图片和提示词
+			prompts.POST("/import", importController.ImportPrompts)                           // 从JSONL/CSV批量导入
+			prompts.POST("/bulk", bulkController.BulkPrompts)                                 // 批量操作（标签、公开状态、模型、删除/恢复、收藏集）
+			prompts.GET("/", conditionalGET, promptController.GetPrompts)                     // 获取提示词列表
+			prompts.GET("/public", conditionalGET, promptController.GetPublicPrompts)         // 获取公开提示词列表
+			prompts.GET("/recent", conditionalGET, promptController.GetRecentPrompts)         // 获取最近的提示词
+			prompts.GET("/stats", promptController.GetPromptStats)                            // 获取提示词统计信息
+			prompts.GET("/export", promptController.ExportPrompts)                            // 按过滤条件导出（JSONL/CSV/Markdown/TXT，可选zip）
+			prompts.GET("/search/tags", conditionalGET, promptController.SearchPromptsByTags) // 根据标签搜索提示词
+			prompts.GET("/check-duplicate", promptController.CheckDuplicate)                  // 检查重复提示词
+			prompts.GET("/:id", promptController.GetPrompt)                                   // 获取单个提示词（ETag 为版本号，支持 If-None-Match）
+			prompts.PUT("/:id", promptController.UpdatePrompt)                                // 更新提示词（支持 If-Match）
+			prompts.PATCH("/:id", promptController.PatchPrompt)                               // 部分更新提示词（Merge Patch / JSON Patch）
+			prompts.DELETE("/:id", promptController.DeletePrompt)                             // 删除提示词（支持 If-Match）
+			prompts.POST("/:id/fork", promptController.ForkPrompt)                            // 分叉提示词
+			prompts.GET("/:id/lineage", promptController.GetPromptLineage)                    // 获取衍生关系（祖先链和子孙树）
+			prompts.GET("/:id/collections", collectionController.GetPromptCollections)        // 获取包含该提示词的收藏集
+			prompts.POST("/:id/share", shareController.CreateShareLink)                       // 创建分享链接
+			prompts.GET("/:id/shares", shareController.GetShareLinks)                         // 获取分享链接列表
+			prompts.DELETE("/:id/shares/:share_id", shareController.RevokeShareLink)          // 吊销分享链接
+		}
+
+		// 展开动态提示词（通配符/组合）只读取数据，不受提示词路由组按方法要求写权限的限制
+		v1.POST("/prompts/expand", middleware.RequirePermission(models.PermPromptRead), promptController.ExpandPrompt)
+
+		// 后台任务（异步分析），任务由 services.JobRunner 执行
+		jobs := v1.Group("/jobs", middleware.Authorize(models.PermPromptRead, models.PermPromptWrite))
+		{
+			jobs.POST("/analyze", jobController.CreateAnalyzeJob) // 创建异步分析任务
+			jobs.GET("/:id", jobController.GetJob)                // 查询任务状态和结果
+			jobs.POST("/:id/cancel", jobController.CancelJob)     // 取消任务
+		}
+
+		// 资料库变更事件流（Server-Sent Events）
+		v1.GET("/events", middleware.Authorize(models.PermPromptRead, models.PermPromptWrite), eventController.Stream)
+
+		// 事件推送订阅（webhook），推送由 services.WebhookDispatcher 发送
+		webhooks := v1.Group("/webhooks", middleware.RequireAuth(), middleware.Authorize(models.PermPromptRead, models.PermPromptWrite))
+		{
+			webhooks.POST("/", webhookController.CreateWebhook)                                  // 创建订阅
+			webhooks.GET("/", webhookController.GetWebhooks)                                     // 获取订阅列表
+			webhooks.GET("/:id", webhookController.GetWebhook)                                   // 获取订阅详情
+			webhooks.PUT("/:id", webhookController.UpdateWebhook)                                // 更新订阅
+			webhooks.DELETE("/:id", webhookController.DeleteWebhook)                             // 删除订阅
+			webhooks.GET("/:id/deliveries", webhookController.GetDeliveries)                     // 推送记录
+			webhooks.GET("/:id/deliveries/:delivery_id", webhookController.GetDelivery)          // 推送记录详情
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookController.Redeliver) // 重新推送
+		}
+
+		// 分享链接访问（令牌即凭证，无需登录）
+		shared := v1.Group("/shared")
+		{
+			shared.GET("/:token", shareController.GetSharedPrompt)                // 查看分享的提示词
+			shared.GET("/:token/images/:image_id", shareController.GetSharedFile) // 通过签名URL获取图片
 		}
 
 		// 标签相关路由
-		tags := v1.Group("/tags")
+		tags := v1.Group("/tags", middleware.Authorize(models.PermTagRead, models.PermTagWrite))
 		{
-			tags.POST("/", tagController.CreateTag)       // 创建标签
-			tags.GET("/", tagController.GetAllTags)       // 获取所有标签
-			tags.GET("/search", tagController.SearchTags) // 搜索标签
-			tags.GET("/stats", tagController.GetTagStats) // 获取标签统计信息
-			tags.GET("/:id", tagController.GetTag)        // 获取单个标签
-			tags.DELETE("/:id", tagController.DeleteTag)  // 删除标签
+			tags.POST("/", tagController.CreateTag)                                                            // 创建标签
+			tags.GET("/", tagController.GetAllTags)                                                            // 获取所有标签
+			tags.GET("/search", tagController.SearchTags)                                                      // 搜索标签
+			tags.GET("/stats", tagController.GetTagStats)                                                      // 获取标签统计信息
+			tags.GET("/:id", tagController.GetTag)                                                             // 获取单个标签
+			tags.DELETE("/:id", middleware.RequirePermission(models.PermTagDelete), tagController.DeleteTag)   // 删除标签
+			tags.POST("/:id/merge", middleware.RequirePermission(models.PermTagMerge), tagController.MergeTag) // 合并到目标标签
+		}
+
+		// 收藏集相关路由
+		collections := v1.Group("/collections", middleware.Authorize(models.PermCollectionRead, models.PermCollectionWrite))
+		{
+			collections.POST("/", collectionController.CreateCollection)                 // 创建收藏集
+			collections.GET("/", collectionController.GetCollections)                    // 获取收藏集列表
+			collections.GET("/:id", collectionController.GetCollection)                  // 获取收藏集详情
+			collections.PUT("/:id", collectionController.UpdateCollection)               // 更新收藏集
+			collections.DELETE("/:id", collectionController.DeleteCollection)            // 删除收藏集
+			collections.POST("/:id/items", collectionController.AddItems)                // 添加提示词
+			collections.PUT("/:id/items/order", collectionController.ReorderItems)       // 重新排序
+			collections.DELETE("/:id/items/:prompt_id", collectionController.RemoveItem) // 移除提示词
+		}
+
+		// GraphQL：一次请求获取提示词及其标签、图片、收藏集，至少需要读取提示词的权限，其余权限按字段检查
+		graphQL := v1.Group("/graphql")
+		{
+			graphQL.POST("", middleware.RequirePermission(models.PermPromptRead), graphQLController.Query) // 执行查询或变更
+			graphQL.GET("", middleware.RequirePermission(models.PermPromptRead), graphQLController.Query)  // 通过查询参数执行查询
+			graphQL.GET("/schema", graphQLController.Schema)                                               // 获取 schema（SDL）
+		}
+
+		// 通配符相关路由（动态提示词 __name__ 引用）
+		wildcards := v1.Group("/wildcards", middleware.Authorize(models.PermWildcardRead, models.PermWildcardWrite))
+		{
+			wildcards.POST("/", wildcardController.CreateWildcard)      // 创建通配符
+			wildcards.GET("/", wildcardController.GetAllWildcards)      // 获取所有通配符
+			wildcards.GET("/:id", wildcardController.GetWildcard)       // 获取单个通配符
+			wildcards.PUT("/:id", wildcardController.UpdateWildcard)    // 更新通配符
+			wildcards.DELETE("/:id", wildcardController.DeleteWildcard) // 删除通配符
 		}
 	}
 
@@ -68,6 +189,10 @@ func SetupRoutes() *gin.Engine {
 		})
 	})
 
+	// 接口文档：OpenAPI 3.1 文档和 Swagger UI 页面
+	r.GET("/openapi.json", serveOpenAPISpec)
+	r.GET("/docs", serveSwaggerUI)
+
 	// 根路径
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -81,12 +206,24 @@ func SetupRoutes() *gin.Engine {
 				"统计信息",
 			},
 			"endpoints": gin.H{
-				"health":    "/health",
-				"db_status": "/db-status",
-				"api":       "/api/v1",
-				"prompts":   "/api/v1/prompts",
-				"tags":      "/api/v1/tags",
-				"uploads":   "/uploads",
+				"health":      "/health",
+				"db_status":   "/db-status",
+				"api":         "/api/v1",
+				"prompts":     "/api/v1/prompts",
+				"tags":        "/api/v1/tags",
+				"wildcards":   "/api/v1/wildcards",
+				"collections": "/api/v1/collections",
+				"auth":        "/api/v1/auth",
+				"api_keys":    "/api/v1/me/api-keys",
+				"users":       "/api/v1/users",
+				"shared":      "/api/v1/shared/:token",
+				"jobs":        "/api/v1/jobs",
+				"events":      "/api/v1/events",
+				"webhooks":    "/api/v1/webhooks",
+				"graphql":     "/api/v1/graphql",
+				"uploads":     "/uploads",
+				"openapi":     "/openapi.json",
+				"docs":        "/docs",
 			},
 		})
 	})
@@ -1,21 +1,31 @@
 package routes_test
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"imgGeneratePrompts/config"
 	"imgGeneratePrompts/models"
 	"imgGeneratePrompts/routes"
+	"imgGeneratePrompts/services"
 	"imgGeneratePrompts/utils"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -46,6 +56,8 @@ func (s *APITestSuite) SetupSuite() {
 		log.Fatalf("无法加载配置: %v", err)
 	}
 	s.cfg = config.AppConfig
+	// 大部分用例以匿名身份创建和编辑内容，测试中让匿名请求使用编辑者角色
+	s.cfg.Auth.AnonymousRole = models.RoleEditor
 
 	// --- 安全措施：创建一个专用的测试数据库 ---
 	originalDBName := s.cfg.Database.DBName
@@ -95,9 +107,19 @@ func (s *APITestSuite) TearDownSuite() {
 // SetupTest 在每个测试方法运行之前执行
 func (s *APITestSuite) SetupTest() {
 	// 清理所有表，确保每个测试都在干净的环境中运行
+	s.db.Exec("DELETE FROM webhook_deliveries")
+	s.db.Exec("DELETE FROM webhooks")
+	s.db.Exec("DELETE FROM jobs")
+	s.db.Exec("DELETE FROM share_links")
+	s.db.Exec("DELETE FROM collection_items")
+	s.db.Exec("DELETE FROM collections")
+	s.db.Exec("DELETE FROM prompt_images")
 	s.db.Exec("DELETE FROM prompt_tags")
 	s.db.Exec("DELETE FROM prompts")
 	s.db.Exec("DELETE FROM tags")
+	s.db.Exec("DELETE FROM wildcards")
+	s.db.Exec("DELETE FROM api_keys")
+	s.db.Exec("DELETE FROM users")
 	// 重新插入初始标签
 	config.GetDB().AutoMigrate(&models.Tag{}, &models.Prompt{})
 }
@@ -157,9 +179,12 @@ func (s *APITestSuite) TestTagsAPI() {
 	w = s.performRequest("GET", "/api/v1/tags/stats", nil, nil)
 	assert.Equal(s.T(), http.StatusOK, w.Code)
 
-	// 5. Delete the tag
+	// 5. Deleting requires the curator role
 	deleteURL := fmt.Sprintf("/api/v1/tags/%d", s.testTagID)
 	w = s.performRequest("DELETE", deleteURL, nil, nil)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+	adminToken := s.registerAdmin("admin")
+	w = s.performRequest("DELETE", deleteURL, nil, map[string]string{"Authorization": "Bearer " + adminToken})
 	assert.Equal(s.T(), http.StatusOK, w.Code)
 }
 
@@ -250,3 +275,1314 @@ func (s *APITestSuite) TestPromptSearchAndFilterAPI() {
 	w = s.performRequest("GET", "/api/v1/prompts/stats", nil, nil)
 	assert.Equal(s.T(), http.StatusOK, w.Code)
 }
+
+// TestExpandPromptAPI 测试通配符与动态提示词展开接口
+func (s *APITestSuite) TestExpandPromptAPI() {
+	// 1. Create a wildcard
+	createBody := bytes.NewBufferString(`{"name": "colors", "values": ["red", "green"]}`)
+	w := s.performRequest("POST", "/api/v1/wildcards/", createBody, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	// Names are unique on create and on rename
+	w = s.performRequest("POST", "/api/v1/wildcards/", bytes.NewBufferString(`{"name": "colors", "values": ["blue"]}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+	w = s.performRequest("POST", "/api/v1/wildcards/", bytes.NewBufferString(`{"name": "shapes", "values": ["round"]}`), map[string]string{"Content-Type": "application/json"})
+	s.Require().Equal(http.StatusOK, w.Code)
+	var created utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &created)
+	shapesURL := fmt.Sprintf("/api/v1/wildcards/%d", uint(created.Data.(map[string]interface{})["id"].(float64)))
+	w = s.performRequest("PUT", shapesURL, bytes.NewBufferString(`{"name": "colors"}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+	w = s.performRequest("PUT", shapesURL, bytes.NewBufferString(`{"name": "shapes", "values": ["square"]}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusOK, w.Code, "保留自己的名称不算重复")
+	w = s.performRequest("PUT", "/api/v1/wildcards/999999", bytes.NewBufferString(`{"name": "x"}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	// 2. Expand all combinations
+	expandBody := bytes.NewBufferString(`{"prompt_text": "a __colors__ {car|bike}", "mode": "all"}`)
+	w = s.performRequest("POST", "/api/v1/prompts/expand", expandBody, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	data := response.Data.(map[string]interface{})
+	assert.Equal(s.T(), float64(4), data["total"])
+	assert.Len(s.T(), data["prompts"], 4)
+
+	// 3. Random samples with a seed
+	expandBody = bytes.NewBufferString(`{"prompt_text": "a __colors__ {car|bike}", "mode": "random", "count": 3, "seed": 7}`)
+	w = s.performRequest("POST", "/api/v1/prompts/expand", expandBody, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	// 4. Unknown wildcard
+	expandBody = bytes.NewBufferString(`{"prompt_text": "__missing__"}`)
+	w = s.performRequest("POST", "/api/v1/prompts/expand", expandBody, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 5. Unknown prompt
+	expandBody = bytes.NewBufferString(`{"prompt_id": 999999}`)
+	w = s.performRequest("POST", "/api/v1/prompts/expand", expandBody, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	// 6. Expanding is read-only, so viewers can use it
+	s.cfg.Auth.AnonymousRole = models.RoleViewer
+	defer func() { s.cfg.Auth.AnonymousRole = models.RoleEditor }()
+	expandBody = bytes.NewBufferString(`{"prompt_text": "a {car|bike}"}`)
+	w = s.performRequest("POST", "/api/v1/prompts/expand", expandBody, map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("POST", "/api/v1/prompts/", bytes.NewBufferString(`{"prompt_text": "x"}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+}
+
+// registerUser 辅助函数，注册用户并返回访问令牌
+func (s *APITestSuite) registerUser(username string) string {
+	body := bytes.NewBufferString(fmt.Sprintf(`{"username": "%s", "password": "password123"}`, username))
+	w := s.performRequest("POST", "/api/v1/auth/register", body, map[string]string{"Content-Type": "application/json"})
+	s.Require().Equal(http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data.(map[string]interface{})["access_token"].(string)
+}
+
+// registerAdmin 注册用户并将其提升为管理员（管理员只能通过 db-manager 创建）
+func (s *APITestSuite) registerAdmin(username string) string {
+	token := s.registerUser(username)
+	_, err := services.NewAuthService().CreateAdmin(username, "")
+	s.Require().NoError(err)
+	return token
+}
+
+// TestAuthAPI 测试注册登录以及私有提示词的访问控制
+func (s *APITestSuite) TestAuthAPI() {
+	aliceToken := s.registerUser("alice")
+	bobToken := s.registerUser("bob")
+	jsonHeader := map[string]string{"Content-Type": "application/json"}
+
+	// 1. Login and get current user
+	w := s.performRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"username": "alice", "password": "password123"}`), jsonHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("POST", "/api/v1/auth/login", bytes.NewBufferString(`{"username": "alice", "password": "wrong"}`), jsonHeader)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	w = s.performRequest("GET", "/api/v1/auth/me", nil, map[string]string{"Authorization": "Bearer " + aliceToken})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", "/api/v1/auth/me", nil, nil)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	w = s.performRequest("GET", "/api/v1/prompts/", nil, map[string]string{"Authorization": "Bearer invalid"})
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	// 2. Alice creates a private prompt
+	w = s.performRequest("POST", "/api/v1/prompts/", bytes.NewBufferString(`{"prompt_text": "alice的私有提示词"}`),
+		map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + aliceToken})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var createResponse utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &createResponse)
+	promptURL := fmt.Sprintf("/api/v1/prompts/%d", uint(createResponse.Data.(map[string]interface{})["id"].(float64)))
+
+	// 3. Only the owner can see and modify it
+	w = s.performRequest("GET", promptURL, nil, map[string]string{"Authorization": "Bearer " + aliceToken})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", promptURL, nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = s.performRequest("DELETE", promptURL, nil, map[string]string{"Authorization": "Bearer " + bobToken})
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	// 4. Once public, others can see it but still cannot modify it
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"is_public": true}`),
+		map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + aliceToken})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", promptURL, nil, map[string]string{"Authorization": "Bearer " + bobToken})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("DELETE", promptURL, nil, map[string]string{"Authorization": "Bearer " + bobToken})
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+}
+
+// TestAPIKeyAPI 测试API密钥的管理以及使用API密钥调用接口
+func (s *APITestSuite) TestAPIKeyAPI() {
+	token := s.registerUser("alice")
+	authHeader := map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + token}
+
+	// 1. Create a read/write key
+	w := s.performRequest("POST", "/api/v1/me/api-keys/", bytes.NewBufferString(`{"name": "script", "scopes": ["read", "write"]}`), authHeader)
+	s.Require().Equal(http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	data := response.Data.(map[string]interface{})
+	keyHeader := map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + data["key"].(string)}
+	keyURL := fmt.Sprintf("/api/v1/me/api-keys/%d", uint(data["id"].(float64)))
+
+	// 2. Invalid scope is rejected
+	w = s.performRequest("POST", "/api/v1/me/api-keys/", bytes.NewBufferString(`{"name": "bad", "scopes": ["root"]}`), authHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 3. The key can create prompts owned by alice but cannot analyze or manage keys
+	w = s.performRequest("POST", "/api/v1/prompts/", bytes.NewBufferString(`{"prompt_text": "通过API密钥创建"}`), keyHeader)
+	s.Require().Equal(http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NotNil(s.T(), response.Data.(map[string]interface{})["owner_id"])
+	w = s.performRequest("POST", "/api/v1/prompts/analyze", bytes.NewBufferString(`{}`), keyHeader)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+	w = s.performRequest("GET", "/api/v1/me/api-keys/", nil, keyHeader)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	// 4. Listing never exposes the plaintext key
+	w = s.performRequest("GET", "/api/v1/me/api-keys/", nil, authHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NotContains(s.T(), w.Body.String(), data["key"].(string))
+
+	// 5. Revoked keys are rejected
+	w = s.performRequest("DELETE", keyURL, nil, authHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", "/api/v1/prompts/", nil, keyHeader)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+// TestRBACAPI 测试角色权限矩阵
+func (s *APITestSuite) TestRBACAPI() {
+	adminToken := s.registerAdmin("admin")
+	bobToken := s.registerUser("bob")
+	adminHeader := map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + adminToken}
+	bobHeader := map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + bobToken}
+
+	createTag := func(name string) uint {
+		w := s.performRequest("POST", "/api/v1/tags/", bytes.NewBufferString(fmt.Sprintf(`{"name": "%s"}`, name)), bobHeader)
+		s.Require().Equal(http.StatusOK, w.Code)
+		var response utils.ResponseData
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return uint(response.Data.(map[string]interface{})["id"].(float64))
+	}
+	sourceID := createTag("猫")
+	targetID := createTag("猫咪")
+	mergeURL := fmt.Sprintf("/api/v1/tags/%d/merge", sourceID)
+	mergeBody := fmt.Sprintf(`{"target_id": %d}`, targetID)
+
+	// 1. Editors can create tags but cannot merge them or manage users
+	w := s.performRequest("POST", mergeURL, bytes.NewBufferString(mergeBody), bobHeader)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(s.T(), http.StatusForbidden, response.Code)
+	w = s.performRequest("GET", "/api/v1/users/", nil, bobHeader)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	// 2. Admin promotes bob to curator
+	w = s.performRequest("GET", "/api/v1/auth/me", nil, bobHeader)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	bobID := uint(response.Data.(map[string]interface{})["id"].(float64))
+	w = s.performRequest("PUT", fmt.Sprintf("/api/v1/users/%d/role", bobID), bytes.NewBufferString(`{"role": "curator"}`), adminHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	// 3. Curators can merge tags
+	w = s.performRequest("POST", mergeURL, bytes.NewBufferString(mergeBody), bobHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", fmt.Sprintf("/api/v1/tags/%d", sourceID), nil, nil)
+	assert.NotEqual(s.T(), http.StatusOK, w.Code)
+
+	// 4. Viewers are read-only
+	w = s.performRequest("PUT", fmt.Sprintf("/api/v1/users/%d/role", bobID), bytes.NewBufferString(`{"role": "viewer"}`), adminHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", "/api/v1/prompts/", nil, bobHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("POST", "/api/v1/prompts/", bytes.NewBufferString(`{"prompt_text": "viewer"}`), bobHeader)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+}
+
+// TestShareLinkAPI 测试分享链接和限时图片URL
+func (s *APITestSuite) TestShareLinkAPI() {
+	aliceToken := s.registerUser("alice")
+	bobToken := s.registerUser("bob")
+	aliceHeader := map[string]string{"Authorization": "Bearer " + aliceToken}
+
+	// 1. Alice uploads a private prompt with an image
+	imageContent := []byte("这是一个假的图片")
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("output_image", "share.jpg")
+	part.Write(imageContent)
+	writer.WriteField("prompt_text", "分享的私有提示词")
+	writer.Close()
+	w := s.performRequest("POST", "/api/v1/prompts/upload", body, map[string]string{
+		"Content-Type": writer.FormDataContentType(), "Authorization": "Bearer " + aliceToken,
+	})
+	s.Require().Equal(http.StatusOK, w.Code)
+	defer os.RemoveAll(filepath.Join(s.cfg.Server.UploadPath))
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	promptID := uint(response.Data.(map[string]interface{})["id"].(float64))
+	fileURL := response.Data.(map[string]interface{})["output_image_url"].(string)
+
+	// The uploaded file is as private as the prompt that references it
+	w = s.performRequest("GET", fileURL, nil, aliceHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", fileURL, nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = s.performRequest("GET", fileURL, nil, map[string]string{"Authorization": "Bearer " + bobToken})
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	// 2. Only the owner can mint share links
+	shareURL := fmt.Sprintf("/api/v1/prompts/%d/share", promptID)
+	w = s.performRequest("POST", shareURL, nil, map[string]string{"Authorization": "Bearer " + bobToken})
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = s.performRequest("POST", shareURL, bytes.NewBufferString(`{"expires_in_hours": 1}`),
+		map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + aliceToken})
+	s.Require().Equal(http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	share := response.Data.(map[string]interface{})
+	sharedURL := share["url"].(string)
+
+	// 3. Anyone with the token can view the prompt and its image through a signed URL
+	w = s.performRequest("GET", sharedURL, nil, nil)
+	s.Require().Equal(http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	prompt := response.Data.(map[string]interface{})["prompt"].(map[string]interface{})
+	assert.Equal(s.T(), "分享的私有提示词", prompt["prompt_text"])
+	imageURL := prompt["output_image_url"].(string)
+	assert.Contains(s.T(), imageURL, "signature=")
+	assert.NotContains(s.T(), imageURL, filepath.Base(fileURL), "签名URL不应暴露真实文件名")
+
+	w = s.performRequest("GET", imageURL, nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), imageContent, w.Body.Bytes())
+	w = s.performRequest("GET", imageURL+"0", nil, nil)
+	assert.Equal(s.T(), http.StatusForbidden, w.Code, "篡改签名应被拒绝")
+
+	// 4. Revoked links stop working
+	w = s.performRequest("GET", fmt.Sprintf("/api/v1/prompts/%d/shares", promptID), nil, aliceHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("DELETE", fmt.Sprintf("/api/v1/prompts/%d/shares/%d", promptID, uint(share["id"].(float64))), nil, aliceHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", sharedURL, nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = s.performRequest("GET", imageURL, nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+// importFile 辅助函数，以 multipart 表单上传导入文件
+func (s *APITestSuite) importFile(filename, content string, fields map[string]string, headers map[string]string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	writer.Close()
+
+	allHeaders := map[string]string{"Content-Type": writer.FormDataContentType()}
+	for key, value := range headers {
+		allHeaders[key] = value
+	}
+	return s.performRequest("POST", "/api/v1/prompts/import", body, allHeaders)
+}
+
+// TestImportAPI 测试JSONL/CSV批量导入、重复处理和逐行错误报告
+func (s *APITestSuite) TestImportAPI() {
+	token := s.registerUser("importer")
+	authHeader := map[string]string{"Authorization": "Bearer " + token}
+
+	// 1. JSONL import with a per-row error report
+	jsonl := `{"prompt": "导入的猫", "tags": ["动物", "导入"]}
+{"prompt": "导入的狗", "tags": "动物,狗", "is_public": true}
+{broken
+{"prompt": ""}`
+	w := s.importFile("dump.jsonl", jsonl, map[string]string{"chunk_size": "1"}, authHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	result := response.Data.(map[string]interface{})
+	assert.Equal(s.T(), float64(4), result["total"])
+	assert.Equal(s.T(), float64(2), result["created"])
+	assert.Equal(s.T(), float64(2), result["failed"])
+	errorLines := []float64{}
+	for _, rowErr := range result["errors"].([]interface{}) {
+		errorLines = append(errorLines, rowErr.(map[string]interface{})["line"].(float64))
+	}
+	assert.Equal(s.T(), []float64{3, 4}, errorLines)
+
+	var prompt models.Prompt
+	assert.NoError(s.T(), s.db.Preload("Tags").Where("prompt_text = ?", "导入的猫").First(&prompt).Error)
+	assert.Len(s.T(), prompt.Tags, 2)
+	assert.NotNil(s.T(), prompt.OwnerID, "导入的提示词应归属于当前用户")
+
+	// 2. CSV with a column mapping: duplicates are skipped by default
+	csvContent := "Text,Labels,Negative\n导入的猫,动物,模糊\n导入的鸟,\"动物,鸟\",\n"
+	mapping := `{"Text": "prompt_text", "Labels": "tag_names", "Negative": "negative_prompt"}`
+	w = s.importFile("sheet.csv", csvContent, map[string]string{"mapping": mapping}, authHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	result = response.Data.(map[string]interface{})
+	assert.Equal(s.T(), float64(1), result["created"])
+	assert.Equal(s.T(), float64(1), result["skipped"])
+
+	// 3. Upsert updates only the columns present in the file
+	w = s.importFile("sheet.csv", csvContent, map[string]string{"mapping": mapping, "on_duplicate": "upsert"}, authHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	result = response.Data.(map[string]interface{})
+	assert.Equal(s.T(), float64(2), result["updated"])
+	prompt = models.Prompt{}
+	s.db.Preload("Tags").Where("prompt_text = ?", "导入的猫").First(&prompt)
+	assert.Equal(s.T(), "模糊", prompt.NegativePrompt)
+	assert.Len(s.T(), prompt.Tags, 1)
+
+	// 4. Tags that differ only in case share one tag and keep the rest of the row's tags
+	mixedCase := `{"prompt": "大小写标签一", "tags": ["Cat", "cat", "Dog"]}
+{"prompt": "大小写标签二", "tags": ["dog", "Bird"]}`
+	w = s.importFile("mixed.jsonl", mixedCase, nil, authHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	tagNames := func(text string) []string {
+		var p models.Prompt
+		s.Require().NoError(s.db.Preload("Tags").Where("prompt_text = ?", text).First(&p).Error)
+		names := []string{}
+		for _, tag := range p.Tags {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(s.T(), []string{"Cat", "Dog"}, tagNames("大小写标签一"))
+	assert.ElementsMatch(s.T(), []string{"Dog", "Bird"}, tagNames("大小写标签二"))
+
+	// 5. Invalid files and options are rejected up front
+	w = s.importFile("sheet.csv", "a,b\n1,2\n", nil, authHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	w = s.importFile("sheet.xlsx", csvContent, nil, authHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	w = s.importFile("sheet.csv", csvContent, map[string]string{"mapping": "Text=unknown"}, authHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+// TestExportAPI 测试按过滤条件导出提示词
+func (s *APITestSuite) TestExportAPI() {
+	for _, body := range []string{
+		`{"prompt_text": "导出的湖景", "model_name": "sdxl", "tag_names": ["风景"]}`,
+		`{"prompt_text": "导出的城市", "model_name": "flux", "negative_prompt": "模糊", "tag_names": ["城市"]}`,
+	} {
+		w := s.performRequest("POST", "/api/v1/prompts/", bytes.NewBufferString(body), map[string]string{"Content-Type": "application/json"})
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+	}
+
+	// 1. JSONL honours PromptQuery filters
+	w := s.performRequest("GET", "/api/v1/prompts/export?model_name=sdxl", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Header().Get("Content-Disposition"), ".jsonl")
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	assert.Len(s.T(), lines, 1)
+	assert.Contains(s.T(), string(lines[0]), "导出的湖景")
+
+	// 2. CSV filtered by tag
+	w = s.performRequest("GET", "/api/v1/prompts/export?format=csv&tag_names=城市", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "prompt_text")
+	assert.Contains(s.T(), w.Body.String(), "导出的城市")
+	assert.NotContains(s.T(), w.Body.String(), "导出的湖景")
+
+	// 3. A1111 text, oldest first
+	w = s.performRequest("GET", "/api/v1/prompts/export?format=txt&sort_by=created_at&sort_order=asc", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), "导出的湖景\n--prompt \"导出的城市\" --negative_prompt \"模糊\"\n", w.Body.String())
+
+	// 4. Markdown gallery bundled as a zip
+	w = s.performRequest("GET", "/api/v1/prompts/export?format=markdown&zip=true", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), "application/zip", w.Header().Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "prompts.md", archive.File[0].Name)
+
+	// 5. Unknown formats are rejected
+	w = s.performRequest("GET", "/api/v1/prompts/export?format=xml", nil, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+// TestCivitaiAPI 测试 Civitai 元数据的导入和导出
+func (s *APITestSuite) TestCivitaiAPI() {
+	payload := `{"items": [{"id": 1, "url": "https://image.civitai.com/a.jpeg", "meta": {
+		"prompt": "a lighthouse in a storm", "negativePrompt": "lowres", "seed": 99, "sampler": "Euler a",
+		"cfgScale": 6, "Model": "dreamshaper_8", "resources": [{"name": "stormy", "type": "lora", "weight": 0.7}]}}]}`
+
+	// 1. Import a saved API response (format detected from the .json extension)
+	w := s.importFile("civitai.json", payload, nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(s.T(), float64(1), response.Data.(map[string]interface{})["created"])
+
+	var prompt models.Prompt
+	assert.NoError(s.T(), s.db.Preload("Tags").Where("prompt_text = ?", "a lighthouse in a storm").First(&prompt).Error)
+	assert.Equal(s.T(), "dreamshaper_8", prompt.ModelName)
+	assert.Equal(s.T(), "stormy", prompt.Tags[0].Name)
+	assert.Contains(s.T(), string(prompt.GenerationParams), `"seed": 99`)
+
+	// 2. Generation params are exposed on the prompt response
+	w = s.performRequest("GET", fmt.Sprintf("/api/v1/prompts/%d", prompt.ID), nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `"generation_params"`)
+
+	// 3. Export in the same shape as the Civitai images API
+	w = s.performRequest("GET", "/api/v1/prompts/export?format=civitai&model_name=dreamshaper_8", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var exported struct {
+		Items []struct {
+			URL  string                 `json:"url"`
+			Meta map[string]interface{} `json:"meta"`
+		} `json:"items"`
+	}
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &exported))
+	assert.Len(s.T(), exported.Items, 1)
+	assert.Equal(s.T(), "https://image.civitai.com/a.jpeg", exported.Items[0].URL)
+	assert.Equal(s.T(), "a lighthouse in a storm", exported.Items[0].Meta["prompt"])
+	assert.Equal(s.T(), "lowres", exported.Items[0].Meta["negativePrompt"])
+	assert.Equal(s.T(), float64(99), exported.Items[0].Meta["seed"])
+	assert.Equal(s.T(), "dreamshaper_8", exported.Items[0].Meta["Model"])
+}
+
+// createPrompt 辅助函数，以JSON创建提示词并返回ID
+func (s *APITestSuite) createPrompt(body string, headers map[string]string) uint {
+	allHeaders := map[string]string{"Content-Type": "application/json"}
+	for key, value := range headers {
+		allHeaders[key] = value
+	}
+	w := s.performRequest("POST", "/api/v1/prompts/", bytes.NewBufferString(body), allHeaders)
+	s.Require().Equal(http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return uint(response.Data.(map[string]interface{})["id"].(float64))
+}
+
+// bulk 辅助函数，执行批量操作并返回结果
+func (s *APITestSuite) bulk(body string, headers map[string]string) (int, map[string]interface{}) {
+	allHeaders := map[string]string{"Content-Type": "application/json"}
+	for key, value := range headers {
+		allHeaders[key] = value
+	}
+	w := s.performRequest("POST", "/api/v1/prompts/bulk", bytes.NewBufferString(body), allHeaders)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	result, _ := response.Data.(map[string]interface{})
+	return w.Code, result
+}
+
+// bulkStatuses 提取批量操作结果中每个提示词的状态
+func bulkStatuses(result map[string]interface{}) map[uint]string {
+	statuses := make(map[uint]string)
+	for _, item := range result["items"].([]interface{}) {
+		entry := item.(map[string]interface{})
+		statuses[uint(entry["id"].(float64))] = entry["status"].(string)
+	}
+	return statuses
+}
+
+// TestBulkAPI 测试批量操作：预演、按ID和按过滤条件选择、删除恢复以及加入收藏集
+func (s *APITestSuite) TestBulkAPI() {
+	aliceHeader := map[string]string{"Authorization": "Bearer " + s.registerUser("alice")}
+	bobHeader := map[string]string{"Authorization": "Bearer " + s.registerUser("bob")}
+	first := s.createPrompt(`{"prompt_text": "批量一", "model_name": "sd15"}`, aliceHeader)
+	second := s.createPrompt(`{"prompt_text": "批量二", "model_name": "sd15"}`, aliceHeader)
+	bobs := s.createPrompt(`{"prompt_text": "别人的提示词", "model_name": "sd15", "is_public": true}`, bobHeader)
+
+	// 1. Dry run reports per-item results without touching the data
+	body := fmt.Sprintf(`{"ids": [%d, %d, %d, 999999], "operation": "add_tags", "tag_names": ["批量"], "dry_run": true}`, first, second, bobs)
+	code, result := s.bulk(body, aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	assert.Equal(s.T(), true, result["dry_run"])
+	assert.Equal(s.T(), float64(2), result["succeeded"])
+	assert.Equal(s.T(), float64(2), result["failed"])
+	statuses := bulkStatuses(result)
+	assert.Equal(s.T(), models.BulkStatusFailed, statuses[bobs], "他人的提示词不能修改")
+	assert.Equal(s.T(), models.BulkStatusFailed, statuses[999999])
+	var tagCount int64
+	s.db.Model(&models.Tag{}).Where("name = ?", "批量").Count(&tagCount)
+	assert.Equal(s.T(), int64(0), tagCount, "预演不应创建标签")
+
+	// 2. The real run applies the change; repeating it skips every item
+	body = fmt.Sprintf(`{"ids": [%d, %d], "operation": "add_tags", "tag_names": ["批量"]}`, first, second)
+	code, result = s.bulk(body, aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	assert.Equal(s.T(), float64(2), result["succeeded"])
+	code, result = s.bulk(body, aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	assert.Equal(s.T(), float64(2), result["skipped"])
+
+	// 3. Select by filter
+	code, result = s.bulk(`{"filter": {"tag_names": ["批量"]}, "operation": "set_public", "is_public": true}`, aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	assert.Equal(s.T(), float64(2), result["total"])
+	var publicCount int64
+	s.db.Model(&models.Prompt{}).Where("id IN ? AND is_public = ?", []uint{first, second}, true).Count(&publicCount)
+	assert.Equal(s.T(), int64(2), publicCount)
+
+	// 4. Delete, then restore through a filter that only matches deleted prompts
+	code, _ = s.bulk(fmt.Sprintf(`{"ids": [%d], "operation": "delete"}`, first), aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	w := s.performRequest("GET", fmt.Sprintf("/api/v1/prompts/%d", first), nil, aliceHeader)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	code, result = s.bulk(`{"filter": {"model_name": "sd15"}, "operation": "restore"}`, aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	assert.Equal(s.T(), map[uint]string{first: models.BulkStatusSuccess}, bulkStatuses(result))
+	w = s.performRequest("GET", fmt.Sprintf("/api/v1/prompts/%d", first), nil, aliceHeader)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	// 5. Add to a collection; prompts already in it are skipped, and other users' public prompts can be added
+	w = s.performRequest("POST", "/api/v1/collections/", bytes.NewBufferString(fmt.Sprintf(`{"name": "批量收藏", "prompt_ids": [%d]}`, first)),
+		map[string]string{"Content-Type": "application/json"})
+	s.Require().Equal(http.StatusOK, w.Code)
+	var response utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &response)
+	collectionID := uint(response.Data.(map[string]interface{})["id"].(float64))
+	code, result = s.bulk(fmt.Sprintf(`{"ids": [%d, %d, %d], "operation": "add_to_collection", "collection_id": %d}`, first, second, bobs, collectionID), aliceHeader)
+	s.Require().Equal(http.StatusOK, code)
+	assert.Equal(s.T(), map[uint]string{first: models.BulkStatusSkipped, second: models.BulkStatusSuccess, bobs: models.BulkStatusSuccess}, bulkStatuses(result))
+	var itemCount int64
+	s.db.Model(&models.CollectionItem{}).Where("collection_id = ?", collectionID).Count(&itemCount)
+	assert.Equal(s.T(), int64(3), itemCount)
+
+	// 6. Invalid requests
+	code, _ = s.bulk(fmt.Sprintf(`{"ids": [%d], "filter": {}, "operation": "delete"}`, first), aliceHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	code, _ = s.bulk(fmt.Sprintf(`{"ids": [%d], "operation": "set_public"}`, first), aliceHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	code, _ = s.bulk(fmt.Sprintf(`{"ids": [%d], "operation": "explode"}`, first), aliceHeader)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	code, _ = s.bulk(fmt.Sprintf(`{"ids": [%d], "operation": "add_to_collection", "collection_id": 999999}`, first), aliceHeader)
+	assert.Equal(s.T(), http.StatusNotFound, code)
+}
+
+// TestETagAPI 测试 ETag、If-Match 乐观并发控制和 If-None-Match 条件请求
+func (s *APITestSuite) TestETagAPI() {
+	id := s.createPrompt(`{"prompt_text": "并发编辑的提示词"}`, nil)
+	promptURL := fmt.Sprintf("/api/v1/prompts/%d", id)
+
+	// 1. GET returns the version as an ETag; If-None-Match yields 304 with no body
+	w := s.performRequest("GET", promptURL, nil, nil)
+	s.Require().Equal(http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(s.T(), fmt.Sprintf(`"%d-v1"`, id), etag)
+	w = s.performRequest("GET", promptURL, nil, map[string]string{"If-None-Match": etag})
+	assert.Equal(s.T(), http.StatusNotModified, w.Code)
+	assert.Empty(s.T(), w.Body.Bytes())
+
+	// 2. The first writer wins; the second one, holding the stale ETag, gets 412
+	jsonHeaders := func(ifMatch string) map[string]string {
+		return map[string]string{"Content-Type": "application/json", "If-Match": ifMatch}
+	}
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"prompt_text": "第一个编辑"}`), jsonHeaders(etag))
+	s.Require().Equal(http.StatusOK, w.Code)
+	newETag := w.Header().Get("ETag")
+	assert.Equal(s.T(), fmt.Sprintf(`"%d-v2"`, id), newETag)
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"prompt_text": "第二个编辑"}`), jsonHeaders(etag))
+	assert.Equal(s.T(), http.StatusPreconditionFailed, w.Code)
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"prompt_text": "第二个编辑"}`), jsonHeaders(`"garbage"`))
+	assert.Equal(s.T(), http.StatusPreconditionFailed, w.Code)
+	var prompt models.Prompt
+	s.db.First(&prompt, id)
+	assert.Equal(s.T(), "第一个编辑", prompt.PromptText)
+
+	// 3. The old ETag no longer matches If-None-Match
+	w = s.performRequest("GET", promptURL, nil, map[string]string{"If-None-Match": etag})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	// 4. List endpoints answer 304 while their content is unchanged
+	w = s.performRequest("GET", "/api/v1/prompts/?page=1&page_size=10", nil, nil)
+	s.Require().Equal(http.StatusOK, w.Code)
+	listETag := w.Header().Get("ETag")
+	s.Require().NotEmpty(listETag)
+	w = s.performRequest("GET", "/api/v1/prompts/?page=1&page_size=10", nil, map[string]string{"If-None-Match": listETag})
+	assert.Equal(s.T(), http.StatusNotModified, w.Code)
+	s.createPrompt(`{"prompt_text": "新的提示词"}`, nil)
+	w = s.performRequest("GET", "/api/v1/prompts/?page=1&page_size=10", nil, map[string]string{"If-None-Match": listETag})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	// 5. DELETE honours If-Match too
+	w = s.performRequest("DELETE", promptURL, nil, map[string]string{"If-Match": etag})
+	assert.Equal(s.T(), http.StatusPreconditionFailed, w.Code)
+	w = s.performRequest("DELETE", promptURL, nil, map[string]string{"If-Match": newETag})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+}
+
+func (s *APITestSuite) TestPatchAPI() {
+	id := s.createPrompt(`{"prompt_text": "待修改的提示词", "negative_prompt": "blurry", "input_image_urls": ["http://example.com/a.png", "http://example.com/b.png"], "tag_names": ["风景", "夜晚"]}`, nil)
+	promptURL := fmt.Sprintf("/api/v1/prompts/%d", id)
+	patch := func(contentType, body string, headers map[string]string) *httptest.ResponseRecorder {
+		h := map[string]string{"Content-Type": contentType}
+		for k, v := range headers {
+			h[k] = v
+		}
+		return s.performRequest("PATCH", promptURL, bytes.NewBufferString(body), h)
+	}
+	load := func() models.Prompt {
+		var prompt models.Prompt
+		s.Require().NoError(s.db.Preload("Tags").Preload("Images").First(&prompt, id).Error)
+		return prompt
+	}
+
+	// 1. Merge patch: fields not in the patch are kept, explicit null clears
+	w := patch("application/merge-patch+json", `{"model_name": "SDXL", "negative_prompt": null, "input_image_urls": null}`, nil)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(s.T(), fmt.Sprintf(`"%d-v2"`, id), w.Header().Get("ETag"))
+	prompt := load()
+	assert.Equal(s.T(), "待修改的提示词", prompt.PromptText)
+	assert.Equal(s.T(), "SDXL", prompt.ModelName)
+	assert.Empty(s.T(), prompt.NegativePrompt)
+	assert.Empty(s.T(), prompt.GetInputImageURLs())
+	assert.Len(s.T(), prompt.Tags, 2)
+
+	// 2. JSON Patch edits the tag list in place
+	w = patch("application/json-patch+json", `[
+		{"op": "test", "path": "/tag_names/0", "value": "风景"},
+		{"op": "remove", "path": "/tag_names/1"},
+		{"op": "add", "path": "/tag_names/-", "value": "城市"},
+		{"op": "add", "path": "/input_image_urls/-", "value": "http://example.com/c.png"}
+	]`, nil)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	prompt = load()
+	tagNames := []string{}
+	for _, tag := range prompt.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	assert.ElementsMatch(s.T(), []string{"风景", "城市"}, tagNames)
+	assert.Equal(s.T(), []string{"http://example.com/c.png"}, prompt.GetInputImageURLs())
+	assert.Equal(s.T(), uint(3), prompt.Version)
+
+	// 3. A failing test op rejects the whole patch
+	w = patch("application/json-patch+json", `[{"op": "replace", "path": "/model_name", "value": "SD15"}, {"op": "test", "path": "/model_name", "value": "SDXL"}]`, nil)
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+	assert.Equal(s.T(), "SDXL", load().ModelName)
+
+	// 4. Invalid patches: unknown field, bad type, clearing prompt_text, bad index
+	for _, body := range []string{`{"owner_id": 1}`, `{"is_public": "yes"}`, `{"prompt_text": null}`} {
+		w = patch("application/merge-patch+json", body, nil)
+		assert.Equal(s.T(), http.StatusBadRequest, w.Code, body)
+	}
+	w = patch("application/json-patch+json", `[{"op": "remove", "path": "/tag_names/5"}]`, nil)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 5. Unsupported media types and stale ETags
+	w = patch("text/plain", `{"model_name": "x"}`, nil)
+	assert.Equal(s.T(), http.StatusUnsupportedMediaType, w.Code)
+	w = patch("application/merge-patch+json", `{"model_name": "x"}`, map[string]string{"If-Match": fmt.Sprintf(`"%d-v1"`, id)})
+	assert.Equal(s.T(), http.StatusPreconditionFailed, w.Code)
+	w = patch("application/merge-patch+json", `{"model_name": "x"}`, map[string]string{"If-Match": fmt.Sprintf(`"%d-v3"`, id)})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), "x", load().ModelName)
+}
+
+func (s *APITestSuite) TestPromptImagesAPI() {
+	longURL := "https://example.com/" + strings.Repeat("a", 600) + ".png"
+	body := fmt.Sprintf(`{
+		"prompt_text": "多图片提示词",
+		"input_image_urls": ["https://example.com/in,1.png", %q],
+		"output_image_url": "https://example.com/out-1.png",
+		"images": [
+			{"role": "output", "url": "https://example.com/out-2.png", "caption": "第二张", "width": 1024, "height": 768},
+			{"role": "mask", "url": "https://example.com/mask.png"}
+		]
+	}`, longURL)
+	id := s.createPrompt(body, nil)
+	promptURL := fmt.Sprintf("/api/v1/prompts/%d", id)
+
+	// 1. Commas and long URLs survive; the legacy fields are derived from the images
+	w := s.performRequest("GET", promptURL, nil, nil)
+	s.Require().Equal(http.StatusOK, w.Code)
+	var resp struct {
+		Data models.PromptResponse `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), []string{"https://example.com/in,1.png", longURL}, resp.Data.InputImageURLs)
+	assert.Equal(s.T(), "https://example.com/out-1.png", resp.Data.OutputImageURL)
+	s.Require().Len(resp.Data.Images, 5)
+	roles := map[string]int{}
+	for _, image := range resp.Data.Images {
+		roles[image.Role]++
+		if image.URL == "https://example.com/out-2.png" {
+			assert.Equal(s.T(), "第二张", image.Caption)
+			assert.Equal(s.T(), 1024, image.Width)
+			assert.Equal(s.T(), 1, image.Position)
+		}
+	}
+	assert.Equal(s.T(), map[string]int{"input": 2, "output": 2, "mask": 1}, roles)
+
+	// 2. Updating output_image_url replaces the output images only
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"output_image_url": "https://example.com/new.png"}`), map[string]string{"Content-Type": "application/json"})
+	s.Require().Equal(http.StatusOK, w.Code)
+	var count int64
+	s.db.Model(&models.PromptImage{}).Where("prompt_id = ? AND role = ?", id, models.ImageRoleOutput).Count(&count)
+	assert.Equal(s.T(), int64(1), count)
+	s.db.Model(&models.PromptImage{}).Where("prompt_id = ?", id).Count(&count)
+	assert.Equal(s.T(), int64(4), count)
+
+	// 3. An images array replaces every image
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"images": [{"role": "controlnet", "url": "https://example.com/depth.png"}]}`), map[string]string{"Content-Type": "application/json"})
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(s.T(), resp.Data.InputImageURLs)
+	assert.Empty(s.T(), resp.Data.OutputImageURL)
+	s.Require().Len(resp.Data.Images, 1)
+	assert.Equal(s.T(), models.ImageRoleControlNet, resp.Data.Images[0].Role)
+
+	// 4. Unknown roles are rejected
+	w = s.performRequest("PUT", promptURL, bytes.NewBufferString(`{"images": [{"role": "thumbnail", "url": "https://example.com/x.png"}]}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 5. Hard-deleting the prompt removes its images
+	s.db.Unscoped().Delete(&models.Prompt{}, id)
+	s.db.Model(&models.PromptImage{}).Where("prompt_id = ?", id).Count(&count)
+	assert.Equal(s.T(), int64(0), count)
+}
+
+// TestFetchRemoteImagesAPI 测试创建提示词时下载远程图片
+func (s *APITestSuite) TestFetchRemoteImagesAPI() {
+	var pngData bytes.Buffer
+	s.Require().NoError(pngEncode(&pngData))
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page.html" {
+			w.Write([]byte("<html></html>"))
+			return
+		}
+		w.Write(pngData.Bytes())
+	}))
+	defer remote.Close()
+	defer os.RemoveAll(filepath.Join(s.cfg.Server.UploadPath))
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	// 1. By default the test server's loopback address is blocked
+	body := fmt.Sprintf(`{"prompt_text": "远程图片", "output_image_url": %q, "fetch_remote": true}`, remote.URL+"/out.png")
+	w := s.performRequest("POST", "/api/v1/prompts", bytes.NewBufferString(body), headers)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	var count int64
+	s.db.Model(&models.Prompt{}).Count(&count)
+	assert.Equal(s.T(), int64(0), count)
+
+	s.cfg.Server.RemoteFetchAllowPrivate = true
+	defer func() { s.cfg.Server.RemoteFetchAllowPrivate = false }()
+
+	// 2. Remote URLs are downloaded and rewritten; the same URL is fetched once
+	body = fmt.Sprintf(`{
+		"prompt_text": "远程图片",
+		"input_image_urls": [%q, "/uploads/local.png"],
+		"output_image_url": %q,
+		"images": [{"role": "mask", "url": %q}]
+	}`, remote.URL+"/in.png", remote.URL+"/out.png", remote.URL+"/in.png")
+	w = s.performRequest("POST", "/api/v1/prompts?fetch_remote=true", bytes.NewBufferString(body), headers)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data models.PromptResponse `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	s.Require().Len(resp.Data.InputImageURLs, 2)
+	assert.True(s.T(), strings.HasPrefix(resp.Data.InputImageURLs[0], "/uploads/in_"))
+	assert.Equal(s.T(), "/uploads/local.png", resp.Data.InputImageURLs[1])
+	assert.True(s.T(), strings.HasPrefix(resp.Data.OutputImageURL, "/uploads/out_"))
+	for _, image := range resp.Data.Images {
+		if image.Role == models.ImageRoleMask {
+			assert.Equal(s.T(), resp.Data.InputImageURLs[0], image.URL)
+		}
+		if image.Role == models.ImageRoleOutput {
+			assert.Equal(s.T(), 4, image.Width)
+			assert.NotEmpty(s.T(), image.Hash)
+		}
+	}
+	assert.FileExists(s.T(), filepath.Join(s.cfg.Server.UploadPath, filepath.Base(resp.Data.OutputImageURL)))
+
+	// 3. Without fetch_remote the URLs are stored as given
+	body = fmt.Sprintf(`{"prompt_text": "远程图片", "output_image_url": %q}`, remote.URL+"/out.png")
+	w = s.performRequest("POST", "/api/v1/prompts", bytes.NewBufferString(body), headers)
+	s.Require().Equal(http.StatusOK, w.Code)
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), remote.URL+"/out.png", resp.Data.OutputImageURL)
+
+	// 4. A non-image response fails the request and leaves no files behind
+	entries, _ := os.ReadDir(s.cfg.Server.UploadPath)
+	body = fmt.Sprintf(`{"prompt_text": "远程图片", "input_image_urls": [%q, %q], "fetch_remote": true}`, remote.URL+"/ok.png", remote.URL+"/page.html")
+	w = s.performRequest("POST", "/api/v1/prompts", bytes.NewBufferString(body), headers)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	after, _ := os.ReadDir(s.cfg.Server.UploadPath)
+	assert.Len(s.T(), after, len(entries))
+
+	// 5. Too many distinct remote URLs are rejected before anything is downloaded
+	urls := make([]string, s.cfg.Server.RemoteFetchMaxImages+1)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%q", fmt.Sprintf("%s/%d.png", remote.URL, i))
+	}
+	body = fmt.Sprintf(`{"prompt_text": "远程图片", "input_image_urls": [%s], "fetch_remote": true}`, strings.Join(urls, ", "))
+	w = s.performRequest("POST", "/api/v1/prompts", bytes.NewBufferString(body), headers)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "远程图片超过")
+	after, _ = os.ReadDir(s.cfg.Server.UploadPath)
+	assert.Len(s.T(), after, len(entries))
+}
+
+// pngEncode 写入一张 4x4 的PNG图片
+func pngEncode(w io.Writer) error {
+	return png.Encode(w, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+}
+
+// TestAnalyzeJobAPI 测试异步分析任务
+func (s *APITestSuite) TestAnalyzeJobAPI() {
+	defer os.RemoveAll(filepath.Join(s.cfg.Server.UploadPath))
+	runner := services.NewJobRunner()
+	runner.Start()
+	defer runner.Stop()
+
+	id := s.createPrompt(`{"prompt_text": "portrait of a cat", "tag_names": ["猫"]}`, nil)
+	analyzeForm := func(fields map[string]string, withImage bool) (*bytes.Buffer, map[string]string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		if withImage {
+			part, _ := writer.CreateFormFile("output_image", "cat.jpg")
+			part.Write([]byte("这是一个假的图片"))
+		}
+		for key, value := range fields {
+			writer.WriteField(key, value)
+		}
+		writer.Close()
+		return body, map[string]string{"Content-Type": writer.FormDataContentType()}
+	}
+
+	// 1. Invalid requests are rejected before a job is created
+	body, headers := analyzeForm(map[string]string{"prompt_text": "缺少图片"}, false)
+	w := s.performRequest("POST", "/api/v1/jobs/analyze", body, headers)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	body, headers = analyzeForm(map[string]string{"prompt_text": "p", "auto_apply": "true"}, true)
+	w = s.performRequest("POST", "/api/v1/jobs/analyze", body, headers)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 2. The job is accepted immediately and auto-applied once it finishes
+	body, headers = analyzeForm(map[string]string{"prompt_id": fmt.Sprint(id), "auto_apply": "true"}, true)
+	w = s.performRequest("POST", "/api/v1/jobs/analyze", body, headers)
+	s.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+	var resp struct {
+		Data models.Job `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	jobURL := fmt.Sprintf("/api/v1/jobs/%d", resp.Data.ID)
+	assert.Equal(s.T(), jobURL, w.Header().Get("Location"))
+	assert.Equal(s.T(), models.JobStatusPending, resp.Data.Status)
+	// Anonymous jobs are only reachable with the access token returned on creation
+	s.Require().NotEmpty(resp.Data.AccessToken)
+	jobHeader := map[string]string{"X-Job-Token": resp.Data.AccessToken}
+	w = s.performRequest("GET", jobURL, nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for resp.Data.Status != models.JobStatusSucceeded && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		w = s.performRequest("GET", jobURL, nil, jobHeader)
+		s.Require().Equal(http.StatusOK, w.Code)
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+		s.Require().NotEqual(models.JobStatusFailed, resp.Data.Status, resp.Data.Error)
+	}
+	s.Require().Equal(models.JobStatusSucceeded, resp.Data.Status)
+	assert.NotNil(s.T(), resp.Data.AppliedAt)
+
+	var prompt models.Prompt
+	s.db.Preload("Tags").First(&prompt, id)
+	assert.NotEmpty(s.T(), prompt.StyleDescription)
+	assert.Greater(s.T(), len(prompt.Tags), 1)
+
+	// 3. Finished jobs cannot be canceled; unknown jobs are 404
+	w = s.performRequest("POST", jobURL+"/cancel", nil, jobHeader)
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+	w = s.performRequest("GET", "/api/v1/jobs/999999", nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+// sseEvent 从事件流中读取的一条消息
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// openEventStream 连接事件流，测试结束或超时后自动断开
+func (s *APITestSuite) openEventStream(server *httptest.Server, headers map[string]string) *bufio.Reader {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	s.T().Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/v1/events", nil)
+	s.Require().NoError(err)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := server.Client().Do(req)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { resp.Body.Close() })
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Require().Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent 读取下一条事件，跳过注释和 retry 消息
+func (s *APITestSuite) readEvent(r *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		s.Require().NoError(err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if event.Data != "" {
+				return event
+			}
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// TestEventsAPI 测试事件流：只推送可见的事件，断线后根据 Last-Event-ID 补发
+func (s *APITestSuite) TestEventsAPI() {
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+	aliceToken := s.registerUser("alice")
+	bobToken := s.registerUser("bob")
+	alice := map[string]string{"Authorization": "Bearer " + aliceToken}
+	bob := map[string]string{"Authorization": "Bearer " + bobToken}
+
+	// 1. Bob only sees alice's public changes; new tags are broadcast
+	stream := s.openEventStream(server, bob)
+	privateID := s.createPrompt(`{"prompt_text": "私有的提示词"}`, alice)
+	publicID := s.createPrompt(`{"prompt_text": "公开的提示词", "is_public": true, "tag_names": ["事件标签"]}`, alice)
+
+	tagEvent := s.readEvent(stream)
+	assert.Equal(s.T(), models.EventTagCreated, tagEvent.Event)
+	assert.Contains(s.T(), tagEvent.Data, "事件标签")
+
+	created := s.readEvent(stream)
+	s.Require().Equal(models.EventPromptCreated, created.Event)
+	var payload struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			ID      uint   `json:"id"`
+			Version uint   `json:"version"`
+			Text    string `json:"prompt_text"`
+		} `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal([]byte(created.Data), &payload))
+	assert.Equal(s.T(), created.ID, payload.ID)
+	assert.Equal(s.T(), publicID, payload.Data.ID, "其他用户的私有提示词不应推送")
+	assert.NotEqual(s.T(), privateID, payload.Data.ID)
+
+	// 2. Updates and deletes follow; the owner also sees private changes
+	owner := s.openEventStream(server, alice)
+	w := s.performRequest("PUT", fmt.Sprintf("/api/v1/prompts/%d", publicID), bytes.NewBufferString(`{"prompt_text": "更新后的提示词"}`),
+		map[string]string{"Content-Type": "application/json", "Authorization": alice["Authorization"]})
+	s.Require().Equal(http.StatusOK, w.Code)
+	w = s.performRequest("DELETE", fmt.Sprintf("/api/v1/prompts/%d", privateID), nil, alice)
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	updated := s.readEvent(stream)
+	assert.Equal(s.T(), models.EventPromptUpdated, updated.Event)
+	assert.Contains(s.T(), updated.Data, "更新后的提示词")
+
+	assert.Equal(s.T(), models.EventPromptUpdated, s.readEvent(owner).Event)
+	deleted := s.readEvent(owner)
+	assert.Equal(s.T(), models.EventPromptDeleted, deleted.Event)
+	assert.Contains(s.T(), deleted.Data, fmt.Sprintf(`"id":%d`, privateID))
+
+	// 3. Reconnecting with Last-Event-ID replays what was missed
+	resumed := s.openEventStream(server, map[string]string{"Authorization": bob["Authorization"], "Last-Event-ID": created.ID})
+	assert.Equal(s.T(), updated.ID, s.readEvent(resumed).ID)
+
+	// 4. Unknown IDs (e.g. from before a restart) reset the stream
+	reset := s.openEventStream(server, map[string]string{"Authorization": bob["Authorization"], "Last-Event-ID": "0-1"})
+	assert.Equal(s.T(), models.EventStreamReset, s.readEvent(reset).Event)
+
+	// 5. Bulk imports publish the same events once each chunk commits
+	jsonl := `{"prompt_text": "导入的提示词", "is_public": true, "tag_names": ["导入标签"]}` + "\n" +
+		`{"prompt_text": "更新后的提示词", "is_public": true}` + "\n"
+	w = s.importFile("events.jsonl", jsonl, map[string]string{"on_duplicate": "upsert"}, alice)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+	importedTag := s.readEvent(stream)
+	assert.Equal(s.T(), models.EventTagCreated, importedTag.Event)
+	assert.Contains(s.T(), importedTag.Data, "导入标签")
+	upserted := s.readEvent(stream)
+	assert.Equal(s.T(), models.EventPromptUpdated, upserted.Event)
+	assert.Contains(s.T(), upserted.Data, fmt.Sprintf(`"id":%d`, publicID))
+	imported := s.readEvent(stream)
+	assert.Equal(s.T(), models.EventPromptCreated, imported.Event)
+	assert.Contains(s.T(), imported.Data, "导入的提示词")
+}
+
+// TestWebhooksAPI 测试订阅管理、签名推送、推送记录和手动重新推送
+func (s *APITestSuite) TestWebhooksAPI() {
+	config.AppConfig.Webhooks.AllowPrivate = true
+	config.AppConfig.Webhooks.PollInterval = 10 * time.Millisecond
+	config.AppConfig.Webhooks.RetryBackoff = 10 * time.Millisecond
+	dispatcher := services.NewWebhookDispatcher()
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	aliceToken := s.registerUser("alice")
+	bobToken := s.registerUser("bob")
+	alice := map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + aliceToken}
+	bob := map[string]string{"Authorization": "Bearer " + bobToken}
+
+	// 1. Creating a webhook returns the secret once; validation and login are required
+	w := s.performRequest("POST", "/api/v1/webhooks/", bytes.NewBufferString(`{"url": "not a url", "events": ["prompt.created"]}`), alice)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	w = s.performRequest("POST", "/api/v1/webhooks/", bytes.NewBufferString(`{"url": "`+receiver.URL+`", "events": ["unknown"]}`), alice)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	w = s.performRequest("GET", "/api/v1/webhooks/", nil, nil)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	w = s.performRequest("POST", "/api/v1/webhooks/", bytes.NewBufferString(`{"url": "`+receiver.URL+`", "events": ["prompt.created", "prompt.deleted"]}`), alice)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	var created struct {
+		Data models.WebhookSecretResponse `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &created))
+	s.Require().NotEmpty(created.Data.Secret)
+	webhookURL := fmt.Sprintf("/api/v1/webhooks/%d", created.Data.ID)
+
+	w = s.performRequest("GET", webhookURL, nil, alice)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NotContains(s.T(), w.Body.String(), created.Data.Secret, "签名密钥只在创建时返回")
+	w = s.performRequest("GET", webhookURL, nil, bob)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	// 2. A prompt change is delivered with a valid signature
+	promptID := s.createPrompt(`{"prompt_text": "推送到webhook的提示词"}`, alice)
+	var deliveries struct {
+		Data struct {
+			Items []models.WebhookDelivery `json:"items"`
+			Total int64                    `json:"total"`
+		} `json:"data"`
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		w = s.performRequest("GET", webhookURL+"/deliveries", nil, alice)
+		s.Require().Equal(http.StatusOK, w.Code)
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &deliveries))
+		if len(deliveries.Data.Items) == 1 && deliveries.Data.Items[0].Status == models.DeliveryStatusSucceeded {
+			break
+		}
+		s.Require().True(time.Now().Before(deadline), "等待推送超时: %s", w.Body.String())
+		time.Sleep(20 * time.Millisecond)
+	}
+	delivery := deliveries.Data.Items[0]
+	assert.Equal(s.T(), models.EventPromptCreated, delivery.EventType)
+	assert.Equal(s.T(), http.StatusOK, delivery.ResponseStatus)
+
+	mu.Lock()
+	s.Require().Len(received, 1)
+	assert.Equal(s.T(), fmt.Sprint(delivery.ID), received[0].Header.Get(utils.WebhookDeliveryHeader))
+	assert.NoError(s.T(), utils.VerifyWebhookSignature(created.Data.Secret, received[0].Header.Get(utils.WebhookSignatureHeader),
+		received[0].Header.Get(utils.WebhookTimestampHeader), bodies[0], time.Minute, time.Now()))
+	assert.Contains(s.T(), string(bodies[0]), fmt.Sprintf(`"id":%d`, promptID))
+	mu.Unlock()
+
+	// 3. Deliveries can be inspected and redelivered
+	deliveryURL := fmt.Sprintf("%s/deliveries/%d", webhookURL, delivery.ID)
+	w = s.performRequest("GET", deliveryURL, nil, alice)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", webhookURL+"/deliveries/999999", nil, alice)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+	w = s.performRequest("POST", deliveryURL+"/redeliver", nil, alice)
+	s.Require().Equal(http.StatusAccepted, w.Code, w.Body.String())
+
+	deadline = time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		count := len(received)
+		mu.Unlock()
+		if count == 2 {
+			break
+		}
+		s.Require().True(time.Now().Before(deadline), "等待重新推送超时")
+		time.Sleep(20 * time.Millisecond)
+	}
+	mu.Lock()
+	assert.Equal(s.T(), bodies[0], bodies[1], "重新推送的内容应与原推送相同")
+	mu.Unlock()
+
+	// 4. Rotating the secret, disabling and deleting
+	w = s.performRequest("PUT", webhookURL, bytes.NewBufferString(`{"rotate_secret": true, "active": false}`), alice)
+	s.Require().Equal(http.StatusOK, w.Code)
+	var rotated struct {
+		Data models.WebhookSecretResponse `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEqual(s.T(), created.Data.Secret, rotated.Data.Secret)
+	assert.False(s.T(), rotated.Data.Active)
+	w = s.performRequest("POST", deliveryURL+"/redeliver", nil, alice)
+	assert.Equal(s.T(), http.StatusConflict, w.Code)
+
+	w = s.performRequest("DELETE", webhookURL, nil, alice)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.performRequest("GET", webhookURL+"/deliveries", nil, alice)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+// graphQL 辅助函数，以 POST 执行 GraphQL 请求并返回状态码和响应
+func (s *APITestSuite) graphQL(query string, variables map[string]interface{}, headers map[string]string) (int, map[string]interface{}) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	allHeaders := map[string]string{"Content-Type": "application/json"}
+	for key, value := range headers {
+		allHeaders[key] = value
+	}
+	w := s.performRequest("POST", "/api/v1/graphql", bytes.NewReader(body), allHeaders)
+	var response map[string]interface{}
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+	return w.Code, response
+}
+
+// graphQLErrorCode 提取第一个错误的错误码
+func graphQLErrorCode(response map[string]interface{}) string {
+	errs, _ := response["errors"].([]interface{})
+	if len(errs) == 0 {
+		return ""
+	}
+	extensions, _ := errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	code, _ := extensions["code"].(string)
+	return code
+}
+
+// TestGraphQLAPI 测试 GraphQL 查询、关联字段和变更
+func (s *APITestSuite) TestGraphQLAPI() {
+	aliceToken := s.registerAdmin("alice")
+	bobToken := s.registerUser("bob")
+	alice := map[string]string{"Authorization": "Bearer " + aliceToken}
+	bob := map[string]string{"Authorization": "Bearer " + bobToken}
+
+	rootID := s.createPrompt(`{"prompt_text": "屋顶上的猫", "is_public": true, "tag_names": ["猫", "夜晚"], "output_image_url": "http://example.com/out.png", "input_image_urls": ["http://example.com/in.png"]}`, alice)
+	s.createPrompt(`{"prompt_text": "城市夜景", "is_public": true, "tag_names": ["夜晚"]}`, alice)
+	s.createPrompt(`{"prompt_text": "alice 的私有提示词", "tag_names": ["猫"]}`, alice)
+	w := s.performRequest("POST", fmt.Sprintf("/api/v1/prompts/%d/fork", rootID), nil, bob)
+	s.Require().Equal(http.StatusOK, w.Code)
+	w = s.performRequest("POST", "/api/v1/collections/", bytes.NewBufferString(fmt.Sprintf(`{"name": "猫图", "visibility": "public", "prompt_ids": [%d]}`, rootID)),
+		map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + aliceToken})
+	s.Require().Equal(http.StatusOK, w.Code)
+
+	// 1. One request returns prompts with tags, images, collections and counts; private prompts stay hidden
+	query := `query Gallery($tags: [String!], $size: Int) {
+		prompts(tag_names: $tags, page_size: $size, sort_by: "created_at", sort_order: "asc") {
+			total
+			items {
+				prompt_text version fork_count output_image_url input_image_urls
+				tags { name prompt_count }
+				collections { name item_count }
+				parent { id }
+			}
+		}
+	}`
+	code, response := s.graphQL(query, map[string]interface{}{"tags": []string{"夜晚"}, "size": 10}, bob)
+	s.Require().Equal(http.StatusOK, code)
+	s.Require().Nil(response["errors"], response)
+	page := response["data"].(map[string]interface{})["prompts"].(map[string]interface{})
+	assert.Equal(s.T(), float64(3), page["total"], "分叉复制了标签")
+	first := page["items"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(s.T(), "屋顶上的猫", first["prompt_text"])
+	assert.Equal(s.T(), float64(1), first["version"])
+	assert.Equal(s.T(), float64(1), first["fork_count"])
+	assert.Equal(s.T(), "http://example.com/out.png", first["output_image_url"])
+	assert.Equal(s.T(), []interface{}{"http://example.com/in.png"}, first["input_image_urls"])
+	assert.Nil(s.T(), first["parent"])
+	tags := first["tags"].([]interface{})
+	s.Require().Len(tags, 2)
+	assert.Contains(s.T(), tags, map[string]interface{}{"name": "猫", "prompt_count": float64(2)}, "bob 看不到 alice 的私有提示词")
+	assert.Equal(s.T(), []interface{}{map[string]interface{}{"name": "猫图", "item_count": float64(1)}}, first["collections"])
+
+	// 2. Fork points to its parent; GET requests run queries with JSON variables
+	vars := url.QueryEscape(`{"tags": ["猫"]}`)
+	w = s.performRequest("GET", "/api/v1/graphql?query="+url.QueryEscape(`query($tags: [String!]) { prompts(tag_names: $tags) { items { id parent { prompt_text } } } }`)+"&variables="+vars, nil, bob)
+	s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(s.T(), w.Body.String(), `"parent":{"prompt_text":"屋顶上的猫"}`)
+	w = s.performRequest("GET", "/api/v1/graphql?query="+url.QueryEscape(`mutation { create_tag(name: "x") { id } }`), nil, alice)
+	assert.Equal(s.T(), http.StatusMethodNotAllowed, w.Code)
+	w = s.performRequest("POST", "/api/v1/graphql", bytes.NewBufferString(`{"query": "{ prompts { nope } }"}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	// 3. Mutations mirror the REST endpoints, including ownership and version checks
+	code, response = s.graphQL(`mutation($input: CreatePromptInput!) { create_prompt(input: $input) { id owner_id version tags { name } } }`,
+		map[string]interface{}{"input": map[string]interface{}{"prompt_text": "GraphQL 创建", "is_public": true, "tag_names": []string{"新标签"}}}, alice)
+	s.Require().Equal(http.StatusOK, code)
+	s.Require().Nil(response["errors"], response)
+	created := response["data"].(map[string]interface{})["create_prompt"].(map[string]interface{})
+	assert.NotNil(s.T(), created["owner_id"])
+	assert.Equal(s.T(), []interface{}{map[string]interface{}{"name": "新标签"}}, created["tags"])
+	update := `mutation($id: ID!, $v: Int) { update_prompt(id: $id, if_version: $v, input: {model_name: "SDXL", tag_names: ["猫"]}) { model_name version tags { name } } }`
+
+	_, response = s.graphQL(update, map[string]interface{}{"id": created["id"], "v": 1}, bob)
+	assert.Equal(s.T(), "FORBIDDEN", graphQLErrorCode(response))
+	_, response = s.graphQL(update, map[string]interface{}{"id": created["id"], "v": 5}, alice)
+	assert.Equal(s.T(), "VERSION_CONFLICT", graphQLErrorCode(response))
+	assert.Nil(s.T(), response["data"], "非空的根字段出错时 data 为 null")
+	_, response = s.graphQL(update, map[string]interface{}{"id": created["id"], "v": 1}, alice)
+	s.Require().Nil(response["errors"], response)
+	updated := response["data"].(map[string]interface{})["update_prompt"].(map[string]interface{})
+	assert.Equal(s.T(), "SDXL", updated["model_name"])
+	assert.Equal(s.T(), float64(2), updated["version"])
+	assert.Equal(s.T(), []interface{}{map[string]interface{}{"name": "猫"}}, updated["tags"])
+	_, response = s.graphQL(`mutation { create_prompt(input: {prompt_text: "x", images: [{url: "a.png", role: "poster"}]}) { id } }`, nil, alice)
+	assert.Equal(s.T(), "BAD_USER_INPUT", graphQLErrorCode(response))
+
+	// 4. Tag operations respect role permissions
+	_, response = s.graphQL(`mutation { create_tag(name: "临时") { id name } }`, nil, bob)
+	s.Require().Nil(response["errors"], response)
+	tempID := response["data"].(map[string]interface{})["create_tag"].(map[string]interface{})["id"]
+	_, response = s.graphQL(`mutation($id: ID!) { delete_tag(id: $id) }`, map[string]interface{}{"id": tempID}, bob)
+	assert.Equal(s.T(), "FORBIDDEN", graphQLErrorCode(response), "编辑者没有删除标签的权限")
+	_, response = s.graphQL(`mutation($id: ID!) { delete_tag(id: $id) }`, map[string]interface{}{"id": tempID}, alice)
+	assert.Equal(s.T(), map[string]interface{}{"delete_tag": true}, response["data"])
+
+	var newTag, cat models.Tag
+	s.db.Where("name = ?", "新标签").First(&newTag)
+	s.db.Where("name = ?", "猫").First(&cat)
+	_, response = s.graphQL(`mutation($id: ID!, $target: ID!) { merge_tag(id: $id, target_id: $target) { merged_prompts tag { name } } }`,
+		map[string]interface{}{"id": newTag.ID, "target": cat.ID}, alice)
+	s.Require().Nil(response["errors"], response)
+	assert.Equal(s.T(), map[string]interface{}{"merged_prompts": float64(0), "tag": map[string]interface{}{"name": "猫"}},
+		response["data"].(map[string]interface{})["merge_tag"], "新标签的提示词已改为只有猫")
+
+	// 5. Read-only API keys can query over POST but cannot mutate
+	w = s.performRequest("POST", "/api/v1/me/api-keys/", bytes.NewBufferString(`{"name": "gallery", "scopes": ["read"]}`),
+		map[string]string{"Content-Type": "application/json", "Authorization": "Bearer " + aliceToken})
+	s.Require().Equal(http.StatusOK, w.Code)
+	var keyResponse utils.ResponseData
+	json.Unmarshal(w.Body.Bytes(), &keyResponse)
+	readKey := map[string]string{"Authorization": "Bearer " + keyResponse.Data.(map[string]interface{})["key"].(string)}
+	code, response = s.graphQL(`{ tags { name } }`, nil, readKey)
+	assert.Equal(s.T(), http.StatusOK, code)
+	assert.Nil(s.T(), response["errors"])
+	_, response = s.graphQL(`mutation { create_tag(name: "y") { id } }`, nil, readKey)
+	assert.Equal(s.T(), "FORBIDDEN", graphQLErrorCode(response))
+
+	// 6. The schema is published as SDL
+	w = s.performRequest("GET", "/api/v1/graphql/schema", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "type Prompt {")
+
+	// 7. Oversized operations are rejected before any resolver runs
+	var aliases strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&aliases, "a%d: prompts(page_size: 100) { total } ", i)
+	}
+	code, response = s.graphQL("{ "+aliases.String()+"}", nil, bob)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	assert.Contains(s.T(), response["errors"].([]interface{})[0].(map[string]interface{})["message"], "别名")
+	code, response = s.graphQL(`{ prompts(page_size: 100) { items {
+		tags { id name created_at prompt_count }
+		images { id role position url caption width height hash }
+		collections { name description visibility item_count }
+	} } }`, nil, bob)
+	assert.Equal(s.T(), http.StatusBadRequest, code)
+	assert.Contains(s.T(), response["errors"].([]interface{})[0].(map[string]interface{})["message"], "复杂度")
+	w = s.performRequest("POST", "/api/v1/graphql", strings.NewReader(`{"query": "{ tags { name } }", "padding": "`+strings.Repeat("x", 100<<10)+`"}`),
+		map[string]string{"Content-Type": "application/json"})
+	assert.Equal(s.T(), http.StatusRequestEntityTooLarge, w.Code)
+}
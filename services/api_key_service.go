@@ -0,0 +1,130 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAPIKeyNotFound API密钥不存在
+	ErrAPIKeyNotFound = errors.New("API密钥不存在")
+	// ErrInvalidAPIKey API密钥无效、已吊销或已过期
+	ErrInvalidAPIKey = errors.New("API密钥无效、已吊销或已过期")
+)
+
+// apiKeyLastUsedInterval 最后使用时间的更新间隔，避免每个请求都写数据库
+const apiKeyLastUsedInterval = time.Minute
+
+// APIKeyService 个人API密钥服务
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyService 创建API密钥服务实例
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		db: config.GetDB(),
+	}
+}
+
+// CreateAPIKey 为用户创建API密钥，返回的明文密钥只在此时可见
+func (s *APIKeyService) CreateAPIKey(userID uint, req *models.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("生成API密钥失败: %v", err)
+	}
+	plainKey := models.APIKeyPrefix + hex.EncodeToString(buf)
+
+	// 去重并保持顺序
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool)
+	for _, scope := range req.Scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	apiKey := &models.APIKey{
+		UserID:  userID,
+		Name:    strings.TrimSpace(req.Name),
+		Prefix:  plainKey[:len(models.APIKeyPrefix)+8],
+		KeyHash: hashToken(plainKey),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(apiKey).Error; err != nil {
+		return nil, "", fmt.Errorf("创建API密钥失败: %v", err)
+	}
+	return apiKey, plainKey, nil
+}
+
+// GetUserAPIKeys 获取用户的所有API密钥
+func (s *APIKeyService) GetUserAPIKeys(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("获取API密钥列表失败: %v", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 吊销用户的API密钥
+func (s *APIKeyService) RevokeAPIKey(userID, keyID uint) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	if err := s.db.Where("id = ? AND user_id = ?", keyID, userID).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("获取API密钥失败: %v", err)
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("吊销API密钥失败: %v", err)
+		}
+		apiKey.RevokedAt = &now
+	}
+	return &apiKey, nil
+}
+
+// AuthenticateAPIKey 校验明文API密钥，返回密钥记录（含所属用户）并记录使用时间
+func (s *APIKeyService) AuthenticateAPIKey(plainKey string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	err := s.db.Preload("User").Where("key_hash = ?", hashToken(plainKey)).First(&apiKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("校验API密钥失败: %v", err)
+	}
+	if !apiKey.IsActive() || apiKey.User == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedInterval {
+		s.db.Model(&apiKey).UpdateColumn("last_used_at", now)
+		apiKey.LastUsedAt = &now
+	}
+	return &apiKey, nil
+}
+
+// hashToken 计算令牌（API密钥、分享令牌）的SHA-256哈希
+func hashToken(plainKey string) string {
+	sum := sha256.Sum256([]byte(plainKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrPermissionDenied 当前用户没有执行该操作的权限
+	ErrPermissionDenied = errors.New("没有执行该操作的权限")
+)
+
+// AuthService 用户认证服务
+type AuthService struct {
+	db *gorm.DB
+}
+
+// NewAuthService 创建用户认证服务实例
+func NewAuthService() *AuthService {
+	return &AuthService{
+		db: config.GetDB(),
+	}
+}
+
+// Register 注册新用户
+func (s *AuthService) Register(req *models.RegisterRequest) (*models.User, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		return nil, fmt.Errorf("用户名不能为空")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("处理密码失败: %v", err)
+	}
+
+	// 公开注册的用户一律为编辑者，管理员只能通过 db-manager -create-admin 创建
+	user := &models.User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         models.RoleEditor,
+	}
+	if email := strings.TrimSpace(req.Email); email != "" {
+		user.Email = &email
+	}
+
+	// 用户名和邮箱由唯一索引保证不重复，并发注册同一用户名时只有一个请求会成功
+	if err := s.db.Create(user).Error; err != nil {
+		if key, ok := duplicateKey(err); ok {
+			if key == "email" {
+				return nil, fmt.Errorf("邮箱 %s 已被注册", *user.Email)
+			}
+			return nil, fmt.Errorf("用户名 %s 已被注册", username)
+		}
+		return nil, fmt.Errorf("创建用户失败: %v", err)
+	}
+	return user, nil
+}
+
+// CreateAdmin 创建管理员账号；用户已存在时将其提升为管理员（不修改密码）
+func (s *AuthService) CreateAdmin(username, password string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, fmt.Errorf("用户名不能为空")
+	}
+
+	var user models.User
+	err := s.db.Where("username = ?", username).First(&user).Error
+	if err == nil {
+		if err := s.db.Model(&user).Update("role", models.RoleAdmin).Error; err != nil {
+			return nil, fmt.Errorf("提升管理员失败: %v", err)
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+
+	if len(password) < 8 || len(password) > 72 {
+		return nil, fmt.Errorf("密码长度必须在 8 到 72 位之间")
+	}
+	created, err := s.Register(&models.RegisterRequest{Username: username, Password: password})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(created).Update("role", models.RoleAdmin).Error; err != nil {
+		return nil, fmt.Errorf("提升管理员失败: %v", err)
+	}
+	return created, nil
+}
+
+// duplicateKey 判断错误是否为违反唯一索引（MySQL 1062），并返回索引名
+func duplicateKey(err error) (string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1062 {
+		return "", false
+	}
+	// 错误信息形如 Duplicate entry 'x' for key 'users.email'（MySQL 8 之前没有表名前缀）
+	message := strings.TrimSuffix(mysqlErr.Message, "'")
+	key := message[strings.LastIndex(message, "'")+1:]
+	return key[strings.LastIndex(key, ".")+1:], true
+}
+
+// Login 校验用户名和密码，成功后签发令牌
+func (s *AuthService) Login(req *models.LoginRequest) (*models.TokenResponse, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", strings.TrimSpace(req.Username)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.IssueTokens(&user)
+}
+
+// VerifyPermission 校验用户名和密码，并确认该用户拥有指定权限（供命令行工具使用）
+func (s *AuthService) VerifyPermission(username, password string, perm models.Permission) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", strings.TrimSpace(username)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("获取用户失败: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.HasPermission(perm) {
+		return nil, ErrPermissionDenied
+	}
+	return &user, nil
+}
+
+// GetUsers 获取所有用户
+func (s *AuthService) GetUsers() ([]models.User, error) {
+	var users []models.User
+	if err := s.db.Order("id ASC").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("获取用户列表失败: %v", err)
+	}
+	return users, nil
+}
+
+// UpdateUserRole 修改用户角色，不允许移除最后一个管理员
+func (s *AuthService) UpdateUserRole(id uint, role string) (*models.User, error) {
+	if !models.IsValidRole(role) {
+		return nil, fmt.Errorf("无效的角色: %s", role)
+	}
+
+	var user models.User
+	err := runTransaction(s.db, func(tx *gorm.DB) error {
+		// 先锁住全部管理员行，使并发的降级请求串行执行，统计结果在提交前保持有效
+		var admins []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			Where("role = ?", models.RoleAdmin).Order("id ASC").Find(&admins).Error; err != nil {
+			return fmt.Errorf("获取管理员失败: %v", err)
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("获取用户失败: %v", err)
+		}
+
+		if user.Role == models.RoleAdmin && role != models.RoleAdmin && len(admins) <= 1 {
+			return fmt.Errorf("不能移除最后一个管理员")
+		}
+		if err := tx.Model(&user).Update("role", role).Error; err != nil {
+			return fmt.Errorf("修改用户角色失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RefreshTokens 使用刷新令牌换取新的令牌对
+func (s *AuthService) RefreshTokens(refreshToken string) (*models.TokenResponse, error) {
+	user, err := s.userFromToken(refreshToken, utils.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return s.IssueTokens(user)
+}
+
+// AuthenticateAccessToken 校验访问令牌并返回对应用户
+func (s *AuthService) AuthenticateAccessToken(accessToken string) (*models.User, error) {
+	return s.userFromToken(accessToken, utils.TokenTypeAccess)
+}
+
+// IssueTokens 为用户签发访问令牌和刷新令牌
+func (s *AuthService) IssueTokens(user *models.User) (*models.TokenResponse, error) {
+	authConfig := config.AppConfig.Auth
+	now := time.Now()
+
+	accessToken, err := utils.GenerateJWT(utils.JWTClaims{
+		Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		Username:  user.Username,
+		TokenType: utils.TokenTypeAccess,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(authConfig.AccessTokenTTL).Unix(),
+	}, authConfig.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateJWT(utils.JWTClaims{
+		Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		Username:  user.Username,
+		TokenType: utils.TokenTypeRefresh,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(authConfig.RefreshTokenTTL).Unix(),
+	}, authConfig.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(authConfig.AccessTokenTTL.Seconds()),
+		RefreshExpiresIn: int64(authConfig.RefreshTokenTTL.Seconds()),
+		User:             user.ToResponse(),
+	}, nil
+}
+
+// GetUserByID 根据ID获取用户
+func (s *AuthService) GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	result := s.db.First(&user, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("获取用户失败: %v", result.Error)
+	}
+	return &user, nil
+}
+
+// userFromToken 校验指定类型的令牌并加载用户
+func (s *AuthService) userFromToken(token, tokenType string) (*models.User, error) {
+	claims, err := utils.ParseJWT(token, config.AppConfig.Auth.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, utils.ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, utils.ErrInvalidToken
+	}
+	return s.GetUserByID(uint(userID))
+}
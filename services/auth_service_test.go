@@ -0,0 +1,230 @@
+package services_test
+
+import (
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// AuthServiceTestSuite 是 AuthService 的测试套件
+// 复用 PromptServiceTestSuite 的数据库设置
+type AuthServiceTestSuite struct {
+	PromptServiceTestSuite
+	authSvc *services.AuthService
+}
+
+// SetupTest 在每个测试方法运行前清理数据库并创建服务实例
+func (s *AuthServiceTestSuite) SetupTest() {
+	s.PromptServiceTestSuite.SetupTest()
+	s.authSvc = services.NewAuthService()
+}
+
+// TestRegisterAndLogin 测试注册、登录和令牌校验
+func (s *AuthServiceTestSuite) TestRegisterAndLogin() {
+	user, err := s.authSvc.Register(&models.RegisterRequest{Username: "alice", Password: "password123"})
+	s.NoError(err)
+	s.NotEqual("password123", user.PasswordHash, "密码应以哈希形式存储")
+
+	// 重复注册由唯一索引拦截
+	_, err = s.authSvc.Register(&models.RegisterRequest{Username: "alice", Password: "password123"})
+	s.ErrorContains(err, "用户名 alice 已被注册")
+	_, err = s.authSvc.Register(&models.RegisterRequest{Username: "bob", Password: "password123", Email: "shared@example.com"})
+	s.NoError(err)
+	_, err = s.authSvc.Register(&models.RegisterRequest{Username: "carol", Password: "password123", Email: "shared@example.com"})
+	s.ErrorContains(err, "邮箱 shared@example.com 已被注册")
+
+	// 并发注册同一用户名只有一个成功
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.authSvc.Register(&models.RegisterRequest{Username: "dave", Password: "password123"}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	s.Equal(1, succeeded)
+
+	// 错误密码
+	_, err = s.authSvc.Login(&models.LoginRequest{Username: "alice", Password: "wrong-password"})
+	s.ErrorIs(err, services.ErrInvalidCredentials)
+
+	// 正确登录
+	tokens, err := s.authSvc.Login(&models.LoginRequest{Username: "alice", Password: "password123"})
+	s.NoError(err)
+	s.NotEmpty(tokens.AccessToken)
+	s.NotEmpty(tokens.RefreshToken)
+
+	authed, err := s.authSvc.AuthenticateAccessToken(tokens.AccessToken)
+	s.NoError(err)
+	s.Equal(user.ID, authed.ID)
+
+	// 刷新令牌不能当作访问令牌使用，反之亦然
+	_, err = s.authSvc.AuthenticateAccessToken(tokens.RefreshToken)
+	s.Error(err)
+	_, err = s.authSvc.RefreshTokens(tokens.AccessToken)
+	s.Error(err)
+
+	refreshed, err := s.authSvc.RefreshTokens(tokens.RefreshToken)
+	s.NoError(err)
+	s.Equal(user.ID, refreshed.User.ID)
+}
+
+// TestPromptVisibility 测试私有提示词仅对所有者可见
+func (s *AuthServiceTestSuite) TestPromptVisibility() {
+	alice, _ := s.authSvc.Register(&models.RegisterRequest{Username: "alice", Password: "password123"})
+	bob, _ := s.authSvc.Register(&models.RegisterRequest{Username: "bob", Password: "password123"})
+
+	private, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "alice的私有提示词", OwnerID: &alice.ID})
+	s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "alice的公开提示词", IsPublic: true, OwnerID: &alice.ID})
+	ownerless, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "无所有者的提示词"})
+	s.Require().NoError(err)
+	s.True(ownerless.IsPublic, "没有所有者的提示词总是公开的")
+
+	// 所有者可见全部
+	_, total, err := s.service.GetPrompts(&models.PromptQuery{Page: 1, PageSize: 10, ViewerID: &alice.ID})
+	s.NoError(err)
+	s.Equal(int64(3), total)
+
+	// 其他用户和匿名用户看不到私有提示词
+	_, total, _ = s.service.GetPrompts(&models.PromptQuery{Page: 1, PageSize: 10, ViewerID: &bob.ID})
+	s.Equal(int64(2), total)
+	_, total, _ = s.service.GetPrompts(&models.PromptQuery{Page: 1, PageSize: 10})
+	s.Equal(int64(2), total)
+
+	// 统计信息同样只包含可见的提示词
+	stats, err := s.service.GetPromptStats(&bob.ID)
+	s.Require().NoError(err)
+	s.Equal(int64(2), stats["total_prompts"])
+	s.Equal(int64(0), stats["private_prompts"])
+	stats, err = s.service.GetPromptStats(&alice.ID)
+	s.Require().NoError(err)
+	s.Equal(int64(3), stats["total_prompts"])
+	s.Equal(int64(1), stats["private_prompts"])
+
+	_, err = s.service.GetVisiblePrompt(private.ID, &bob.ID)
+	s.ErrorIs(err, services.ErrPromptNotFound)
+	_, err = s.service.GetVisiblePrompt(private.ID, &alice.ID)
+	s.NoError(err)
+
+	s.False(private.CanBeModifiedBy(&bob.ID))
+	s.False(private.CanBeModifiedBy(nil))
+	s.True(private.CanBeModifiedBy(&alice.ID))
+
+	// 没有所有者的提示词不能设为私有，否则将对所有人可见或无人可见
+	isPublic := false
+	_, err = s.service.UpdatePrompt(ownerless.ID, &models.UpdatePromptRequest{IsPublic: &isPublic})
+	s.ErrorIs(err, services.ErrOwnerlessPrivate)
+	s.False((&models.Prompt{}).IsVisibleTo(&bob.ID), "没有所有者的私有提示词对任何人都不可见")
+}
+
+// TestAPIKeys 测试API密钥的创建、校验和吊销
+func (s *AuthServiceTestSuite) TestAPIKeys() {
+	keySvc := services.NewAPIKeyService()
+	alice, _ := s.authSvc.Register(&models.RegisterRequest{Username: "alice", Password: "password123"})
+
+	apiKey, plainKey, err := keySvc.CreateAPIKey(alice.ID, &models.CreateAPIKeyRequest{
+		Name:   "上传脚本",
+		Scopes: []string{models.ScopeRead, models.ScopeWrite, models.ScopeRead},
+	})
+	s.NoError(err)
+	s.True(strings.HasPrefix(plainKey, models.APIKeyPrefix))
+	s.NotEqual(plainKey, apiKey.KeyHash, "密钥应以哈希形式存储")
+	s.Equal([]string{models.ScopeRead, models.ScopeWrite}, apiKey.GetScopes())
+
+	authed, err := keySvc.AuthenticateAPIKey(plainKey)
+	s.NoError(err)
+	s.Equal(alice.ID, authed.User.ID)
+	s.NotNil(authed.LastUsedAt)
+	s.True(authed.HasScope(models.ScopeWrite))
+	s.False(authed.HasScope(models.ScopeAnalyze))
+
+	_, err = keySvc.AuthenticateAPIKey(plainKey + "x")
+	s.ErrorIs(err, services.ErrInvalidAPIKey)
+
+	// 其他用户不能吊销
+	_, err = keySvc.RevokeAPIKey(alice.ID+1, apiKey.ID)
+	s.ErrorIs(err, services.ErrAPIKeyNotFound)
+
+	revoked, err := keySvc.RevokeAPIKey(alice.ID, apiKey.ID)
+	s.NoError(err)
+	s.False(revoked.IsActive())
+	_, err = keySvc.AuthenticateAPIKey(plainKey)
+	s.ErrorIs(err, services.ErrInvalidAPIKey)
+}
+
+// TestRolesAndTagMerge 测试角色分配和标签合并
+func (s *AuthServiceTestSuite) TestRolesAndTagMerge() {
+	first, _ := s.authSvc.Register(&models.RegisterRequest{Username: "first", Password: "password123"})
+	s.Equal(models.RoleEditor, first.Role, "公开注册不会产生管理员")
+	admin, err := s.authSvc.CreateAdmin("admin", "password123")
+	s.Require().NoError(err)
+	s.Equal(models.RoleAdmin, admin.Role)
+	editor, _ := s.authSvc.Register(&models.RegisterRequest{Username: "editor", Password: "password123"})
+	s.Equal(models.RoleEditor, editor.Role)
+	_, err = s.authSvc.CreateAdmin("admin2", "short")
+	s.Error(err)
+
+	s.True(admin.HasPermission(models.PermDatabaseReset))
+	s.False(editor.HasPermission(models.PermTagDelete))
+	s.True(models.RoleHasPermission(models.RoleCurator, models.PermTagMerge))
+	s.False(models.RoleHasPermission(models.RoleViewer, models.PermPromptWrite))
+
+	_, err = s.authSvc.VerifyPermission("editor", "password123", models.PermDatabaseReset)
+	s.ErrorIs(err, services.ErrPermissionDenied)
+	_, err = s.authSvc.VerifyPermission("admin", "password123", models.PermDatabaseReset)
+	s.NoError(err)
+
+	// 不能移除最后一个管理员，并发降级两个管理员时至少保留一个
+	_, err = s.authSvc.UpdateUserRole(admin.ID, models.RoleViewer)
+	s.Error(err)
+	promoted, err := s.authSvc.CreateAdmin("first", "")
+	s.Require().NoError(err)
+	s.Equal(first.ID, promoted.ID)
+	var wg sync.WaitGroup
+	for _, id := range []uint{admin.ID, first.ID} {
+		wg.Add(1)
+		go func(id uint) {
+			defer wg.Done()
+			s.authSvc.UpdateUserRole(id, models.RoleEditor)
+		}(id)
+	}
+	wg.Wait()
+	var adminCount int64
+	s.db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&adminCount)
+	s.Equal(int64(1), adminCount)
+	updated, err := s.authSvc.UpdateUserRole(editor.ID, models.RoleCurator)
+	s.NoError(err)
+	s.Equal(models.RoleCurator, updated.Role)
+
+	// 合并标签：已拥有目标标签的提示词不会重复关联
+	tagSvc := services.NewTagService()
+	s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "p1", TagNames: []string{"cat"}})
+	s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "p2", TagNames: []string{"cat", "kitty"}})
+	source, _ := tagSvc.GetTagByName("cat")
+	target, _ := tagSvc.GetTagByName("kitty")
+
+	merged, moved, err := tagSvc.MergeTags(source.ID, target.ID)
+	s.NoError(err)
+	s.Equal(target.ID, merged.ID)
+	s.Equal(int64(1), moved)
+	_, err = tagSvc.GetTagByID(source.ID)
+	s.Error(err)
+	_, total, _ := s.service.SearchPromptsByTags([]string{"kitty"}, 1, 10, nil)
+	s.Equal(int64(2), total)
+}
+
+// TestAuthService runs the test suite for the auth service
+func TestAuthService(t *testing.T) {
+	suite.Run(t, new(AuthServiceTestSuite))
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidBulk 批量操作请求无效
+var ErrInvalidBulk = errors.New("批量操作请求无效")
+
+// errBulkDryRun 预演结束后用于回滚事务，不会返回给调用方
+var errBulkDryRun = errors.New("批量操作预演")
+
+// BulkService 提示词批量操作服务
+type BulkService struct {
+	db         *gorm.DB
+	tagService *TagService
+}
+
+// NewBulkService 创建批量操作服务实例
+func NewBulkService() *BulkService {
+	return &BulkService{
+		db:         config.GetDB(),
+		tagService: NewTagService(),
+	}
+}
+
+// BulkPrompts 对一组提示词执行批量操作
+// 所有修改在同一个事务中执行，任何数据库错误都会回滚全部修改；
+// 不存在或无权限修改的提示词只在结果中标记为失败，不影响其他提示词。
+// 预演模式下同样在事务中执行全部操作以得到准确的逐项结果，最后回滚事务。
+func (s *BulkService) BulkPrompts(req *models.BulkPromptRequest) (*models.BulkPromptResult, error) {
+	if err := validateBulkRequest(req); err != nil {
+		return nil, err
+	}
+	if req.Operation == models.BulkOpAddToCollection {
+		var collection models.Collection
+		if err := s.db.First(&collection, req.CollectionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrCollectionNotFound
+			}
+			return nil, fmt.Errorf("获取收藏集失败: %v", err)
+		}
+		if !collection.IsVisibleTo(req.ViewerID) {
+			return nil, ErrCollectionNotFound
+		}
+		if !collection.CanBeModifiedBy(req.ViewerID) {
+			return nil, ErrCollectionForbidden
+		}
+	}
+
+	var result *models.BulkPromptResult
+	var changed []*models.Prompt
+	var createdTags []*models.Tag
+	err := runTransaction(s.db, func(tx *gorm.DB) error {
+		changed = nil
+		result = &models.BulkPromptResult{
+			Operation: req.Operation,
+			DryRun:    req.DryRun,
+			Items:     []models.BulkItemResult{},
+		}
+
+		targets, err := s.loadTargets(tx, req)
+		if err != nil {
+			return err
+		}
+
+		// 先逐个检查是否存在和可修改，再对通过检查的提示词执行操作
+		prompts := make([]*models.Prompt, 0, len(targets))
+		failed := make(map[uint]string)
+		for _, target := range targets {
+			if message := checkBulkTarget(target.prompt, req); message != "" {
+				failed[target.id] = message
+				continue
+			}
+			prompts = append(prompts, target.prompt)
+		}
+
+		outcomes, created, err := s.apply(tx, req, prompts)
+		createdTags = created
+		if err != nil {
+			return err
+		}
+
+		for _, target := range targets {
+			if message, ok := failed[target.id]; ok {
+				result.Add(target.id, models.BulkStatusFailed, message)
+				continue
+			}
+			outcome := outcomes[target.id]
+			result.Add(target.id, outcome.status, outcome.message)
+			if outcome.status == models.BulkStatusSuccess {
+				changed = append(changed, target.prompt)
+			}
+		}
+
+		if req.DryRun {
+			return errBulkDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkDryRun) {
+		return nil, err
+	}
+	if !req.DryRun {
+		publishTagsCreated(createdTags)
+		s.publishChanges(req.Operation, changed)
+	}
+	return result, nil
+}
+
+// publishChanges 事务提交后为修改过的提示词发布事件，加入收藏集不修改提示词本身
+func (s *BulkService) publishChanges(operation string, prompts []*models.Prompt) {
+	if len(prompts) == 0 || operation == models.BulkOpAddToCollection {
+		return
+	}
+	if operation == models.BulkOpDelete {
+		for _, prompt := range prompts {
+			publishPromptDeleted(prompt)
+		}
+		return
+	}
+
+	ids := make([]uint, 0, len(prompts))
+	for _, prompt := range prompts {
+		ids = append(ids, prompt.ID)
+	}
+	var updated []*models.Prompt
+	if err := s.db.Preload("Tags").Preload("Images", models.OrderedImages).Where("id IN ?", ids).Order("id ASC").Find(&updated).Error; err != nil {
+		log.Printf("读取批量修改的提示词失败: %v", err)
+		return
+	}
+	for _, prompt := range updated {
+		publishPromptEvent(models.EventPromptUpdated, prompt)
+	}
+}
+
+// bulkTarget 批量操作的目标，prompt 为空表示提示词不存在
+type bulkTarget struct {
+	id     uint
+	prompt *models.Prompt
+}
+
+// bulkOutcome 单个提示词的执行结果
+type bulkOutcome struct {
+	status  string
+	message string
+}
+
+// validateBulkRequest 校验目标选择方式和操作参数
+func validateBulkRequest(req *models.BulkPromptRequest) error {
+	if (len(req.IDs) > 0) == (req.Filter != nil) {
+		return fmt.Errorf("%w: 必须且只能指定 ids 或 filter 之一", ErrInvalidBulk)
+	}
+	if len(req.IDs) > models.MaxBulkPrompts {
+		return fmt.Errorf("%w: 单次最多操作 %d 个提示词", ErrInvalidBulk, models.MaxBulkPrompts)
+	}
+
+	switch req.Operation {
+	case models.BulkOpAddTags, models.BulkOpRemoveTags:
+		var names []string
+		for _, name := range req.TagNames {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("%w: %s 需要 tag_names", ErrInvalidBulk, req.Operation)
+		}
+		req.TagNames = names
+	case models.BulkOpSetPublic:
+		if req.IsPublic == nil {
+			return fmt.Errorf("%w: set_public 需要 is_public", ErrInvalidBulk)
+		}
+	case models.BulkOpSetModelName:
+		if req.ModelName == nil {
+			return fmt.Errorf("%w: set_model_name 需要 model_name", ErrInvalidBulk)
+		}
+	case models.BulkOpAddToCollection:
+		if req.CollectionID == 0 {
+			return fmt.Errorf("%w: add_to_collection 需要 collection_id", ErrInvalidBulk)
+		}
+	case models.BulkOpDelete, models.BulkOpRestore:
+	default:
+		return fmt.Errorf("%w: 不支持的操作 %s", ErrInvalidBulk, req.Operation)
+	}
+	return nil
+}
+
+// loadTargets 加载操作目标（包含已软删除的提示词，由 checkBulkTarget 判断是否可操作）
+// 按ID选择时按请求顺序返回（去重），按过滤条件选择时按列表的默认顺序返回
+func (s *BulkService) loadTargets(tx *gorm.DB, req *models.BulkPromptRequest) ([]bulkTarget, error) {
+	if req.Filter != nil {
+		query := req.Filter.ToPromptQuery(req.ViewerID)
+		db := tx.Model(&models.Prompt{}).Preload("Tags")
+		if req.Operation == models.BulkOpRestore {
+			// 恢复操作只匹配已删除的提示词
+			db = db.Unscoped().Where("prompts.deleted_at IS NOT NULL")
+		}
+		var prompts []models.Prompt
+		err := db.Scopes(promptFilters(query)).
+			Order(promptOrder(query)).
+			Limit(models.MaxBulkPrompts + 1).
+			Find(&prompts).Error
+		if err != nil {
+			return nil, fmt.Errorf("获取提示词列表失败: %v", err)
+		}
+		if len(prompts) > models.MaxBulkPrompts {
+			return nil, fmt.Errorf("%w: 过滤条件匹配的提示词超过 %d 个，请缩小范围", ErrInvalidBulk, models.MaxBulkPrompts)
+		}
+
+		targets := make([]bulkTarget, len(prompts))
+		for i := range prompts {
+			targets[i] = bulkTarget{id: prompts[i].ID, prompt: &prompts[i]}
+		}
+		return targets, nil
+	}
+
+	var prompts []models.Prompt
+	if err := tx.Unscoped().Preload("Tags").Where("id IN ?", req.IDs).Find(&prompts).Error; err != nil {
+		return nil, fmt.Errorf("获取提示词失败: %v", err)
+	}
+	byID := make(map[uint]*models.Prompt, len(prompts))
+	for i := range prompts {
+		byID[prompts[i].ID] = &prompts[i]
+	}
+
+	targets := make([]bulkTarget, 0, len(req.IDs))
+	seen := make(map[uint]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		targets = append(targets, bulkTarget{id: id, prompt: byID[id]})
+	}
+	return targets, nil
+}
+
+// checkBulkTarget 检查提示词是否可以执行该操作，返回失败原因
+// 已删除的提示词只能恢复，他人的私有提示词视为不存在；
+// 加入收藏集不修改提示词本身，可见即可，其余操作要求可修改
+func checkBulkTarget(prompt *models.Prompt, req *models.BulkPromptRequest) string {
+	if prompt == nil || !prompt.IsVisibleTo(req.ViewerID) {
+		return ErrPromptNotFound.Error()
+	}
+	if prompt.DeletedAt.Valid != (req.Operation == models.BulkOpRestore) {
+		if prompt.DeletedAt.Valid {
+			return ErrPromptNotFound.Error()
+		}
+		return "提示词未被删除"
+	}
+	if req.Operation != models.BulkOpAddToCollection && !prompt.CanBeModifiedBy(req.ViewerID) {
+		return "只有提示词的所有者可以修改或删除"
+	}
+	return ""
+}
+
+// apply 对通过检查的提示词执行操作，返回每个提示词的结果，
+// 以及添加标签时新创建的标签
+func (s *BulkService) apply(tx *gorm.DB, req *models.BulkPromptRequest, prompts []*models.Prompt) (map[uint]bulkOutcome, []*models.Tag, error) {
+	outcomes := make(map[uint]bulkOutcome, len(prompts))
+	success := bulkOutcome{status: models.BulkStatusSuccess}
+	var createdTags []*models.Tag
+
+	switch req.Operation {
+	case models.BulkOpAddTags:
+		tags, created, err := s.tagService.WithTx(tx).getOrCreateTags(req.TagNames)
+		if err != nil {
+			return nil, nil, fmt.Errorf("处理标签失败: %v", err)
+		}
+		createdTags = created
+		for _, prompt := range prompts {
+			missing := bulkTagDifference(tags, prompt.Tags)
+			if len(missing) == 0 {
+				outcomes[prompt.ID] = bulkOutcome{models.BulkStatusSkipped, "已有这些标签"}
+				continue
+			}
+			if err := tx.Model(prompt).Association("Tags").Append(missing); err != nil {
+				return nil, nil, fmt.Errorf("添加标签失败: %v", err)
+			}
+			if err := bumpPromptVersions(tx, []uint{prompt.ID}); err != nil {
+				return nil, nil, err
+			}
+			outcomes[prompt.ID] = success
+		}
+
+	case models.BulkOpRemoveTags:
+		var tags []*models.Tag
+		if err := tx.Where("name IN ?", req.TagNames).Find(&tags).Error; err != nil {
+			return nil, nil, fmt.Errorf("获取标签失败: %v", err)
+		}
+		for _, prompt := range prompts {
+			present := bulkTagIntersection(tags, prompt.Tags)
+			if len(present) == 0 {
+				outcomes[prompt.ID] = bulkOutcome{models.BulkStatusSkipped, "没有这些标签"}
+				continue
+			}
+			if err := tx.Model(prompt).Association("Tags").Delete(present); err != nil {
+				return nil, nil, fmt.Errorf("移除标签失败: %v", err)
+			}
+			if err := bumpPromptVersions(tx, []uint{prompt.ID}); err != nil {
+				return nil, nil, err
+			}
+			outcomes[prompt.ID] = success
+		}
+
+	case models.BulkOpSetPublic:
+		for _, prompt := range prompts {
+			if prompt.IsPublic == *req.IsPublic {
+				outcomes[prompt.ID] = bulkOutcome{models.BulkStatusSkipped, "已是目标状态"}
+				continue
+			}
+			if !*req.IsPublic && prompt.OwnerID == nil {
+				outcomes[prompt.ID] = bulkOutcome{models.BulkStatusFailed, ErrOwnerlessPrivate.Error()}
+				continue
+			}
+			if err := tx.Model(prompt).Updates(map[string]interface{}{"is_public": *req.IsPublic, "version": gorm.Expr("version + 1")}).Error; err != nil {
+				return nil, nil, fmt.Errorf("更新提示词失败: %v", err)
+			}
+			outcomes[prompt.ID] = success
+		}
+
+	case models.BulkOpSetModelName:
+		for _, prompt := range prompts {
+			if prompt.ModelName == *req.ModelName {
+				outcomes[prompt.ID] = bulkOutcome{models.BulkStatusSkipped, "已是目标模型"}
+				continue
+			}
+			if err := tx.Model(prompt).Updates(map[string]interface{}{"model_name": *req.ModelName, "version": gorm.Expr("version + 1")}).Error; err != nil {
+				return nil, nil, fmt.Errorf("更新提示词失败: %v", err)
+			}
+			outcomes[prompt.ID] = success
+		}
+
+	case models.BulkOpDelete:
+		for _, prompt := range prompts {
+			if err := tx.Delete(prompt).Error; err != nil {
+				return nil, nil, fmt.Errorf("删除提示词失败: %v", err)
+			}
+			outcomes[prompt.ID] = success
+		}
+
+	case models.BulkOpRestore:
+		for _, prompt := range prompts {
+			if err := tx.Unscoped().Model(prompt).Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error; err != nil {
+				return nil, nil, fmt.Errorf("恢复提示词失败: %v", err)
+			}
+			outcomes[prompt.ID] = success
+		}
+
+	case models.BulkOpAddToCollection:
+		var existing []uint
+		if err := tx.Model(&models.CollectionItem{}).Where("collection_id = ?", req.CollectionID).Pluck("prompt_id", &existing).Error; err != nil {
+			return nil, nil, fmt.Errorf("获取收藏集条目失败: %v", err)
+		}
+		inCollection := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			inCollection[id] = true
+		}
+
+		var promptIDs []uint
+		for _, prompt := range prompts {
+			if inCollection[prompt.ID] {
+				outcomes[prompt.ID] = bulkOutcome{models.BulkStatusSkipped, "已在收藏集中"}
+				continue
+			}
+			promptIDs = append(promptIDs, prompt.ID)
+			outcomes[prompt.ID] = success
+		}
+		if len(promptIDs) > 0 {
+			if err := addCollectionItems(tx, req.CollectionID, promptIDs, req.ViewerID); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return outcomes, createdTags, nil
+}
+
+// bulkTagDifference 返回 tags 中不在 current 里的标签
+func bulkTagDifference(tags, current []*models.Tag) []*models.Tag {
+	has := make(map[uint]bool, len(current))
+	for _, tag := range current {
+		has[tag.ID] = true
+	}
+	var missing []*models.Tag
+	for _, tag := range tags {
+		if !has[tag.ID] {
+			has[tag.ID] = true
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// bulkTagIntersection 返回 tags 中已在 current 里的标签
+func bulkTagIntersection(tags, current []*models.Tag) []*models.Tag {
+	has := make(map[uint]bool, len(current))
+	for _, tag := range current {
+		has[tag.ID] = true
+	}
+	var present []*models.Tag
+	for _, tag := range tags {
+		if has[tag.ID] {
+			present = append(present, tag)
+		}
+	}
+	return present
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrCollectionNotFound 收藏集不存在
+var ErrCollectionNotFound = errors.New("收藏集不存在")
+
+// ErrCollectionForbidden 无权修改收藏集
+var ErrCollectionForbidden = errors.New("只有收藏集的所有者可以修改或删除")
+
+// ErrInvalidCollection 收藏集请求无效
+var ErrInvalidCollection = errors.New("收藏集请求无效")
+
+// ErrCollectionPromptNotFound 要加入收藏集的提示词不存在或不可见
+var ErrCollectionPromptNotFound = errors.New("提示词不存在")
+
+// ErrCollectionItemNotFound 提示词不在收藏集中
+var ErrCollectionItemNotFound = errors.New("提示词不在该收藏集中")
+
+// CollectionService 收藏集服务
+type CollectionService struct {
+	db *gorm.DB
+}
+
+// NewCollectionService 创建收藏集服务实例
+func NewCollectionService() *CollectionService {
+	return &CollectionService{
+		db: config.GetDB(),
+	}
+}
+
+// CreateCollection 创建收藏集
+// 登录用户创建的收藏集默认为私有；未登录时创建的收藏集没有所有者，只能是公开的
+func (s *CollectionService) CreateCollection(req *models.CreateCollectionRequest) (*models.Collection, error) {
+	collection := &models.Collection{
+		Name:          strings.TrimSpace(req.Name),
+		Description:   req.Description,
+		CoverImageURL: req.CoverImageURL,
+		Visibility:    req.Visibility,
+		OwnerID:       req.OwnerID,
+	}
+	if collection.Visibility == "" {
+		collection.Visibility = models.CollectionVisibilityPrivate
+		if req.OwnerID == nil {
+			collection.Visibility = models.CollectionVisibilityPublic
+		}
+	}
+	if req.OwnerID == nil && collection.Visibility == models.CollectionVisibilityPrivate {
+		return nil, fmt.Errorf("%w: 未登录时只能创建公开的收藏集", ErrInvalidCollection)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(collection).Error; err != nil {
+			return fmt.Errorf("创建收藏集失败: %v", err)
+		}
+		if len(req.PromptIDs) > 0 {
+			return addCollectionItems(tx, collection.ID, req.PromptIDs, req.OwnerID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return collection, nil
+}
+
+// GetCollectionByID 根据ID获取收藏集
+func (s *CollectionService) GetCollectionByID(id uint) (*models.Collection, error) {
+	var collection models.Collection
+	result := s.db.First(&collection, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("获取收藏集失败: %v", result.Error)
+	}
+	return &collection, nil
+}
+
+// GetVisibleCollection 获取对指定用户可见的收藏集，他人的私有收藏集视为不存在
+func (s *CollectionService) GetVisibleCollection(id uint, viewerID *uint) (*models.Collection, error) {
+	collection, err := s.GetCollectionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !collection.IsVisibleTo(viewerID) {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, nil
+}
+
+// getModifiableCollection 获取指定用户可以修改的收藏集
+func (s *CollectionService) getModifiableCollection(id uint, userID *uint) (*models.Collection, error) {
+	collection, err := s.GetVisibleCollection(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !collection.CanBeModifiedBy(userID) {
+		return nil, ErrCollectionForbidden
+	}
+	return collection, nil
+}
+
+// GetCollectionDetail 获取收藏集详情，条目按位置排序（已删除或对当前用户不可见的提示词不返回）
+func (s *CollectionService) GetCollectionDetail(id uint, viewerID *uint) (*models.CollectionDetailResponse, error) {
+	collection, err := s.GetVisibleCollection(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.CollectionItem
+	err = s.db.Preload("Prompt").Preload("Prompt.Tags").Preload("Prompt.Images", models.OrderedImages).
+		Where("collection_id = ?", id).
+		Order("position ASC").
+		Find(&items).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取收藏集条目失败: %v", err)
+	}
+
+	responses := make([]models.CollectionItemResponse, 0, len(items))
+	for _, item := range items {
+		if item.Prompt == nil || !item.Prompt.IsVisibleTo(viewerID) {
+			continue
+		}
+		responses = append(responses, models.CollectionItemResponse{
+			Position: item.Position,
+			AddedAt:  item.CreatedAt,
+			Prompt:   item.Prompt.ToResponse(),
+		})
+	}
+
+	return &models.CollectionDetailResponse{
+		CollectionResponse: collection.ToResponse(int64(len(responses))),
+		Items:              responses,
+	}, nil
+}
+
+// GetCollections 获取收藏集列表
+func (s *CollectionService) GetCollections(query *models.CollectionQuery) ([]models.CollectionResponse, error) {
+	var collections []models.Collection
+	db := s.db.Model(&models.Collection{}).Scopes(collectionVisibleTo(query.ViewerID))
+	if query.Visibility != "" {
+		db = db.Where("visibility = ?", query.Visibility)
+	}
+	if query.Keyword != "" {
+		keyword := "%" + strings.TrimSpace(query.Keyword) + "%"
+		db = db.Where("name LIKE ? OR description LIKE ?", keyword, keyword)
+	}
+	if err := db.Order("updated_at DESC").Find(&collections).Error; err != nil {
+		return nil, fmt.Errorf("获取收藏集列表失败: %v", err)
+	}
+	return s.toResponses(collections)
+}
+
+// GetCollectionsByPrompt 获取包含指定提示词、且对当前用户可见的所有收藏集
+func (s *CollectionService) GetCollectionsByPrompt(promptID uint, viewerID *uint) ([]models.CollectionResponse, error) {
+	var collections []models.Collection
+	err := s.db.Joins("JOIN collection_items ON collection_items.collection_id = collections.id").
+		Scopes(collectionVisibleTo(viewerID)).
+		Where("collection_items.prompt_id = ?", promptID).
+		Order("collections.name ASC").
+		Find(&collections).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取提示词所属收藏集失败: %v", err)
+	}
+	return s.toResponses(collections)
+}
+
+// GetCollectionsByPromptIDs 批量获取包含各提示词、且对当前用户可见的收藏集，按提示词ID分组
+func (s *CollectionService) GetCollectionsByPromptIDs(promptIDs []uint, viewerID *uint) (map[uint][]models.CollectionResponse, error) {
+	result := make(map[uint][]models.CollectionResponse, len(promptIDs))
+	if len(promptIDs) == 0 {
+		return result, nil
+	}
+
+	var items []models.CollectionItem
+	if err := s.db.Where("prompt_id IN ?", promptIDs).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("获取提示词所属收藏集失败: %v", err)
+	}
+	collectionIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		collectionIDs = append(collectionIDs, item.CollectionID)
+	}
+
+	var collections []models.Collection
+	if len(collectionIDs) > 0 {
+		err := s.db.Scopes(collectionVisibleTo(viewerID)).Where("id IN ?", collectionIDs).Order("name ASC").Find(&collections).Error
+		if err != nil {
+			return nil, fmt.Errorf("获取提示词所属收藏集失败: %v", err)
+		}
+	}
+	responses, err := s.toResponses(collections)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按收藏集名称的顺序分组，与 GetCollectionsByPrompt 一致
+	for _, response := range responses {
+		for _, item := range items {
+			if item.CollectionID == response.ID {
+				result[item.PromptID] = append(result[item.PromptID], response)
+			}
+		}
+	}
+	return result, nil
+}
+
+// UpdateCollection 更新收藏集
+func (s *CollectionService) UpdateCollection(id uint, req *models.UpdateCollectionRequest, userID *uint) (*models.Collection, error) {
+	collection, err := s.getModifiableCollection(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: 收藏集名称不能为空", ErrInvalidCollection)
+		}
+		updates["name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.CoverImageURL != nil {
+		updates["cover_image_url"] = *req.CoverImageURL
+	}
+	if req.Visibility != nil {
+		if collection.OwnerID == nil && *req.Visibility == models.CollectionVisibilityPrivate {
+			return nil, fmt.Errorf("%w: 没有所有者的收藏集不能设为私有", ErrInvalidCollection)
+		}
+		updates["visibility"] = *req.Visibility
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(collection).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新收藏集失败: %v", err)
+		}
+	}
+
+	return s.GetCollectionByID(id)
+}
+
+// DeleteCollection 删除收藏集及其所有条目（不影响提示词本身）
+func (s *CollectionService) DeleteCollection(id uint, userID *uint) error {
+	if _, err := s.getModifiableCollection(id, userID); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", id).Delete(&models.CollectionItem{}).Error; err != nil {
+			return fmt.Errorf("删除收藏集条目失败: %v", err)
+		}
+		result := tx.Delete(&models.Collection{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("删除收藏集失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrCollectionNotFound
+		}
+		return nil
+	})
+}
+
+// AddItems 向收藏集末尾添加提示词（已存在的会被跳过），提示词必须对当前用户可见
+func (s *CollectionService) AddItems(id uint, promptIDs []uint, userID *uint) error {
+	if _, err := s.getModifiableCollection(id, userID); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return addCollectionItems(tx, id, promptIDs, userID)
+	})
+}
+
+// RemoveItem 从收藏集中移除提示词，并压缩后续条目的位置
+func (s *CollectionService) RemoveItem(id, promptID uint, userID *uint) error {
+	if _, err := s.getModifiableCollection(id, userID); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var item models.CollectionItem
+		err := tx.Where("collection_id = ? AND prompt_id = ?", id, promptID).First(&item).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCollectionItemNotFound
+			}
+			return fmt.Errorf("获取收藏集条目失败: %v", err)
+		}
+
+		if err := tx.Where("collection_id = ? AND prompt_id = ?", id, promptID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return fmt.Errorf("移除收藏集条目失败: %v", err)
+		}
+
+		err = tx.Model(&models.CollectionItem{}).
+			Where("collection_id = ? AND position > ?", id, item.Position).
+			UpdateColumn("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return fmt.Errorf("调整条目位置失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// ReorderItems 按给定顺序重新排列收藏集条目
+// promptIDs必须恰好包含 GetCollectionDetail 返回的所有条目；
+// 已删除或对当前用户不可见的提示词不参与排序，保持原有的相对顺序排在最后
+func (s *CollectionService) ReorderItems(id uint, promptIDs []uint, userID *uint) error {
+	if _, err := s.getModifiableCollection(id, userID); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var items []models.CollectionItem
+		if err := tx.Preload("Prompt").Where("collection_id = ?", id).Order("position ASC").Find(&items).Error; err != nil {
+			return fmt.Errorf("获取收藏集条目失败: %v", err)
+		}
+
+		current := make(map[uint]bool, len(items))
+		var hidden []uint
+		for _, item := range items {
+			if item.Prompt == nil || !item.Prompt.IsVisibleTo(userID) {
+				hidden = append(hidden, item.PromptID)
+				continue
+			}
+			current[item.PromptID] = true
+		}
+		if len(promptIDs) != len(current) {
+			return fmt.Errorf("%w: 排序列表必须包含收藏集的全部 %d 个条目", ErrInvalidCollection, len(current))
+		}
+		seen := make(map[uint]bool, len(promptIDs))
+		for _, promptID := range promptIDs {
+			if !current[promptID] {
+				return fmt.Errorf("%w: %d", ErrCollectionItemNotFound, promptID)
+			}
+			if seen[promptID] {
+				return fmt.Errorf("%w: 提示词 %d 重复出现", ErrInvalidCollection, promptID)
+			}
+			seen[promptID] = true
+		}
+
+		for position, promptID := range append(append([]uint{}, promptIDs...), hidden...) {
+			err := tx.Model(&models.CollectionItem{}).
+				Where("collection_id = ? AND prompt_id = ?", id, promptID).
+				UpdateColumn("position", position).Error
+			if err != nil {
+				return fmt.Errorf("更新条目位置失败: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// toResponses 将收藏集转换为响应格式，并批量统计条目数量
+func (s *CollectionService) toResponses(collections []models.Collection) ([]models.CollectionResponse, error) {
+	responses := make([]models.CollectionResponse, len(collections))
+	if len(collections) == 0 {
+		return responses, nil
+	}
+
+	ids := make([]uint, len(collections))
+	for i, collection := range collections {
+		ids[i] = collection.ID
+	}
+
+	type itemCount struct {
+		CollectionID uint
+		Count        int64
+	}
+	var counts []itemCount
+	err := s.db.Table("collection_items").
+		Select("collection_items.collection_id, COUNT(*) as count").
+		Joins("JOIN prompts ON prompts.id = collection_items.prompt_id AND prompts.deleted_at IS NULL").
+		Where("collection_items.collection_id IN ?", ids).
+		Group("collection_items.collection_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计收藏集条目失败: %v", err)
+	}
+
+	countMap := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		countMap[c.CollectionID] = c.Count
+	}
+	for i := range collections {
+		responses[i] = collections[i].ToResponse(countMap[collections[i].ID])
+	}
+	return responses, nil
+}
+
+// collectionVisibleTo 收藏集可见性过滤：公开的以及属于当前用户的收藏集（与 Collection.IsVisibleTo 一致）
+func collectionVisibleTo(viewerID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == nil {
+			return db.Where("collections.visibility = ?", models.CollectionVisibilityPublic)
+		}
+		return db.Where("(collections.visibility = ? OR collections.owner_id = ?)", models.CollectionVisibilityPublic, *viewerID)
+	}
+}
+
+// addCollectionItems 在事务中将提示词追加到收藏集末尾，他人的私有提示词视为不存在
+func addCollectionItems(tx *gorm.DB, collectionID uint, promptIDs []uint, viewerID *uint) error {
+	// 校验提示词是否存在且可见
+	var found []uint
+	if err := tx.Model(&models.Prompt{}).Scopes(visibleTo(viewerID)).Where("id IN ?", promptIDs).Pluck("id", &found).Error; err != nil {
+		return fmt.Errorf("校验提示词失败: %v", err)
+	}
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, id := range promptIDs {
+		if !exists[id] {
+			return fmt.Errorf("%w: %d", ErrCollectionPromptNotFound, id)
+		}
+	}
+
+	var existing []uint
+	if err := tx.Model(&models.CollectionItem{}).Where("collection_id = ?", collectionID).Pluck("prompt_id", &existing).Error; err != nil {
+		return fmt.Errorf("获取收藏集条目失败: %v", err)
+	}
+	inCollection := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		inCollection[id] = true
+	}
+
+	position := len(existing)
+	for _, promptID := range promptIDs {
+		if inCollection[promptID] {
+			continue
+		}
+		inCollection[promptID] = true
+		item := &models.CollectionItem{CollectionID: collectionID, PromptID: promptID, Position: position}
+		if err := tx.Create(item).Error; err != nil {
+			return fmt.Errorf("添加收藏集条目失败: %v", err)
+		}
+		position++
+	}
+
+	// 更新收藏集的修改时间
+	return tx.Model(&models.Collection{}).Where("id = ?", collectionID).Update("updated_at", gorm.Expr("NOW()")).Error
+}
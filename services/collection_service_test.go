@@ -0,0 +1,184 @@
+package services_test
+
+import (
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// CollectionServiceTestSuite 是 CollectionService 的测试套件
+// 复用 PromptServiceTestSuite 的数据库设置
+type CollectionServiceTestSuite struct {
+	PromptServiceTestSuite
+	collectionSvc *services.CollectionService
+}
+
+// SetupTest 在每个测试方法运行前清理数据库并创建服务实例
+func (s *CollectionServiceTestSuite) SetupTest() {
+	s.PromptServiceTestSuite.SetupTest()
+	s.collectionSvc = services.NewCollectionService()
+}
+
+// createPrompts 创建若干测试提示词并返回其ID
+func (s *CollectionServiceTestSuite) createPrompts(texts ...string) []uint {
+	ids := make([]uint, len(texts))
+	for i, text := range texts {
+		prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: text})
+		s.Require().NoError(err)
+		ids[i] = prompt.ID
+	}
+	return ids
+}
+
+// TestCreateCollectionWithItems 测试创建收藏集并按顺序加入提示词
+func (s *CollectionServiceTestSuite) TestCreateCollectionWithItems() {
+	ids := s.createPrompts("p1", "p2")
+	ownerID := uint(7)
+
+	collection, err := s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{
+		Name:      "客户X情绪板",
+		PromptIDs: ids,
+		OwnerID:   &ownerID,
+	})
+	s.NoError(err)
+	s.Equal(models.CollectionVisibilityPrivate, collection.Visibility, "登录用户创建的收藏集默认为私有")
+
+	detail, err := s.collectionSvc.GetCollectionDetail(collection.ID, &ownerID)
+	s.NoError(err)
+	s.Equal(int64(2), detail.ItemCount)
+	s.Equal("p1", detail.Items[0].Prompt.PromptText)
+	s.Equal("p2", detail.Items[1].Prompt.PromptText)
+
+	// 私有收藏集对其他用户不可见
+	_, err = s.collectionSvc.GetCollectionDetail(collection.ID, nil)
+	s.ErrorIs(err, services.ErrCollectionNotFound)
+	collections, err := s.collectionSvc.GetCollections(&models.CollectionQuery{})
+	s.NoError(err)
+	s.Empty(collections)
+
+	// 未登录时创建的收藏集默认公开，且不能设为私有
+	anonymous, err := s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "匿名收藏集"})
+	s.NoError(err)
+	s.Equal(models.CollectionVisibilityPublic, anonymous.Visibility)
+	_, err = s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "匿名私有", Visibility: models.CollectionVisibilityPrivate})
+	s.ErrorIs(err, services.ErrInvalidCollection)
+	private := models.CollectionVisibilityPrivate
+	_, err = s.collectionSvc.UpdateCollection(anonymous.ID, &models.UpdateCollectionRequest{Visibility: &private}, nil)
+	s.ErrorIs(err, services.ErrInvalidCollection)
+
+	// 不存在的提示词应该报错
+	_, err = s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "无效", PromptIDs: []uint{999999}})
+	s.ErrorIs(err, services.ErrCollectionPromptNotFound)
+}
+
+// TestCollectionOwnership 测试只有所有者可以修改收藏集，且不能加入他人的私有提示词
+func (s *CollectionServiceTestSuite) TestCollectionOwnership() {
+	aliceID, bobID := uint(1), uint(2)
+	bobsPrivate, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "bob的私有提示词", OwnerID: &bobID})
+	s.Require().NoError(err)
+	collection, err := s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{
+		Name: "alice的收藏集", Visibility: models.CollectionVisibilityPublic, OwnerID: &aliceID,
+	})
+	s.Require().NoError(err)
+
+	s.ErrorIs(s.collectionSvc.AddItems(collection.ID, []uint{bobsPrivate.ID}, &aliceID), services.ErrCollectionPromptNotFound, "他人的私有提示词视为不存在")
+	_, err = s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "偷看", PromptIDs: []uint{bobsPrivate.ID}, OwnerID: &aliceID})
+	s.Error(err)
+
+	// 公开收藏集所有人可见，但只有所有者可以修改
+	_, err = s.collectionSvc.GetCollectionDetail(collection.ID, &bobID)
+	s.NoError(err)
+	ids := s.createPrompts("公共提示词")
+	s.ErrorIs(s.collectionSvc.AddItems(collection.ID, ids, &bobID), services.ErrCollectionForbidden)
+	s.ErrorIs(s.collectionSvc.DeleteCollection(collection.ID, nil), services.ErrCollectionForbidden)
+	s.NoError(s.collectionSvc.AddItems(collection.ID, ids, &aliceID))
+}
+
+// TestAddRemoveAndReorderItems 测试添加、移除和重新排序条目
+func (s *CollectionServiceTestSuite) TestAddRemoveAndReorderItems() {
+	ids := s.createPrompts("a", "b", "c")
+	collection, _ := s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "排序测试"})
+
+	// 重复添加的提示词应被跳过
+	s.NoError(s.collectionSvc.AddItems(collection.ID, ids, nil))
+	s.NoError(s.collectionSvc.AddItems(collection.ID, ids[:1], nil))
+
+	// 重新排序
+	s.NoError(s.collectionSvc.ReorderItems(collection.ID, []uint{ids[2], ids[0], ids[1]}, nil))
+	detail, _ := s.collectionSvc.GetCollectionDetail(collection.ID, nil)
+	s.Len(detail.Items, 3)
+	s.Equal("c", detail.Items[0].Prompt.PromptText)
+	s.Equal("a", detail.Items[1].Prompt.PromptText)
+
+	// 排序列表不完整或包含不在收藏集中的提示词时应报错
+	s.ErrorIs(s.collectionSvc.ReorderItems(collection.ID, []uint{ids[0], ids[1]}, nil), services.ErrInvalidCollection)
+	s.ErrorIs(s.collectionSvc.ReorderItems(collection.ID, []uint{ids[0], ids[1], 999999}, nil), services.ErrCollectionItemNotFound)
+
+	// 移除后位置应被压缩，再次移除时报错
+	s.NoError(s.collectionSvc.RemoveItem(collection.ID, ids[2], nil))
+	s.ErrorIs(s.collectionSvc.RemoveItem(collection.ID, ids[2], nil), services.ErrCollectionItemNotFound)
+	detail, _ = s.collectionSvc.GetCollectionDetail(collection.ID, nil)
+	s.Len(detail.Items, 2)
+	s.Equal(0, detail.Items[0].Position)
+	s.Equal(1, detail.Items[1].Position)
+	s.Equal("a", detail.Items[0].Prompt.PromptText)
+
+	// 已删除的提示词不在详情中，也不参与排序
+	s.NoError(s.collectionSvc.AddItems(collection.ID, ids[2:], nil))
+	s.NoError(s.service.DeletePrompt(ids[0]))
+	s.NoError(s.collectionSvc.ReorderItems(collection.ID, []uint{ids[2], ids[1]}, nil))
+	detail, _ = s.collectionSvc.GetCollectionDetail(collection.ID, nil)
+	s.Require().Len(detail.Items, 2)
+	s.Equal("c", detail.Items[0].Prompt.PromptText)
+	s.Equal("b", detail.Items[1].Prompt.PromptText)
+}
+
+// TestGetCollectionsByPrompt 测试查询包含某提示词的收藏集
+func (s *CollectionServiceTestSuite) TestGetCollectionsByPrompt() {
+	ids := s.createPrompts("共享提示词", "其他提示词")
+	s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "集合A", PromptIDs: ids[:1]})
+	s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "集合B", PromptIDs: ids})
+	s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "集合C", PromptIDs: ids[1:]})
+
+	collections, err := s.collectionSvc.GetCollectionsByPrompt(ids[0], nil)
+	s.NoError(err)
+	s.Len(collections, 2)
+	s.Equal("集合A", collections[0].Name)
+	s.Equal(int64(1), collections[0].ItemCount)
+	s.Equal(int64(2), collections[1].ItemCount)
+}
+
+// TestGetCollectionsByPromptIDs 测试批量查询包含各提示词的收藏集
+func (s *CollectionServiceTestSuite) TestGetCollectionsByPromptIDs() {
+	ids := s.createPrompts("提示词1", "提示词2", "提示词3")
+	s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "集合B", PromptIDs: ids[:2]})
+	s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "集合A", PromptIDs: ids[:1]})
+
+	collections, err := s.collectionSvc.GetCollectionsByPromptIDs(ids, nil)
+	s.NoError(err)
+	s.Require().Len(collections[ids[0]], 2)
+	s.Equal("集合A", collections[ids[0]][0].Name, "按名称排序")
+	s.Equal(int64(2), collections[ids[0]][1].ItemCount)
+	s.Len(collections[ids[1]], 1)
+	s.Empty(collections[ids[2]])
+}
+
+// TestDeleteCollection 测试删除收藏集不影响提示词
+func (s *CollectionServiceTestSuite) TestDeleteCollection() {
+	ids := s.createPrompts("保留的提示词")
+	collection, _ := s.collectionSvc.CreateCollection(&models.CreateCollectionRequest{Name: "待删除", PromptIDs: ids})
+
+	s.NoError(s.collectionSvc.DeleteCollection(collection.ID, nil))
+	_, err := s.collectionSvc.GetCollectionByID(collection.ID)
+	s.ErrorIs(err, services.ErrCollectionNotFound)
+
+	_, err = s.service.GetPromptByID(ids[0])
+	s.NoError(err)
+}
+
+// TestCollectionService runs the test suite for the collection service
+func TestCollectionService(t *testing.T) {
+	suite.Run(t, new(CollectionServiceTestSuite))
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"imgGeneratePrompts/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// eventReplaySize 重放缓冲区保留的事件数量
+	eventReplaySize = 1000
+	// eventSubscriberBuffer 每个订阅者的待发送事件数量，写满时断开该订阅者，由客户端用 Last-Event-ID 重连补发
+	eventSubscriberBuffer = 256
+)
+
+// Events 进程内的资料库变更事件总线，服务在事务提交后发布事件，GET /api/v1/events 订阅
+var Events = NewEventBus(eventReplaySize)
+
+// EventBus 进程内事件总线：发布的事件广播给所有订阅者，并保留最近的事件用于断线续传
+// 事件不持久化，多个服务进程之间也不共享
+type EventBus struct {
+	mu          sync.Mutex
+	epoch       int64 // 创建时间，作为事件ID前缀区分服务重启前后的事件
+	seq         uint64
+	replay      []models.Event // 环形缓冲区
+	next        int            // 下一个事件在缓冲区中的位置
+	subscribers map[*EventSubscription]struct{}
+}
+
+// EventSubscription 事件订阅，事件从 C 读取；订阅者过慢被断开时 C 会被关闭
+type EventSubscription struct {
+	C <-chan models.Event
+
+	ch  chan models.Event
+	bus *EventBus
+}
+
+// NewEventBus 创建事件总线，replaySize 为重放缓冲区大小
+func NewEventBus(replaySize int) *EventBus {
+	return &EventBus{
+		epoch:       time.Now().UnixMilli(),
+		replay:      make([]models.Event, 0, replaySize),
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Publish 发布事件，ownerID 和 public 决定哪些订阅者可以收到
+func (b *EventBus) Publish(eventType string, data interface{}, ownerID *uint, public bool) models.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := models.Event{
+		ID:      b.eventID(b.seq),
+		Type:    eventType,
+		Time:    time.Now(),
+		Data:    data,
+		Seq:     b.seq,
+		OwnerID: ownerID,
+		Public:  public,
+	}
+
+	if len(b.replay) < cap(b.replay) {
+		b.replay = append(b.replay, event)
+	} else if cap(b.replay) > 0 {
+		b.replay[b.next] = event
+	}
+	if cap(b.replay) > 0 {
+		b.next = (b.next + 1) % cap(b.replay)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe 订阅事件，lastEventID 为客户端最后收到的事件ID，为空时只接收之后发布的事件
+// 返回需要补发的事件；lastEventID 之后的事件已不在缓冲区中或ID无效时 complete 为 false，客户端应重新拉取数据
+func (b *EventBus) Subscribe(lastEventID string) (sub *EventSubscription, replay []models.Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan models.Event, eventSubscriberBuffer)
+	sub = &EventSubscription{C: ch, ch: ch, bus: b}
+	b.subscribers[sub] = struct{}{}
+
+	lastEventID = strings.TrimSpace(lastEventID)
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	seq, ok := b.parseEventID(lastEventID)
+	if !ok || seq > b.seq {
+		return sub, nil, false
+	}
+
+	events := b.ordered()
+	if seq < b.seq && (len(events) == 0 || events[0].Seq > seq+1) {
+		return sub, nil, false
+	}
+	for _, event := range events {
+		if event.Seq > seq {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, true
+}
+
+// LastEventID 最近一个事件的ID，还没有事件时返回序号为0的ID
+func (b *EventBus) LastEventID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.eventID(b.seq)
+}
+
+// Close 取消订阅
+func (s *EventSubscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// remove 移除订阅者并关闭其通道，调用方需持有锁
+func (b *EventBus) remove(sub *EventSubscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// ordered 按发布顺序返回缓冲区中的事件，调用方需持有锁
+func (b *EventBus) ordered() []models.Event {
+	if len(b.replay) < cap(b.replay) {
+		return b.replay
+	}
+	return append(append([]models.Event(nil), b.replay[b.next:]...), b.replay[:b.next]...)
+}
+
+// eventID 生成事件ID
+func (b *EventBus) eventID(seq uint64) string {
+	return fmt.Sprintf("%d-%d", b.epoch, seq)
+}
+
+// parseEventID 解析本进程生成的事件ID，返回序号
+func (b *EventBus) parseEventID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != strconv.FormatInt(b.epoch, 10) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// publishPromptEvent 发布提示词创建或更新事件，私有提示词只发送给所有者
+func publishPromptEvent(eventType string, prompt *models.Prompt) {
+	Events.Publish(eventType, prompt.ToResponse(), prompt.OwnerID, prompt.IsPublic)
+}
+
+// publishPromptDeleted 发布提示词删除事件
+func publishPromptDeleted(prompt *models.Prompt) {
+	Events.Publish(models.EventPromptDeleted, models.PromptDeletedEvent{ID: prompt.ID}, prompt.OwnerID, prompt.IsPublic)
+}
+
+// publishTagsCreated 为新创建的标签发布事件
+func publishTagsCreated(tags []*models.Tag) {
+	for _, tag := range tags {
+		Events.Publish(models.EventTagCreated, tag, nil, true)
+	}
+}
+
+// publishJobFinished 发布任务结束事件，只发送给任务的创建者
+func publishJobFinished(job *models.Job) {
+	Events.Publish(models.EventJobFinished, job, job.OwnerID, false)
+}
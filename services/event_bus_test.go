@@ -0,0 +1,114 @@
+package services_test
+
+import (
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEventBusPublish 测试订阅者按顺序收到发布的事件
+func TestEventBusPublish(t *testing.T) {
+	bus := services.NewEventBus(10)
+	sub, replay, complete := bus.Subscribe("")
+	defer sub.Close()
+	assert.True(t, complete)
+	assert.Empty(t, replay)
+
+	first := bus.Publish(models.EventTagCreated, "a", nil, true)
+	second := bus.Publish(models.EventTagCreated, "b", nil, true)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, second.ID, bus.LastEventID())
+
+	assert.Equal(t, first.ID, (<-sub.C).ID)
+	assert.Equal(t, second.ID, (<-sub.C).ID)
+
+	sub.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok, "取消订阅后通道应被关闭")
+	sub.Close() // 重复关闭不应 panic
+}
+
+// TestEventBusReplay 测试根据 Last-Event-ID 补发错过的事件
+func TestEventBusReplay(t *testing.T) {
+	bus := services.NewEventBus(3)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ids = append(ids, bus.Publish(models.EventTagCreated, i, nil, true).ID)
+	}
+
+	// 缓冲区中保留最后3个事件，从第2个之后可以完整补发
+	sub, replay, complete := bus.Subscribe(ids[1])
+	defer sub.Close()
+	require.True(t, complete)
+	require.Len(t, replay, 3)
+	assert.Equal(t, ids[2:], []string{replay[0].ID, replay[1].ID, replay[2].ID})
+
+	// 已是最新事件时无需补发
+	latest, replay, complete := bus.Subscribe(ids[4])
+	defer latest.Close()
+	assert.True(t, complete)
+	assert.Empty(t, replay)
+
+	// 错过的事件已被覆盖
+	gap, replay, complete := bus.Subscribe(ids[0])
+	defer gap.Close()
+	assert.False(t, complete)
+	assert.Empty(t, replay)
+}
+
+// TestEventBusInvalidLastEventID 测试无效或其他进程的事件ID需要客户端重新拉取数据
+func TestEventBusInvalidLastEventID(t *testing.T) {
+	bus := services.NewEventBus(10)
+	id := bus.Publish(models.EventTagCreated, 1, nil, true).ID
+	epoch, _, _ := strings.Cut(id, "-")
+
+	for _, lastEventID := range []string{"abc", "1-1", epoch + "-x", epoch + "-99"} {
+		sub, replay, complete := bus.Subscribe(lastEventID)
+		assert.False(t, complete, lastEventID)
+		assert.Empty(t, replay, lastEventID)
+		sub.Close()
+	}
+
+	// 订阅仍然有效，之后的事件照常接收
+	sub, _, _ := bus.Subscribe("abc")
+	defer sub.Close()
+	next := bus.Publish(models.EventTagCreated, 2, nil, true)
+	assert.Equal(t, next.ID, (<-sub.C).ID)
+}
+
+// TestEventBusSlowSubscriber 测试过慢的订阅者被断开，不影响发布和其他订阅者
+func TestEventBusSlowSubscriber(t *testing.T) {
+	bus := services.NewEventBus(10)
+	slow, _, _ := bus.Subscribe("")
+	defer slow.Close()
+
+	var received int
+	for i := 0; i < 300; i++ {
+		bus.Publish(models.EventTagCreated, i, nil, true)
+	}
+	for range slow.C {
+		received++
+	}
+	assert.Less(t, received, 300, "通道写满后订阅者应被断开")
+	assert.Greater(t, received, 0)
+}
+
+// TestEventVisibility 测试事件的可见性与提示词一致
+func TestEventVisibility(t *testing.T) {
+	ownerID, otherID := uint(1), uint(2)
+	private := models.Event{OwnerID: &ownerID}
+	assert.True(t, private.IsVisibleTo(&ownerID))
+	assert.False(t, private.IsVisibleTo(&otherID))
+	assert.False(t, private.IsVisibleTo(nil))
+
+	public := models.Event{OwnerID: &ownerID, Public: true}
+	assert.True(t, public.IsVisibleTo(nil))
+
+	unowned := models.Event{}
+	assert.False(t, unowned.IsVisibleTo(&otherID), "没有所有者的非公开事件不发送给任何人")
+	assert.True(t, (&models.Event{Public: true}).IsVisibleTo(nil))
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"io"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidImport 导入文件或导入选项无效
+var ErrInvalidImport = errors.New("导入文件无效")
+
+// importStatus 单行的导入结果
+type importStatus int
+
+const (
+	importCreated importStatus = iota
+	importUpdated
+	importSkipped
+	importFailed
+)
+
+// importOutcome 单行在事务中的处理结果，事务提交后才计入总结果
+type importOutcome struct {
+	line     int
+	status   importStatus
+	promptID uint
+	err      error
+}
+
+// ImportService 批量导入服务
+type ImportService struct {
+	db         *gorm.DB
+	tagService *TagService
+}
+
+// NewImportService 创建批量导入服务实例
+func NewImportService() *ImportService {
+	return &ImportService{
+		db:         config.GetDB(),
+		tagService: NewTagService(),
+	}
+}
+
+// ImportPrompts 从 JSONL 或 CSV 批量导入提示词
+// 每 ChunkSize 行在一个事务中处理：先批量处理本块的所有标签，再逐行创建或更新提示词。
+// 单行失败只回滚该行（事务保存点），不影响同一块中的其他行；块提交失败时整块记为失败。
+func (s *ImportService) ImportPrompts(r io.Reader, opts *models.ImportOptions) (*models.ImportResult, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = models.ImportOnDuplicateSkip
+	}
+	if opts.OnDuplicate != models.ImportOnDuplicateSkip && opts.OnDuplicate != models.ImportOnDuplicateUpsert {
+		return nil, fmt.Errorf("%w: 不支持的重复处理方式 %s", ErrInvalidImport, opts.OnDuplicate)
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = models.DefaultImportChunkSize
+	}
+	if opts.ChunkSize > models.MaxImportChunkSize {
+		opts.ChunkSize = models.MaxImportChunkSize
+	}
+
+	reader, err := utils.NewImportReader(r, opts.Format, opts.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	result := &models.ImportResult{Errors: []models.ImportRowError{}}
+	chunk := make([]*utils.ImportRow, 0, opts.ChunkSize)
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("读取导入文件失败: %v", err)
+		}
+
+		result.Total++
+		if row.Err != nil {
+			addImportOutcome(result, importOutcome{line: row.Line, status: importFailed, err: row.Err})
+			continue
+		}
+		chunk = append(chunk, row)
+		if len(chunk) == opts.ChunkSize {
+			s.importChunk(chunk, opts, result)
+			chunk = chunk[:0]
+		}
+	}
+	if len(chunk) > 0 {
+		s.importChunk(chunk, opts, result)
+	}
+
+	return result, nil
+}
+
+// importChunk 在一个事务中导入一块数据行
+func (s *ImportService) importChunk(rows []*utils.ImportRow, opts *models.ImportOptions, result *models.ImportResult) {
+	var outcomes []importOutcome
+	var createdTags []*models.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		outcomes = outcomes[:0]
+
+		// 批量处理本块中出现的所有标签
+		names := collectImportTagNames(rows)
+		tags, created, err := s.tagService.WithTx(tx).getOrCreateTags(names)
+		createdTags = created
+		if err != nil {
+			return fmt.Errorf("处理标签失败: %v", err)
+		}
+		if len(tags) != len(names) {
+			return fmt.Errorf("处理标签失败: 期望 %d 个标签，实际得到 %d 个", len(names), len(tags))
+		}
+		// 标签名称不区分大小写，按小写名称查找
+		tagsByName := make(map[string]*models.Tag, len(names))
+		for i, tag := range tags {
+			tagsByName[strings.ToLower(names[i])] = tag
+		}
+
+		for i, row := range rows {
+			savepoint := fmt.Sprintf("import_row_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			status, promptID, err := s.importRow(tx, row, tagsByName, opts)
+			if err != nil {
+				if rbErr := tx.RollbackTo(savepoint).Error; rbErr != nil {
+					return rbErr
+				}
+				outcomes = append(outcomes, importOutcome{line: row.Line, status: importFailed, err: err})
+				continue
+			}
+			outcomes = append(outcomes, importOutcome{line: row.Line, status: status, promptID: promptID})
+		}
+		return nil
+	})
+
+	if err != nil {
+		for _, row := range rows {
+			addImportOutcome(result, importOutcome{line: row.Line, status: importFailed, err: err})
+		}
+		return
+	}
+	for _, outcome := range outcomes {
+		addImportOutcome(result, outcome)
+	}
+	s.publishChanges(createdTags, outcomes)
+}
+
+// publishChanges 块提交后为新建的标签和创建、更新的提示词发布事件
+func (s *ImportService) publishChanges(createdTags []*models.Tag, outcomes []importOutcome) {
+	publishTagsCreated(createdTags)
+
+	eventTypes := make(map[uint]string, len(outcomes))
+	ids := make([]uint, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		var eventType string
+		switch outcome.status {
+		case importCreated:
+			eventType = models.EventPromptCreated
+		case importUpdated:
+			eventType = models.EventPromptUpdated
+		default:
+			continue
+		}
+		// 同一块中后面的行可能更新前面刚创建的提示词，只发布一次
+		if _, ok := eventTypes[outcome.promptID]; !ok {
+			eventTypes[outcome.promptID] = eventType
+			ids = append(ids, outcome.promptID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var prompts []*models.Prompt
+	if err := s.db.Preload("Tags").Preload("Images", models.OrderedImages).Where("id IN ?", ids).Order("id ASC").Find(&prompts).Error; err != nil {
+		log.Printf("读取导入的提示词失败: %v", err)
+		return
+	}
+	for _, prompt := range prompts {
+		publishPromptEvent(eventTypes[prompt.ID], prompt)
+	}
+}
+
+// importRow 导入单行：不存在时创建，存在时按 OnDuplicate 跳过或更新
+// 重复判断以提示词文本为准，只在导入者可以修改的提示词中查找
+func (s *ImportService) importRow(tx *gorm.DB, row *utils.ImportRow, tagsByName map[string]*models.Tag, opts *models.ImportOptions) (importStatus, uint, error) {
+	req := row.Request
+	req.OwnerID = opts.OwnerID
+
+	tags := make([]*models.Tag, 0, len(req.TagNames))
+	for _, name := range req.TagNames {
+		if tag, ok := tagsByName[strings.ToLower(strings.TrimSpace(name))]; ok {
+			tags = append(tags, tag)
+		}
+	}
+
+	var existing models.Prompt
+	err := tx.Scopes(modifiableBy(opts.OwnerID)).
+		Where("prompt_text = ?", req.PromptText).
+		Order("id ASC").
+		First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return importFailed, 0, fmt.Errorf("检查重复提示词失败: %v", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		prompt := newPromptFromRequest(req, tags)
+		if err := tx.Create(prompt).Error; err != nil {
+			return importFailed, 0, fmt.Errorf("创建提示词失败: %v", err)
+		}
+		return importCreated, prompt.ID, nil
+	}
+
+	if opts.OnDuplicate == models.ImportOnDuplicateSkip {
+		return importSkipped, 0, nil
+	}
+
+	// upsert：只更新该行中出现的字段
+	updates := importUpdates(row)
+	if existing.OwnerID == nil {
+		// 没有所有者的提示词总是公开的，与创建时一致
+		delete(updates, "is_public")
+	}
+	updates["version"] = gorm.Expr("version + 1")
+	if err := tx.Model(&existing).Updates(updates).Error; err != nil {
+		return importFailed, 0, fmt.Errorf("更新提示词失败: %v", err)
+	}
+	if row.HasField("tag_names") {
+		if err := tx.Model(&existing).Association("Tags").Replace(tags); err != nil {
+			return importFailed, 0, fmt.Errorf("更新标签关联失败: %v", err)
+		}
+	}
+	if row.HasField("input_image_urls") {
+		if err := replacePromptImages(tx, existing.ID, requestImages(req.InputImageURLs, "", nil), models.ImageRoleInput); err != nil {
+			return importFailed, 0, err
+		}
+	}
+	if row.HasField("output_image_url") {
+		if err := replacePromptImages(tx, existing.ID, requestImages(nil, req.OutputImageURL, nil), models.ImageRoleOutput); err != nil {
+			return importFailed, 0, err
+		}
+	}
+	return importUpdated, existing.ID, nil
+}
+
+// importUpdates 根据行中出现的字段生成更新内容（图片由 importRow 单独替换）
+func importUpdates(row *utils.ImportRow) map[string]interface{} {
+	req := row.Request
+	updates := make(map[string]interface{})
+	for _, field := range row.Fields {
+		switch field {
+		case "negative_prompt":
+			updates[field] = req.NegativePrompt
+		case "model_name":
+			updates[field] = req.ModelName
+		case "is_public":
+			updates[field] = req.IsPublic
+		case "style_description":
+			updates[field] = req.StyleDescription
+		case "usage_scenario":
+			updates[field] = req.UsageScenario
+		case "atmosphere_description":
+			updates[field] = req.AtmosphereDescription
+		case "expressive_intent":
+			updates[field] = req.ExpressiveIntent
+		case "structure_analysis":
+			if strings.TrimSpace(req.StructureAnalysis) == "" {
+				updates[field] = []byte("{}")
+			} else {
+				updates[field] = []byte(req.StructureAnalysis)
+			}
+		case "generation_params":
+			updates[field] = generationParamsValue(req.GenerationParams)
+		}
+	}
+	return updates
+}
+
+// collectImportTagNames 收集一块数据行中去重后的标签名称
+// 与 GetOrCreateTags 一样按不区分大小写去重，使返回的标签与名称一一对应
+func collectImportTagNames(rows []*utils.ImportRow) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, row := range rows {
+		for _, name := range row.Request.TagNames {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[strings.ToLower(name)] {
+				continue
+			}
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// addImportOutcome 将单行结果计入导入结果
+func addImportOutcome(result *models.ImportResult, outcome importOutcome) {
+	switch outcome.status {
+	case importCreated:
+		result.Created++
+	case importUpdated:
+		result.Updated++
+	case importSkipped:
+		result.Skipped++
+	case importFailed:
+		result.Failed++
+		result.Errors = append(result.Errors, models.ImportRowError{Line: outcome.line, Message: outcome.err.Error()})
+	}
+}
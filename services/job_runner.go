@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnalyzeFunc 执行分析任务的函数
+type AnalyzeFunc func(ctx context.Context, payload *models.AnalyzeJobPayload) (*models.AnalyzePromptResponse, error)
+
+// JobRunner 后台任务的worker池：固定数量的worker从 jobs 表领取任务并发执行，失败时按指数退避重试
+// 任务状态全部保存在数据库中，多个进程可以同时运行 JobRunner
+type JobRunner struct {
+	service       *JobService
+	promptService *PromptService
+	cfg           config.JobConfig
+
+	// Analyze 执行分析，默认读取上传目录中的图片后调用 PromptService.AnalyzePromptData
+	Analyze AnalyzeFunc
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobRunner 创建后台任务执行器，使用 config.AppConfig.Jobs 中的配置
+func NewJobRunner() *JobRunner {
+	r := &JobRunner{
+		service:       NewJobService(),
+		promptService: NewPromptService(),
+		cfg:           config.AppConfig.Jobs,
+	}
+	r.Analyze = r.analyzeLocalImages
+	return r
+}
+
+// Start 启动 cfg.Workers 个worker
+func (r *JobRunner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	for i := 0; i < r.cfg.Workers; i++ {
+		r.wg.Add(1)
+		go r.work(ctx)
+	}
+}
+
+// Stop 停止所有worker并等待其退出，执行中的任务会被中断并归还，之后由其他worker重新执行
+func (r *JobRunner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// work 循环领取并执行任务，没有任务时等待新任务入队或下一次轮询
+func (r *JobRunner) work(ctx context.Context) {
+	defer r.wg.Done()
+	for ctx.Err() == nil {
+		job, err := r.service.claimJob(r.cfg.Timeout)
+		if err != nil {
+			log.Printf("领取后台任务失败: %v", err)
+		}
+		if job != nil {
+			r.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-jobWakeup:
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+// run 执行一个已领取的任务并记录结果
+func (r *JobRunner) run(ctx context.Context, job *models.Job) {
+	jobCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	runningJobs.Store(job.ID, cancel)
+	defer runningJobs.Delete(job.ID)
+
+	result, appliedAt, err := r.execute(jobCtx, job)
+	switch {
+	case ctx.Err() != nil:
+		err = r.service.releaseJob(job)
+	case err == nil:
+		err = r.service.completeJob(job, result, appliedAt)
+	default:
+		log.Printf("后台任务 %d 第 %d 次执行失败: %v", job.ID, job.Attempts, err)
+		err = r.service.failJob(job, err, r.backoff(job.Attempts))
+	}
+	if err != nil {
+		log.Printf("更新后台任务 %d 状态失败: %v", job.ID, err)
+	}
+}
+
+// backoff 第 attempts 次失败后的重试等待时间
+func (r *JobRunner) backoff(attempts int) time.Duration {
+	return retryBackoff(r.cfg.RetryBackoff, r.cfg.MaxBackoff, attempts)
+}
+
+// retryBackoff 指数退避：第 attempts 次失败后等待 base，之后每次翻倍，不超过 max（max 为0时不限制）
+func retryBackoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && (max <= 0 || delay < max); i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// execute 按类型执行任务，返回结果和结果应用到提示词的时间
+func (r *JobRunner) execute(ctx context.Context, job *models.Job) (json.RawMessage, *time.Time, error) {
+	switch job.Type {
+	case models.JobTypeAnalyze:
+		return r.runAnalyzeJob(ctx, job)
+	default:
+		return nil, nil, fmt.Errorf("%w: 未知的任务类型 %s", errJobNotRetryable, job.Type)
+	}
+}
+
+// runAnalyzeJob 执行分析任务，auto_apply 时将结果应用到关联的提示词
+func (r *JobRunner) runAnalyzeJob(ctx context.Context, job *models.Job) (json.RawMessage, *time.Time, error) {
+	var payload models.AnalyzeJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, nil, fmt.Errorf("%w: 任务参数无效: %v", errJobNotRetryable, err)
+	}
+
+	analysis, err := r.Analyze(ctx, &payload)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: 序列化分析结果失败: %v", errJobNotRetryable, err)
+	}
+	if !job.AutoApply || job.PromptID == nil {
+		return result, nil, nil
+	}
+
+	// 已请求取消的任务不再修改提示词
+	if canceled, err := r.service.isCancelRequested(job.ID); err != nil || canceled {
+		if err == nil {
+			err = context.Canceled
+		}
+		return nil, nil, err
+	}
+	// 入队后提示词可能已被设为私有或转给他人，应用前按任务创建者的身份重新校验
+	if _, err := r.promptService.ApplyAnalysis(*job.PromptID, job.OwnerID, analysis); err != nil {
+		if errors.Is(err, ErrPromptNotFound) || errors.Is(err, ErrPermissionDenied) {
+			err = fmt.Errorf("%w: %w", errJobNotRetryable, err)
+		}
+		return nil, nil, err
+	}
+	appliedAt := time.Now()
+	return result, &appliedAt, nil
+}
+
+// analyzeLocalImages 读取上传目录中的图片并调用AI分析
+// 图片文件不存在时任务直接失败，不再重试
+func (r *JobRunner) analyzeLocalImages(ctx context.Context, payload *models.AnalyzeJobPayload) (*models.AnalyzePromptResponse, error) {
+	outputImage, err := readUploadedImageBase64(payload.OutputImageURL)
+	if err != nil {
+		return nil, err
+	}
+	inputImages := make([]string, 0, len(payload.InputImageURLs))
+	for _, url := range payload.InputImageURLs {
+		image, err := readUploadedImageBase64(url)
+		if err != nil {
+			return nil, err
+		}
+		inputImages = append(inputImages, image)
+	}
+	return r.promptService.AnalyzePromptData(payload.PromptText, payload.ModelName, outputImage, inputImages)
+}
+
+// readUploadedImageBase64 读取上传目录中的图片并转换为Base64
+func readUploadedImageBase64(url string) (string, error) {
+	if !strings.HasPrefix(url, "/uploads/") {
+		return "", fmt.Errorf("%w: 图片不在上传目录中: %s", errJobNotRetryable, url)
+	}
+	data, err := os.ReadFile(filepath.Join(config.AppConfig.Server.UploadPath, filepath.Base(url)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: 图片文件不存在: %s", errJobNotRetryable, url)
+		}
+		return "", fmt.Errorf("读取图片失败: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrJobFinished 任务已结束，无法取消
+	ErrJobFinished = errors.New("任务已结束，无法取消")
+)
+
+// errJobNotRetryable 标记不可重试的任务错误（如参数无效、图片文件不存在），任务直接失败
+var errJobNotRetryable = errors.New("不可重试")
+
+// claimBatchSize 领取任务时每次查询的候选任务数量
+const claimBatchSize = 10
+
+var (
+	// jobWakeup 有新任务入队时唤醒一个空闲的worker，避免等待下一次轮询
+	jobWakeup = make(chan struct{}, 1)
+	// runningJobs 本进程中正在执行的任务及其取消函数，用于立即中断被取消的任务
+	runningJobs sync.Map
+)
+
+// JobService 后台任务服务
+type JobService struct {
+	db *gorm.DB
+}
+
+// NewJobService 创建后台任务服务实例
+func NewJobService() *JobService {
+	return &JobService{db: config.GetDB()}
+}
+
+// EnqueueAnalyzeJob 创建分析任务，maxAttempts 为0时使用配置的默认值
+// 匿名创建的任务生成一个访问令牌（job.AccessToken，只在此时可见），查询和取消时需要提供
+func (s *JobService) EnqueueAnalyzeJob(payload *models.AnalyzeJobPayload, promptID *uint, autoApply bool, ownerID *uint, maxAttempts int) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务参数失败: %v", err)
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = config.AppConfig.Jobs.MaxAttempts
+	}
+
+	job := &models.Job{
+		Type:        models.JobTypeAnalyze,
+		Status:      models.JobStatusPending,
+		OwnerID:     ownerID,
+		PromptID:    promptID,
+		AutoApply:   autoApply,
+		Payload:     data,
+		MaxAttempts: maxAttempts,
+		RunAt:       time.Now(),
+	}
+	if ownerID == nil {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("生成任务访问令牌失败: %v", err)
+		}
+		job.AccessToken = hex.EncodeToString(buf)
+		job.AccessTokenHash = hashToken(job.AccessToken)
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("创建任务失败: %v", err)
+	}
+
+	select {
+	case jobWakeup <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// GetJob 获取对指定用户可见的任务，他人的任务视为不存在
+// 匿名创建的任务需要提供创建时返回的访问令牌
+func (s *JobService) GetJob(id uint, userID *uint, accessToken string) (*models.Job, error) {
+	var job models.Job
+	if err := s.db.First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("获取任务失败: %v", err)
+	}
+	if !job.IsVisibleTo(userID) && !jobTokenMatches(&job, accessToken) {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// jobTokenMatches 判断访问令牌是否与匿名任务的令牌一致
+func jobTokenMatches(job *models.Job, accessToken string) bool {
+	if job.OwnerID != nil || job.AccessTokenHash == "" || accessToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(accessToken)), []byte(job.AccessTokenHash)) == 1
+}
+
+// CancelJob 取消任务：等待中的任务直接取消；执行中的任务标记为请求取消，
+// 本进程执行的任务会立即中断，其他进程执行的任务在结束时被标记为已取消
+func (s *JobService) CancelJob(id uint, userID *uint, accessToken string) (*models.Job, error) {
+	job, err := s.GetJob(id, userID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	switch job.Status {
+	case models.JobStatusPending:
+		now := time.Now()
+		result := s.db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", id, models.JobStatusPending).
+			Updates(map[string]interface{}{"status": models.JobStatusCanceled, "cancel_requested": true, "finished_at": now})
+		if result.Error != nil {
+			return nil, fmt.Errorf("取消任务失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// 任务刚被领取或已结束，按最新状态重新处理
+			return s.CancelJob(id, userID, accessToken)
+		}
+		s.publishFinished(id)
+	case models.JobStatusRunning:
+		if err := s.db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", id, models.JobStatusRunning).
+			Update("cancel_requested", true).Error; err != nil {
+			return nil, fmt.Errorf("取消任务失败: %v", err)
+		}
+		if cancel, ok := runningJobs.Load(id); ok {
+			cancel.(context.CancelFunc)()
+		}
+	default:
+		return nil, ErrJobFinished
+	}
+	return s.GetJob(id, userID, accessToken)
+}
+
+// claimJob 领取一个可执行的任务：到期的等待中任务，或租约已过期的执行中任务
+// 通过带原状态的条件更新领取，多个worker或多个进程同时领取时只有一个会成功；尝试次数同时作为租约的标识
+func (s *JobService) claimJob(lease time.Duration) (*models.Job, error) {
+	if err := s.expireJobs(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var candidates []models.Job
+	err := s.db.Select("id, status, attempts").
+		Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+			models.JobStatusPending, now, models.JobStatusRunning, now).
+		Order("run_at ASC, id ASC").
+		Limit(claimBatchSize).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询待执行任务失败: %v", err)
+	}
+
+	for _, candidate := range candidates {
+		lockedUntil := now.Add(lease)
+		result := s.db.Model(&models.Job{}).
+			Where("id = ? AND status = ? AND attempts = ?", candidate.ID, candidate.Status, candidate.Attempts).
+			Updates(map[string]interface{}{
+				"status":       models.JobStatusRunning,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"started_at":   now,
+				"locked_until": lockedUntil,
+			})
+		if result.Error != nil {
+			return nil, fmt.Errorf("领取任务失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue // 已被其他worker领取
+		}
+
+		var job models.Job
+		if err := s.db.First(&job, candidate.ID).Error; err != nil {
+			return nil, fmt.Errorf("获取任务失败: %v", err)
+		}
+		return &job, nil
+	}
+	return nil, nil
+}
+
+// expireJobs 结束租约已过期、不应再被领取的执行中任务：已请求取消的标记为取消，尝试次数用尽的标记为失败
+func (s *JobService) expireJobs() error {
+	now := time.Now()
+	var expired []models.Job
+	err := s.db.Select("id, attempts, cancel_requested").
+		Where("status = ? AND locked_until < ? AND (cancel_requested = ? OR attempts >= max_attempts)",
+			models.JobStatusRunning, now, true).
+		Limit(claimBatchSize).
+		Find(&expired).Error
+	if err != nil {
+		return fmt.Errorf("查询超时任务失败: %v", err)
+	}
+
+	for _, job := range expired {
+		updates := map[string]interface{}{"status": models.JobStatusFailed, "error": "执行超时", "finished_at": now, "locked_until": nil}
+		if job.CancelRequested {
+			updates = map[string]interface{}{"status": models.JobStatusCanceled, "finished_at": now, "locked_until": nil}
+		}
+		// 带尝试次数的条件更新，期间被其他worker处理过的任务不会重复结束
+		result := s.db.Model(&models.Job{}).
+			Where("id = ? AND status = ? AND attempts = ? AND locked_until < ?", job.ID, models.JobStatusRunning, job.Attempts, now).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("更新超时任务失败: %v", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			s.publishFinished(job.ID)
+		}
+	}
+	return nil
+}
+
+// leased 限定为仍由本次执行持有租约的任务
+func (s *JobService) leased(job *models.Job) *gorm.DB {
+	return s.db.Model(&models.Job{}).Where("id = ? AND status = ? AND attempts = ?", job.ID, models.JobStatusRunning, job.Attempts)
+}
+
+// completeJob 记录任务成功
+func (s *JobService) completeJob(job *models.Job, result json.RawMessage, appliedAt *time.Time) error {
+	updated := s.leased(job).Updates(map[string]interface{}{
+		"status":       models.JobStatusSucceeded,
+		"result":       []byte(result),
+		"error":        "",
+		"finished_at":  time.Now(),
+		"applied_at":   appliedAt,
+		"locked_until": nil,
+	})
+	if updated.Error == nil && updated.RowsAffected > 0 {
+		s.publishFinished(job.ID)
+	}
+	return updated.Error
+}
+
+// failJob 记录任务失败：已请求取消的任务标记为取消；可重试且还有剩余次数时在 backoff 后重新执行，否则标记为失败
+func (s *JobService) failJob(job *models.Job, cause error, backoff time.Duration) error {
+	now := time.Now()
+	result := s.leased(job).Where("cancel_requested = ?", true).
+		Updates(map[string]interface{}{"status": models.JobStatusCanceled, "finished_at": now, "locked_until": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		s.publishFinished(job.ID)
+		return nil
+	}
+
+	updates := map[string]interface{}{"error": cause.Error(), "locked_until": nil}
+	retry := job.Attempts < job.MaxAttempts && !errors.Is(cause, errJobNotRetryable)
+	if retry {
+		updates["status"] = models.JobStatusPending
+		updates["run_at"] = now.Add(backoff)
+	} else {
+		updates["status"] = models.JobStatusFailed
+		updates["finished_at"] = now
+	}
+	result = s.leased(job).Updates(updates)
+	if result.Error == nil && result.RowsAffected > 0 && !retry {
+		s.publishFinished(job.ID)
+	}
+	return result.Error
+}
+
+// releaseJob 服务关闭时归还执行中的任务，本次执行不计入尝试次数
+func (s *JobService) releaseJob(job *models.Job) error {
+	return s.leased(job).Updates(map[string]interface{}{
+		"status":       models.JobStatusPending,
+		"attempts":     gorm.Expr("attempts - 1"),
+		"locked_until": nil,
+	}).Error
+}
+
+// publishFinished 重新读取已结束的任务，删除为任务上传的图片并发布 job.finished 事件
+// 每个任务只有一次结束状态的条件更新会成功，因此只会调用一次
+func (s *JobService) publishFinished(id uint) {
+	var job models.Job
+	if err := s.db.First(&job, id).Error; err != nil {
+		log.Printf("读取已结束的任务 %d 失败: %v", id, err)
+		return
+	}
+	removeJobFiles(&job)
+	publishJobFinished(&job)
+}
+
+// removeJobFiles 删除为任务上传的图片，任务结束后不再需要
+func removeJobFiles(job *models.Job) {
+	if job.Type != models.JobTypeAnalyze {
+		return
+	}
+	var payload models.AnalyzeJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return
+	}
+	for _, filename := range payload.UploadedFiles {
+		if err := utils.DeleteFile(filepath.Join(config.AppConfig.Server.UploadPath, filepath.Base(filename))); err != nil {
+			log.Printf("删除任务 %d 的图片 %s 失败: %v", job.ID, filename, err)
+		}
+	}
+}
+
+// isCancelRequested 判断任务是否已被请求取消
+func (s *JobService) isCancelRequested(id uint) (bool, error) {
+	var job models.Job
+	if err := s.db.Select("cancel_requested").First(&job, id).Error; err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
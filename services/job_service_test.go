@@ -0,0 +1,300 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// JobServiceTestSuite 是 JobService 和 JobRunner 的测试套件
+// 复用 PromptServiceTestSuite 的数据库设置
+type JobServiceTestSuite struct {
+	PromptServiceTestSuite
+	jobSvc *services.JobService
+}
+
+// SetupTest 在每个测试方法运行前清理数据库，并缩短轮询和重试间隔
+func (s *JobServiceTestSuite) SetupTest() {
+	s.PromptServiceTestSuite.SetupTest()
+	s.jobSvc = services.NewJobService()
+	config.AppConfig.Jobs = config.JobConfig{
+		Workers:      2,
+		MaxAttempts:  3,
+		RetryBackoff: 10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+		Timeout:      5 * time.Second,
+	}
+}
+
+// startRunner 使用指定的分析函数启动worker，测试结束时停止
+func (s *JobServiceTestSuite) startRunner(analyze services.AnalyzeFunc) {
+	runner := services.NewJobRunner()
+	if analyze != nil {
+		runner.Analyze = analyze
+	}
+	runner.Start()
+	s.T().Cleanup(runner.Stop)
+}
+
+// enqueue 创建一个分析任务
+func (s *JobServiceTestSuite) enqueue(promptID *uint, autoApply bool, maxAttempts int) *models.Job {
+	payload := &models.AnalyzeJobPayload{PromptText: "a portrait", OutputImageURL: "/uploads/missing.png"}
+	job, err := s.jobSvc.EnqueueAnalyzeJob(payload, promptID, autoApply, nil, maxAttempts)
+	s.Require().NoError(err)
+	s.Equal(models.JobStatusPending, job.Status)
+	return job
+}
+
+// waitFinished 等待任务结束并返回最终状态
+func (s *JobServiceTestSuite) waitFinished(id uint) *models.Job {
+	return s.waitFor(id, func(job *models.Job) bool { return job.IsFinished() })
+}
+
+// waitFor 等待任务满足条件（直接读取数据库，不受任务可见性限制）
+func (s *JobServiceTestSuite) waitFor(id uint, cond func(job *models.Job) bool) *models.Job {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		job := &models.Job{}
+		s.Require().NoError(s.db.First(job, id).Error)
+		if cond(job) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			s.FailNow("等待任务超时", "任务状态: %s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fixedAnalysis 返回固定结果的分析函数
+func fixedAnalysis(ctx context.Context, payload *models.AnalyzeJobPayload) (*models.AnalyzePromptResponse, error) {
+	return &models.AnalyzePromptResponse{
+		NegativePrompt:   "blurry",
+		StyleDescription: "写实肖像",
+		TagNames:         []string{"肖像", "已有"},
+	}, nil
+}
+
+// TestAnalyzeJobAutoApply 测试任务成功后将结果应用到提示词：只填充空字段，标签合并
+func (s *JobServiceTestSuite) TestAnalyzeJobAutoApply() {
+	prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{
+		PromptText:     "a portrait",
+		NegativePrompt: "用户填写的负面提示词",
+		TagNames:       []string{"已有"},
+	})
+	s.Require().NoError(err)
+	s.startRunner(fixedAnalysis)
+
+	job := s.waitFinished(s.enqueue(&prompt.ID, true, 0).ID)
+	s.Equal(models.JobStatusSucceeded, job.Status)
+	s.Equal(1, job.Attempts)
+	s.NotNil(job.AppliedAt)
+	s.Contains(string(job.Result), "写实肖像")
+
+	updated, err := s.service.GetPromptByID(prompt.ID)
+	s.Require().NoError(err)
+	s.Equal("用户填写的负面提示词", updated.NegativePrompt)
+	s.Equal("写实肖像", updated.StyleDescription)
+	var tagNames []string
+	for _, tag := range updated.Tags {
+		tagNames = append(tagNames, tag.Name)
+	}
+	s.ElementsMatch([]string{"已有", "肖像"}, tagNames)
+	s.Equal(prompt.Version+1, updated.Version)
+
+	// 不自动应用时只保存结果
+	job = s.waitFinished(s.enqueue(&prompt.ID, false, 0).ID)
+	s.Equal(models.JobStatusSucceeded, job.Status)
+	s.Nil(job.AppliedAt)
+}
+
+// TestAnalyzeJobRetries 测试失败后按退避时间重试，次数用尽后失败
+func (s *JobServiceTestSuite) TestAnalyzeJobRetries() {
+	var calls int32
+	s.startRunner(func(ctx context.Context, payload *models.AnalyzeJobPayload) (*models.AnalyzePromptResponse, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errors.New("模型暂时不可用")
+		}
+		return fixedAnalysis(ctx, payload)
+	})
+
+	job := s.waitFinished(s.enqueue(nil, false, 3).ID)
+	s.Equal(models.JobStatusSucceeded, job.Status)
+	s.Equal(3, job.Attempts)
+	s.Empty(job.Error)
+
+	atomic.StoreInt32(&calls, -10)
+	job = s.waitFinished(s.enqueue(nil, false, 2).ID)
+	s.Equal(models.JobStatusFailed, job.Status)
+	s.Equal(2, job.Attempts)
+	s.Contains(job.Error, "模型暂时不可用")
+	s.NotNil(job.FinishedAt)
+}
+
+// TestAnalyzeJobNotRetryable 测试图片文件不存在时直接失败，不再重试
+func (s *JobServiceTestSuite) TestAnalyzeJobNotRetryable() {
+	s.startRunner(nil)
+
+	job := s.waitFinished(s.enqueue(nil, false, 3).ID)
+	s.Equal(models.JobStatusFailed, job.Status)
+	s.Equal(1, job.Attempts)
+	s.Contains(job.Error, "图片文件不存在")
+}
+
+// TestCancelJob 测试取消等待中和执行中的任务
+func (s *JobServiceTestSuite) TestCancelJob() {
+	// 1. Pending jobs are canceled immediately
+	job := s.enqueue(nil, false, 0)
+	canceled, err := s.jobSvc.CancelJob(job.ID, nil, job.AccessToken)
+	s.Require().NoError(err)
+	s.Equal(models.JobStatusCanceled, canceled.Status)
+
+	_, err = s.jobSvc.CancelJob(job.ID, nil, job.AccessToken)
+	s.ErrorIs(err, services.ErrJobFinished)
+
+	// 2. Running jobs are interrupted and never retried
+	started := make(chan struct{}, 1)
+	s.startRunner(func(ctx context.Context, payload *models.AnalyzeJobPayload) (*models.AnalyzePromptResponse, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	job = s.enqueue(nil, false, 3)
+	<-started
+	s.waitFor(job.ID, func(job *models.Job) bool { return job.Status == models.JobStatusRunning })
+	_, err = s.jobSvc.CancelJob(job.ID, nil, job.AccessToken)
+	s.Require().NoError(err)
+
+	job = s.waitFinished(job.ID)
+	s.Equal(models.JobStatusCanceled, job.Status)
+	s.Equal(1, job.Attempts)
+}
+
+// TestJobWorkerConcurrency 测试同时执行的任务数不超过worker数量，且每个任务只执行一次
+func (s *JobServiceTestSuite) TestJobWorkerConcurrency() {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var calls int32
+	s.startRunner(func(ctx context.Context, payload *models.AnalyzeJobPayload) (*models.AnalyzePromptResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(30 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return fixedAnalysis(ctx, payload)
+	})
+
+	var ids []uint
+	for i := 0; i < 6; i++ {
+		ids = append(ids, s.enqueue(nil, false, 0).ID)
+	}
+	for _, id := range ids {
+		s.Equal(models.JobStatusSucceeded, s.waitFinished(id).Status)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	s.LessOrEqual(maxRunning, 2)
+	s.Equal(int32(6), atomic.LoadInt32(&calls))
+}
+
+// TestGetJobVisibility 测试他人的任务视为不存在，匿名任务只能凭访问令牌访问
+func (s *JobServiceTestSuite) TestGetJobVisibility() {
+	ownerID, otherID := uint(1), uint(2)
+	payload := &models.AnalyzeJobPayload{PromptText: "p", OutputImageURL: "/uploads/x.png"}
+	job, err := s.jobSvc.EnqueueAnalyzeJob(payload, nil, false, &ownerID, 0)
+	s.Require().NoError(err)
+	s.Equal(config.AppConfig.Jobs.MaxAttempts, job.MaxAttempts)
+	s.Empty(job.AccessToken, "有创建者的任务不需要访问令牌")
+
+	_, err = s.jobSvc.GetJob(job.ID, &ownerID, "")
+	s.NoError(err)
+	_, err = s.jobSvc.GetJob(job.ID, &otherID, "")
+	s.ErrorIs(err, services.ErrJobNotFound)
+	_, err = s.jobSvc.CancelJob(job.ID, nil, "")
+	s.ErrorIs(err, services.ErrJobNotFound)
+
+	anonymous := s.enqueue(nil, false, 0)
+	s.NotEmpty(anonymous.AccessToken)
+	_, err = s.jobSvc.GetJob(anonymous.ID, nil, "")
+	s.ErrorIs(err, services.ErrJobNotFound)
+	_, err = s.jobSvc.GetJob(anonymous.ID, &otherID, anonymous.AccessToken+"0")
+	s.ErrorIs(err, services.ErrJobNotFound)
+	_, err = s.jobSvc.CancelJob(anonymous.ID, nil, "")
+	s.ErrorIs(err, services.ErrJobNotFound)
+	found, err := s.jobSvc.GetJob(anonymous.ID, nil, anonymous.AccessToken)
+	s.NoError(err)
+	s.Empty(found.AccessToken, "令牌只在创建时返回")
+}
+
+// TestAnalyzeJobRechecksOwnership 测试应用结果前重新校验创建者对提示词的修改权限
+func (s *JobServiceTestSuite) TestAnalyzeJobRechecksOwnership() {
+	ownerID := uint(1)
+	prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "a portrait", OwnerID: &ownerID})
+	s.Require().NoError(err)
+	payload := &models.AnalyzeJobPayload{PromptText: "a portrait", OutputImageURL: "/uploads/x.png"}
+	job, err := s.jobSvc.EnqueueAnalyzeJob(payload, &prompt.ID, true, &ownerID, 3)
+	s.Require().NoError(err)
+
+	// 入队后提示词转给了其他用户
+	s.db.Model(&models.Prompt{}).Where("id = ?", prompt.ID).Update("owner_id", 2)
+	s.startRunner(fixedAnalysis)
+
+	job = s.waitFinished(job.ID)
+	s.Equal(models.JobStatusFailed, job.Status)
+	s.Equal(1, job.Attempts, "权限不足不再重试")
+	s.Nil(job.AppliedAt)
+	updated, err := s.service.GetPromptByID(prompt.ID)
+	s.Require().NoError(err)
+	s.Empty(updated.StyleDescription)
+}
+
+// TestJobUploadedFilesRemoved 测试任务结束后删除为任务上传的图片
+func (s *JobServiceTestSuite) TestJobUploadedFilesRemoved() {
+	uploadDir := s.T().TempDir()
+	originalUploadPath := config.AppConfig.Server.UploadPath
+	config.AppConfig.Server.UploadPath = uploadDir
+	defer func() { config.AppConfig.Server.UploadPath = originalUploadPath }()
+	uploaded := filepath.Join(uploadDir, "job.png")
+	kept := filepath.Join(uploadDir, "prompt.png")
+	s.Require().NoError(os.WriteFile(uploaded, []byte("job"), 0644))
+	s.Require().NoError(os.WriteFile(kept, []byte("prompt"), 0644))
+
+	s.startRunner(fixedAnalysis)
+	payload := &models.AnalyzeJobPayload{
+		PromptText:     "a portrait",
+		OutputImageURL: "/uploads/job.png",
+		InputImageURLs: []string{"/uploads/prompt.png"},
+		UploadedFiles:  []string{"job.png"},
+	}
+	job, err := s.jobSvc.EnqueueAnalyzeJob(payload, nil, false, nil, 0)
+	s.Require().NoError(err)
+
+	s.Equal(models.JobStatusSucceeded, s.waitFinished(job.ID).Status)
+	s.Eventually(func() bool { _, err := os.Stat(uploaded); return os.IsNotExist(err) }, 5*time.Second, 10*time.Millisecond)
+	s.FileExists(kept, "提示词已有的图片不应被删除")
+}
+
+// TestJobService 运行 JobService 测试套件
+func TestJobService(t *testing.T) {
+	suite.Run(t, new(JobServiceTestSuite))
+}
@@ -1,110 +1,292 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"imgGeneratePrompts/config"
 	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrPromptNotFound 提示词不存在
+var ErrPromptNotFound = errors.New("提示词不存在")
+
+// ErrOwnerlessPrivate 没有所有者的提示词不能设为私有
+var ErrOwnerlessPrivate = errors.New("没有所有者的提示词只能是公开的，请登录后创建私有提示词")
+
+// ErrUploadNotFound 上传的文件不存在，或当前用户不能查看引用它的提示词
+var ErrUploadNotFound = errors.New("文件不存在")
+
+// ErrVersionConflict 提示词已被他人修改（If-Match 与当前版本不一致）
+var ErrVersionConflict = errors.New("提示词已被修改，请重新获取后再提交")
+
+// exportBatchSize 导出时每批读取的提示词数量
+const exportBatchSize = 200
+
+// maxTransactionAttempts 事务因死锁被回滚时的最大尝试次数
+const maxTransactionAttempts = 3
+
 // PromptService 提示词服务
 type PromptService struct {
-	db         *gorm.DB
-	tagService *TagService
+	db              *gorm.DB
+	tagService      *TagService
+	wildcardService *WildcardService
 }
 
 // NewPromptService 创建提示词服务实例
 func NewPromptService() *PromptService {
 	return &PromptService{
-		db:         config.GetDB(),
-		tagService: NewTagService(),
+		db:              config.GetDB(),
+		tagService:      NewTagService(),
+		wildcardService: NewWildcardService(),
+	}
+}
+
+// WithTx 返回使用指定事务的提示词服务，用于在调用方的事务中读写提示词
+func (s *PromptService) WithTx(tx *gorm.DB) *PromptService {
+	return &PromptService{
+		db:              tx,
+		tagService:      s.tagService.WithTx(tx),
+		wildcardService: s.wildcardService,
 	}
 }
 
 // CreatePrompt 创建提示词（兼容旧版本）
 func (s *PromptService) CreatePrompt(req *models.CreatePromptRequest, imageURL string) (*models.Prompt, error) {
-	// 处理标签
-	tags, err := s.tagService.GetOrCreateTags(req.TagNames)
-	if err != nil {
-		return nil, fmt.Errorf("处理标签失败: %v", err)
+	return s.createPrompt(req, imageURL)
+}
+
+// createPrompt 在一个事务中创建标签和提示词并重新读取，任何一步失败都不会留下孤立的标签
+// imageURL 在请求未指定输出图片时作为输出图片
+func (s *PromptService) createPrompt(req *models.CreatePromptRequest, imageURL string) (*models.Prompt, error) {
+	var downloaded []string
+	if req.FetchRemote {
+		var err error
+		if req, downloaded, err = fetchRemoteImages(req); err != nil {
+			return nil, err
+		}
 	}
 
-	prompt := &models.Prompt{
-		PromptText:            req.PromptText,
-		NegativePrompt:        req.NegativePrompt,
-		ModelName:             req.ModelName,
-		OutputImageURL:        req.OutputImageURL,
-		IsPublic:              req.IsPublic,
-		StyleDescription:      req.StyleDescription,
-		UsageScenario:         req.UsageScenario,
-		AtmosphereDescription: req.AtmosphereDescription,
-		ExpressiveIntent:      req.ExpressiveIntent,
-		// --- FIX: Convert string from request to []byte for json.RawMessage ---
-		StructureAnalysis: []byte(req.StructureAnalysis),
-		Tags:              tags,
+	var prompt *models.Prompt
+	var createdTags []*models.Tag
+	err := runTransaction(s.db, func(tx *gorm.DB) error {
+		// 处理标签
+		tags, created, err := s.tagService.WithTx(tx).getOrCreateTags(req.TagNames)
+		createdTags = created
+		if err != nil {
+			return fmt.Errorf("处理标签失败: %w", err)
+		}
+
+		prompt = newPromptFromRequest(req, tags)
+		if prompt.GetOutputImageURL() == "" {
+			prompt.SetOutputImageURL(imageURL)
+		}
+		fillLocalImageInfo(prompt.Images)
+
+		if err := tx.Create(prompt).Error; err != nil {
+			return fmt.Errorf("创建提示词失败: %w", err)
+		}
+
+		// 预加载标签和图片信息
+		if err := tx.Preload("Tags").Preload("Images", models.OrderedImages).First(prompt, prompt.ID).Error; err != nil {
+			return fmt.Errorf("获取创建的提示词失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		deleteUploadedFiles(downloaded)
+		return nil, err
 	}
 
-	// 设置输入图片URLs
-	prompt.SetInputImageURLs(req.InputImageURLs)
+	publishTagsCreated(createdTags)
+	publishPromptEvent(models.EventPromptCreated, prompt)
+	return prompt, nil
+}
 
-	// 如果没有指定输出图片，使用传入的imageURL作为输出图片
-	if prompt.OutputImageURL == "" {
-		prompt.OutputImageURL = imageURL
+// fetchRemoteImages 将请求中的 http/https 图片下载到上传目录，返回改写为本地URL的请求副本和已下载的文件名
+// 远程图片超过数量限制或任意一张下载失败时删除已下载的文件并返回 utils.ErrRemoteImage
+func fetchRemoteImages(req *models.CreatePromptRequest) (*models.CreatePromptRequest, []string, error) {
+	cfg := config.AppConfig.Server
+	fetcher := utils.NewRemoteImageFetcher(cfg.MaxFileSize, cfg.RemoteFetchTimeout, cfg.RemoteFetchMaxRedirects)
+	fetcher.AllowPrivateNetworks = cfg.RemoteFetchAllowPrivate
+	ctx := req.Context
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	result := s.db.Create(prompt)
-	if result.Error != nil {
-		return nil, fmt.Errorf("创建提示词失败: %v", result.Error)
+	// 下载前先检查数量，避免一个请求占用大量下载时间和磁盘
+	remote := make(map[string]bool)
+	for _, url := range append(append([]string{req.OutputImageURL}, req.InputImageURLs...), requestImageURLs(req.Images)...) {
+		if url = strings.TrimSpace(url); utils.IsRemoteURL(url) {
+			remote[url] = true
+		}
+	}
+	if cfg.RemoteFetchMaxImages > 0 && len(remote) > cfg.RemoteFetchMaxImages {
+		return nil, nil, fmt.Errorf("%w: 远程图片超过 %d 张", utils.ErrRemoteImage, cfg.RemoteFetchMaxImages)
 	}
 
-	// 预加载标签信息
-	if err := s.db.Preload("Tags").First(prompt, prompt.ID).Error; err != nil {
-		return nil, fmt.Errorf("获取创建的提示词失败: %v", err)
+	var downloaded []string
+	local := make(map[string]string) // 同一URL只下载一次
+	localize := func(url string) (string, error) {
+		url = strings.TrimSpace(url)
+		if !utils.IsRemoteURL(url) {
+			return url, nil
+		}
+		if localURL, ok := local[url]; ok {
+			return localURL, nil
+		}
+		filename, err := fetcher.Fetch(ctx, url, cfg.UploadPath)
+		if err != nil {
+			return "", err
+		}
+		downloaded = append(downloaded, filename)
+		local[url] = "/uploads/" + filename
+		return local[url], nil
 	}
 
-	return prompt, nil
+	copied := *req
+	var err error
+	copied.InputImageURLs = make([]string, len(req.InputImageURLs))
+	for i, url := range req.InputImageURLs {
+		if copied.InputImageURLs[i], err = localize(url); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		copied.OutputImageURL, err = localize(req.OutputImageURL)
+	}
+	if err == nil && req.Images != nil {
+		copied.Images = make([]models.PromptImageInput, len(req.Images))
+		for i, image := range req.Images {
+			copied.Images[i] = image
+			if copied.Images[i].URL, err = localize(image.URL); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		deleteUploadedFiles(downloaded)
+		return nil, nil, err
+	}
+	return &copied, downloaded, nil
 }
 
-// CreatePromptWithImages 创建提示词（新版本，支持多图片）
-func (s *PromptService) CreatePromptWithImages(req *models.CreatePromptRequest) (*models.Prompt, error) {
-	// 处理标签
-	tags, err := s.tagService.GetOrCreateTags(req.TagNames)
-	if err != nil {
-		return nil, fmt.Errorf("处理标签失败: %v", err)
+// requestImageURLs 请求中其他图片的URL
+func requestImageURLs(images []models.PromptImageInput) []string {
+	urls := make([]string, len(images))
+	for i, image := range images {
+		urls[i] = image.URL
 	}
+	return urls
+}
 
+// deleteUploadedFiles 删除上传目录中的文件
+func deleteUploadedFiles(filenames []string) {
+	for _, filename := range filenames {
+		utils.DeleteFile(filepath.Join(config.AppConfig.Server.UploadPath, filename))
+	}
+}
+
+// newPromptFromRequest 根据创建请求和已处理的标签构造提示词模型
+// 未登录时创建的提示词没有所有者，总是公开的，否则将无人可见
+func newPromptFromRequest(req *models.CreatePromptRequest, tags []*models.Tag) *models.Prompt {
 	prompt := &models.Prompt{
 		PromptText:            req.PromptText,
 		NegativePrompt:        req.NegativePrompt,
 		ModelName:             req.ModelName,
-		OutputImageURL:        req.OutputImageURL,
-		IsPublic:              req.IsPublic,
+		IsPublic:              req.IsPublic || req.OwnerID == nil,
 		StyleDescription:      req.StyleDescription,
 		UsageScenario:         req.UsageScenario,
 		AtmosphereDescription: req.AtmosphereDescription,
 		ExpressiveIntent:      req.ExpressiveIntent,
 		// --- FIX: Convert string from request to []byte for json.RawMessage ---
 		StructureAnalysis: []byte(req.StructureAnalysis),
+		GenerationParams:  []byte(req.GenerationParams),
+		OwnerID:           req.OwnerID,
 		Tags:              tags,
+		Images:            requestImages(req.InputImageURLs, req.OutputImageURL, req.Images),
+	}
+	return prompt
+}
+
+// requestImages 将请求中的输入图片URL、输出图片URL和其他图片合并为图片记录
+func requestImages(inputImageURLs []string, outputImageURL string, images []models.PromptImageInput) []*models.PromptImage {
+	inputs := make([]models.PromptImageInput, 0, len(inputImageURLs)+1+len(images))
+	for _, url := range inputImageURLs {
+		inputs = append(inputs, models.PromptImageInput{Role: models.ImageRoleInput, URL: url})
 	}
+	inputs = append(inputs, models.PromptImageInput{Role: models.ImageRoleOutput, URL: outputImageURL})
+	return models.NewPromptImages(append(inputs, images...))
+}
 
-	// 设置输入图片URLs（多个图片以逗号分隔存储）
-	prompt.SetInputImageURLs(req.InputImageURLs)
+// replacePromptImages 替换提示词的图片，roles 非空时只替换这些角色的图片
+func replacePromptImages(tx *gorm.DB, promptID uint, images []*models.PromptImage, roles ...string) error {
+	db := tx.Where("prompt_id = ?", promptID)
+	if len(roles) > 0 {
+		db = db.Where("role IN ?", roles)
+	}
+	if err := db.Delete(&models.PromptImage{}).Error; err != nil {
+		return fmt.Errorf("删除原有图片失败: %w", err)
+	}
+	if len(images) == 0 {
+		return nil
+	}
 
-	result := s.db.Create(prompt)
-	if result.Error != nil {
-		return nil, fmt.Errorf("创建提示词失败: %v", result.Error)
+	fillLocalImageInfo(images)
+	for _, image := range images {
+		image.ID = 0
+		image.PromptID = promptID
+	}
+	if err := tx.Create(&images).Error; err != nil {
+		return fmt.Errorf("保存图片失败: %w", err)
 	}
+	return nil
+}
 
-	// 预加载标签信息
-	if err := s.db.Preload("Tags").First(prompt, prompt.ID).Error; err != nil {
-		return nil, fmt.Errorf("获取创建的提示词失败: %v", err)
+// fillLocalImageInfo 为上传目录中的图片补充尺寸和内容哈希，已提供的值保持不变
+func fillLocalImageInfo(images []*models.PromptImage) {
+	for _, image := range images {
+		if image.Hash != "" && image.Width > 0 && image.Height > 0 {
+			continue
+		}
+		if !strings.HasPrefix(image.URL, "/uploads/") {
+			continue
+		}
+		info, err := utils.InspectImageFile(filepath.Join(config.AppConfig.Server.UploadPath, filepath.Base(image.URL)))
+		if err != nil {
+			continue
+		}
+		if image.Hash == "" {
+			image.Hash = info.Hash
+		}
+		if image.Width == 0 && image.Height == 0 {
+			image.Width, image.Height = info.Width, info.Height
+		}
 	}
+}
 
-	return prompt, nil
+// generationParamsValue 生成参数的更新值：空字符串清除为 NULL
+// 按 map 更新时不会触发模型的 BeforeSave 钩子，因此在这里处理空值
+func generationParamsValue(params string) interface{} {
+	if strings.TrimSpace(params) == "" {
+		return gorm.Expr("NULL")
+	}
+	return []byte(params)
+}
+
+// CreatePromptWithImages 创建提示词（新版本，支持多图片）
+func (s *PromptService) CreatePromptWithImages(req *models.CreatePromptRequest) (*models.Prompt, error) {
+	return s.createPrompt(req, "")
 }
 
 // AnalyzePromptData AI分析图片和提示词，返回建议内容
@@ -171,135 +353,752 @@ func (s *PromptService) AnalyzePromptData(promptText, modelName, outputImageBase
 	return mockResponse, nil
 }
 
+// ApplyAnalysis 以 userID 的身份将分析结果应用到提示词：只填充为空的描述字段，标签与已有标签合并
+// 以读取时的版本作为 If-Match，期间提示词被修改时返回 ErrVersionConflict
+func (s *PromptService) ApplyAnalysis(id uint, userID *uint, analysis *models.AnalyzePromptResponse) (*models.Prompt, error) {
+	prompt, err := s.GetPromptByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !prompt.CanBeModifiedBy(userID) {
+		return nil, ErrPermissionDenied
+	}
+
+	// 提交时校验版本号，检查之后提示词被修改会返回 ErrVersionConflict
+	req := &models.UpdatePromptRequest{IfMatch: []uint{prompt.Version}}
+	fill := func(current, suggested string) *string {
+		if strings.TrimSpace(current) != "" || strings.TrimSpace(suggested) == "" {
+			return nil
+		}
+		return &suggested
+	}
+	req.NegativePrompt = fill(prompt.NegativePrompt, analysis.NegativePrompt)
+	req.StyleDescription = fill(prompt.StyleDescription, analysis.StyleDescription)
+	req.UsageScenario = fill(prompt.UsageScenario, analysis.UsageScenario)
+	req.AtmosphereDescription = fill(prompt.AtmosphereDescription, analysis.AtmosphereDescription)
+	req.ExpressiveIntent = fill(prompt.ExpressiveIntent, analysis.ExpressiveIntent)
+	structure := string(prompt.StructureAnalysis)
+	if structure == "null" {
+		structure = ""
+	}
+	req.StructureAnalysis = fill(structure, analysis.StructureAnalysis)
+
+	if len(analysis.TagNames) > 0 {
+		tagNames := make([]string, 0, len(prompt.Tags)+len(analysis.TagNames))
+		for _, tag := range prompt.Tags {
+			tagNames = append(tagNames, tag.Name)
+		}
+		req.TagNames = append(tagNames, analysis.TagNames...)
+	}
+	return s.UpdatePrompt(id, req)
+}
+
 // GetPromptByID 根据ID获取提示词
 func (s *PromptService) GetPromptByID(id uint) (*models.Prompt, error) {
 	var prompt models.Prompt
-	result := s.db.Preload("Tags").First(&prompt, id)
+	result := s.db.Preload("Tags").Preload("Images", models.OrderedImages).First(&prompt, id)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("提示词不存在")
+			return nil, ErrPromptNotFound
 		}
 		return nil, fmt.Errorf("获取提示词失败: %v", result.Error)
 	}
 	return &prompt, nil
 }
 
+// GetVisiblePrompt 获取对指定用户可见的提示词，他人的私有提示词视为不存在
+func (s *PromptService) GetVisiblePrompt(id uint, viewerID *uint) (*models.Prompt, error) {
+	prompt, err := s.GetPromptByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !prompt.IsVisibleTo(viewerID) {
+		return nil, ErrPromptNotFound
+	}
+	return prompt, nil
+}
+
+// ResolveUploadedFile 返回上传文件的本地路径
+// 只有当前用户可见的提示词或收藏集封面引用的文件才能访问，其余（包括后台任务的临时文件）一律视为不存在
+func (s *PromptService) ResolveUploadedFile(filename string, viewerID *uint) (string, error) {
+	if filename != filepath.Base(filename) || strings.HasPrefix(filename, ".") {
+		return "", ErrUploadNotFound
+	}
+	fileURL := "/uploads/" + filename
+
+	var ids []uint
+	if err := s.db.Model(&models.PromptImage{}).
+		Joins("JOIN prompts ON prompts.id = prompt_images.prompt_id AND prompts.deleted_at IS NULL").
+		Scopes(visibleTo(viewerID)).Where("prompt_images.url = ?", fileURL).
+		Limit(1).Pluck("prompt_images.id", &ids).Error; err != nil {
+		return "", fmt.Errorf("获取图片失败: %v", err)
+	}
+	if len(ids) == 0 {
+		if err := s.db.Model(&models.Collection{}).Scopes(collectionVisibleTo(viewerID)).
+			Where("collections.cover_image_url = ?", fileURL).Limit(1).Pluck("collections.id", &ids).Error; err != nil {
+			return "", fmt.Errorf("获取收藏集失败: %v", err)
+		}
+	}
+	if len(ids) == 0 {
+		return "", ErrUploadNotFound
+	}
+
+	path := filepath.Join(config.AppConfig.Server.UploadPath, filename)
+	if !utils.FileExists(path) {
+		return "", ErrUploadNotFound
+	}
+	return path, nil
+}
+
+// GetVisiblePromptsByIDs 批量获取对指定用户可见的提示词（不预加载关联），不存在或不可见的ID不在结果中
+func (s *PromptService) GetVisiblePromptsByIDs(ids []uint, viewerID *uint) (map[uint]*models.Prompt, error) {
+	result := make(map[uint]*models.Prompt, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var prompts []*models.Prompt
+	if err := s.db.Scopes(visibleTo(viewerID)).Where("prompts.id IN ?", ids).Find(&prompts).Error; err != nil {
+		return nil, fmt.Errorf("获取提示词失败: %v", err)
+	}
+	for _, prompt := range prompts {
+		result[prompt.ID] = prompt
+	}
+	return result, nil
+}
+
+// GetImagesByPromptIDs 批量获取提示词的图片，按提示词ID分组并保持图片顺序
+func (s *PromptService) GetImagesByPromptIDs(ids []uint) (map[uint][]*models.PromptImage, error) {
+	result := make(map[uint][]*models.PromptImage, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var images []*models.PromptImage
+	if err := s.db.Scopes(models.OrderedImages).Where("prompt_id IN ?", ids).Find(&images).Error; err != nil {
+		return nil, fmt.Errorf("获取提示词图片失败: %v", err)
+	}
+	for _, image := range images {
+		result[image.PromptID] = append(result[image.PromptID], image)
+	}
+	return result, nil
+}
+
+// CountVisibleForksByPromptIDs 批量统计提示词被分叉的次数（只统计对指定用户可见的分叉）
+func (s *PromptService) CountVisibleForksByPromptIDs(ids []uint, viewerID *uint) (map[uint]int64, error) {
+	result := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	type forkCount struct {
+		ParentID uint
+		Count    int64
+	}
+	var counts []forkCount
+	err := s.db.Model(&models.Prompt{}).
+		Select("prompts.parent_id, COUNT(*) as count").
+		Scopes(visibleTo(viewerID)).
+		Where("prompts.parent_id IN ?", ids).
+		Group("prompts.parent_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计分叉数量失败: %v", err)
+	}
+	for _, c := range counts {
+		result[c.ParentID] = c.Count
+	}
+	return result, nil
+}
+
 // UpdatePrompt 更新提示词
+// 字段和标签在同一个事务中更新，并锁定提示词行，使并发更新依次执行
 func (s *PromptService) UpdatePrompt(id uint, req *models.UpdatePromptRequest) (*models.Prompt, error) {
-	prompt, err := s.GetPromptByID(id)
+	var updated *models.Prompt
+	var createdTags []*models.Tag
+	err := runTransaction(s.db, func(tx *gorm.DB) error {
+		createdTags = nil
+		var prompt models.Prompt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&prompt, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPromptNotFound
+			}
+			return fmt.Errorf("获取提示词失败: %w", err)
+		}
+		if len(req.IfMatch) > 0 && !versionMatches(prompt.Version, req.IfMatch) {
+			return ErrVersionConflict
+		}
+
+		// 更新字段，包括标签在内的任何修改都会使版本号加一
+		updates := map[string]interface{}{"version": gorm.Expr("version + 1")}
+
+		if req.PromptText != nil {
+			updates["prompt_text"] = *req.PromptText
+		}
+		if req.NegativePrompt != nil {
+			updates["negative_prompt"] = *req.NegativePrompt
+		}
+		if req.ModelName != nil {
+			updates["model_name"] = *req.ModelName
+		}
+		if req.IsPublic != nil {
+			if !*req.IsPublic && prompt.OwnerID == nil {
+				return ErrOwnerlessPrivate
+			}
+			updates["is_public"] = *req.IsPublic
+		}
+		if req.StyleDescription != nil {
+			updates["style_description"] = *req.StyleDescription
+		}
+		if req.UsageScenario != nil {
+			updates["usage_scenario"] = *req.UsageScenario
+		}
+		if req.AtmosphereDescription != nil {
+			updates["atmosphere_description"] = *req.AtmosphereDescription
+		}
+		if req.ExpressiveIntent != nil {
+			updates["expressive_intent"] = *req.ExpressiveIntent
+		}
+		if req.StructureAnalysis != nil {
+			// --- FIX: Convert string pointer from request to []byte for json.RawMessage ---
+			updates["structure_analysis"] = []byte(*req.StructureAnalysis)
+		}
+		if req.GenerationParams != nil {
+			updates["generation_params"] = generationParamsValue(*req.GenerationParams)
+		}
+
+		if err := tx.Model(&prompt).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新提示词失败: %w", err)
+		}
+
+		// 处理图片更新：images 替换全部图片，input_image_urls / output_image_url 分别替换对应角色的图片
+		if req.Images != nil {
+			if err := replacePromptImages(tx, id, models.NewPromptImages(req.Images)); err != nil {
+				return err
+			}
+		} else {
+			if len(req.InputImageURLs) > 0 {
+				if err := replacePromptImages(tx, id, requestImages(req.InputImageURLs, "", nil), models.ImageRoleInput); err != nil {
+					return err
+				}
+			}
+			if req.OutputImageURL != nil {
+				if err := replacePromptImages(tx, id, requestImages(nil, *req.OutputImageURL, nil), models.ImageRoleOutput); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 处理标签更新
+		if len(req.TagNames) > 0 {
+			tags, created, err := s.tagService.WithTx(tx).getOrCreateTags(req.TagNames)
+			createdTags = created
+			if err != nil {
+				return fmt.Errorf("处理标签失败: %w", err)
+			}
+
+			// 替换关联的标签
+			if err := tx.Model(&prompt).Association("Tags").Replace(tags); err != nil {
+				return fmt.Errorf("更新标签关联失败: %w", err)
+			}
+		} else if req.TagNames != nil {
+			// 如果传入空数组，清除所有标签
+			if err := tx.Model(&prompt).Association("Tags").Clear(); err != nil {
+				return fmt.Errorf("清除标签关联失败: %w", err)
+			}
+		}
+
+		// 重新获取更新后的数据
+		var err error
+		updated, err = s.WithTx(tx).GetPromptByID(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTagsCreated(createdTags)
+	publishPromptEvent(models.EventPromptUpdated, updated)
+	return updated, nil
+}
+
+// PatchPrompt 以 JSON Merge Patch（RFC 7396）或 JSON Patch（RFC 6902）部分更新提示词
+// 补丁作用于与 PromptResponse 字段一致的文档（包括 tag_names 和 input_image_urls 列表），
+// 值为 null 或被移除的字段会被清空；ifMatch 非空时当前版本必须是其中之一
+func (s *PromptService) PatchPrompt(id uint, contentType string, patch []byte, ifMatch []uint) (*models.Prompt, error) {
+	var updated *models.Prompt
+	var changed bool
+	var createdTags []*models.Tag
+	err := runTransaction(s.db, func(tx *gorm.DB) error {
+		changed, createdTags = false, nil
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Prompt{}, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPromptNotFound
+			}
+			return fmt.Errorf("获取提示词失败: %w", err)
+		}
+		prompt, err := s.WithTx(tx).GetPromptByID(id)
+		if err != nil {
+			return err
+		}
+		if len(ifMatch) > 0 && !versionMatches(prompt.Version, ifMatch) {
+			return ErrVersionConflict
+		}
+
+		before, err := promptPatchDocument(prompt)
+		if err != nil {
+			return err
+		}
+		doc, err := promptPatchDocument(prompt)
+		if err != nil {
+			return err
+		}
+		var patched interface{}
+		if contentType == utils.JSONPatchContentType {
+			patched, err = utils.ApplyJSONPatch(doc, patch)
+		} else {
+			patched, err = utils.ApplyMergePatch(doc, patch)
+		}
+		if err != nil {
+			return err
+		}
+		after, ok := patched.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: 补丁结果必须是JSON对象", utils.ErrInvalidPatch)
+		}
+
+		changes, err := promptPatchChanges(before, after)
+		if err != nil {
+			return err
+		}
+		if changes.empty() {
+			updated = prompt
+			return nil
+		}
+		if isPublic, ok := changes.updates["is_public"].(bool); ok && !isPublic && prompt.OwnerID == nil {
+			return ErrOwnerlessPrivate
+		}
+		changed = true
+
+		// 使用不带关联的模型更新，避免 GORM 回写已加载的标签和图片
+		changes.updates["version"] = gorm.Expr("version + 1")
+		if err := tx.Model(&models.Prompt{ID: id}).Updates(changes.updates).Error; err != nil {
+			return fmt.Errorf("更新提示词失败: %w", err)
+		}
+		if changes.tagNames != nil {
+			tags, created, err := s.tagService.WithTx(tx).getOrCreateTags(changes.tagNames)
+			createdTags = created
+			if err != nil {
+				return fmt.Errorf("处理标签失败: %w", err)
+			}
+			if err := tx.Model(&models.Prompt{ID: id}).Association("Tags").Replace(tags); err != nil {
+				return fmt.Errorf("更新标签关联失败: %w", err)
+			}
+		}
+		for role, images := range changes.images {
+			if err := replacePromptImages(tx, id, images, role); err != nil {
+				return err
+			}
+		}
+
+		updated, err = s.WithTx(tx).GetPromptByID(id)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// 更新字段
-	updates := make(map[string]interface{})
+	if changed {
+		publishTagsCreated(createdTags)
+		publishPromptEvent(models.EventPromptUpdated, updated)
+	}
+	return updated, nil
+}
+
+// promptPatchStringFields 补丁中可以修改的字符串字段（同时也是数据库列名）
+var promptPatchStringFields = map[string]bool{
+	"prompt_text":            true,
+	"negative_prompt":        true,
+	"model_name":             true,
+	"style_description":      true,
+	"usage_scenario":         true,
+	"atmosphere_description": true,
+	"expressive_intent":      true,
+}
+
+// promptPatchChangeSet 补丁产生的修改
+type promptPatchChangeSet struct {
+	updates  map[string]interface{}           // 列更新
+	tagNames []string                         // 新的标签列表，标签未变化时为 nil
+	images   map[string][]*models.PromptImage // 按角色替换的图片
+}
+
+// empty 判断补丁是否没有产生任何修改
+func (c *promptPatchChangeSet) empty() bool {
+	return len(c.updates) == 0 && c.tagNames == nil && len(c.images) == 0
+}
 
-	if req.PromptText != nil {
-		updates["prompt_text"] = *req.PromptText
+// promptPatchDocument 构造补丁作用的文档，字段与 PromptResponse 一致，只包含可修改的字段
+func promptPatchDocument(p *models.Prompt) (map[string]interface{}, error) {
+	tagNames := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		tagNames[i] = tag.Name
+	}
+	doc := map[string]interface{}{
+		"prompt_text":            p.PromptText,
+		"negative_prompt":        p.NegativePrompt,
+		"model_name":             p.ModelName,
+		"is_public":              p.IsPublic,
+		"style_description":      p.StyleDescription,
+		"usage_scenario":         p.UsageScenario,
+		"atmosphere_description": p.AtmosphereDescription,
+		"expressive_intent":      p.ExpressiveIntent,
+		"input_image_urls":       p.GetInputImageURLs(),
+		"output_image_url":       p.GetOutputImageURL(),
+		"tag_names":              tagNames,
+	}
+	if json.Valid(p.StructureAnalysis) {
+		doc["structure_analysis"] = p.StructureAnalysis
+	} else {
+		doc["structure_analysis"] = json.RawMessage("{}")
 	}
-	if req.NegativePrompt != nil {
-		updates["negative_prompt"] = *req.NegativePrompt
+	if len(p.GenerationParams) > 0 && json.Valid(p.GenerationParams) {
+		doc["generation_params"] = p.GenerationParams
 	}
-	if req.ModelName != nil {
-		updates["model_name"] = *req.ModelName
+
+	normalized, err := utils.NormalizeJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("构造补丁文档失败: %w", err)
 	}
-	if req.IsPublic != nil {
-		updates["is_public"] = *req.IsPublic
+	return normalized.(map[string]interface{}), nil
+}
+
+// promptPatchChanges 比较补丁前后的文档，生成列更新、新的标签列表和需要替换的图片
+// 值为 null 或字段被移除表示清空：字符串清空为 ""、is_public 为 false、列表清空、JSON字段恢复默认值
+// input_image_urls 和 output_image_url 分别替换全部输入图片和输出图片
+func promptPatchChanges(before, after map[string]interface{}) (*promptPatchChangeSet, error) {
+	for key := range after {
+		if _, ok := before[key]; !ok && key != "generation_params" {
+			return nil, fmt.Errorf("%w: 不支持修改字段 %s", utils.ErrInvalidPatch, key)
+		}
 	}
-	if req.StyleDescription != nil {
-		updates["style_description"] = *req.StyleDescription
+
+	changes := &promptPatchChangeSet{
+		updates: make(map[string]interface{}),
+		images:  make(map[string][]*models.PromptImage),
 	}
-	if req.UsageScenario != nil {
-		updates["usage_scenario"] = *req.UsageScenario
+	updates := changes.updates
+	for key, old := range before {
+		value, present := after[key]
+		if present && reflect.DeepEqual(value, old) {
+			continue
+		}
+		cleared := !present || value == nil
+
+		switch {
+		case promptPatchStringFields[key]:
+			if cleared {
+				value = ""
+			}
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s 必须是字符串", utils.ErrInvalidPatch, key)
+			}
+			if key == "prompt_text" && strings.TrimSpace(text) == "" {
+				return nil, fmt.Errorf("%w: prompt_text 不能为空", utils.ErrInvalidPatch)
+			}
+			updates[key] = text
+
+		case key == "is_public":
+			if cleared {
+				value = false
+			}
+			isPublic, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%w: is_public 必须是布尔值", utils.ErrInvalidPatch)
+			}
+			updates[key] = isPublic
+
+		case key == "structure_analysis":
+			if cleared {
+				value = map[string]interface{}{}
+			}
+			if _, ok := value.(map[string]interface{}); !ok {
+				return nil, fmt.Errorf("%w: structure_analysis 必须是JSON对象", utils.ErrInvalidPatch)
+			}
+			data, _ := json.Marshal(value)
+			updates[key] = data
+
+		case key == "output_image_url":
+			if cleared {
+				value = ""
+			}
+			url, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: output_image_url 必须是字符串", utils.ErrInvalidPatch)
+			}
+			changes.images[models.ImageRoleOutput] = requestImages(nil, url, nil)
+
+		case key == "input_image_urls":
+			urls, err := patchStringList(key, value, cleared)
+			if err != nil {
+				return nil, err
+			}
+			changes.images[models.ImageRoleInput] = requestImages(urls, "", nil)
+
+		case key == "tag_names":
+			names, err := patchStringList(key, value, cleared)
+			if err != nil {
+				return nil, err
+			}
+			changes.tagNames = append([]string{}, names...)
+		}
 	}
-	if req.AtmosphereDescription != nil {
-		updates["atmosphere_description"] = *req.AtmosphereDescription
+
+	// generation_params 可能原本不存在
+	if value, present := after["generation_params"]; !reflect.DeepEqual(value, before["generation_params"]) {
+		if !present || value == nil {
+			updates["generation_params"] = gorm.Expr("NULL")
+		} else if _, ok := value.(map[string]interface{}); ok {
+			data, _ := json.Marshal(value)
+			updates["generation_params"] = data
+		} else {
+			return nil, fmt.Errorf("%w: generation_params 必须是JSON对象", utils.ErrInvalidPatch)
+		}
 	}
-	if req.ExpressiveIntent != nil {
-		updates["expressive_intent"] = *req.ExpressiveIntent
+
+	return changes, nil
+}
+
+// patchStringList 将补丁中的列表字段转换为字符串列表
+func patchStringList(key string, value interface{}, cleared bool) ([]string, error) {
+	if cleared {
+		return []string{}, nil
 	}
-	if req.StructureAnalysis != nil {
-		// --- FIX: Convert string pointer from request to []byte for json.RawMessage ---
-		updates["structure_analysis"] = []byte(*req.StructureAnalysis)
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s 必须是字符串数组", utils.ErrInvalidPatch, key)
 	}
-	if req.OutputImageURL != nil {
-		updates["output_image_url"] = *req.OutputImageURL
+	list := make([]string, len(items))
+	for i, item := range items {
+		text, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s 必须是字符串数组", utils.ErrInvalidPatch, key)
+		}
+		list[i] = text
 	}
-	if len(req.InputImageURLs) > 0 {
-		// 创建一个临时Prompt对象来使用SetInputImageURLs方法
-		tempPrompt := &models.Prompt{}
-		tempPrompt.SetInputImageURLs(req.InputImageURLs)
-		updates["input_image_url"] = tempPrompt.InputImageURL
+	return list, nil
+}
+
+// ForkPrompt 分叉提示词：复制提示词、标签和图片为一条新的可编辑记录，并记录父提示词
+// 分叉后的记录归属于发起分叉的用户
+func (s *PromptService) ForkPrompt(id uint, userID *uint) (*models.Prompt, error) {
+	source, err := s.GetVisiblePrompt(id, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(updates) > 0 {
-		result := s.db.Model(prompt).Updates(updates)
-		if result.Error != nil {
-			return nil, fmt.Errorf("更新提示词失败: %v", result.Error)
+	// 复制本地图片文件，使分叉后的记录可以独立修改和删除图片
+	uploadPath := config.AppConfig.Server.UploadPath
+	var copiedFiles []string
+	cleanup := func() {
+		for _, url := range copiedFiles {
+			utils.DeleteFile(filepath.Join(uploadPath, filepath.Base(url)))
+		}
+	}
+	duplicate := func(url string) (string, error) {
+		newURL, copied, err := utils.DuplicateUploadedFile(url, uploadPath)
+		if err != nil {
+			return "", err
+		}
+		if copied {
+			copiedFiles = append(copiedFiles, newURL)
 		}
+		return newURL, nil
 	}
 
-	// 处理标签更新
-	if req.TagNames != nil && len(req.TagNames) > 0 {
-		tags, err := s.tagService.GetOrCreateTags(req.TagNames)
+	images := make([]*models.PromptImage, len(source.Images))
+	for i, image := range source.Images {
+		url, err := duplicate(image.URL)
 		if err != nil {
-			return nil, fmt.Errorf("处理标签失败: %v", err)
+			cleanup()
+			return nil, fmt.Errorf("复制图片失败: %v", err)
+		}
+		images[i] = &models.PromptImage{
+			Role:     image.Role,
+			Position: image.Position,
+			URL:      url,
+			Caption:  image.Caption,
+			Width:    image.Width,
+			Height:   image.Height,
+			Hash:     image.Hash,
+		}
+	}
+
+	parentID := source.ID
+	fork := &models.Prompt{
+		PromptText:            source.PromptText,
+		NegativePrompt:        source.NegativePrompt,
+		ModelName:             source.ModelName,
+		IsPublic:              source.IsPublic,
+		StyleDescription:      source.StyleDescription,
+		UsageScenario:         source.UsageScenario,
+		AtmosphereDescription: source.AtmosphereDescription,
+		ExpressiveIntent:      source.ExpressiveIntent,
+		StructureAnalysis:     source.StructureAnalysis,
+		GenerationParams:      source.GenerationParams,
+		ParentID:              &parentID,
+		OwnerID:               userID,
+		Tags:                  source.Tags,
+		Images:                images,
+	}
+
+	if err := s.db.Create(fork).Error; err != nil {
+		cleanup()
+		return nil, fmt.Errorf("分叉提示词失败: %v", err)
+	}
+
+	created, err := s.GetPromptByID(fork.ID)
+	if err != nil {
+		return nil, err
+	}
+	publishPromptEvent(models.EventPromptCreated, created)
+	return created, nil
+}
+
+// GetPromptLineage 获取提示词的衍生关系：祖先链和子孙树（不包含他人的私有提示词）
+func (s *PromptService) GetPromptLineage(id uint, viewerID *uint) (*models.PromptLineageResponse, error) {
+	prompt, err := s.GetVisiblePrompt(id, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := &models.PromptLineageResponse{
+		Prompt:      prompt.ToLineageNode(),
+		Ancestors:   []*models.PromptLineageNode{},
+		Descendants: []*models.PromptLineageNode{},
+	}
+
+	// 向上查找祖先，visited用于防止异常数据导致的循环
+	visited := map[uint]bool{prompt.ID: true}
+	parentID := prompt.ParentID
+	for parentID != nil && !visited[*parentID] {
+		var parent models.Prompt
+		if err := s.db.Preload("Images", models.OrderedImages).First(&parent, *parentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break // 父提示词已被删除
+			}
+			return nil, fmt.Errorf("获取祖先提示词失败: %v", err)
+		}
+		visited[parent.ID] = true
+		if parent.IsVisibleTo(viewerID) {
+			lineage.Ancestors = append([]*models.PromptLineageNode{parent.ToLineageNode()}, lineage.Ancestors...)
 		}
+		parentID = parent.ParentID
+	}
 
-		// 替换关联的标签
-		if err := s.db.Model(prompt).Association("Tags").Replace(tags); err != nil {
-			return nil, fmt.Errorf("更新标签关联失败: %v", err)
+	// 按层级向下查找子孙
+	nodes := map[uint]*models.PromptLineageNode{prompt.ID: lineage.Prompt}
+	level := []uint{prompt.ID}
+	for len(level) > 0 {
+		var children []models.Prompt
+		if err := s.db.Preload("Images", models.OrderedImages).Scopes(visibleTo(viewerID)).Where("parent_id IN ?", level).Order("created_at ASC, id ASC").Find(&children).Error; err != nil {
+			return nil, fmt.Errorf("获取衍生提示词失败: %v", err)
 		}
-	} else if req.TagNames != nil && len(req.TagNames) == 0 {
-		// 如果传入空数组，清除所有标签
-		if err := s.db.Model(prompt).Association("Tags").Clear(); err != nil {
-			return nil, fmt.Errorf("清除标签关联失败: %v", err)
+
+		level = level[:0]
+		for i := range children {
+			child := &children[i]
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			node := child.ToLineageNode()
+			parent := nodes[*child.ParentID]
+			parent.Children = append(parent.Children, node)
+			nodes[child.ID] = node
+			level = append(level, child.ID)
 		}
 	}
+	// 子孙树单独返回，避免在prompt节点中重复输出
+	if lineage.Prompt.Children != nil {
+		lineage.Descendants = lineage.Prompt.Children
+		lineage.Prompt.Children = nil
+	}
 
-	// 重新获取更新后的数据
-	return s.GetPromptByID(id)
+	return lineage, nil
 }
 
 // DeletePrompt 删除提示词（软删除）
 func (s *PromptService) DeletePrompt(id uint) error {
-	result := s.db.Delete(&models.Prompt{}, id)
+	return s.DeletePromptIfMatch(id, nil)
+}
+
+// DeletePromptIfMatch 删除提示词（软删除），versions 非空时当前版本必须是其中之一
+func (s *PromptService) DeletePromptIfMatch(id uint, versions []uint) error {
+	db := s.db
+	if len(versions) > 0 {
+		db = db.Where("version IN ?", versions)
+	}
+	result := db.Delete(&models.Prompt{}, id)
 	if result.Error != nil {
 		return fmt.Errorf("删除提示词失败: %v", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("提示词不存在")
+		// 区分提示词不存在和版本不一致
+		if len(versions) > 0 {
+			if _, err := s.GetPromptByID(id); err == nil {
+				return ErrVersionConflict
+			}
+		}
+		return ErrPromptNotFound
+	}
+
+	var deleted models.Prompt
+	if err := s.db.Unscoped().Select("id, owner_id, is_public").First(&deleted, id).Error; err == nil {
+		publishPromptDeleted(&deleted)
+	}
+	return nil
+}
+
+// bumpPromptVersions 将指定提示词的版本号加一，用于只修改关联（如标签）的操作
+func bumpPromptVersions(tx *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.Prompt{}).Where("id IN ?", ids).Update("version", gorm.Expr("version + 1")).Error; err != nil {
+		return fmt.Errorf("更新提示词版本失败: %w", err)
 	}
 	return nil
 }
 
+// versionMatches 判断当前版本是否在 If-Match 给出的版本列表中
+func versionMatches(version uint, versions []uint) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // GetPrompts 获取提示词列表
 func (s *PromptService) GetPrompts(query *models.PromptQuery) ([]models.Prompt, int64, error) {
+	return s.getPrompts(query, true)
+}
+
+// GetPromptRows 获取提示词列表，不预加载标签和图片
+// 用于 GraphQL 等按需批量加载关联数据的调用方
+func (s *PromptService) GetPromptRows(query *models.PromptQuery) ([]models.Prompt, int64, error) {
+	return s.getPrompts(query, false)
+}
+
+// getPrompts 获取提示词列表，preload 表示是否预加载标签和图片
+func (s *PromptService) getPrompts(query *models.PromptQuery, preload bool) ([]models.Prompt, int64, error) {
 	var prompts []models.Prompt
 	var total int64
 
 	// 构建查询
-	db := s.db.Model(&models.Prompt{}).Preload("Tags")
-
-	// 添加过滤条件
-	if query.ModelName != "" {
-		db = db.Where("model_name = ?", query.ModelName)
-	}
-	if query.IsPublic != nil {
-		db = db.Where("is_public = ?", *query.IsPublic)
-	}
-	if query.Keyword != "" {
-		keyword := "%" + strings.TrimSpace(query.Keyword) + "%"
-		db = db.Where("prompt_text LIKE ? OR negative_prompt LIKE ? OR style_description LIKE ? OR usage_scenario LIKE ?",
-			keyword, keyword, keyword, keyword)
-	}
-
-	// 标签过滤
-	if len(query.TagNames) > 0 {
-		db = db.Joins("JOIN prompt_tags ON prompts.id = prompt_tags.prompt_id").
-			Joins("JOIN tags ON prompt_tags.tag_id = tags.id").
-			Where("tags.name IN ?", query.TagNames).
-			Group("prompts.id")
+	db := s.db.Model(&models.Prompt{}).Scopes(promptFilters(query))
+	if preload {
+		db = db.Preload("Tags").Preload("Images", models.OrderedImages)
 	}
 
 	// 获取总数
@@ -308,18 +1107,7 @@ func (s *PromptService) GetPrompts(query *models.PromptQuery) ([]models.Prompt,
 	}
 
 	// 排序
-	orderBy := "created_at desc"
-	if query.SortBy != "" {
-		sortOrder := "desc"
-		if query.SortOrder == "asc" {
-			sortOrder = "asc"
-		}
-		switch query.SortBy {
-		case "created_at":
-			orderBy = fmt.Sprintf("%s %s", query.SortBy, sortOrder)
-		}
-	}
-	db = db.Order(orderBy)
+	db = db.Order(promptOrder(query))
 
 	// 分页
 	if query.Page > 0 && query.PageSize > 0 {
@@ -335,6 +1123,40 @@ func (s *PromptService) GetPrompts(query *models.PromptQuery) ([]models.Prompt,
 	return prompts, total, nil
 }
 
+// ExportPrompts 按查询条件分批读取所有匹配的提示词（忽略分页参数），每批调用一次 fn
+// 用于流式导出，避免一次性把整个提示词库加载到内存中
+func (s *PromptService) ExportPrompts(query *models.PromptQuery, fn func(batch []models.Prompt) error) error {
+	// 按 (created_at, id) 键集分页：导出过程中新增或删除的提示词不会使后续批次重复或遗漏
+	comparison := "<"
+	if strings.HasSuffix(promptOrder(query), "asc") {
+		comparison = ">"
+	}
+	keyset := fmt.Sprintf("(prompts.created_at %s ? OR (prompts.created_at = ? AND prompts.id %s ?))", comparison, comparison)
+
+	var last *models.Prompt
+	for {
+		db := s.db.Model(&models.Prompt{}).Preload("Tags").Preload("Images", models.OrderedImages).
+			Scopes(promptFilters(query))
+		if last != nil {
+			db = db.Where(keyset, last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		var batch []models.Prompt
+		if err := db.Order(promptOrder(query)).Limit(exportBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("获取提示词列表失败: %v", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
+
 // GetPublicPrompts 获取公开的提示词列表
 func (s *PromptService) GetPublicPrompts(page, pageSize int) ([]models.Prompt, int64, error) {
 	isPublic := true
@@ -347,21 +1169,22 @@ func (s *PromptService) GetPublicPrompts(page, pageSize int) ([]models.Prompt, i
 }
 
 // SearchPromptsByTags 根据标签搜索提示词
-func (s *PromptService) SearchPromptsByTags(tagNames []string, page, pageSize int) ([]models.Prompt, int64, error) {
+func (s *PromptService) SearchPromptsByTags(tagNames []string, page, pageSize int, viewerID *uint) ([]models.Prompt, int64, error) {
 	query := &models.PromptQuery{
 		Page:     page,
 		PageSize: pageSize,
 		TagNames: tagNames,
+		ViewerID: viewerID,
 	}
 	return s.GetPrompts(query)
 }
 
-// GetPromptStats 获取提示词统计信息
-func (s *PromptService) GetPromptStats() (map[string]interface{}, error) {
+// GetPromptStats 获取当前用户可见的提示词统计信息，私有提示词只统计用户自己的
+func (s *PromptService) GetPromptStats(viewerID *uint) (map[string]interface{}, error) {
 	var totalPrompts, publicPrompts int64
 
 	// 总提示词数量
-	if err := s.db.Model(&models.Prompt{}).Count(&totalPrompts).Error; err != nil {
+	if err := s.db.Model(&models.Prompt{}).Scopes(visibleTo(viewerID)).Count(&totalPrompts).Error; err != nil {
 		return nil, fmt.Errorf("获取提示词总数失败: %v", err)
 	}
 
@@ -379,6 +1202,7 @@ func (s *PromptService) GetPromptStats() (map[string]interface{}, error) {
 	var modelStats []ModelStats
 	err := s.db.Model(&models.Prompt{}).
 		Select("model_name, COUNT(*) as count").
+		Scopes(visibleTo(viewerID)).
 		Where("model_name != ''").
 		Group("model_name").
 		Order("count DESC").
@@ -399,7 +1223,7 @@ func (s *PromptService) GetPromptStats() (map[string]interface{}, error) {
 // GetRecentPrompts 获取最近的提示词
 func (s *PromptService) GetRecentPrompts(limit int) ([]models.Prompt, error) {
 	var prompts []models.Prompt
-	result := s.db.Preload("Tags").
+	result := s.db.Preload("Tags").Preload("Images", models.OrderedImages).
 		Where("is_public = ?", true).
 		Order("created_at DESC").
 		Limit(limit).
@@ -413,9 +1237,10 @@ func (s *PromptService) GetRecentPrompts(limit int) ([]models.Prompt, error) {
 }
 
 // DuplicateCheck 检查重复的提示词
-func (s *PromptService) DuplicateCheck(promptText string) ([]models.Prompt, error) {
+func (s *PromptService) DuplicateCheck(promptText string, viewerID *uint) ([]models.Prompt, error) {
 	var prompts []models.Prompt
-	result := s.db.Preload("Tags").
+	result := s.db.Preload("Tags").Preload("Images", models.OrderedImages).
+		Scopes(visibleTo(viewerID)).
 		Where("prompt_text = ?", promptText).
 		Find(&prompts)
 
@@ -425,3 +1250,118 @@ func (s *PromptService) DuplicateCheck(promptText string) ([]models.Prompt, erro
 
 	return prompts, nil
 }
+
+// ExpandPrompt 展开动态提示词（支持 {a|b}、{2$$a|b|c} 和 __wildcard__ 语法）
+func (s *PromptService) ExpandPrompt(req *models.ExpandPromptRequest) (*models.ExpandPromptResponse, error) {
+	promptText := req.PromptText
+	if req.PromptID > 0 {
+		prompt, err := s.GetVisiblePrompt(req.PromptID, req.ViewerID)
+		if err != nil {
+			return nil, err
+		}
+		promptText = prompt.PromptText
+	}
+	if strings.TrimSpace(promptText) == "" {
+		return nil, fmt.Errorf("请提供提示词文本或提示词ID")
+	}
+
+	response := &models.ExpandPromptResponse{Mode: req.Mode}
+
+	var result *utils.DynamicPromptResult
+	var err error
+	switch req.Mode {
+	case "", "all":
+		response.Mode = "all"
+		result, err = utils.ExpandDynamicPromptAll(promptText, req.Limit, s.wildcardService.ResolveWildcard)
+	case "random":
+		// 未指定种子时生成一个，并在响应中返回以便复现
+		seed := time.Now().UnixNano()
+		if req.Seed != nil {
+			seed = *req.Seed
+		}
+		response.Seed = &seed
+		result, err = utils.ExpandDynamicPromptRandom(promptText, req.Count, seed, s.wildcardService.ResolveWildcard)
+	default:
+		return nil, fmt.Errorf("不支持的展开模式: %s", req.Mode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("展开提示词失败: %v", err)
+	}
+
+	response.Prompts = result.Prompts
+	response.Count = len(result.Prompts)
+	response.Total = result.Total
+	response.Truncated = result.Truncated
+	return response, nil
+}
+
+// promptFilters 提示词列表的过滤条件：可见性、模型、公开状态、关键词和标签
+func promptFilters(query *models.PromptQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(visibleTo(query.ViewerID))
+
+		if query.ModelName != "" {
+			db = db.Where("prompts.model_name = ?", query.ModelName)
+		}
+		if query.IsPublic != nil {
+			db = db.Where("prompts.is_public = ?", *query.IsPublic)
+		}
+		if query.Keyword != "" {
+			keyword := "%" + strings.TrimSpace(query.Keyword) + "%"
+			db = db.Where("(prompts.prompt_text LIKE ? OR prompts.negative_prompt LIKE ? OR prompts.style_description LIKE ? OR prompts.usage_scenario LIKE ?)",
+				keyword, keyword, keyword, keyword)
+		}
+
+		// 标签过滤
+		if len(query.TagNames) > 0 {
+			db = db.Joins("JOIN prompt_tags ON prompts.id = prompt_tags.prompt_id").
+				Joins("JOIN tags ON prompt_tags.tag_id = tags.id").
+				Where("tags.name IN ?", query.TagNames).
+				Group("prompts.id")
+		}
+		return db
+	}
+}
+
+// promptOrder 提示词列表的排序方式，默认按创建时间倒序，创建时间相同时按ID保证顺序稳定
+func promptOrder(query *models.PromptQuery) string {
+	sortOrder := "desc"
+	if query.SortBy == "created_at" && query.SortOrder == "asc" {
+		sortOrder = "asc"
+	}
+	return fmt.Sprintf("prompts.created_at %s, prompts.id %s", sortOrder, sortOrder)
+}
+
+// visibleTo 可见性过滤：公开的以及属于当前用户的提示词（与 Prompt.IsVisibleTo 一致）
+func visibleTo(viewerID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == nil {
+			return db.Where("prompts.is_public = ?", true)
+		}
+		return db.Where("(prompts.is_public = ? OR prompts.owner_id = ?)", true, *viewerID)
+	}
+}
+
+// modifiableBy 可修改过滤：无所有者的以及属于当前用户的提示词（与 Prompt.CanBeModifiedBy 一致）
+func modifiableBy(userID *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userID == nil {
+			return db.Where("prompts.owner_id IS NULL")
+		}
+		return db.Where("(prompts.owner_id IS NULL OR prompts.owner_id = ?)", *userID)
+	}
+}
+
+// runTransaction 在事务中执行 fn，MySQL 检测到死锁（1213）回滚事务时整体重试
+// 并发创建同名标签或更新同一提示词时，InnoDB 可能选择回滚其中一个事务；fn 必须可以重复执行
+func runTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = db.Transaction(fn)
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1213 {
+			return err
+		}
+	}
+	return err
+}
@@ -6,7 +6,10 @@ import (
 	"imgGeneratePrompts/models"
 	"imgGeneratePrompts/services"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/mysql"
@@ -82,9 +85,18 @@ func (s *PromptServiceTestSuite) TearDownSuite() {
 
 // SetupTest 在每个测试方法运行前清理数据库
 func (s *PromptServiceTestSuite) SetupTest() {
+	s.db.Exec("DELETE FROM webhook_deliveries")
+	s.db.Exec("DELETE FROM webhooks")
+	s.db.Exec("DELETE FROM jobs")
+	s.db.Exec("DELETE FROM share_links")
+	s.db.Exec("DELETE FROM collection_items")
+	s.db.Exec("DELETE FROM collections")
+	s.db.Exec("DELETE FROM prompt_images")
 	s.db.Exec("DELETE FROM prompt_tags")
 	s.db.Exec("DELETE FROM prompts")
 	s.db.Exec("DELETE FROM tags")
+	s.db.Exec("DELETE FROM api_keys")
+	s.db.Exec("DELETE FROM users")
 }
 
 // TestCreateAndGetPrompt 测试创建和获取提示词
@@ -151,7 +163,8 @@ func (s *PromptServiceTestSuite) TestGetPrompts() {
 	// 准备数据
 	tagPublic, _ := s.tagSvc.CreateTag(&models.CreateTagRequest{Name: "公开"})
 	s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "公开提示词1", ModelName: "SD1.5", IsPublic: true, TagNames: []string{"公开"}})
-	s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "私有提示词1", ModelName: "SDXL", IsPublic: false})
+	ownerID := uint(7)
+	s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "私有提示词1", ModelName: "SDXL", IsPublic: false, OwnerID: &ownerID})
 
 	// 1. 测试按 IsPublic 过滤
 	isPublic := true
@@ -162,7 +175,7 @@ func (s *PromptServiceTestSuite) TestGetPrompts() {
 	s.Equal("公开提示词1", prompts[0].PromptText)
 
 	// 2. 测试按 ModelName 过滤
-	query = &models.PromptQuery{ModelName: "SDXL", Page: 1, PageSize: 10}
+	query = &models.PromptQuery{ModelName: "SDXL", Page: 1, PageSize: 10, ViewerID: &ownerID}
 	prompts, total, err = s.service.GetPrompts(query)
 	s.NoError(err)
 	s.Equal(int64(1), total)
@@ -176,6 +189,216 @@ func (s *PromptServiceTestSuite) TestGetPrompts() {
 	s.Len(prompts[0].Tags, 1)
 }
 
+// TestForkPromptAndLineage 测试分叉提示词和衍生关系
+func (s *PromptServiceTestSuite) TestForkPromptAndLineage() {
+	root, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{
+		PromptText:     "原始提示词",
+		TagNames:       []string{"标签A", "标签B"},
+		InputImageURLs: []string{"https://example.com/a.jpg"},
+	})
+
+	// 1. 分叉应复制内容、标签和图片，并记录父提示词
+	child, err := s.service.ForkPrompt(root.ID, nil)
+	s.NoError(err)
+	s.NotEqual(root.ID, child.ID)
+	s.Require().NotNil(child.ParentID)
+	s.Equal(root.ID, *child.ParentID)
+	s.Equal(root.PromptText, child.PromptText)
+	s.Len(child.Tags, 2)
+	s.Equal([]string{"https://example.com/a.jpg"}, child.GetInputImageURLs())
+
+	grandchild, err := s.service.ForkPrompt(child.ID, nil)
+	s.NoError(err)
+	sibling, err := s.service.ForkPrompt(root.ID, nil)
+	s.NoError(err)
+
+	// 2. 从中间节点查看：一个祖先，一个子孙
+	lineage, err := s.service.GetPromptLineage(child.ID, nil)
+	s.NoError(err)
+	s.Len(lineage.Ancestors, 1)
+	s.Equal(root.ID, lineage.Ancestors[0].ID)
+	s.Len(lineage.Descendants, 1)
+	s.Equal(grandchild.ID, lineage.Descendants[0].ID)
+
+	// 3. 从根节点查看：完整的子孙树
+	lineage, err = s.service.GetPromptLineage(root.ID, nil)
+	s.NoError(err)
+	s.Empty(lineage.Ancestors)
+	s.Len(lineage.Descendants, 2)
+	s.Equal(child.ID, lineage.Descendants[0].ID)
+	s.Equal(sibling.ID, lineage.Descendants[1].ID)
+	s.Len(lineage.Descendants[0].Children, 1)
+
+	// 4. 不存在的提示词
+	_, err = s.service.ForkPrompt(999999, nil)
+	s.ErrorIs(err, services.ErrPromptNotFound)
+}
+
+// TestBatchLoadPrompts 测试按ID批量获取提示词、图片和分叉数量
+func (s *PromptServiceTestSuite) TestBatchLoadPrompts() {
+	owner := uint(7)
+	shared, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{
+		PromptText:     "无所有者",
+		InputImageURLs: []string{"https://example.com/a.jpg", "https://example.com/b.jpg"},
+		OutputImageURL: "https://example.com/out.jpg",
+	})
+	private, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "私有", OwnerID: &owner})
+	s.service.ForkPrompt(shared.ID, nil)
+	s.service.ForkPrompt(shared.ID, &owner)
+
+	prompts, err := s.service.GetVisiblePromptsByIDs([]uint{shared.ID, private.ID, 999999}, nil)
+	s.NoError(err)
+	s.Len(prompts, 1)
+	s.Equal("无所有者", prompts[shared.ID].PromptText)
+	s.Empty(prompts[shared.ID].Tags, "不预加载关联")
+
+	prompts, err = s.service.GetVisiblePromptsByIDs([]uint{shared.ID, private.ID}, &owner)
+	s.NoError(err)
+	s.Len(prompts, 2)
+
+	images, err := s.service.GetImagesByPromptIDs([]uint{shared.ID, private.ID})
+	s.NoError(err)
+	s.Require().Len(images[shared.ID], 3)
+	s.Equal("https://example.com/a.jpg", images[shared.ID][0].URL)
+	s.Empty(images[private.ID])
+
+	forks, err := s.service.CountVisibleForksByPromptIDs([]uint{shared.ID, private.ID}, nil)
+	s.NoError(err)
+	s.Equal(int64(1), forks[shared.ID], "他人的私有分叉不计入")
+	s.Equal(int64(0), forks[private.ID])
+}
+
+// TestExportPromptsKeyset 测试导出过程中删除和新增提示词不会使后续批次重复或遗漏
+func (s *PromptServiceTestSuite) TestExportPromptsKeyset() {
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	prompts := make([]models.Prompt, 250)
+	for i := range prompts {
+		prompts[i] = models.Prompt{PromptText: fmt.Sprintf("导出 %d", i), IsPublic: true, CreatedAt: createdAt}
+	}
+	s.Require().NoError(s.db.Create(&prompts).Error)
+
+	seen := make(map[uint]int)
+	batches := 0
+	err := s.service.ExportPrompts(&models.PromptQuery{}, func(batch []models.Prompt) error {
+		batches++
+		for _, prompt := range batch {
+			seen[prompt.ID]++
+		}
+		if batches == 1 {
+			// 删除已导出的提示词并新增一条更新的提示词，OFFSET 分页会因此跳过或重复行
+			s.db.Delete(&batch[0])
+			s.db.Delete(&batch[1])
+			s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "导出期间新增", IsPublic: true})
+		}
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(2, batches)
+	s.Len(seen, 250)
+	for _, prompt := range prompts {
+		s.Equal(1, seen[prompt.ID], "提示词 %d 应恰好导出一次", prompt.ID)
+	}
+}
+
+// TestConcurrentCreateWithNewTags 测试并发创建使用相同新标签的提示词
+func (s *PromptServiceTestSuite) TestConcurrentCreateWithNewTags() {
+	const workers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{
+				PromptText: fmt.Sprintf("并发提示词%d", i),
+				TagNames:   []string{"并发标签B", "并发标签A", fmt.Sprintf("独有标签%d", i)},
+			})
+			if err == nil && len(prompt.Tags) != 3 {
+				err = fmt.Errorf("提示词 %d 的标签数量为 %d", prompt.ID, len(prompt.Tags))
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	// 同名标签只创建一次
+	var count int64
+	s.db.Model(&models.Tag{}).Where("name IN ?", []string{"并发标签A", "并发标签B"}).Count(&count)
+	s.Equal(int64(2), count)
+	s.db.Table("prompt_tags").Count(&count)
+	s.Equal(int64(workers*3), count)
+}
+
+// TestConcurrentUpdateTags 测试并发更新同一提示词的标签
+func (s *PromptServiceTestSuite) TestConcurrentUpdateTags() {
+	prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "被并发更新的提示词"})
+	s.Require().NoError(err)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.service.UpdatePrompt(prompt.ID, &models.UpdatePromptRequest{
+				TagNames: []string{"共享新标签", fmt.Sprintf("更新标签%d", i)},
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		s.NoError(err)
+	}
+
+	// 最后一次更新完整生效，不会混入其他更新的标签
+	final, err := s.service.GetPromptByID(prompt.ID)
+	s.Require().NoError(err)
+	s.Len(final.Tags, 2)
+	var count int64
+	s.db.Model(&models.Tag{}).Where("name = ?", "共享新标签").Count(&count)
+	s.Equal(int64(1), count)
+}
+
+// TestCreateAndUpdateRollback 测试中途失败时整个操作回滚
+func (s *PromptServiceTestSuite) TestCreateAndUpdateRollback() {
+	tooLong := strings.Repeat("长", 101) // 超过 tags.name 的 varchar(100)
+
+	// 1. 创建失败时不留下已创建的标签和提示词
+	_, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{
+		PromptText: "不应存在的提示词",
+		TagNames:   []string{"孤立标签", tooLong},
+	})
+	s.Error(err)
+	var count int64
+	s.db.Model(&models.Tag{}).Where("name = ?", "孤立标签").Count(&count)
+	s.Equal(int64(0), count)
+	s.db.Model(&models.Prompt{}).Where("prompt_text = ?", "不应存在的提示词").Count(&count)
+	s.Equal(int64(0), count)
+
+	// 2. 更新失败时字段保持原值
+	prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "原值", TagNames: []string{"原标签"}})
+	s.Require().NoError(err)
+	newText := "新值"
+	_, err = s.service.UpdatePrompt(prompt.ID, &models.UpdatePromptRequest{PromptText: &newText, TagNames: []string{tooLong}})
+	s.Error(err)
+	unchanged, err := s.service.GetPromptByID(prompt.ID)
+	s.Require().NoError(err)
+	s.Equal("原值", unchanged.PromptText)
+	s.Require().Len(unchanged.Tags, 1)
+	s.Equal("原标签", unchanged.Tags[0].Name)
+
+	// 3. 不存在的提示词
+	_, err = s.service.UpdatePrompt(999999, &models.UpdatePromptRequest{PromptText: &newText})
+	s.ErrorIs(err, services.ErrPromptNotFound)
+}
+
 // TestAnalyzePromptData 测试AI分析模拟函数
 func (s *PromptServiceTestSuite) TestAnalyzePromptData() {
 	res, err := s.service.AnalyzePromptData("test", "test_model", "base64data", nil)
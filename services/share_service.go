@@ -0,0 +1,219 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultShareLinkTTL 分享链接的默认有效期
+	DefaultShareLinkTTL = 72 * time.Hour
+	// SharedImageURLTTL 分享页面中图片签名URL的有效期
+	SharedImageURLTTL = 15 * time.Minute
+)
+
+var (
+	// ErrShareLinkNotFound 分享链接不存在
+	ErrShareLinkNotFound = errors.New("分享链接不存在")
+	// ErrShareLinkInvalid 分享链接无效、已吊销或已过期
+	ErrShareLinkInvalid = errors.New("分享链接无效、已吊销或已过期")
+)
+
+// ShareService 提示词分享链接服务
+type ShareService struct {
+	db            *gorm.DB
+	promptService *PromptService
+}
+
+// NewShareService 创建分享链接服务实例
+func NewShareService() *ShareService {
+	return &ShareService{
+		db:            config.GetDB(),
+		promptService: NewPromptService(),
+	}
+}
+
+// CreateShareLink 为提示词创建分享链接，只有可以修改该提示词的用户才能分享
+// 返回的明文令牌只在此时可见
+func (s *ShareService) CreateShareLink(promptID uint, userID *uint, req *models.CreateShareLinkRequest) (*models.ShareLink, string, error) {
+	if err := s.checkShareable(promptID, userID); err != nil {
+		return nil, "", err
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("生成分享令牌失败: %v", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	ttl := DefaultShareLinkTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	link := &models.ShareLink{
+		PromptID:  promptID,
+		CreatedBy: userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.db.Create(link).Error; err != nil {
+		return nil, "", fmt.Errorf("创建分享链接失败: %v", err)
+	}
+	return link, token, nil
+}
+
+// GetShareLinks 获取提示词的所有分享链接
+func (s *ShareService) GetShareLinks(promptID uint, userID *uint) ([]models.ShareLink, error) {
+	if err := s.checkShareable(promptID, userID); err != nil {
+		return nil, err
+	}
+
+	var links []models.ShareLink
+	if err := s.db.Where("prompt_id = ?", promptID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("获取分享链接失败: %v", err)
+	}
+	return links, nil
+}
+
+// RevokeShareLink 吊销分享链接
+func (s *ShareService) RevokeShareLink(promptID, shareID uint, userID *uint) (*models.ShareLink, error) {
+	if err := s.checkShareable(promptID, userID); err != nil {
+		return nil, err
+	}
+
+	var link models.ShareLink
+	if err := s.db.Where("id = ? AND prompt_id = ?", shareID, promptID).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("获取分享链接失败: %v", err)
+	}
+
+	if link.RevokedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&link).Update("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("吊销分享链接失败: %v", err)
+		}
+		link.RevokedAt = &now
+	}
+	return &link, nil
+}
+
+// GetSharedPrompt 通过分享令牌获取提示词，本地图片替换为限时签名URL
+func (s *ShareService) GetSharedPrompt(token string) (*models.SharedPromptResponse, error) {
+	link, prompt, err := s.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.db.Model(link).Updates(map[string]interface{}{
+		"access_count":     gorm.Expr("access_count + 1"),
+		"last_accessed_at": now,
+	})
+
+	imageExpiresAt := now.Add(SharedImageURLTTL)
+	if link.ExpiresAt.Before(imageExpiresAt) {
+		imageExpiresAt = link.ExpiresAt
+	}
+
+	response := prompt.ToResponse()
+	// 分享页面不暴露所有者信息
+	response.OwnerID = nil
+	// 本地图片以图片ID代替文件名，不在分享页面中暴露上传目录中的真实文件名
+	imageIDs := make(map[string]uint, len(prompt.Images))
+	for _, image := range prompt.Images {
+		if _, ok := imageIDs[image.URL]; !ok {
+			imageIDs[image.URL] = image.ID
+		}
+	}
+	response.MapImageURLs(func(imageURL string) string {
+		imageID, ok := imageIDs[imageURL]
+		if !ok || !strings.HasPrefix(imageURL, "/uploads/") {
+			return imageURL
+		}
+		return utils.SignPath(sharedImagePath(token, imageID), imageExpiresAt, config.AppConfig.Auth.URLSigningKey)
+	})
+
+	return &models.SharedPromptResponse{
+		Prompt:            response,
+		ExpiresAt:         link.ExpiresAt,
+		ImageURLExpiresAt: imageExpiresAt,
+	}, nil
+}
+
+// ResolveSharedFile 校验签名URL并返回分享提示词中图片的本地路径
+func (s *ShareService) ResolveSharedFile(token, imageID, expires, signature string) (string, error) {
+	id, err := strconv.ParseUint(imageID, 10, 64)
+	if err != nil {
+		return "", utils.ErrInvalidSignature
+	}
+	if err := utils.VerifySignedPath(sharedImagePath(token, uint(id)), expires, signature, config.AppConfig.Auth.URLSigningKey); err != nil {
+		return "", err
+	}
+
+	_, prompt, err := s.resolveToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	// 只允许访问属于该提示词的本地图片
+	for _, image := range prompt.Images {
+		if image.ID == uint(id) && strings.HasPrefix(image.URL, "/uploads/") {
+			return filepath.Join(config.AppConfig.Server.UploadPath, filepath.Base(image.URL)), nil
+		}
+	}
+	return "", ErrShareLinkNotFound
+}
+
+// resolveToken 校验分享令牌并加载提示词
+func (s *ShareService) resolveToken(token string) (*models.ShareLink, *models.Prompt, error) {
+	var link models.ShareLink
+	if err := s.db.Where("token_hash = ?", hashToken(token)).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrShareLinkInvalid
+		}
+		return nil, nil, fmt.Errorf("获取分享链接失败: %v", err)
+	}
+	if !link.IsActive() {
+		return nil, nil, ErrShareLinkInvalid
+	}
+
+	prompt, err := s.promptService.GetPromptByID(link.PromptID)
+	if err != nil {
+		if errors.Is(err, ErrPromptNotFound) {
+			return nil, nil, ErrShareLinkInvalid
+		}
+		return nil, nil, err
+	}
+	return &link, prompt, nil
+}
+
+// checkShareable 校验用户是否可以管理提示词的分享链接
+func (s *ShareService) checkShareable(promptID uint, userID *uint) error {
+	prompt, err := s.promptService.GetVisiblePrompt(promptID, userID)
+	if err != nil {
+		return err
+	}
+	if !prompt.CanBeModifiedBy(userID) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// sharedImagePath 分享图片的访问路径
+func sharedImagePath(token string, imageID uint) string {
+	return "/api/v1/shared/" + token + "/images/" + strconv.FormatUint(uint64(imageID), 10)
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"imgGeneratePrompts/config"
 	"imgGeneratePrompts/models"
+	"sort"
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TagService 标签服务
@@ -22,24 +24,22 @@ func NewTagService() *TagService {
 	}
 }
 
-// CreateTag 创建标签
-func (s *TagService) CreateTag(req *models.CreateTagRequest) (*models.Tag, error) {
-	// 检查标签是否已存在
-	var existingTag models.Tag
-	if err := s.db.Where("name = ?", req.Name).First(&existingTag).Error; err == nil {
-		return &existingTag, nil // 如果已存在，直接返回
-	}
+// WithTx 返回使用指定事务的标签服务，用于在调用方的事务中处理标签
+func (s *TagService) WithTx(tx *gorm.DB) *TagService {
+	return &TagService{db: tx}
+}
 
-	tag := &models.Tag{
-		Name: req.Name,
+// CreateTag 创建标签（已存在时直接返回现有标签）
+func (s *TagService) CreateTag(req *models.CreateTagRequest) (*models.Tag, error) {
+	tags, created, err := s.getOrCreateTags([]string{req.Name})
+	if err != nil {
+		return nil, err
 	}
-
-	result := s.db.Create(tag)
-	if result.Error != nil {
-		return nil, fmt.Errorf("创建标签失败: %v", result.Error)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("标签名称不能为空")
 	}
-
-	return tag, nil
+	publishTagsCreated(created)
+	return tags[0], nil
 }
 
 // GetTagByID 根据ID获取标签
@@ -78,33 +78,79 @@ func (s *TagService) GetAllTags() ([]models.Tag, error) {
 	return tags, nil
 }
 
-// GetOrCreateTags 获取或创建标签（批量）
+// GetOrCreateTags 获取或创建标签（批量），按输入顺序返回，忽略空名称和重复名称
+// 使用 INSERT ... ON DUPLICATE KEY UPDATE 创建，多个请求并发创建同名标签时不会违反唯一索引
 func (s *TagService) GetOrCreateTags(tagNames []string) ([]*models.Tag, error) {
-	if len(tagNames) == 0 {
-		return []*models.Tag{}, nil
-	}
-
-	var tags []*models.Tag
+	tags, _, err := s.getOrCreateTags(tagNames)
+	return tags, err
+}
 
+// getOrCreateTags 获取或创建标签，同时返回其中新创建的标签，由调用方在事务提交后发布 tag.created 事件
+// 并发创建同名标签时两个请求都可能把它视为新创建的标签
+func (s *TagService) getOrCreateTags(tagNames []string) (tags []*models.Tag, created []*models.Tag, err error) {
+	names := make([]string, 0, len(tagNames))
+	seen := make(map[string]bool, len(tagNames))
 	for _, name := range tagNames {
 		name = strings.TrimSpace(name)
-		if name == "" {
+		if name == "" || seen[strings.ToLower(name)] {
 			continue
 		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return []*models.Tag{}, nil, nil
+	}
 
-		// 尝试获取现有标签
-		tag, err := s.GetTagByName(name)
-		if err != nil {
-			// 如果不存在，创建新标签
-			tag, err = s.CreateTag(&models.CreateTagRequest{Name: name})
-			if err != nil {
-				return nil, fmt.Errorf("创建标签 %s 失败: %v", name, err)
+	var existingNames []string
+	if err := s.db.Model(&models.Tag{}).Where("name IN ?", names).Pluck("name", &existingNames).Error; err != nil {
+		return nil, nil, fmt.Errorf("获取标签失败: %w", err)
+	}
+	existed := make(map[string]bool, len(existingNames))
+	for _, name := range existingNames {
+		existed[strings.ToLower(name)] = true
+	}
+
+	// 按名称排序后插入，使并发事务以相同顺序对唯一索引加锁，减少死锁
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	newTags := make([]models.Tag, len(sorted))
+	for i, name := range sorted {
+		newTags[i].Name = name
+	}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags)
+	if result.Error != nil {
+		return nil, nil, fmt.Errorf("创建标签失败: %w", result.Error)
+	}
+
+	// 使用锁定读：并发事务刚提交的标签在当前事务的一致性快照中可能不可见
+	var existing []*models.Tag
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name IN ?", names).Find(&existing).Error; err != nil {
+		return nil, nil, fmt.Errorf("获取标签失败: %w", err)
+	}
+	byName := make(map[string]*models.Tag, len(existing))
+	for _, tag := range existing {
+		byName[strings.ToLower(tag.Name)] = tag
+	}
+
+	tags = make([]*models.Tag, 0, len(names))
+	for _, name := range names {
+		tag := byName[strings.ToLower(name)]
+		if tag == nil {
+			// 除大小写外还被排序规则视为相同的名称（如重音符号不同），按数据库的比较规则单独查询
+			tag = &models.Tag{}
+			if err := s.db.Where("name = ?", name).First(tag).Error; err != nil {
+				return nil, nil, fmt.Errorf("获取标签 %s 失败: %w", name, err)
 			}
 		}
 		tags = append(tags, tag)
+		if result.RowsAffected > 0 && !existed[strings.ToLower(tag.Name)] {
+			existed[strings.ToLower(tag.Name)] = true
+			created = append(created, tag)
+		}
 	}
 
-	return tags, nil
+	return tags, created, nil
 }
 
 // DeleteTag 删除标签
@@ -126,6 +172,132 @@ func (s *TagService) DeleteTag(id uint) error {
 	return nil
 }
 
+// MergeTags 将源标签合并到目标标签：源标签的提示词关联转移到目标标签后删除源标签
+// 返回目标标签及新增关联的提示词数量
+func (s *TagService) MergeTags(sourceID, targetID uint) (*models.Tag, int64, error) {
+	if sourceID == targetID {
+		return nil, 0, fmt.Errorf("不能将标签合并到自身")
+	}
+	if _, err := s.GetTagByID(sourceID); err != nil {
+		return nil, 0, err
+	}
+	target, err := s.GetTagByID(targetID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var moved int64
+	var promptIDs []uint
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("prompt_tags").Where("tag_id = ?", sourceID).Pluck("prompt_id", &promptIDs).Error; err != nil {
+			return fmt.Errorf("获取标签关联失败: %v", err)
+		}
+
+		// 标签变化的提示词版本号加一
+		err := tx.Exec("UPDATE prompts SET version = version + 1 WHERE id IN (SELECT prompt_id FROM prompt_tags WHERE tag_id = ?)", sourceID).Error
+		if err != nil {
+			return fmt.Errorf("更新提示词版本失败: %v", err)
+		}
+
+		// 已同时拥有两个标签的提示词会被忽略，避免重复关联
+		result := tx.Exec("INSERT IGNORE INTO prompt_tags (prompt_id, tag_id) SELECT prompt_id, ? FROM prompt_tags WHERE tag_id = ?", targetID, sourceID)
+		if result.Error != nil {
+			return fmt.Errorf("转移标签关联失败: %v", result.Error)
+		}
+		moved = result.RowsAffected
+
+		if err := tx.Exec("DELETE FROM prompt_tags WHERE tag_id = ?", sourceID).Error; err != nil {
+			return fmt.Errorf("删除原标签关联失败: %v", err)
+		}
+		if err := tx.Delete(&models.Tag{}, sourceID).Error; err != nil {
+			return fmt.Errorf("删除原标签失败: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if promptIDs == nil {
+		promptIDs = []uint{}
+	}
+	Events.Publish(models.EventTagMerged, models.TagMergedEvent{SourceID: sourceID, Target: target, PromptIDs: promptIDs}, nil, true)
+	return target, moved, nil
+}
+
+// GetTagsByPromptIDs 批量获取提示词的标签，按提示词ID分组，标签按ID排序
+func (s *TagService) GetTagsByPromptIDs(promptIDs []uint) (map[uint][]*models.Tag, error) {
+	result := make(map[uint][]*models.Tag, len(promptIDs))
+	if len(promptIDs) == 0 {
+		return result, nil
+	}
+
+	type promptTag struct {
+		PromptID uint
+		TagID    uint
+	}
+	var links []promptTag
+	err := s.db.Table("prompt_tags").
+		Where("prompt_id IN ?", promptIDs).
+		Order("tag_id ASC").
+		Scan(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取提示词标签失败: %v", err)
+	}
+	if len(links) == 0 {
+		return result, nil
+	}
+
+	tagIDs := make([]uint, 0, len(links))
+	for _, link := range links {
+		tagIDs = append(tagIDs, link.TagID)
+	}
+	var tags []*models.Tag
+	if err := s.db.Where("id IN ?", tagIDs).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("获取提示词标签失败: %v", err)
+	}
+
+	// 同一个标签在各提示词之间共用一个对象
+	byID := make(map[uint]*models.Tag, len(tags))
+	for _, tag := range tags {
+		byID[tag.ID] = tag
+	}
+	for _, link := range links {
+		if tag, ok := byID[link.TagID]; ok {
+			result[link.PromptID] = append(result[link.PromptID], tag)
+		}
+	}
+	return result, nil
+}
+
+// CountVisiblePromptsByTagIDs 批量统计使用各标签、且对指定用户可见的提示词数量
+func (s *TagService) CountVisiblePromptsByTagIDs(tagIDs []uint, viewerID *uint) (map[uint]int64, error) {
+	result := make(map[uint]int64, len(tagIDs))
+	if len(tagIDs) == 0 {
+		return result, nil
+	}
+
+	type tagCount struct {
+		TagID uint
+		Count int64
+	}
+	var counts []tagCount
+	err := s.db.Model(&models.Prompt{}).
+		Select("prompt_tags.tag_id, COUNT(*) as count").
+		Joins("JOIN prompt_tags ON prompt_tags.prompt_id = prompts.id").
+		Scopes(visibleTo(viewerID)).
+		Where("prompt_tags.tag_id IN ?", tagIDs).
+		Group("prompt_tags.tag_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("统计标签使用次数失败: %v", err)
+	}
+	for _, c := range counts {
+		result[c.TagID] = c.Count
+	}
+	return result, nil
+}
+
 // SearchTags 搜索标签
 func (s *TagService) SearchTags(keyword string) ([]models.Tag, error) {
 	var tags []models.Tag
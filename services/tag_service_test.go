@@ -112,6 +112,34 @@ func (s *TagServiceTestSuite) TestTagStats() {
 	s.Equal(int64(2), popularTags[0]["use_count"])
 }
 
+// TestBatchLoadTags 测试按提示词批量获取标签和统计可见的提示词数量
+func (s *TagServiceTestSuite) TestBatchLoadTags() {
+	owner := uint(7)
+	p1, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "p1", TagNames: []string{"猫", "夜晚"}})
+	p2, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "p2", TagNames: []string{"猫"}})
+	p3, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "p3"})
+	private, _ := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "私有", TagNames: []string{"猫"}, OwnerID: &owner})
+
+	tags, err := s.tagSvc.GetTagsByPromptIDs([]uint{p1.ID, p2.ID, p3.ID})
+	s.NoError(err)
+	s.Len(tags[p1.ID], 2)
+	s.Require().Len(tags[p2.ID], 1)
+	s.Equal("猫", tags[p2.ID][0].Name)
+	s.Same(tags[p1.ID][0], tags[p2.ID][0], "同一个标签共用一个对象")
+	s.Empty(tags[p3.ID])
+
+	cat, _ := s.tagSvc.GetTagByName("猫")
+	night, _ := s.tagSvc.GetTagByName("夜晚")
+	counts, err := s.tagSvc.CountVisiblePromptsByTagIDs([]uint{cat.ID, night.ID}, nil)
+	s.NoError(err)
+	s.Equal(int64(2), counts[cat.ID], "他人的私有提示词不计入")
+	s.Equal(int64(1), counts[night.ID])
+
+	counts, err = s.tagSvc.CountVisiblePromptsByTagIDs([]uint{cat.ID}, private.OwnerID)
+	s.NoError(err)
+	s.Equal(int64(3), counts[cat.ID])
+}
+
 // TestTagService runs the test suite for the tag service
 func TestTagService(t *testing.T) {
 	// 复用 PromptServiceTestSuite 的 setup 和 teardown 逻辑
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// webhookResponseBodyLimit 推送记录中保存的响应内容长度上限
+const webhookResponseBodyLimit = 1024
+
+// WebhookDispatcher 事件推送：订阅事件总线，为匹配的订阅保存推送记录，再由固定数量的worker发送
+// 推送记录保存在数据库中，发送失败按指数退避重试；多个进程各自推送本进程发布的事件
+type WebhookDispatcher struct {
+	service *WebhookService
+	bus     *EventBus
+	cfg     config.WebhookConfig
+	client  *http.Client
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWebhookDispatcher 创建事件推送器，使用 config.AppConfig.Webhooks 中的配置
+func NewWebhookDispatcher() *WebhookDispatcher {
+	cfg := config.AppConfig.Webhooks
+	client := utils.NewSafeHTTPClient(cfg.Timeout, cfg.AllowPrivate)
+	client.Timeout = cfg.Timeout
+	// 不跟随重定向，3xx 视为失败，避免被重定向到内网地址
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &WebhookDispatcher{
+		service: NewWebhookService(),
+		bus:     Events,
+		cfg:     cfg,
+		client:  client,
+	}
+}
+
+// Start 开始接收事件并启动 cfg.Workers 个发送worker
+func (d *WebhookDispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	// 在 Start 返回前订阅，之后发布的事件都不会遗漏
+	sub, _, _ := d.bus.Subscribe("")
+	d.wg.Add(1)
+	go d.listen(ctx, sub)
+
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.work(ctx)
+	}
+}
+
+// Stop 停止接收事件和发送，发送中的推送会被归还，之后重新发送
+func (d *WebhookDispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+// listen 为收到的事件保存推送记录；处理过慢被事件总线断开时从最后处理的事件继续订阅
+func (d *WebhookDispatcher) listen(ctx context.Context, sub *EventSubscription) {
+	defer d.wg.Done()
+	var lastEventID string
+	for {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+			return
+		case event, ok := <-sub.C:
+			if ok {
+				lastEventID = event.ID
+				if err := d.service.enqueueEvent(event); err != nil {
+					log.Printf("保存事件 %s 的推送记录失败: %v", event.ID, err)
+				}
+				continue
+			}
+
+			var replay []models.Event
+			var complete bool
+			sub, replay, complete = d.bus.Subscribe(lastEventID)
+			if !complete {
+				log.Printf("警告：事件推送处理过慢，%s 之后的部分事件未推送", lastEventID)
+			}
+			for _, event := range replay {
+				lastEventID = event.ID
+				if err := d.service.enqueueEvent(event); err != nil {
+					log.Printf("保存事件 %s 的推送记录失败: %v", event.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// work 循环领取并发送推送，没有待发送的推送时等待唤醒或下一次轮询
+func (d *WebhookDispatcher) work(ctx context.Context) {
+	defer d.wg.Done()
+	// 租约比请求超时略长，发送者异常退出后由其他worker重新发送
+	lease := d.cfg.Timeout + 30*time.Second
+	for ctx.Err() == nil {
+		delivery, webhook, err := d.service.claimDelivery(lease)
+		if err != nil {
+			log.Printf("领取推送失败: %v", err)
+		}
+		if delivery != nil {
+			d.run(ctx, delivery, webhook)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-webhookWakeup:
+		case <-time.After(d.cfg.PollInterval):
+		}
+	}
+}
+
+// run 发送一条已领取的推送并记录结果
+func (d *WebhookDispatcher) run(ctx context.Context, delivery *models.WebhookDelivery, webhook *models.Webhook) {
+	attempt := d.deliver(ctx, delivery, webhook)
+	var err error
+	if ctx.Err() != nil {
+		err = d.service.releaseDelivery(delivery)
+	} else {
+		err = d.service.recordAttempt(delivery, attempt, retryBackoff(d.cfg.RetryBackoff, d.cfg.MaxBackoff, delivery.Attempts))
+	}
+	if err != nil {
+		log.Printf("更新推送记录 %d 失败: %v", delivery.ID, err)
+	}
+}
+
+// deliver 向订阅的地址发送带签名的推送，接收方返回 2xx 视为成功
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery, webhook *models.Webhook) *deliveryAttempt {
+	if webhook.URL == "" || !webhook.Active {
+		return &deliveryAttempt{err: ErrWebhookInactive}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return &deliveryAttempt{err: fmt.Errorf("创建请求失败: %v", err)}
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "imgGeneratePrompts-Webhook/1.0")
+	req.Header.Set(utils.WebhookEventHeader, delivery.EventType)
+	req.Header.Set(utils.WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(utils.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(utils.WebhookSignatureHeader, utils.SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return &deliveryAttempt{duration: time.Since(start), err: fmt.Errorf("请求失败: %v", err), retry: true}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+
+	attempt := &deliveryAttempt{statusCode: resp.StatusCode, body: string(body), duration: time.Since(start)}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.err = fmt.Errorf("接收方返回状态码 %d", resp.StatusCode)
+		attempt.retry = true
+	}
+	return attempt
+}
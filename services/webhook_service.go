@@ -0,0 +1,410 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrWebhookNotFound 订阅不存在
+	ErrWebhookNotFound = errors.New("订阅不存在")
+	// ErrDeliveryNotFound 推送记录不存在
+	ErrDeliveryNotFound = errors.New("推送记录不存在")
+	// ErrInvalidWebhook 订阅参数无效
+	ErrInvalidWebhook = errors.New("订阅参数无效")
+	// ErrWebhookInactive 订阅已停用
+	ErrWebhookInactive = errors.New("订阅已停用")
+)
+
+// webhookSecretPrefix 自动生成的签名密钥前缀
+const webhookSecretPrefix = "whsec_"
+
+// webhookWakeup 有新的推送时唤醒一个空闲的worker，避免等待下一次轮询
+var webhookWakeup = make(chan struct{}, 1)
+
+// WebhookService 事件推送订阅服务
+type WebhookService struct {
+	db *gorm.DB
+}
+
+// NewWebhookService 创建事件推送订阅服务实例
+func NewWebhookService() *WebhookService {
+	return &WebhookService{db: config.GetDB()}
+}
+
+// CreateWebhook 为用户创建订阅，返回签名密钥（未指定时自动生成）
+func (s *WebhookService) CreateWebhook(ownerID uint, req *models.CreateWebhookRequest) (*models.Webhook, string, error) {
+	targetURL, err := validateWebhookURL(req.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	webhook := &models.Webhook{
+		OwnerID:     ownerID,
+		URL:         targetURL,
+		Events:      strings.Join(normalizeWebhookEvents(req.Events), ","),
+		Secret:      secret,
+		Description: strings.TrimSpace(req.Description),
+		Active:      req.Active == nil || *req.Active,
+	}
+	if err := s.db.Create(webhook).Error; err != nil {
+		return nil, "", fmt.Errorf("创建订阅失败: %v", err)
+	}
+	return webhook, secret, nil
+}
+
+// GetWebhooks 获取用户的所有订阅
+func (s *WebhookService) GetWebhooks(ownerID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := s.db.Where("owner_id = ?", ownerID).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("获取订阅列表失败: %v", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取用户的订阅，他人的订阅视为不存在
+func (s *WebhookService) GetWebhook(id, ownerID uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.Where("id = ? AND owner_id = ?", id, ownerID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("获取订阅失败: %v", err)
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook 更新订阅，重新生成签名密钥时返回新密钥，否则返回空字符串
+func (s *WebhookService) UpdateWebhook(id, ownerID uint, req *models.UpdateWebhookRequest) (*models.Webhook, string, error) {
+	webhook, err := s.GetWebhook(id, ownerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	updates := make(map[string]interface{})
+	if req.URL != nil {
+		targetURL, err := validateWebhookURL(*req.URL)
+		if err != nil {
+			return nil, "", err
+		}
+		updates["url"] = targetURL
+	}
+	if req.Events != nil {
+		updates["events"] = strings.Join(normalizeWebhookEvents(req.Events), ",")
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	var secret string
+	if req.RotateSecret {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, "", err
+		}
+		updates["secret"] = secret
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(webhook).Updates(updates).Error; err != nil {
+			return nil, "", fmt.Errorf("更新订阅失败: %v", err)
+		}
+	}
+	webhook, err = s.GetWebhook(id, ownerID)
+	return webhook, secret, err
+}
+
+// DeleteWebhook 删除订阅及其推送记录
+func (s *WebhookService) DeleteWebhook(id, ownerID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND owner_id = ?", id, ownerID).Delete(&models.Webhook{})
+		if result.Error != nil {
+			return fmt.Errorf("删除订阅失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("删除推送记录失败: %v", err)
+		}
+		return nil
+	})
+}
+
+// GetDeliveries 分页获取订阅的推送记录，最新的在前
+func (s *WebhookService) GetDeliveries(webhookID, ownerID uint, query *models.WebhookDeliveryQuery) ([]models.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhook(webhookID, ownerID); err != nil {
+		return nil, 0, err
+	}
+
+	db := s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取推送记录数量失败: %v", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("id DESC").Offset(offset).Limit(query.PageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取推送记录失败: %v", err)
+	}
+	return deliveries, total, nil
+}
+
+// GetDelivery 获取订阅的一条推送记录
+func (s *WebhookService) GetDelivery(webhookID, deliveryID, ownerID uint) (*models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(webhookID, ownerID); err != nil {
+		return nil, err
+	}
+	var delivery models.WebhookDelivery
+	if err := s.db.Where("id = ? AND webhook_id = ?", deliveryID, webhookID).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("获取推送记录失败: %v", err)
+	}
+	return &delivery, nil
+}
+
+// Redeliver 手动重新推送：以相同的内容创建一条新的推送记录，原记录保持不变
+func (s *WebhookService) Redeliver(webhookID, deliveryID, ownerID uint) (*models.WebhookDelivery, error) {
+	original, err := s.GetDelivery(webhookID, deliveryID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := s.GetWebhook(webhookID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Active {
+		return nil, ErrWebhookInactive
+	}
+
+	delivery := newDelivery(webhookID, original.EventID, original.EventType, original.Payload)
+	delivery.RedeliveryOf = &original.ID
+	if err := s.db.Create(delivery).Error; err != nil {
+		return nil, fmt.Errorf("创建推送记录失败: %v", err)
+	}
+	wakeWebhookWorker()
+	return delivery, nil
+}
+
+// enqueueEvent 为订阅了该事件、且所有者可以看到该事件的启用中订阅创建推送记录
+func (s *WebhookService) enqueueEvent(event models.Event) error {
+	var webhooks []models.Webhook
+	if err := s.db.Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return fmt.Errorf("获取订阅失败: %v", err)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	var payload []byte
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Subscribes(event.Type) || !event.IsVisibleTo(&webhook.OwnerID) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("序列化事件失败: %v", err)
+			}
+		}
+		deliveries = append(deliveries, newDelivery(webhook.ID, event.ID, event.Type, payload))
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := s.db.Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("创建推送记录失败: %v", err)
+	}
+	wakeWebhookWorker()
+	return nil
+}
+
+// claimDelivery 领取一条到期的待发送推送及其订阅，领取方式与 claimJob 相同
+// 发送中但租约已过期的推送视为发送者已退出，次数未用尽时重新领取，否则标记为失败
+func (s *WebhookService) claimDelivery(lease time.Duration) (*models.WebhookDelivery, *models.Webhook, error) {
+	now := time.Now()
+	if err := s.db.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND locked_until < ? AND attempts >= max_attempts", models.DeliveryStatusSending, now).
+		Updates(map[string]interface{}{"status": models.DeliveryStatusFailed, "error": "发送超时", "locked_until": nil}).Error; err != nil {
+		return nil, nil, fmt.Errorf("更新超时推送失败: %v", err)
+	}
+
+	var candidates []models.WebhookDelivery
+	err := s.db.Select("id, status, attempts").
+		Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_until < ?)",
+			models.DeliveryStatusPending, now, models.DeliveryStatusSending, now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(claimBatchSize).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询待发送推送失败: %v", err)
+	}
+
+	for _, candidate := range candidates {
+		result := s.db.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND attempts = ?", candidate.ID, candidate.Status, candidate.Attempts).
+			Updates(map[string]interface{}{
+				"status":       models.DeliveryStatusSending,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"locked_until": now.Add(lease),
+			})
+		if result.Error != nil {
+			return nil, nil, fmt.Errorf("领取推送失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue // 已被其他worker领取
+		}
+
+		var delivery models.WebhookDelivery
+		if err := s.db.First(&delivery, candidate.ID).Error; err != nil {
+			return nil, nil, fmt.Errorf("获取推送记录失败: %v", err)
+		}
+		var webhook models.Webhook
+		if err := s.db.First(&webhook, delivery.WebhookID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, fmt.Errorf("获取订阅失败: %v", err)
+			}
+			webhook = models.Webhook{ID: delivery.WebhookID} // 订阅已被删除，发送时直接失败
+		}
+		return &delivery, &webhook, nil
+	}
+	return nil, nil, nil
+}
+
+// deliveryAttempt 一次发送的结果
+type deliveryAttempt struct {
+	statusCode int
+	body       string
+	duration   time.Duration
+	err        error // 请求失败或接收方未返回 2xx
+	retry      bool  // 失败后是否可以重试
+}
+
+// recordAttempt 记录一次发送的结果：成功、等待 backoff 后重试，或次数用尽后失败
+// 只更新仍由本次发送持有租约的记录
+func (s *WebhookService) recordAttempt(delivery *models.WebhookDelivery, attempt *deliveryAttempt, backoff time.Duration) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"response_status": attempt.statusCode,
+		"response_body":   attempt.body,
+		"duration_ms":     attempt.duration.Milliseconds(),
+		"locked_until":    nil,
+	}
+	switch {
+	case attempt.err == nil:
+		updates["status"] = models.DeliveryStatusSucceeded
+		updates["error"] = ""
+		updates["delivered_at"] = now
+	case attempt.retry && delivery.Attempts < delivery.MaxAttempts:
+		updates["status"] = models.DeliveryStatusPending
+		updates["error"] = attempt.err.Error()
+		updates["next_attempt_at"] = now.Add(backoff)
+	default:
+		updates["status"] = models.DeliveryStatusFailed
+		updates["error"] = attempt.err.Error()
+	}
+
+	return s.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.DeliveryStatusSending, delivery.Attempts).
+		Updates(updates).Error
+}
+
+// releaseDelivery 服务关闭时归还发送中的推送，本次发送不计入尝试次数
+func (s *WebhookService) releaseDelivery(delivery *models.WebhookDelivery) error {
+	return s.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.DeliveryStatusSending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"status":       models.DeliveryStatusPending,
+			"attempts":     gorm.Expr("attempts - 1"),
+			"locked_until": nil,
+		}).Error
+}
+
+// newDelivery 创建待发送的推送记录
+func newDelivery(webhookID uint, eventID, eventType string, payload json.RawMessage) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        models.DeliveryStatusPending,
+		MaxAttempts:   config.AppConfig.Webhooks.MaxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+// wakeWebhookWorker 唤醒一个空闲的推送worker
+func wakeWebhookWorker() {
+	select {
+	case webhookWakeup <- struct{}{}:
+	default:
+	}
+}
+
+// validateWebhookURL 校验接收地址：只允许 http/https，不允许携带用户信息
+// 未允许内网地址时拒绝直接写成内网IP的地址；域名在发送时按实际解析到的IP校验
+func validateWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" || target.User != nil {
+		return "", fmt.Errorf("%w: 接收地址必须是 http/https URL", ErrInvalidWebhook)
+	}
+	if !config.AppConfig.Webhooks.AllowPrivate {
+		if ip := net.ParseIP(target.Hostname()); ip != nil && utils.IsBlockedIP(ip) {
+			return "", fmt.Errorf("%w: %v", ErrInvalidWebhook, utils.ErrBlockedAddress)
+		}
+		if strings.EqualFold(target.Hostname(), "localhost") {
+			return "", fmt.Errorf("%w: %v", ErrInvalidWebhook, utils.ErrBlockedAddress)
+		}
+	}
+	return rawURL, nil
+}
+
+// normalizeWebhookEvents 去重并按 models.WebhookEventTypes 的顺序排列事件类型
+func normalizeWebhookEvents(events []string) []string {
+	selected := make(map[string]bool, len(events))
+	for _, event := range events {
+		selected[event] = true
+	}
+	normalized := make([]string, 0, len(selected))
+	for _, event := range models.WebhookEventTypes {
+		if selected[event] {
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized
+}
+
+// generateWebhookSecret 生成随机签名密钥
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成签名密钥失败: %v", err)
+	}
+	return webhookSecretPrefix + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,252 @@
+package services_test
+
+import (
+	"encoding/json"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/services"
+	"imgGeneratePrompts/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// WebhookServiceTestSuite 是 WebhookService 和 WebhookDispatcher 的测试套件
+// 复用 PromptServiceTestSuite 的数据库设置
+type WebhookServiceTestSuite struct {
+	PromptServiceTestSuite
+	webhookSvc *services.WebhookService
+}
+
+// SetupTest 在每个测试方法运行前清理数据库，并缩短轮询和重试间隔
+func (s *WebhookServiceTestSuite) SetupTest() {
+	s.PromptServiceTestSuite.SetupTest()
+	config.AppConfig.Webhooks = config.WebhookConfig{
+		Workers:      2,
+		MaxAttempts:  3,
+		RetryBackoff: 10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+		Timeout:      5 * time.Second,
+		AllowPrivate: true,
+	}
+	s.webhookSvc = services.NewWebhookService()
+}
+
+// startDispatcher 启动事件推送，测试结束时停止
+func (s *WebhookServiceTestSuite) startDispatcher() {
+	dispatcher := services.NewWebhookDispatcher()
+	dispatcher.Start()
+	s.T().Cleanup(dispatcher.Stop)
+}
+
+// receivedWebhook 接收方收到的一次推送
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver 本地接收方，statuses 依次作为每次请求的响应状态码，用完后返回200
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	received []receivedWebhook
+	server   *httptest.Server
+}
+
+// newWebhookReceiver 启动本地接收方，测试结束时关闭
+func (s *WebhookServiceTestSuite) newWebhookReceiver(statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.received = append(receiver.received, receivedWebhook{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+		receiver.mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte("ok"))
+	}))
+	s.T().Cleanup(receiver.server.Close)
+	return receiver
+}
+
+// requests 返回目前收到的推送
+func (r *webhookReceiver) requests() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+// createWebhook 创建订阅并返回签名密钥
+func (s *WebhookServiceTestSuite) createWebhook(ownerID uint, url string, events ...string) (*models.Webhook, string) {
+	webhook, secret, err := s.webhookSvc.CreateWebhook(ownerID, &models.CreateWebhookRequest{URL: url, Events: events})
+	s.Require().NoError(err)
+	return webhook, secret
+}
+
+// waitDeliveries 等待订阅的推送记录全部结束
+func (s *WebhookServiceTestSuite) waitDeliveries(webhookID, ownerID uint, count int) []models.WebhookDelivery {
+	deadline := time.Now().Add(10 * time.Second)
+	query := &models.WebhookDeliveryQuery{Page: 1, PageSize: 100}
+	for {
+		deliveries, _, err := s.webhookSvc.GetDeliveries(webhookID, ownerID, query)
+		s.Require().NoError(err)
+		finished := 0
+		for _, delivery := range deliveries {
+			if delivery.Status == models.DeliveryStatusSucceeded || delivery.Status == models.DeliveryStatusFailed {
+				finished++
+			}
+		}
+		if len(deliveries) >= count && finished == len(deliveries) {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			s.FailNow("等待推送超时", "推送记录数量: %d，已结束: %d", len(deliveries), finished)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestWebhookCRUD 测试订阅的创建、更新、删除以及所有者隔离
+func (s *WebhookServiceTestSuite) TestWebhookCRUD() {
+	ownerID, otherID := uint(1), uint(2)
+	webhook, secret := s.createWebhook(ownerID, "https://example.com/hook", models.EventTagCreated, models.EventPromptCreated, models.EventTagCreated)
+	s.Contains(secret, "whsec_")
+	s.Equal(secret, webhook.Secret)
+	s.True(webhook.Active)
+	s.Equal([]string{models.EventPromptCreated, models.EventTagCreated}, webhook.GetEvents(), "事件类型应去重并排序")
+
+	_, err := s.webhookSvc.GetWebhook(webhook.ID, otherID)
+	s.ErrorIs(err, services.ErrWebhookNotFound)
+
+	inactive := false
+	updated, newSecret, err := s.webhookSvc.UpdateWebhook(webhook.ID, ownerID, &models.UpdateWebhookRequest{Active: &inactive, RotateSecret: true})
+	s.Require().NoError(err)
+	s.False(updated.Active)
+	s.NotEqual(secret, newSecret)
+	s.Equal(newSecret, updated.Secret)
+
+	// 停用的订阅不能手动重新推送
+	s.Require().NoError(s.db.Create(&models.WebhookDelivery{
+		WebhookID: webhook.ID, EventID: "1-1", EventType: models.EventTagCreated, Payload: []byte(`{}`),
+		Status: models.DeliveryStatusFailed, MaxAttempts: 3, NextAttemptAt: time.Now(),
+	}).Error)
+	deliveries, total, err := s.webhookSvc.GetDeliveries(webhook.ID, ownerID, &models.WebhookDeliveryQuery{Page: 1, PageSize: 10})
+	s.Require().NoError(err)
+	s.Equal(int64(1), total)
+	_, err = s.webhookSvc.Redeliver(webhook.ID, deliveries[0].ID, ownerID)
+	s.ErrorIs(err, services.ErrWebhookInactive)
+
+	s.ErrorIs(s.webhookSvc.DeleteWebhook(webhook.ID, otherID), services.ErrWebhookNotFound)
+	s.Require().NoError(s.webhookSvc.DeleteWebhook(webhook.ID, ownerID))
+	var count int64
+	s.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).Count(&count)
+	s.Zero(count, "删除订阅时应删除推送记录")
+
+	// 不允许内网地址时拒绝内网IP
+	config.AppConfig.Webhooks.AllowPrivate = false
+	_, _, err = s.webhookSvc.CreateWebhook(ownerID, &models.CreateWebhookRequest{URL: "http://127.0.0.1:8080/hook", Events: []string{models.EventTagCreated}})
+	s.ErrorIs(err, services.ErrInvalidWebhook)
+	_, _, err = s.webhookSvc.CreateWebhook(ownerID, &models.CreateWebhookRequest{URL: "ftp://example.com/hook", Events: []string{models.EventTagCreated}})
+	s.ErrorIs(err, services.ErrInvalidWebhook)
+}
+
+// TestWebhookDeliveryRetries 测试推送带有效签名，失败后重试直到成功
+func (s *WebhookServiceTestSuite) TestWebhookDeliveryRetries() {
+	ownerID := uint(1)
+	receiver := s.newWebhookReceiver(http.StatusInternalServerError, http.StatusBadGateway)
+	webhook, secret := s.createWebhook(ownerID, receiver.server.URL, models.EventPromptCreated)
+	s.startDispatcher()
+
+	prompt, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "推送的提示词", OwnerID: &ownerID})
+	s.Require().NoError(err)
+
+	deliveries := s.waitDeliveries(webhook.ID, ownerID, 1)
+	s.Require().Len(deliveries, 1)
+	delivery := deliveries[0]
+	s.Equal(models.DeliveryStatusSucceeded, delivery.Status)
+	s.Equal(3, delivery.Attempts)
+	s.Equal(http.StatusOK, delivery.ResponseStatus)
+	s.Equal("ok", delivery.ResponseBody)
+	s.NotNil(delivery.DeliveredAt)
+
+	requests := receiver.requests()
+	s.Require().Len(requests, 3)
+	for _, request := range requests {
+		s.Equal(models.EventPromptCreated, request.header.Get(utils.WebhookEventHeader))
+		s.NoError(utils.VerifyWebhookSignature(secret, request.header.Get(utils.WebhookSignatureHeader),
+			request.header.Get(utils.WebhookTimestampHeader), request.body, time.Minute, time.Now()))
+	}
+	s.Equal(requests[0].header.Get(utils.WebhookDeliveryHeader), requests[2].header.Get(utils.WebhookDeliveryHeader), "重试时推送记录ID不变")
+
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			ID uint `json:"id"`
+		} `json:"data"`
+	}
+	s.Require().NoError(json.Unmarshal(requests[2].body, &event))
+	s.Equal(models.EventPromptCreated, event.Type)
+	s.Equal(prompt.ID, event.Data.ID)
+
+	// 手动重新推送生成新的记录
+	redelivery, err := s.webhookSvc.Redeliver(webhook.ID, delivery.ID, ownerID)
+	s.Require().NoError(err)
+	s.Equal(delivery.ID, *redelivery.RedeliveryOf)
+	deliveries = s.waitDeliveries(webhook.ID, ownerID, 2)
+	s.Len(deliveries, 2)
+	s.Len(receiver.requests(), 4)
+}
+
+// TestWebhookDeliveryFailure 测试次数用尽后标记为失败
+func (s *WebhookServiceTestSuite) TestWebhookDeliveryFailure() {
+	ownerID := uint(1)
+	receiver := s.newWebhookReceiver(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	webhook, _ := s.createWebhook(ownerID, receiver.server.URL, models.EventTagCreated)
+	s.startDispatcher()
+
+	_, err := services.NewTagService().CreateTag(&models.CreateTagRequest{Name: "推送失败的标签"})
+	s.Require().NoError(err)
+
+	deliveries := s.waitDeliveries(webhook.ID, ownerID, 1)
+	s.Equal(models.DeliveryStatusFailed, deliveries[0].Status)
+	s.Equal(3, deliveries[0].Attempts)
+	s.Contains(deliveries[0].Error, "500")
+}
+
+// TestWebhookVisibility 测试只推送订阅的、所有者可见的事件
+func (s *WebhookServiceTestSuite) TestWebhookVisibility() {
+	ownerID, otherID := uint(1), uint(2)
+	receiver := s.newWebhookReceiver()
+	webhook, _ := s.createWebhook(ownerID, receiver.server.URL, models.EventPromptCreated)
+	s.startDispatcher()
+
+	// 其他用户的私有提示词和未订阅的事件不推送
+	_, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "他人的私有提示词", OwnerID: &otherID, TagNames: []string{"新标签"}})
+	s.Require().NoError(err)
+	public, err := s.service.CreatePromptWithImages(&models.CreatePromptRequest{PromptText: "他人的公开提示词", OwnerID: &otherID, IsPublic: true})
+	s.Require().NoError(err)
+
+	deliveries := s.waitDeliveries(webhook.ID, ownerID, 1)
+	s.Require().Len(deliveries, 1)
+	s.Contains(string(deliveries[0].Payload), `"id":`+jsonNumber(public.ID))
+}
+
+// jsonNumber 将ID格式化为JSON数字
+func jsonNumber(id uint) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+// TestWebhookService 运行 WebhookService 测试套件
+func TestWebhookService(t *testing.T) {
+	suite.Run(t, new(WebhookServiceTestSuite))
+}
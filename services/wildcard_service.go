@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrWildcardNotFound 通配符不存在
+var ErrWildcardNotFound = errors.New("通配符不存在")
+
+// ErrWildcardExists 通配符名称已被使用
+var ErrWildcardExists = errors.New("通配符已存在")
+
+// ErrInvalidWildcard 通配符请求无效
+var ErrInvalidWildcard = errors.New("通配符请求无效")
+
+// WildcardService 通配符服务
+type WildcardService struct {
+	db *gorm.DB
+}
+
+// NewWildcardService 创建通配符服务实例
+func NewWildcardService() *WildcardService {
+	return &WildcardService{
+		db: config.GetDB(),
+	}
+}
+
+// CreateWildcard 创建通配符
+func (s *WildcardService) CreateWildcard(req *models.CreateWildcardRequest) (*models.Wildcard, error) {
+	name := normalizeWildcardName(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: 名称不能为空", ErrInvalidWildcard)
+	}
+
+	if err := s.checkNameAvailable(name, 0); err != nil {
+		return nil, err
+	}
+
+	wildcard := &models.Wildcard{Name: name}
+	wildcard.SetValues(req.Values)
+	if wildcard.Content == "" {
+		return nil, fmt.Errorf("%w: 至少需要一个选项", ErrInvalidWildcard)
+	}
+
+	if err := s.db.Create(wildcard).Error; err != nil {
+		return nil, fmt.Errorf("创建通配符失败: %v", err)
+	}
+	return wildcard, nil
+}
+
+// GetWildcardByID 根据ID获取通配符
+func (s *WildcardService) GetWildcardByID(id uint) (*models.Wildcard, error) {
+	var wildcard models.Wildcard
+	result := s.db.First(&wildcard, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrWildcardNotFound
+		}
+		return nil, fmt.Errorf("获取通配符失败: %v", result.Error)
+	}
+	return &wildcard, nil
+}
+
+// GetWildcardByName 根据名称获取通配符
+func (s *WildcardService) GetWildcardByName(name string) (*models.Wildcard, error) {
+	var wildcard models.Wildcard
+	result := s.db.Where("name = ?", normalizeWildcardName(name)).First(&wildcard)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("通配符 __%s__ 不存在", name)
+		}
+		return nil, fmt.Errorf("获取通配符失败: %v", result.Error)
+	}
+	return &wildcard, nil
+}
+
+// GetAllWildcards 获取所有通配符
+func (s *WildcardService) GetAllWildcards() ([]models.Wildcard, error) {
+	var wildcards []models.Wildcard
+	result := s.db.Order("name ASC").Find(&wildcards)
+	if result.Error != nil {
+		return nil, fmt.Errorf("获取通配符列表失败: %v", result.Error)
+	}
+	return wildcards, nil
+}
+
+// UpdateWildcard 更新通配符
+func (s *WildcardService) UpdateWildcard(id uint, req *models.UpdateWildcardRequest) (*models.Wildcard, error) {
+	wildcard, err := s.GetWildcardByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := normalizeWildcardName(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: 名称不能为空", ErrInvalidWildcard)
+		}
+		if err := s.checkNameAvailable(name, id); err != nil {
+			return nil, err
+		}
+		updates["name"] = name
+	}
+	if req.Values != nil {
+		temp := &models.Wildcard{}
+		temp.SetValues(req.Values)
+		if temp.Content == "" {
+			return nil, fmt.Errorf("%w: 至少需要一个选项", ErrInvalidWildcard)
+		}
+		updates["content"] = temp.Content
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(wildcard).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新通配符失败: %v", err)
+		}
+	}
+
+	return s.GetWildcardByID(id)
+}
+
+// DeleteWildcard 删除通配符
+func (s *WildcardService) DeleteWildcard(id uint) error {
+	result := s.db.Delete(&models.Wildcard{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除通配符失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWildcardNotFound
+	}
+	return nil
+}
+
+// checkNameAvailable 检查名称是否已被其他通配符使用，exceptID 为正在修改的通配符
+func (s *WildcardService) checkNameAvailable(name string, exceptID uint) error {
+	var count int64
+	if err := s.db.Model(&models.Wildcard{}).Where("name = ? AND id != ?", name, exceptID).Count(&count).Error; err != nil {
+		return fmt.Errorf("检查通配符名称失败: %v", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrWildcardExists, name)
+	}
+	return nil
+}
+
+// ResolveWildcard 返回通配符的所有选项，供动态提示词展开使用
+func (s *WildcardService) ResolveWildcard(name string) ([]string, error) {
+	wildcard, err := s.GetWildcardByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return wildcard.GetValues(), nil
+}
+
+// normalizeWildcardName 规范化通配符名称，允许传入 __name__ 形式
+func normalizeWildcardName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > 4 && strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__") {
+		name = name[2 : len(name)-2]
+	}
+	return name
+}
@@ -0,0 +1,733 @@
+package utils
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"imgGeneratePrompts/config"
+	"imgGeneratePrompts/models"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// BackupFormat 备份归档的格式标识
+	BackupFormat = "img-generate-prompts-backup"
+	// BackupSchemaVersion 当前备份归档的结构版本，结构变化时递增
+	// 2: 图片保存在提示词记录的 images 中，不再使用 input_image_url / output_image_url
+	// 3: 增加 users.jsonl，恢复时按用户名映射提示词的所有者
+	BackupSchemaVersion = 3
+
+	backupManifestName   = "manifest.json"
+	backupUsersName      = "data/users.jsonl"
+	backupTagsName       = "data/tags.jsonl"
+	backupPromptsName    = "data/prompts.jsonl"
+	backupPromptTagsName = "data/prompt_tags.jsonl"
+	backupUploadsDir     = "uploads/"
+	backupBatchSize      = 500
+)
+
+// BackupManifest 备份归档的清单文件，总是归档中的第一个条目
+type BackupManifest struct {
+	Format        string    `json:"format"`
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Users         int64     `json:"users"`
+	Prompts       int64     `json:"prompts"`
+	Tags          int64     `json:"tags"`
+	PromptTags    int64     `json:"prompt_tags"`
+	Files         int64     `json:"files"`
+	MissingFiles  []string  `json:"missing_files"` // 被引用但在uploads目录中不存在的文件
+}
+
+// BackupUser 备份中的用户记录，只用于恢复时按用户名映射所有者，不包含密码等账号信息
+type BackupUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// BackupTag 备份中的标签记录
+type BackupTag struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupPrompt 备份中的提示词记录（包含软删除时间）
+type BackupPrompt struct {
+	ID                    uint                `json:"id"`
+	CreatedAt             time.Time           `json:"created_at"`
+	UpdatedAt             time.Time           `json:"updated_at"`
+	DeletedAt             *time.Time          `json:"deleted_at"`
+	PromptText            string              `json:"prompt_text"`
+	NegativePrompt        string              `json:"negative_prompt"`
+	ModelName             string              `json:"model_name"`
+	InputImageURL         string              `json:"input_image_url,omitempty"`  // 仅结构版本 1：逗号分隔的输入图片
+	OutputImageURL        string              `json:"output_image_url,omitempty"` // 仅结构版本 1：输出图片
+	IsPublic              bool                `json:"is_public"`
+	StyleDescription      string              `json:"style_description"`
+	UsageScenario         string              `json:"usage_scenario"`
+	AtmosphereDescription string              `json:"atmosphere_description"`
+	ExpressiveIntent      string              `json:"expressive_intent"`
+	StructureAnalysis     json.RawMessage     `json:"structure_analysis"`
+	GenerationParams      json.RawMessage     `json:"generation_params,omitempty"`
+	ParentID              *uint               `json:"parent_id"`
+	OwnerID               *uint               `json:"owner_id"`
+	Version               uint                `json:"version,omitempty"` // 旧备份没有版本号，恢复时为 1
+	Images                []BackupPromptImage `json:"images,omitempty"`
+}
+
+// BackupPromptImage 备份中的提示词图片记录
+type BackupPromptImage struct {
+	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role"`
+	Position  int       `json:"position"`
+	URL       string    `json:"url"`
+	Caption   string    `json:"caption,omitempty"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+// BackupPromptTag 备份中的提示词-标签关联记录
+type BackupPromptTag struct {
+	PromptID uint `json:"prompt_id"`
+	TagID    uint `json:"tag_id"`
+}
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	// RemapIDs 为恢复的记录分配新ID（用于恢复到已有数据的数据库）
+	// 标签按名称与已有标签合并，提示词、父提示词和标签关联按新ID重写
+	RemapIDs bool
+	// OwnerFallback 所有者在目标数据库中不存在（按用户名匹配）时改为归属的管理员用户名
+	// 为空时遇到无法映射的所有者则恢复失败
+	OwnerFallback string
+}
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	Manifest         BackupManifest `json:"manifest"`
+	Prompts          int64          `json:"prompts"`
+	Tags             int64          `json:"tags"`        // 新建的标签数量
+	MergedTags       int64          `json:"merged_tags"` // 与已有标签合并的数量
+	PromptTags       int64          `json:"prompt_tags"`
+	Files            int64          `json:"files"`             // 写入的文件数量
+	SkippedFiles     int64          `json:"skipped_files"`     // 目标目录中已存在而跳过的文件
+	ReassignedOwners int64          `json:"reassigned_owners"` // 改为归属 OwnerFallback 的提示词数量
+}
+
+// BackupData 将全部提示词（含软删除）、标签、标签关联以及引用的上传文件写入 tar.gz 归档
+func (dm *DatabaseManager) BackupData(outputPath string) (*BackupManifest, error) {
+	db := config.GetDB()
+	manifest := &BackupManifest{
+		Format:        BackupFormat,
+		SchemaVersion: BackupSchemaVersion,
+		CreatedAt:     time.Now(),
+		MissingFiles:  []string{},
+	}
+
+	tempDir, err := os.MkdirTemp("", "prompts-backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// 1. 先将数据导出到临时文件，得到各部分的数量和大小
+	var users []BackupUser
+	if db.Migrator().HasTable(&models.User{}) {
+		if err := db.Model(&models.User{}).Order("id ASC").Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("读取用户失败: %v", err)
+		}
+	}
+	manifest.Users = int64(len(users))
+	if err := writeJSONLines(filepath.Join(tempDir, "users.jsonl"), func(emit func(interface{}) error) error {
+		for _, user := range users {
+			if err := emit(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var tags []BackupTag
+	if err := db.Model(&models.Tag{}).Order("id ASC").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("读取标签失败: %v", err)
+	}
+	manifest.Tags = int64(len(tags))
+	if err := writeJSONLines(filepath.Join(tempDir, "tags.jsonl"), func(emit func(interface{}) error) error {
+		for _, tag := range tags {
+			if err := emit(tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	referencedFiles := make(map[string]bool)
+	if err := writeJSONLines(filepath.Join(tempDir, "prompts.jsonl"), func(emit func(interface{}) error) error {
+		var batch []models.Prompt
+		return db.Unscoped().Preload("Images", models.OrderedImages).Order("id ASC").FindInBatches(&batch, backupBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				record := toBackupPrompt(&batch[i])
+				if err := emit(record); err != nil {
+					return err
+				}
+				manifest.Prompts++
+				for _, fileURL := range batch[i].ImageURLs() {
+					if name, ok := uploadedFileName(fileURL); ok {
+						referencedFiles[name] = true
+					}
+				}
+			}
+			return nil
+		}).Error
+	}); err != nil {
+		return nil, fmt.Errorf("导出提示词失败: %v", err)
+	}
+
+	if err := writeJSONLines(filepath.Join(tempDir, "prompt_tags.jsonl"), func(emit func(interface{}) error) error {
+		var links []BackupPromptTag
+		if err := db.Table("prompt_tags").Order("prompt_id ASC, tag_id ASC").Find(&links).Error; err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err := emit(link); err != nil {
+				return err
+			}
+		}
+		manifest.PromptTags = int64(len(links))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("导出标签关联失败: %v", err)
+	}
+
+	uploadPath := config.AppConfig.Server.UploadPath
+	files := make([]string, 0, len(referencedFiles))
+	for name := range referencedFiles {
+		if _, err := os.Stat(filepath.Join(uploadPath, name)); err != nil {
+			manifest.MissingFiles = append(manifest.MissingFiles, "/uploads/"+name)
+			continue
+		}
+		files = append(files, name)
+	}
+	manifest.Files = int64(len(files))
+
+	// 2. 写入归档：清单在最前面，恢复时可以先校验版本
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("创建备份文件失败: %v", err)
+	}
+	defer out.Close()
+
+	gzipWriter := gzip.NewWriter(out)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("生成清单失败: %v", err)
+	}
+	if err := writeTarBytes(tarWriter, backupManifestName, manifestData); err != nil {
+		return nil, err
+	}
+	for _, entry := range []string{backupUsersName, backupTagsName, backupPromptsName, backupPromptTagsName} {
+		if err := writeTarFile(tarWriter, entry, filepath.Join(tempDir, path.Base(entry))); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range files {
+		if err := writeTarFile(tarWriter, backupUploadsDir+name, filepath.Join(uploadPath, name)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("写入备份文件失败: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("写入备份文件失败: %v", err)
+	}
+
+	log.Printf("备份完成: %d 条提示词, %d 个标签, %d 条标签关联, %d 个文件", manifest.Prompts, manifest.Tags, manifest.PromptTags, manifest.Files)
+	return manifest, nil
+}
+
+// RestoreData 从备份归档恢复数据，数据库写入在一个事务中完成
+func (dm *DatabaseManager) RestoreData(archivePath string, opts RestoreOptions) (*RestoreResult, error) {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开备份文件失败: %v", err)
+	}
+	defer in.Close()
+
+	gzipReader, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("备份文件不是有效的gzip格式: %v", err)
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+
+	// 1. 校验清单
+	header, err := tarReader.Next()
+	if err != nil || header.Name != backupManifestName {
+		return nil, fmt.Errorf("备份文件缺少清单 %s", backupManifestName)
+	}
+	result := &RestoreResult{}
+	if err := json.NewDecoder(tarReader).Decode(&result.Manifest); err != nil {
+		return nil, fmt.Errorf("解析备份清单失败: %v", err)
+	}
+	if result.Manifest.Format != BackupFormat {
+		return nil, fmt.Errorf("不支持的备份格式: %s", result.Manifest.Format)
+	}
+	if result.Manifest.SchemaVersion < 1 || result.Manifest.SchemaVersion > BackupSchemaVersion {
+		return nil, fmt.Errorf("不支持的备份结构版本 %d（当前支持 1-%d）", result.Manifest.SchemaVersion, BackupSchemaVersion)
+	}
+
+	// 2. 读取数据并写出上传文件，恢复失败时删除本次写出的文件
+	var (
+		users      []BackupUser
+		tags       []BackupTag
+		prompts    []BackupPrompt
+		promptTags []BackupPromptTag
+		written    []string
+		restored   bool
+	)
+	uploadPath := config.AppConfig.Server.UploadPath
+	if err := os.MkdirAll(uploadPath, 0755); err != nil {
+		return nil, fmt.Errorf("创建上传目录失败: %v", err)
+	}
+	defer func() {
+		if restored {
+			return
+		}
+		for _, name := range written {
+			DeleteFile(filepath.Join(uploadPath, name))
+		}
+	}()
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取备份文件失败: %v", err)
+		}
+
+		switch {
+		case header.Name == backupUsersName:
+			err = readJSONLines(tarReader, func() interface{} { users = append(users, BackupUser{}); return &users[len(users)-1] })
+		case header.Name == backupTagsName:
+			err = readJSONLines(tarReader, func() interface{} { tags = append(tags, BackupTag{}); return &tags[len(tags)-1] })
+		case header.Name == backupPromptsName:
+			err = readJSONLines(tarReader, func() interface{} { prompts = append(prompts, BackupPrompt{}); return &prompts[len(prompts)-1] })
+		case header.Name == backupPromptTagsName:
+			err = readJSONLines(tarReader, func() interface{} {
+				promptTags = append(promptTags, BackupPromptTag{})
+				return &promptTags[len(promptTags)-1]
+			})
+		case strings.HasPrefix(header.Name, backupUploadsDir) && header.Typeflag == tar.TypeReg:
+			name := strings.TrimPrefix(header.Name, backupUploadsDir)
+			var created bool
+			created, err = restoreUploadedFile(tarReader, uploadPath, name)
+			if created {
+				written = append(written, name)
+				result.Files++
+			} else if err == nil {
+				result.SkippedFiles++
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %v", header.Name, err)
+		}
+	}
+
+	// 3. 在事务中写入数据库
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		tagIDs, err := restoreTags(tx, tags, opts, result)
+		if err != nil {
+			return err
+		}
+		owners, err := restoreOwners(tx, users, prompts, opts, result)
+		if err != nil {
+			return err
+		}
+		promptIDs, err := restorePrompts(tx, prompts, owners, opts, result)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range promptTags {
+			promptID, tagID := promptIDs[link.PromptID], tagIDs[link.TagID]
+			if promptID == 0 || tagID == 0 {
+				continue
+			}
+			res := tx.Exec("INSERT IGNORE INTO prompt_tags (prompt_id, tag_id) VALUES (?, ?)", promptID, tagID)
+			if res.Error != nil {
+				return fmt.Errorf("恢复标签关联失败: %v", res.Error)
+			}
+			result.PromptTags += res.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	restored = true
+
+	log.Printf("恢复完成: %d 条提示词, %d 个新标签, %d 条标签关联, %d 个文件", result.Prompts, result.Tags, result.PromptTags, result.Files)
+	return result, nil
+}
+
+// restoreTags 恢复标签，返回备份ID到数据库ID的映射
+func restoreTags(tx *gorm.DB, tags []BackupTag, opts RestoreOptions, result *RestoreResult) (map[uint]uint, error) {
+	ids := make(map[uint]uint, len(tags))
+	for _, backupTag := range tags {
+		var existing models.Tag
+		err := tx.Where("name = ?", backupTag.Name).First(&existing).Error
+		if err == nil {
+			// 同名标签：重映射模式下直接合并；保留ID模式下要求ID一致
+			if !opts.RemapIDs && existing.ID != backupTag.ID {
+				return nil, fmt.Errorf("标签 %s 在数据库中的ID为 %d，与备份中的 %d 冲突，请使用ID重映射选项", backupTag.Name, existing.ID, backupTag.ID)
+			}
+			ids[backupTag.ID] = existing.ID
+			result.MergedTags++
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("查询标签失败: %v", err)
+		}
+
+		tag := models.Tag{Name: backupTag.Name, CreatedAt: backupTag.CreatedAt}
+		if !opts.RemapIDs {
+			tag.ID = backupTag.ID
+		}
+		if err := tx.Create(&tag).Error; err != nil {
+			return nil, fmt.Errorf("恢复标签 %s 失败（可能存在ID冲突，请使用ID重映射选项）: %v", backupTag.Name, err)
+		}
+		ids[backupTag.ID] = tag.ID
+		result.Tags++
+	}
+	return ids, nil
+}
+
+// restorePrompts 恢复提示词，返回备份ID到数据库ID的映射
+func restorePrompts(tx *gorm.DB, prompts []BackupPrompt, owners map[uint]uint, opts RestoreOptions, result *RestoreResult) (map[uint]uint, error) {
+	if !opts.RemapIDs && len(prompts) > 0 {
+		backupIDs := make([]uint, len(prompts))
+		for i, p := range prompts {
+			backupIDs[i] = p.ID
+		}
+		var conflicts int64
+		if err := tx.Unscoped().Model(&models.Prompt{}).Where("id IN ?", backupIDs).Count(&conflicts).Error; err != nil {
+			return nil, fmt.Errorf("检查提示词ID冲突失败: %v", err)
+		}
+		if conflicts > 0 {
+			return nil, fmt.Errorf("数据库中已存在 %d 条ID冲突的提示词，请使用ID重映射选项", conflicts)
+		}
+	}
+
+	ids := make(map[uint]uint, len(prompts))
+	for _, backupPrompt := range prompts {
+		prompt := fromBackupPrompt(&backupPrompt)
+		prompt.ParentID = nil // 所有提示词写入后再设置，父提示词可能排在后面
+		if prompt.OwnerID != nil {
+			ownerID := owners[*prompt.OwnerID]
+			prompt.OwnerID = &ownerID
+		}
+		if opts.RemapIDs {
+			prompt.ID = 0
+		}
+		if err := tx.Create(prompt).Error; err != nil {
+			return nil, fmt.Errorf("恢复提示词 %d 失败: %v", backupPrompt.ID, err)
+		}
+		ids[backupPrompt.ID] = prompt.ID
+		result.Prompts++
+	}
+
+	for _, backupPrompt := range prompts {
+		if backupPrompt.ParentID == nil {
+			continue
+		}
+		parentID, ok := ids[*backupPrompt.ParentID]
+		if !ok {
+			if opts.RemapIDs {
+				continue // 父提示词不在备份中，无法映射
+			}
+			parentID = *backupPrompt.ParentID
+		}
+		err := tx.Unscoped().Model(&models.Prompt{}).Where("id = ?", ids[backupPrompt.ID]).UpdateColumn("parent_id", parentID).Error
+		if err != nil {
+			return nil, fmt.Errorf("恢复提示词衍生关系失败: %v", err)
+		}
+	}
+	return ids, nil
+}
+
+// restoreOwners 按用户名把备份中的所有者ID映射为目标数据库中的用户ID
+// 用户ID在不同数据库之间不稳定，不能直接沿用；找不到同名用户时改为归属 opts.OwnerFallback 指定的管理员，
+// 未指定时恢复失败，避免私有提示词归属无关用户或变成无所有者的记录
+func restoreOwners(tx *gorm.DB, users []BackupUser, prompts []BackupPrompt, opts RestoreOptions, result *RestoreResult) (map[uint]uint, error) {
+	owners := make(map[uint]uint)
+	var backupOwners []uint
+	for _, p := range prompts {
+		if p.OwnerID != nil {
+			if _, ok := owners[*p.OwnerID]; !ok {
+				owners[*p.OwnerID] = 0
+				backupOwners = append(backupOwners, *p.OwnerID)
+			}
+		}
+	}
+	if len(backupOwners) == 0 {
+		return owners, nil
+	}
+
+	var targetUsers []models.User
+	if tx.Migrator().HasTable(&models.User{}) {
+		if err := tx.Select("id", "username", "role").Find(&targetUsers).Error; err != nil {
+			return nil, fmt.Errorf("查询用户失败: %v", err)
+		}
+	}
+	targetIDs := make(map[string]uint, len(targetUsers))
+	for _, user := range targetUsers {
+		targetIDs[user.Username] = user.ID
+	}
+	var fallbackID uint
+	if opts.OwnerFallback != "" {
+		for _, user := range targetUsers {
+			if user.Username == opts.OwnerFallback {
+				if user.Role != models.RoleAdmin {
+					return nil, fmt.Errorf("用户 %s 不是管理员，不能接收无法映射的提示词", user.Username)
+				}
+				fallbackID = user.ID
+			}
+		}
+		if fallbackID == 0 {
+			return nil, fmt.Errorf("管理员 %s 不存在", opts.OwnerFallback)
+		}
+	}
+
+	usernames := make(map[uint]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+	reassigned := make(map[uint]bool)
+	var unmapped []string
+	for _, backupID := range backupOwners {
+		name, ok := usernames[backupID]
+		if id, exists := targetIDs[name]; ok && exists {
+			owners[backupID] = id
+			continue
+		}
+		if !ok {
+			name = fmt.Sprintf("ID %d", backupID) // 结构版本 1、2 的备份没有用户记录
+		}
+		if fallbackID == 0 {
+			unmapped = append(unmapped, name)
+			continue
+		}
+		owners[backupID] = fallbackID
+		reassigned[backupID] = true
+	}
+	if len(unmapped) > 0 {
+		return nil, fmt.Errorf("目标数据库中没有以下所有者: %s，请先创建这些用户或指定接收其提示词的管理员", strings.Join(unmapped, ", "))
+	}
+	for _, p := range prompts {
+		if p.OwnerID != nil && reassigned[*p.OwnerID] {
+			result.ReassignedOwners++
+		}
+	}
+	return owners, nil
+}
+
+// toBackupPrompt 将提示词转换为备份记录
+func toBackupPrompt(p *models.Prompt) BackupPrompt {
+	record := BackupPrompt{
+		ID:                    p.ID,
+		CreatedAt:             p.CreatedAt,
+		UpdatedAt:             p.UpdatedAt,
+		PromptText:            p.PromptText,
+		NegativePrompt:        p.NegativePrompt,
+		ModelName:             p.ModelName,
+		IsPublic:              p.IsPublic,
+		StyleDescription:      p.StyleDescription,
+		UsageScenario:         p.UsageScenario,
+		AtmosphereDescription: p.AtmosphereDescription,
+		ExpressiveIntent:      p.ExpressiveIntent,
+		StructureAnalysis:     p.StructureAnalysis,
+		GenerationParams:      p.GenerationParams,
+		ParentID:              p.ParentID,
+		OwnerID:               p.OwnerID,
+		Version:               p.Version,
+	}
+	for _, image := range p.Images {
+		record.Images = append(record.Images, BackupPromptImage{
+			CreatedAt: image.CreatedAt,
+			Role:      image.Role,
+			Position:  image.Position,
+			URL:       image.URL,
+			Caption:   image.Caption,
+			Width:     image.Width,
+			Height:    image.Height,
+			Hash:      image.Hash,
+		})
+	}
+	if p.DeletedAt.Valid {
+		deletedAt := p.DeletedAt.Time
+		record.DeletedAt = &deletedAt
+	}
+	return record
+}
+
+// fromBackupPrompt 将备份记录转换为提示词
+func fromBackupPrompt(record *BackupPrompt) *models.Prompt {
+	prompt := &models.Prompt{
+		ID:                    record.ID,
+		CreatedAt:             record.CreatedAt,
+		UpdatedAt:             record.UpdatedAt,
+		PromptText:            record.PromptText,
+		NegativePrompt:        record.NegativePrompt,
+		ModelName:             record.ModelName,
+		IsPublic:              record.IsPublic,
+		StyleDescription:      record.StyleDescription,
+		UsageScenario:         record.UsageScenario,
+		AtmosphereDescription: record.AtmosphereDescription,
+		ExpressiveIntent:      record.ExpressiveIntent,
+		StructureAnalysis:     record.StructureAnalysis,
+		GenerationParams:      record.GenerationParams,
+		ParentID:              record.ParentID,
+		OwnerID:               record.OwnerID,
+		Version:               record.Version,
+	}
+	for _, image := range record.Images {
+		prompt.Images = append(prompt.Images, &models.PromptImage{
+			CreatedAt: image.CreatedAt,
+			Role:      image.Role,
+			Position:  image.Position,
+			URL:       image.URL,
+			Caption:   image.Caption,
+			Width:     image.Width,
+			Height:    image.Height,
+			Hash:      image.Hash,
+		})
+	}
+	if len(record.Images) == 0 {
+		// 结构版本 1 的备份
+		prompt.SetInputImageURLs(strings.Split(record.InputImageURL, ","))
+		prompt.SetOutputImageURL(record.OutputImageURL)
+	}
+	if record.DeletedAt != nil {
+		prompt.DeletedAt = gorm.DeletedAt{Time: *record.DeletedAt, Valid: true}
+	}
+	return prompt
+}
+
+// uploadedFileName 从 /uploads/ 开头的URL中提取文件名
+func uploadedFileName(fileURL string) (string, bool) {
+	if !strings.HasPrefix(fileURL, "/uploads/") {
+		return "", false
+	}
+	name := strings.TrimPrefix(fileURL, "/uploads/")
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	return name, true
+}
+
+// restoreUploadedFile 将归档中的文件写入上传目录，同名文件已存在时跳过
+func restoreUploadedFile(r io.Reader, uploadPath, name string) (bool, error) {
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		return false, fmt.Errorf("非法的文件名: %s", name)
+	}
+	file, err := os.OpenFile(filepath.Join(uploadPath, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return false, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return false, err
+	}
+	return true, nil
+}
+
+// writeJSONLines 将记录逐行写入JSON Lines文件
+func writeJSONLines(filename string, write func(emit func(interface{}) error) error) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buffered)
+	if err := write(encoder.Encode); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// readJSONLines 逐行解析JSON Lines，next返回用于接收下一条记录的指针
+func readJSONLines(r io.Reader, next func() interface{}) error {
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		if err := decoder.Decode(next()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTarBytes 将内存中的数据写入tar归档
+func writeTarBytes(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// writeTarFile 将磁盘文件写入tar归档
+func writeTarFile(tw *tar.Writer, name, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %v", filename, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %v", filename, err)
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", name, err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"strings"
+	"time"
+)
+
+// Civitai 图片元数据的导入导出。
+// Civitai 的图片页面和 /api/v1/images 接口中，每张图片带有一个 meta 对象：
+//   {"prompt": "...", "negativePrompt": "...", "seed": 1, "sampler": "Euler a", "cfgScale": 7,
+//    "steps": 30, "Model": "...", "resources": [{"name": "...", "type": "lora", "weight": 0.8}]}
+// 导入时 prompt、negativePrompt、Model 分别写入提示词、负面提示词和模型名称，
+// 其余字段原样保存在 generation_params 中，导出时再合并回 meta，保证往返不丢失信息。
+// 只处理本地文件或请求中的JSON，不会访问 Civitai 接口。
+
+// civitaiTagResourceTypes 导入时作为标签的资源类型（checkpoint 已作为模型名称保存）
+var civitaiTagResourceTypes = map[string]bool{
+	"lora":             true,
+	"locon":            true,
+	"lycoris":          true,
+	"embedding":        true,
+	"textualinversion": true,
+	"hypernetwork":     true,
+}
+
+// CivitaiResource meta.resources 中的模型资源
+type CivitaiResource struct {
+	Name   string   `json:"name,omitempty"`
+	Type   string   `json:"type,omitempty"`
+	Weight *float64 `json:"weight,omitempty"`
+	Hash   string   `json:"hash,omitempty"`
+}
+
+// CivitaiImage Civitai 图片条目（/api/v1/images 返回的 items 元素）
+// Tags 不是 Civitai 接口的字段，导出时附带本系统的标签，导入时一并读取
+type CivitaiImage struct {
+	ID        uint                       `json:"id,omitempty"`
+	URL       string                     `json:"url,omitempty"`
+	CreatedAt *time.Time                 `json:"createdAt,omitempty"`
+	Meta      map[string]json.RawMessage `json:"meta"`
+	Tags      []string                   `json:"tags,omitempty"`
+}
+
+// expandCivitaiValue 将一个顶层JSON值展开为图片条目列表
+// 支持接口响应（{"items": [...]}）、条目数组、单个条目（带 meta）以及单独的 meta 对象
+func expandCivitaiValue(raw json.RawMessage) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err == nil {
+		return items, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return nil, fmt.Errorf("不是有效的Civitai JSON: %v", err)
+	}
+	if list, ok := object["items"]; ok {
+		if err := json.Unmarshal(list, &items); err != nil {
+			return nil, fmt.Errorf("items 不是数组: %v", err)
+		}
+		return items, nil
+	}
+	return []json.RawMessage{raw}, nil
+}
+
+// CivitaiToRequest 将一个 Civitai 图片条目（或单独的 meta 对象）映射为创建请求
+// 返回请求和条目中实际出现的字段
+func CivitaiToRequest(raw json.RawMessage) (*models.CreatePromptRequest, []string, error) {
+	var item map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &item); err != nil || item == nil {
+		return nil, nil, fmt.Errorf("条目不是JSON对象")
+	}
+
+	meta := item
+	if metaRaw, ok := item["meta"]; ok {
+		meta = nil
+		if err := json.Unmarshal(metaRaw, &meta); err != nil || meta == nil {
+			return nil, nil, fmt.Errorf("条目的 meta 为空，没有生成信息")
+		}
+	}
+
+	req := &models.CreatePromptRequest{}
+	fields := []string{"prompt_text"}
+	req.PromptText = strings.TrimSpace(civitaiString(meta["prompt"]))
+	if req.PromptText == "" {
+		return nil, nil, fmt.Errorf("meta 中缺少 prompt")
+	}
+	if _, ok := meta["negativePrompt"]; ok {
+		req.NegativePrompt = civitaiString(meta["negativePrompt"])
+		fields = append(fields, "negative_prompt")
+	}
+
+	var resources []CivitaiResource
+	if resourcesRaw, ok := meta["resources"]; ok {
+		json.Unmarshal(resourcesRaw, &resources)
+	}
+
+	req.ModelName = strings.TrimSpace(civitaiString(meta["Model"]))
+	if req.ModelName == "" {
+		for _, resource := range resources {
+			if strings.EqualFold(resource.Type, "model") || strings.EqualFold(resource.Type, "checkpoint") {
+				req.ModelName = resource.Name
+				break
+			}
+		}
+	}
+	if req.ModelName != "" {
+		fields = append(fields, "model_name")
+	}
+
+	if url := strings.TrimSpace(civitaiString(item["url"])); url != "" && item["meta"] != nil {
+		req.OutputImageURL = url
+		fields = append(fields, "output_image_url")
+	}
+
+	// 其余 meta 字段作为生成参数保存
+	params := make(map[string]json.RawMessage, len(meta))
+	for key, value := range meta {
+		switch key {
+		case "prompt", "negativePrompt", "Model":
+			continue
+		}
+		params[key] = value
+	}
+	if len(params) > 0 {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.GenerationParams = string(data)
+		fields = append(fields, "generation_params")
+	}
+
+	// 标签：条目自带的标签 + LoRA/embedding 等资源名称
+	seen := make(map[string]bool)
+	addTag := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			req.TagNames = append(req.TagNames, name)
+		}
+	}
+	if tagsRaw, ok := item["tags"]; ok {
+		for _, tag := range civitaiTags(tagsRaw) {
+			addTag(tag)
+		}
+	}
+	for _, resource := range resources {
+		if civitaiTagResourceTypes[strings.ToLower(resource.Type)] {
+			addTag(resource.Name)
+		}
+	}
+	if len(req.TagNames) > 0 {
+		fields = append(fields, "tag_names")
+	}
+
+	return req, fields, nil
+}
+
+// CivitaiFromPrompt 将提示词转换为 Civitai 图片条目
+func CivitaiFromPrompt(p *models.PromptResponse) (*CivitaiImage, error) {
+	meta := make(map[string]json.RawMessage)
+	if len(p.GenerationParams) > 0 {
+		if err := json.Unmarshal(p.GenerationParams, &meta); err != nil || meta == nil {
+			meta = make(map[string]json.RawMessage)
+		}
+	}
+
+	set := func(key string, value interface{}) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		meta[key] = data
+		return nil
+	}
+	if err := set("prompt", p.PromptText); err != nil {
+		return nil, err
+	}
+	if p.NegativePrompt != "" {
+		if err := set("negativePrompt", p.NegativePrompt); err != nil {
+			return nil, err
+		}
+	}
+	if p.ModelName != "" {
+		if err := set("Model", p.ModelName); err != nil {
+			return nil, err
+		}
+	}
+
+	createdAt := p.CreatedAt
+	image := &CivitaiImage{
+		ID:        p.ID,
+		URL:       p.OutputImageURL,
+		CreatedAt: &createdAt,
+		Meta:      meta,
+	}
+	for _, tag := range p.Tags {
+		image.Tags = append(image.Tags, tag.Name)
+	}
+	return image, nil
+}
+
+// civitaiString 读取JSON字符串值，不是字符串时返回空字符串
+func civitaiString(raw json.RawMessage) string {
+	var s string
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return ""
+	}
+	return s
+}
+
+// civitaiTags 读取标签列表，支持字符串数组和 [{"name": "..."}] 两种形式
+func civitaiTags(raw json.RawMessage) []string {
+	var names []string
+	if err := json.Unmarshal(raw, &names); err == nil {
+		return names
+	}
+	var objects []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &objects); err == nil {
+		for _, object := range objects {
+			names = append(names, object.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,132 @@
+package utils_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// civitaiAPIResponse 保存的 Civitai /api/v1/images 响应
+const civitaiAPIResponse = `{
+  "items": [
+    {
+      "id": 1001,
+      "url": "https://image.civitai.com/xG1nkqKTMzGDvpLrqFT7WA/cat.jpeg",
+      "width": 832,
+      "height": 1216,
+      "nsfw": false,
+      "meta": {
+        "prompt": "a cat wearing a wizard hat, <lora:wizardry:0.8>",
+        "negativePrompt": "blurry, lowres",
+        "seed": 1234567890,
+        "sampler": "DPM++ 2M Karras",
+        "cfgScale": 7,
+        "steps": 30,
+        "Size": "832x1216",
+        "Model": "juggernautXL_v9",
+        "Model hash": "d91d35736d",
+        "resources": [
+          {"name": "juggernautXL_v9", "type": "model", "hash": "d91d35736d"},
+          {"name": "wizardry", "type": "lora", "weight": 0.8}
+        ]
+      }
+    },
+    {"id": 1002, "url": "https://image.civitai.com/no-meta.jpeg", "meta": null}
+  ],
+  "metadata": {"nextCursor": "1003"}
+}`
+
+// TestCivitaiImport 测试 Civitai 接口响应的导入映射
+func TestCivitaiImport(t *testing.T) {
+	reader, err := utils.NewImportReader(strings.NewReader(civitaiAPIResponse), models.ImportFormatCivitai, nil)
+	require.NoError(t, err)
+	rows := readAllImportRows(t, reader)
+	require.Len(t, rows, 2)
+
+	row := rows[0]
+	require.NoError(t, row.Err)
+	assert.Equal(t, 1, row.Line)
+	assert.Equal(t, "a cat wearing a wizard hat, <lora:wizardry:0.8>", row.Request.PromptText)
+	assert.Equal(t, "blurry, lowres", row.Request.NegativePrompt)
+	assert.Equal(t, "juggernautXL_v9", row.Request.ModelName)
+	assert.Equal(t, "https://image.civitai.com/xG1nkqKTMzGDvpLrqFT7WA/cat.jpeg", row.Request.OutputImageURL)
+	assert.Equal(t, []string{"wizardry"}, row.Request.TagNames)
+	assert.True(t, row.HasField("generation_params"))
+
+	var params map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(row.Request.GenerationParams), &params))
+	assert.Equal(t, float64(1234567890), params["seed"])
+	assert.Equal(t, "DPM++ 2M Karras", params["sampler"])
+	assert.Equal(t, float64(7), params["cfgScale"])
+	assert.NotContains(t, params, "prompt")
+	assert.NotContains(t, params, "Model")
+
+	assert.Error(t, rows[1].Err, "没有 meta 的条目应报错")
+	assert.Equal(t, 2, rows[1].Line)
+}
+
+// TestCivitaiImportShapes 测试单独的 meta 对象、条目数组以及格式错误
+func TestCivitaiImportShapes(t *testing.T) {
+	input := `{"prompt": "bare meta", "seed": 1}
+[{"meta": {"prompt": "in array"}, "tags": [{"name": "landscape"}]}]
+{"broken": `
+	reader, err := utils.NewImportReader(strings.NewReader(input), models.ImportFormatCivitai, nil)
+	require.NoError(t, err)
+	rows := readAllImportRows(t, reader)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, "bare meta", rows[0].Request.PromptText)
+	assert.JSONEq(t, `{"seed": 1}`, rows[0].Request.GenerationParams)
+	assert.Equal(t, "in array", rows[1].Request.PromptText)
+	assert.Equal(t, []string{"landscape"}, rows[1].Request.TagNames)
+	assert.Error(t, rows[2].Err)
+}
+
+// TestCivitaiRoundTrip 测试导出的 Civitai 结构可以重新导入且生成参数不丢失
+func TestCivitaiRoundTrip(t *testing.T) {
+	prompt := &models.Prompt{
+		ID:               7,
+		PromptText:       "a castle at dusk",
+		NegativePrompt:   "text, watermark",
+		ModelName:        "sdxl_base",
+		Images:           []*models.PromptImage{{Role: models.ImageRoleOutput, URL: "/uploads/castle.png"}},
+		GenerationParams: json.RawMessage(`{"seed": 42, "sampler": "Euler a", "cfgScale": 5.5, "resources": [{"name": "castles", "type": "lora", "weight": 1}]}`),
+		Tags:             []*models.Tag{{Name: "建筑"}},
+	}
+
+	var buf bytes.Buffer
+	exporter, err := utils.NewPromptExporter(&buf, models.ExportFormatCivitai)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Write(prompt))
+	require.NoError(t, exporter.Close())
+
+	var exported struct {
+		Items []utils.CivitaiImage `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &exported))
+	require.Len(t, exported.Items, 1)
+	meta := exported.Items[0].Meta
+	assert.JSONEq(t, `"a castle at dusk"`, string(meta["prompt"]))
+	assert.JSONEq(t, `"text, watermark"`, string(meta["negativePrompt"]))
+	assert.JSONEq(t, `"sdxl_base"`, string(meta["Model"]))
+	assert.JSONEq(t, `42`, string(meta["seed"]))
+	assert.JSONEq(t, `5.5`, string(meta["cfgScale"]))
+	assert.Equal(t, uint(7), exported.Items[0].ID)
+
+	reader, err := utils.NewImportReader(bytes.NewReader(buf.Bytes()), models.ImportFormatCivitai, nil)
+	require.NoError(t, err)
+	rows := readAllImportRows(t, reader)
+	require.Len(t, rows, 1)
+	require.NoError(t, rows[0].Err)
+	assert.Equal(t, prompt.PromptText, rows[0].Request.PromptText)
+	assert.Equal(t, prompt.ModelName, rows[0].Request.ModelName)
+	assert.Equal(t, prompt.GetOutputImageURL(), rows[0].Request.OutputImageURL)
+	assert.JSONEq(t, string(prompt.GenerationParams), rows[0].Request.GenerationParams)
+	assert.ElementsMatch(t, []string{"建筑", "castles"}, rows[0].Request.TagNames)
+}
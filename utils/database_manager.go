@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"imgGeneratePrompts/config"
 	"imgGeneratePrompts/models"
 	"log"
+
+	"gorm.io/gorm"
 )
 
 // DatabaseManager 数据库管理工具
@@ -74,7 +77,7 @@ func (dm *DatabaseManager) CreateSampleData() error {
 				PromptText:            "a beautiful sunset over mountains, golden hour, cinematic lighting, high quality",
 				NegativePrompt:        "ugly, blurry, low quality, pixelated, noise",
 				ModelName:             "stable-diffusion-v1-5",
-				OutputImageURL:        "/uploads/sample_sunset.jpg",
+				Images:                []*models.PromptImage{{Role: models.ImageRoleOutput, URL: "/uploads/sample_sunset.jpg"}},
 				IsPublic:              true,
 				StyleDescription:      "风景摄影风格，温暖的金色调",
 				UsageScenario:         "适用于自然风光、旅游宣传、背景图片",
@@ -89,7 +92,7 @@ func (dm *DatabaseManager) CreateSampleData() error {
 				PromptText:            "portrait of a cat, professional photography, studio lighting, detailed fur texture",
 				NegativePrompt:        "cartoon, anime, low resolution, distorted",
 				ModelName:             "stable-diffusion-v1-5",
-				OutputImageURL:        "/uploads/sample_cat.jpg",
+				Images:                []*models.PromptImage{{Role: models.ImageRoleOutput, URL: "/uploads/sample_cat.jpg"}},
 				IsPublic:              true,
 				StyleDescription:      "专业摄影风格，细致的毛发质感",
 				UsageScenario:         "适用于宠物摄影、动物主题设计",
@@ -104,7 +107,7 @@ func (dm *DatabaseManager) CreateSampleData() error {
 				PromptText:            "futuristic city skyline, neon lights, cyberpunk style, night scene, high-tech architecture",
 				NegativePrompt:        "old, vintage, daylight, low quality",
 				ModelName:             "stable-diffusion-xl",
-				OutputImageURL:        "/uploads/sample_cyberpunk.jpg",
+				Images:                []*models.PromptImage{{Role: models.ImageRoleOutput, URL: "/uploads/sample_cyberpunk.jpg"}},
 				IsPublic:              true,
 				StyleDescription:      "赛博朋克风格，霓虹灯光效果",
 				UsageScenario:         "适用于科幻题材、游戏背景、未来主题设计",
@@ -174,13 +177,6 @@ func (dm *DatabaseManager) GetDatabaseStats() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// BackupData 备份数据（生成SQL）
-func (dm *DatabaseManager) BackupData() (string, error) {
-	// 这里可以实现数据备份逻辑
-	// 返回SQL语句或保存到文件
-	return "备份功能待实现", nil
-}
-
 // ValidateData 验证数据完整性
 func (dm *DatabaseManager) ValidateData() error {
 	db := config.GetDB()
@@ -200,11 +196,29 @@ func (dm *DatabaseManager) ValidateData() error {
 	return nil
 }
 
-// MigrateData 数据迁移工具
-func (dm *DatabaseManager) MigrateData() error {
-	// 这里可以实现数据迁移逻辑
-	log.Println("数据迁移功能待实现")
-	return nil
+// AssignOwnerlessPrompts 将无所有者的私有提示词（含软删除）交给指定的管理员，返回处理的数量
+// 这些是用户功能上线前创建的提示词，没有所有者时对任何人都不可见
+func (dm *DatabaseManager) AssignOwnerlessPrompts(adminUsername string) (int64, error) {
+	db := config.GetDB()
+	var admin models.User
+	if err := db.Where("username = ?", adminUsername).First(&admin).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("用户 %s 不存在", adminUsername)
+		}
+		return 0, fmt.Errorf("查询用户失败: %v", err)
+	}
+	if admin.Role != models.RoleAdmin {
+		return 0, fmt.Errorf("用户 %s 不是管理员", adminUsername)
+	}
+
+	result := db.Unscoped().Model(&models.Prompt{}).
+		Where("owner_id IS NULL AND is_public = ?", false).
+		UpdateColumn("owner_id", admin.ID)
+	if result.Error != nil {
+		return 0, fmt.Errorf("更新提示词所有者失败: %v", result.Error)
+	}
+	log.Printf("已将 %d 条无所有者的私有提示词交给管理员 %s", result.RowsAffected, admin.Username)
+	return result.RowsAffected, nil
 }
 
 // WriteDatabase 写数据库的主要方法 - 这是你要的核心方法
@@ -7,6 +7,7 @@ import (
 	"imgGeneratePrompts/utils"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -187,6 +188,170 @@ func (s *DatabaseManagerTestSuite) TestWriteDatabase() {
 	assert.Greater(s.T(), tagCount, int64(0), "WriteDatabase 后应该有标签数据")
 }
 
+// TestBackupAndRestore 测试备份后恢复到空数据库和已有数据的数据库
+func (s *DatabaseManagerTestSuite) TestBackupAndRestore() {
+	// Arrange
+	s.manager.InitializeDatabase()
+	s.db.Exec("DELETE FROM prompt_tags")
+	s.manager.CreateSampleData()
+	uploadDir := s.T().TempDir()
+	originalUploadPath := s.cfg.Server.UploadPath
+	s.cfg.Server.UploadPath = uploadDir
+	defer func() { s.cfg.Server.UploadPath = originalUploadPath }()
+	os.WriteFile(filepath.Join(uploadDir, "sample_cat.jpg"), []byte("cat"), 0644)
+
+	var deleted models.Prompt
+	s.db.Where("id IN (SELECT prompt_id FROM prompt_images WHERE url = ?)", "/uploads/sample_cyberpunk.jpg").First(&deleted)
+	s.db.Delete(&deleted)
+	var child models.Prompt
+	s.db.Where("id IN (SELECT prompt_id FROM prompt_images WHERE url = ?)", "/uploads/sample_cat.jpg").First(&child)
+	s.db.Model(&child).Update("parent_id", deleted.ID)
+
+	archive := filepath.Join(s.T().TempDir(), "backup.tar.gz")
+
+	// Act
+	manifest, err := s.manager.BackupData(archive)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), manifest.Prompts, "备份应包含软删除的提示词")
+	assert.Equal(s.T(), int64(1), manifest.Files)
+	assert.Len(s.T(), manifest.MissingFiles, 2)
+
+	// 恢复到空数据库并保留ID
+	s.db.Exec("DELETE FROM prompt_tags")
+	s.db.Unscoped().Where("1 = 1").Delete(&models.Prompt{})
+	s.db.Where("1 = 1").Delete(&models.Tag{})
+	os.Remove(filepath.Join(uploadDir, "sample_cat.jpg"))
+
+	result, err := s.manager.RestoreData(archive, utils.RestoreOptions{})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), result.Prompts)
+	assert.Equal(s.T(), int64(1), result.Files)
+	assert.FileExists(s.T(), filepath.Join(uploadDir, "sample_cat.jpg"))
+
+	var restoredChild models.Prompt
+	s.db.First(&restoredChild, child.ID)
+	assert.Equal(s.T(), deleted.ID, *restoredChild.ParentID)
+	var restoredImages []models.PromptImage
+	s.db.Where("prompt_id = ?", child.ID).Find(&restoredImages)
+	if assert.Len(s.T(), restoredImages, 1, "图片应随提示词恢复") {
+		assert.Equal(s.T(), models.ImageRoleOutput, restoredImages[0].Role)
+		assert.Equal(s.T(), "/uploads/sample_cat.jpg", restoredImages[0].URL)
+	}
+	var visible int64
+	s.db.Model(&models.Prompt{}).Count(&visible)
+	assert.Equal(s.T(), int64(2), visible, "软删除状态应被保留")
+
+	// 不重映射时ID冲突应报错，并删除已写出的文件；重映射后作为新数据导入
+	os.Remove(filepath.Join(uploadDir, "sample_cat.jpg"))
+	_, err = s.manager.RestoreData(archive, utils.RestoreOptions{})
+	assert.Error(s.T(), err)
+	assert.NoFileExists(s.T(), filepath.Join(uploadDir, "sample_cat.jpg"), "恢复失败时不应留下文件")
+
+	var tagsBefore int64
+	s.db.Model(&models.Tag{}).Count(&tagsBefore)
+	result, err = s.manager.RestoreData(archive, utils.RestoreOptions{RemapIDs: true})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(3), result.Prompts)
+	assert.Equal(s.T(), int64(0), result.Tags, "同名标签应被合并")
+	var total int64
+	s.db.Unscoped().Model(&models.Prompt{}).Count(&total)
+	assert.Equal(s.T(), int64(6), total)
+	var tagsAfter int64
+	s.db.Model(&models.Tag{}).Count(&tagsAfter)
+	assert.Equal(s.T(), tagsBefore, tagsAfter)
+}
+
+// TestRestoreOwnersByUsername 测试恢复时按用户名映射所有者，找不到时失败或改为归属指定的管理员
+func (s *DatabaseManagerTestSuite) TestRestoreOwnersByUsername() {
+	// Arrange
+	s.manager.InitializeDatabase()
+	s.db.Exec("DELETE FROM prompt_tags")
+	s.db.Unscoped().Where("1 = 1").Delete(&models.Prompt{})
+	s.db.Where("1 = 1").Delete(&models.User{})
+	alice := models.User{Username: "alice", PasswordHash: "x", Role: models.RoleEditor}
+	admin := models.User{Username: "admin", PasswordHash: "x", Role: models.RoleAdmin}
+	s.db.Create(&alice)
+	s.db.Create(&admin)
+	s.db.Create(&models.Prompt{PromptText: "alice 的私有提示词", ModelName: "sdxl", OwnerID: &alice.ID})
+	archive := filepath.Join(s.T().TempDir(), "backup.tar.gz")
+	manifest, err := s.manager.BackupData(archive)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), int64(2), manifest.Users)
+
+	// 目标数据库中 alice 不存在，同一ID属于其他用户
+	s.db.Unscoped().Where("1 = 1").Delete(&models.Prompt{})
+	s.db.Delete(&alice)
+	bob := models.User{ID: alice.ID, Username: "bob", PasswordHash: "x", Role: models.RoleEditor}
+	s.db.Create(&bob)
+
+	// Act & Assert: 未指定管理员时失败，不会把提示词交给 bob
+	_, err = s.manager.RestoreData(archive, utils.RestoreOptions{})
+	assert.ErrorContains(s.T(), err, "alice")
+	_, err = s.manager.RestoreData(archive, utils.RestoreOptions{OwnerFallback: "bob"})
+	assert.ErrorContains(s.T(), err, "不是管理员")
+
+	result, err := s.manager.RestoreData(archive, utils.RestoreOptions{OwnerFallback: "admin"})
+	s.Require().NoError(err)
+	assert.Equal(s.T(), int64(1), result.ReassignedOwners)
+	var restored models.Prompt
+	s.db.First(&restored)
+	if assert.NotNil(s.T(), restored.OwnerID) {
+		assert.Equal(s.T(), admin.ID, *restored.OwnerID)
+	}
+
+	// 重新创建的 alice ID 不同，仍按用户名映射
+	s.db.Unscoped().Where("1 = 1").Delete(&models.Prompt{})
+	newAlice := models.User{Username: "alice", PasswordHash: "x", Role: models.RoleEditor}
+	s.db.Create(&newAlice)
+	result, err = s.manager.RestoreData(archive, utils.RestoreOptions{})
+	s.Require().NoError(err)
+	assert.Equal(s.T(), int64(0), result.ReassignedOwners)
+	restored = models.Prompt{}
+	s.db.First(&restored)
+	if assert.NotNil(s.T(), restored.OwnerID) {
+		assert.Equal(s.T(), newAlice.ID, *restored.OwnerID)
+	}
+}
+
+// TestAssignOwnerlessPrompts 测试将无所有者的私有提示词交给管理员，公开和已有所有者的提示词不受影响
+func (s *DatabaseManagerTestSuite) TestAssignOwnerlessPrompts() {
+	// Arrange
+	s.manager.InitializeDatabase()
+	s.db.Exec("DELETE FROM prompt_tags")
+	s.db.Unscoped().Where("1 = 1").Delete(&models.Prompt{})
+	s.db.Where("1 = 1").Delete(&models.User{})
+	alice := models.User{Username: "alice", PasswordHash: "x", Role: models.RoleEditor}
+	admin := models.User{Username: "admin", PasswordHash: "x", Role: models.RoleAdmin}
+	s.db.Create(&alice)
+	s.db.Create(&admin)
+	legacy := models.Prompt{PromptText: "旧的私有提示词"}
+	public := models.Prompt{PromptText: "共享的提示词", IsPublic: true}
+	owned := models.Prompt{PromptText: "alice 的私有提示词", OwnerID: &alice.ID}
+	s.db.Create(&legacy)
+	s.db.Create(&public)
+	s.db.Create(&owned)
+
+	// Act
+	_, err := s.manager.AssignOwnerlessPrompts("alice")
+	assert.ErrorContains(s.T(), err, "不是管理员")
+	count, err := s.manager.AssignOwnerlessPrompts("admin")
+
+	// Assert
+	s.Require().NoError(err)
+	assert.Equal(s.T(), int64(1), count)
+	var prompts []models.Prompt
+	s.db.Order("id ASC").Find(&prompts)
+	s.Require().Len(prompts, 3)
+	if assert.NotNil(s.T(), prompts[0].OwnerID) {
+		assert.Equal(s.T(), admin.ID, *prompts[0].OwnerID)
+	}
+	assert.False(s.T(), prompts[0].IsPublic, "私有标记应被保留")
+	assert.Nil(s.T(), prompts[1].OwnerID)
+	assert.Equal(s.T(), alice.ID, *prompts[2].OwnerID)
+}
+
 // TestMain 覆盖 Go 原生的 TestMain，确保我们的测试套件被执行
 func TestMain(m *testing.M) {
 	// 这个函数是可选的，但可以用来在所有测试前后执行全局设置
@@ -0,0 +1,603 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 动态提示词（Dynamic Prompts）语法支持：
+//   {red|green|blue}      从多个选项中选择一个
+//   {2$$a|b|c}            从选项中选择2个（不重复），默认以 ", " 连接
+//   {1-3$$a|b|c}          选择1到3个
+//   {2$$ and $$a|b|c}     自定义连接符
+//   __colors__            引用名为 colors 的通配符（每行一个选项）
+// 语法可以嵌套，通配符的内容中也可以继续使用上述语法。
+
+const (
+	// DefaultExpandLimit 组合模式下默认返回的最大数量
+	DefaultExpandLimit = 100
+	// MaxExpandLimit 组合模式下允许的最大数量
+	MaxExpandLimit = 1000
+	// MaxExpandSamples 随机模式下允许的最大采样数量
+	MaxExpandSamples = 1000
+	// maxWildcardDepth 通配符最大嵌套深度，防止循环引用
+	maxWildcardDepth = 10
+	// defaultVariantSeparator 多选时默认的连接符
+	defaultVariantSeparator = ", "
+)
+
+// WildcardResolver 根据通配符名称返回其所有选项
+type WildcardResolver func(name string) ([]string, error)
+
+// DynamicPromptResult 动态提示词展开结果
+type DynamicPromptResult struct {
+	Prompts   []string // 展开后的提示词
+	Total     int64    // 组合总数（超出int64时为math.MaxInt64）
+	Truncated bool     // 组合模式下结果是否被截断
+}
+
+// dpNode 语法树节点
+type dpNode interface{}
+
+// dpSequence 按顺序拼接的节点序列
+type dpSequence []dpNode
+
+// dpText 普通文本
+type dpText string
+
+// dpVariant {a|b|c} 变体
+type dpVariant struct {
+	options   []dpSequence
+	min, max  int
+	separator string
+}
+
+// dpWildcard __name__ 通配符
+type dpWildcard struct {
+	name string
+}
+
+var variantCountPattern = regexp.MustCompile(`^\s*(\d*)\s*(?:(-)\s*(\d*))?\s*\$\$`)
+
+// dynamicPromptParser 动态提示词解析器
+type dynamicPromptParser struct {
+	src []rune
+	pos int
+}
+
+// parseDynamicPrompt 将提示词文本解析为语法树
+func parseDynamicPrompt(text string) (dpSequence, error) {
+	p := &dynamicPromptParser{src: []rune(text)}
+	seq, err := p.parseSequence(false)
+	if err != nil {
+		return nil, err
+	}
+	return seq, nil
+}
+
+// parseSequence 解析节点序列；inVariant为true时遇到 | 或 } 停止
+func (p *dynamicPromptParser) parseSequence(inVariant bool) (dpSequence, error) {
+	var seq dpSequence
+	var buf strings.Builder
+
+	flush := func() {
+		if buf.Len() > 0 {
+			seq = append(seq, dpText(buf.String()))
+			buf.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		switch {
+		case ch == '\\' && p.pos+1 < len(p.src):
+			// 转义字符，原样输出下一个字符
+			buf.WriteRune(p.src[p.pos+1])
+			p.pos += 2
+		case ch == '{':
+			flush()
+			p.pos++
+			variant, err := p.parseVariant()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, variant)
+		case inVariant && (ch == '|' || ch == '}'):
+			flush()
+			return seq, nil
+		case ch == '_' && p.hasPrefix("__"):
+			name, ok := p.scanWildcardName()
+			if !ok {
+				buf.WriteString("__")
+				p.pos += 2
+				continue
+			}
+			flush()
+			seq = append(seq, &dpWildcard{name: name})
+		default:
+			buf.WriteRune(ch)
+			p.pos++
+		}
+	}
+
+	if inVariant {
+		return nil, fmt.Errorf("变体语法缺少右花括号 '}'")
+	}
+	flush()
+	return seq, nil
+}
+
+// parseVariant 解析 { 之后的变体内容，结束时消费 }
+func (p *dynamicPromptParser) parseVariant() (*dpVariant, error) {
+	variant := &dpVariant{min: 1, max: 1, separator: defaultVariantSeparator}
+
+	// 解析可选的数量前缀，如 2$$、1-3$$、2$$ and $$
+	rest := string(p.src[p.pos:])
+	if m := variantCountPattern.FindStringSubmatchIndex(rest); m != nil {
+		groups := variantCountPattern.FindStringSubmatch(rest)
+		minStr, dash, maxStr := groups[1], groups[2], groups[3]
+		variant.min, variant.max = 1, 1
+		if minStr != "" {
+			variant.min, _ = strconv.Atoi(minStr)
+		}
+		switch {
+		case dash == "":
+			variant.max = variant.min
+		case maxStr == "":
+			variant.max = math.MaxInt32 // 上限在展开时截断为选项数量
+		default:
+			variant.max, _ = strconv.Atoi(maxStr)
+		}
+		if variant.min > variant.max {
+			variant.min, variant.max = variant.max, variant.min
+		}
+		p.pos += len([]rune(rest[:m[1]]))
+
+		// 自定义连接符：下一个 $$ 出现在任何 { | } 之前
+		if sep, ok := p.scanSeparator(); ok {
+			variant.separator = sep
+		}
+	}
+
+	for {
+		option, err := p.parseSequence(true)
+		if err != nil {
+			return nil, err
+		}
+		variant.options = append(variant.options, option)
+		if p.src[p.pos] == '}' {
+			p.pos++
+			break
+		}
+		p.pos++ // 跳过 |
+	}
+
+	return variant, nil
+}
+
+// scanSeparator 尝试读取 xxx$$ 形式的自定义连接符
+func (p *dynamicPromptParser) scanSeparator() (string, bool) {
+	for i := p.pos; i+1 < len(p.src); i++ {
+		switch p.src[i] {
+		case '{', '|', '}':
+			return "", false
+		case '$':
+			if p.src[i+1] == '$' {
+				sep := string(p.src[p.pos:i])
+				p.pos = i + 2
+				return sep, true
+			}
+		}
+	}
+	return "", false
+}
+
+// scanWildcardName 尝试读取 __name__ 形式的通配符
+func (p *dynamicPromptParser) scanWildcardName() (string, bool) {
+	start := p.pos + 2
+	for i := start; i+1 < len(p.src); i++ {
+		ch := p.src[i]
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '{' || ch == '}' || ch == '|' || ch == ',' {
+			return "", false
+		}
+		if ch == '_' && p.src[i+1] == '_' && i > start {
+			p.pos = i + 2
+			return string(p.src[start:i]), true
+		}
+	}
+	return "", false
+}
+
+// hasPrefix 判断当前位置是否以指定字符串开头
+func (p *dynamicPromptParser) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(p.src[p.pos:]), prefix)
+}
+
+// dynamicPromptExpander 展开器，负责通配符解析与缓存
+type dynamicPromptExpander struct {
+	resolve   WildcardResolver
+	wildcards map[string][]dpSequence
+	counts    map[string]int64 // 通配符组合数缓存，避免嵌套引用时重复计算导致指数级耗时
+}
+
+// newDynamicPromptExpander 创建展开器
+func newDynamicPromptExpander(resolve WildcardResolver) *dynamicPromptExpander {
+	return &dynamicPromptExpander{
+		resolve:   resolve,
+		wildcards: make(map[string][]dpSequence),
+		counts:    make(map[string]int64),
+	}
+}
+
+// wildcardOptions 获取并解析通配符的所有选项
+func (e *dynamicPromptExpander) wildcardOptions(name string, depth int) ([]dpSequence, error) {
+	if depth > maxWildcardDepth {
+		return nil, fmt.Errorf("通配符 __%s__ 嵌套层级超过 %d，可能存在循环引用", name, maxWildcardDepth)
+	}
+	if options, ok := e.wildcards[name]; ok {
+		return options, nil
+	}
+	if e.resolve == nil {
+		return nil, fmt.Errorf("通配符 __%s__ 不存在", name)
+	}
+
+	values, err := e.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	options := make([]dpSequence, 0, len(values))
+	for _, value := range values {
+		seq, err := parseDynamicPrompt(value)
+		if err != nil {
+			return nil, fmt.Errorf("解析通配符 __%s__ 失败: %v", name, err)
+		}
+		options = append(options, seq)
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("通配符 __%s__ 没有任何选项", name)
+	}
+	e.wildcards[name] = options
+	return options, nil
+}
+
+// countSequence 计算序列的组合总数
+func (e *dynamicPromptExpander) countSequence(seq dpSequence, depth int) (int64, error) {
+	total := int64(1)
+	for _, node := range seq {
+		n, err := e.countNode(node, depth)
+		if err != nil {
+			return 0, err
+		}
+		total = saturatingMul(total, n)
+	}
+	return total, nil
+}
+
+// countNode 计算单个节点的组合数量
+func (e *dynamicPromptExpander) countNode(node dpNode, depth int) (int64, error) {
+	switch n := node.(type) {
+	case dpText:
+		return 1, nil
+	case *dpWildcard:
+		if total, ok := e.counts[n.name]; ok {
+			return total, nil
+		}
+		options, err := e.wildcardOptions(n.name, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		total := int64(0)
+		for _, option := range options {
+			c, err := e.countSequence(option, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			total = saturatingAdd(total, c)
+		}
+		e.counts[n.name] = total
+		return total, nil
+	case *dpVariant:
+		counts := make([]int64, len(n.options))
+		for i, option := range n.options {
+			c, err := e.countSequence(option, depth)
+			if err != nil {
+				return 0, err
+			}
+			counts[i] = c
+		}
+		// 初等对称多项式：从选项中选k个的所有组合数之和
+		lo, hi := n.bounds()
+		elementary := make([]int64, hi+1)
+		elementary[0] = 1
+		for _, c := range counts {
+			for k := hi; k >= 1; k-- {
+				elementary[k] = saturatingAdd(elementary[k], saturatingMul(elementary[k-1], c))
+			}
+		}
+		total := int64(0)
+		for k := lo; k <= hi; k++ {
+			total = saturatingAdd(total, elementary[k])
+		}
+		return total, nil
+	}
+	return 0, fmt.Errorf("未知的语法节点")
+}
+
+// bounds 返回截断到选项数量后的选择数量范围
+func (v *dpVariant) bounds() (int, int) {
+	lo, hi := v.min, v.max
+	if hi > len(v.options) {
+		hi = len(v.options)
+	}
+	if lo > hi {
+		lo = hi
+	}
+	if lo < 0 {
+		lo = 0
+	}
+	return lo, hi
+}
+
+// expandSequence 按组合方式展开序列，最多返回limit个结果
+func (e *dynamicPromptExpander) expandSequence(seq dpSequence, limit int, depth int) ([]string, bool, error) {
+	results := []string{""}
+	truncated := false
+	for _, node := range seq {
+		parts, partTruncated, err := e.expandNode(node, limit, depth)
+		if err != nil {
+			return nil, false, err
+		}
+		truncated = truncated || partTruncated
+
+		next := make([]string, 0, minInt(len(results)*len(parts), limit))
+	product:
+		for _, prefix := range results {
+			for _, part := range parts {
+				if len(next) >= limit {
+					truncated = true
+					break product
+				}
+				next = append(next, prefix+part)
+			}
+		}
+		results = next
+	}
+	return results, truncated, nil
+}
+
+// expandNode 按组合方式展开单个节点
+func (e *dynamicPromptExpander) expandNode(node dpNode, limit int, depth int) ([]string, bool, error) {
+	switch n := node.(type) {
+	case dpText:
+		return []string{string(n)}, false, nil
+	case *dpWildcard:
+		options, err := e.wildcardOptions(n.name, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		return e.expandOptions(options, limit, depth+1)
+	case *dpVariant:
+		lo, hi := n.bounds()
+		if lo == 1 && hi == 1 {
+			return e.expandOptions(n.options, limit, depth)
+		}
+		var results []string
+		var expandErr error
+		truncated := false
+		for k := lo; k <= hi && len(results) < limit && expandErr == nil; k++ {
+			forEachCombination(len(n.options), k, func(indexes []int) bool {
+				picked := make(dpSequence, 0, len(indexes)*2)
+				for i, idx := range indexes {
+					if i > 0 {
+						picked = append(picked, dpText(n.separator))
+					}
+					picked = append(picked, n.options[idx]...)
+				}
+				parts, partTruncated, err := e.expandSequence(picked, limit-len(results), depth)
+				if err != nil {
+					expandErr = err
+					return false
+				}
+				truncated = truncated || partTruncated
+				results = append(results, parts...)
+				if len(results) >= limit {
+					truncated = true
+					return false
+				}
+				return true
+			})
+		}
+		if expandErr != nil {
+			return nil, false, expandErr
+		}
+		return results, truncated, nil
+	}
+	return nil, false, fmt.Errorf("未知的语法节点")
+}
+
+// expandOptions 依次展开所有选项并合并结果
+func (e *dynamicPromptExpander) expandOptions(options []dpSequence, limit int, depth int) ([]string, bool, error) {
+	var results []string
+	for i, option := range options {
+		parts, truncated, err := e.expandSequence(option, limit-len(results), depth)
+		if err != nil {
+			return nil, false, err
+		}
+		results = append(results, parts...)
+		if len(results) >= limit {
+			return results, truncated || i < len(options)-1, nil
+		}
+		if truncated {
+			return results, true, nil
+		}
+	}
+	return results, false, nil
+}
+
+// sampleSequence 随机展开序列
+func (e *dynamicPromptExpander) sampleSequence(seq dpSequence, rng *rand.Rand, depth int) (string, error) {
+	var sb strings.Builder
+	for _, node := range seq {
+		switch n := node.(type) {
+		case dpText:
+			sb.WriteString(string(n))
+		case *dpWildcard:
+			options, err := e.wildcardOptions(n.name, depth+1)
+			if err != nil {
+				return "", err
+			}
+			part, err := e.sampleSequence(options[rng.Intn(len(options))], rng, depth+1)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(part)
+		case *dpVariant:
+			lo, hi := n.bounds()
+			k := lo
+			if hi > lo {
+				k = lo + rng.Intn(hi-lo+1)
+			}
+			// 随机挑选k个选项，并保持其原始顺序以与组合模式一致
+			picked := rng.Perm(len(n.options))[:k]
+			sort.Ints(picked)
+			for i, idx := range picked {
+				if i > 0 {
+					sb.WriteString(n.separator)
+				}
+				part, err := e.sampleSequence(n.options[idx], rng, depth)
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(part)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// ExpandDynamicPromptAll 以组合模式展开提示词，返回至多limit个结果
+func ExpandDynamicPromptAll(text string, limit int, resolve WildcardResolver) (*DynamicPromptResult, error) {
+	if limit <= 0 {
+		limit = DefaultExpandLimit
+	}
+	if limit > MaxExpandLimit {
+		limit = MaxExpandLimit
+	}
+
+	seq, err := parseDynamicPrompt(text)
+	if err != nil {
+		return nil, err
+	}
+
+	expander := newDynamicPromptExpander(resolve)
+	total, err := expander.countSequence(seq, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts, truncated, err := expander.expandSequence(seq, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynamicPromptResult{
+		Prompts:   prompts,
+		Total:     total,
+		Truncated: truncated || int64(len(prompts)) < total,
+	}, nil
+}
+
+// ExpandDynamicPromptRandom 以随机模式展开提示词，相同的seed得到相同的结果
+func ExpandDynamicPromptRandom(text string, count int, seed int64, resolve WildcardResolver) (*DynamicPromptResult, error) {
+	if count <= 0 {
+		count = 1
+	}
+	if count > MaxExpandSamples {
+		count = MaxExpandSamples
+	}
+
+	seq, err := parseDynamicPrompt(text)
+	if err != nil {
+		return nil, err
+	}
+
+	expander := newDynamicPromptExpander(resolve)
+	total, err := expander.countSequence(seq, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	prompts := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		prompt, err := expander.sampleSequence(seq, rng, 0)
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	return &DynamicPromptResult{
+		Prompts: prompts,
+		Total:   total,
+	}, nil
+}
+
+// forEachCombination 按字典序遍历从n个元素中选k个的所有组合，fn返回false时停止
+func forEachCombination(n, k int, fn func(indexes []int) bool) {
+	if k < 0 || k > n {
+		return
+	}
+	indexes := make([]int, k)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	for {
+		if !fn(indexes) {
+			return
+		}
+		i := k - 1
+		for i >= 0 && indexes[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		indexes[i]++
+		for j := i + 1; j < k; j++ {
+			indexes[j] = indexes[j-1] + 1
+		}
+	}
+}
+
+// saturatingAdd 饱和加法，溢出时返回math.MaxInt64
+func saturatingAdd(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// saturatingMul 饱和乘法，溢出时返回math.MaxInt64
+func saturatingMul(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// minInt 返回两个整数中较小的一个
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,179 @@
+package utils_test
+
+import (
+	"fmt"
+	"imgGeneratePrompts/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testWildcards 测试用的通配符数据
+var testWildcards = map[string][]string{
+	"colors":  {"red", "green", "blue"},
+	"animals": {"cat", "{small|big} dog"},
+	"loop":    {"__loop__"},
+}
+
+// resolveTestWildcard 测试用的通配符解析函数
+func resolveTestWildcard(name string) ([]string, error) {
+	if values, ok := testWildcards[name]; ok {
+		return values, nil
+	}
+	return nil, fmt.Errorf("通配符 __%s__ 不存在", name)
+}
+
+// TestExpandDynamicPromptAll 测试组合模式展开
+func TestExpandDynamicPromptAll(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+		total    int64
+	}{
+		{
+			name:     "无动态语法",
+			input:    "a photo of a cat",
+			expected: []string{"a photo of a cat"},
+			total:    1,
+		},
+		{
+			name:     "简单变体",
+			input:    "a {red|green} car",
+			expected: []string{"a red car", "a green car"},
+			total:    2,
+		},
+		{
+			name:     "多个变体的笛卡尔积",
+			input:    "{a|b}-{1|2}",
+			expected: []string{"a-1", "a-2", "b-1", "b-2"},
+			total:    4,
+		},
+		{
+			name:     "嵌套变体",
+			input:    "{x|{y|z}}",
+			expected: []string{"x", "y", "z"},
+			total:    3,
+		},
+		{
+			name:     "多选",
+			input:    "{2$$a|b|c}",
+			expected: []string{"a, b", "a, c", "b, c"},
+			total:    3,
+		},
+		{
+			name:     "多选范围与自定义连接符",
+			input:    "{1-2$$ and $$a|b}",
+			expected: []string{"a", "b", "a and b"},
+			total:    3,
+		},
+		{
+			name:     "通配符",
+			input:    "__colors__ sky",
+			expected: []string{"red sky", "green sky", "blue sky"},
+			total:    3,
+		},
+		{
+			name:     "通配符内容包含变体",
+			input:    "a __animals__",
+			expected: []string{"a cat", "a small dog", "a big dog"},
+			total:    3,
+		},
+		{
+			name:     "转义字符",
+			input:    `\{not|variant\}`,
+			expected: []string{"{not|variant}"},
+			total:    1,
+		},
+		{
+			name:     "不构成通配符的下划线",
+			input:    "snake__case text",
+			expected: []string{"snake__case text"},
+			total:    1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := utils.ExpandDynamicPromptAll(tc.input, 0, resolveTestWildcard)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Prompts)
+			assert.Equal(t, tc.total, result.Total)
+			assert.False(t, result.Truncated)
+		})
+	}
+}
+
+// TestExpandDynamicPromptAllLimit 测试组合数量上限
+func TestExpandDynamicPromptAllLimit(t *testing.T) {
+	result, err := utils.ExpandDynamicPromptAll("{a|b|c} {1|2|3} __colors__", 5, resolveTestWildcard)
+	assert.NoError(t, err)
+	assert.Len(t, result.Prompts, 5)
+	assert.Equal(t, int64(27), result.Total)
+	assert.True(t, result.Truncated, "超出上限时应标记为截断")
+	assert.Equal(t, "a 1 red", result.Prompts[0])
+}
+
+// TestExpandDynamicPromptNestedWildcards 测试多层嵌套通配符的组合数计算不会重复展开
+func TestExpandDynamicPromptNestedWildcards(t *testing.T) {
+	// level0 到 level9 每层有20个选项，均引用下一层；未缓存时计算组合数需要 20^9 次递归
+	const levels, width = 10, 20
+	resolve := func(name string) ([]string, error) {
+		var level int
+		if _, err := fmt.Sscanf(name, "level%d", &level); err != nil {
+			return nil, fmt.Errorf("通配符 __%s__ 不存在", name)
+		}
+		values := make([]string, width)
+		for i := range values {
+			if level == levels-1 {
+				values[i] = fmt.Sprintf("v%d", i)
+			} else {
+				values[i] = fmt.Sprintf("__level%d__", level+1)
+			}
+		}
+		return values, nil
+	}
+
+	result, err := utils.ExpandDynamicPromptAll("__level0__", 5, resolve)
+	assert.NoError(t, err)
+	assert.Len(t, result.Prompts, 5)
+	assert.Equal(t, int64(10240000000000), result.Total) // 20^10
+	assert.True(t, result.Truncated)
+}
+
+// TestExpandDynamicPromptRandom 测试随机采样模式
+func TestExpandDynamicPromptRandom(t *testing.T) {
+	first, err := utils.ExpandDynamicPromptRandom("{a|b|c} __colors__ {2$$x|y|z}", 10, 42, resolveTestWildcard)
+	assert.NoError(t, err)
+	assert.Len(t, first.Prompts, 10)
+
+	// 相同的种子应得到相同的结果
+	second, err := utils.ExpandDynamicPromptRandom("{a|b|c} __colors__ {2$$x|y|z}", 10, 42, resolveTestWildcard)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Prompts, second.Prompts)
+
+	all, err := utils.ExpandDynamicPromptAll("{a|b|c} __colors__ {2$$x|y|z}", utils.MaxExpandLimit, resolveTestWildcard)
+	assert.NoError(t, err)
+	for _, prompt := range first.Prompts {
+		assert.Contains(t, all.Prompts, prompt, "随机结果应是合法的组合之一")
+	}
+}
+
+// TestExpandDynamicPromptErrors 测试错误情况
+func TestExpandDynamicPromptErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "缺少右花括号", input: "{a|b"},
+		{name: "未知通配符", input: "__unknown__"},
+		{name: "循环引用的通配符", input: "__loop__"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := utils.ExpandDynamicPromptAll(tc.input, 0, resolveTestWildcard)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PromptETag 根据提示词ID和版本号生成强 ETag，如 "12-v3"
+func PromptETag(id, version uint) string {
+	return fmt.Sprintf(`"%d-v%d"`, id, version)
+}
+
+// ContentETag 根据响应内容生成强 ETag（SHA-256 前16字节）
+func ContentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// splitETags 拆分 If-Match / If-None-Match 请求头中逗号分隔的 ETag 列表
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ETagMatchesNoneMatch 判断 If-None-Match 请求头是否匹配当前 ETag（弱比较，忽略 W/ 前缀）
+func ETagMatchesNoneMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range splitETags(header) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIfMatchVersions 从 If-Match 请求头中解析属于指定提示词的版本号（强比较，弱 ETag 不匹配）
+// any 为 true 表示请求头为 "*"，只要求提示词存在
+func ParseIfMatchVersions(header string, id uint) (versions []uint, any bool) {
+	prefix := fmt.Sprintf(`"%d-v`, id)
+	for _, tag := range splitETags(header) {
+		if tag == "*" {
+			return nil, true
+		}
+		if !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		version, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(tag, prefix), `"`), 10, 64)
+		if err == nil {
+			versions = append(versions, uint(version))
+		}
+	}
+	return versions, false
+}
@@ -0,0 +1,44 @@
+package utils_test
+
+import (
+	"imgGeneratePrompts/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseIfMatchVersions 测试 If-Match 请求头的解析
+func TestParseIfMatchVersions(t *testing.T) {
+	etag := utils.PromptETag(12, 3)
+	assert.Equal(t, `"12-v3"`, etag)
+
+	versions, any := utils.ParseIfMatchVersions(etag, 12)
+	assert.False(t, any)
+	assert.Equal(t, []uint{3}, versions)
+
+	versions, _ = utils.ParseIfMatchVersions(`"12-v3", "12-v4", "7-v1"`, 12)
+	assert.Equal(t, []uint{3, 4}, versions, "其他提示词的 ETag 应被忽略")
+
+	versions, _ = utils.ParseIfMatchVersions(`W/"12-v3"`, 12)
+	assert.Empty(t, versions, "If-Match 使用强比较，弱 ETag 不匹配")
+	versions, _ = utils.ParseIfMatchVersions(`"123-v3"`, 12)
+	assert.Empty(t, versions)
+	versions, _ = utils.ParseIfMatchVersions(`"12-vx"`, 12)
+	assert.Empty(t, versions)
+
+	_, any = utils.ParseIfMatchVersions("*", 12)
+	assert.True(t, any)
+}
+
+// TestETagMatchesNoneMatch 测试 If-None-Match 的弱比较
+func TestETagMatchesNoneMatch(t *testing.T) {
+	etag := utils.ContentETag([]byte(`{"code":200}`))
+	assert.Equal(t, etag, utils.ContentETag([]byte(`{"code":200}`)))
+	assert.NotEqual(t, etag, utils.ContentETag([]byte(`{"code":201}`)))
+
+	assert.True(t, utils.ETagMatchesNoneMatch(etag, etag))
+	assert.True(t, utils.ETagMatchesNoneMatch(`"other", W/`+etag, etag))
+	assert.True(t, utils.ETagMatchesNoneMatch("*", etag))
+	assert.False(t, utils.ETagMatchesNoneMatch(`"other"`, etag))
+	assert.False(t, utils.ETagMatchesNoneMatch("", etag))
+}
@@ -1,7 +1,13 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"image"
+	_ "image/gif"  // 注册GIF解码器，用于读取图片尺寸
+	_ "image/jpeg" // 注册JPEG解码器
+	_ "image/png"  // 注册PNG解码器
 	"io"
 	"mime/multipart"
 	"os"
@@ -87,6 +93,43 @@ func GetFileURL(c *gin.Context, filename string) string {
 	return fmt.Sprintf("/uploads/%s", filename)
 }
 
+// DuplicateUploadedFile 复制上传目录中的文件并返回新文件的访问URL
+// 非本地上传的URL（如外部链接）或文件不存在时原样返回，copied为false
+func DuplicateUploadedFile(fileURL, uploadPath string) (newURL string, copied bool, err error) {
+	if !strings.HasPrefix(fileURL, "/uploads/") {
+		return fileURL, false, nil
+	}
+
+	srcPath := filepath.Join(uploadPath, filepath.Base(fileURL))
+	if !FileExists(srcPath) {
+		return fileURL, false, nil
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", false, fmt.Errorf("打开源文件失败: %v", err)
+	}
+	defer src.Close()
+
+	// 使用O_EXCL避免同一秒内生成的同名文件互相覆盖
+	dst, err := createUniqueFile(uploadPath, GenerateUniqueFilename(filepath.Base(srcPath)))
+	if err != nil {
+		return "", false, err
+	}
+
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// 复制失败时删除不完整的目标文件
+		os.Remove(dst.Name())
+		return "", false, fmt.Errorf("复制文件失败: %v", err)
+	}
+
+	return fmt.Sprintf("/uploads/%s", filepath.Base(dst.Name())), true, nil
+}
+
 // DeleteFile 删除文件
 func DeleteFile(filePath string) error {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -109,3 +152,34 @@ func FileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
 	return !os.IsNotExist(err)
 }
+
+// ImageFileInfo 图片文件的尺寸和内容哈希
+type ImageFileInfo struct {
+	Width  int
+	Height int
+	Hash   string // 文件内容的SHA-256（十六进制）
+}
+
+// InspectImageFile 读取图片文件的尺寸和内容哈希
+// 无法识别的图片格式（如WebP）尺寸为0，只返回哈希
+func InspectImageFile(filePath string) (*ImageFileInfo, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	info := &ImageFileInfo{Hash: hex.EncodeToString(hash.Sum(nil))}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if config, _, err := image.DecodeConfig(file); err == nil {
+		info.Width, info.Height = config.Width, config.Height
+	}
+	return info, nil
+}
@@ -1,9 +1,15 @@
 package utils_test
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"image"
+	"image/png"
 	"imgGeneratePrompts/utils"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -11,6 +17,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestGenerateUniqueFilename 测试生成唯一文件名
@@ -117,3 +124,61 @@ func TestGetFileURL(t *testing.T) {
 		assert.Equal(t, expected, url)
 	})
 }
+
+// TestInspectImageFile 测试读取图片尺寸和内容哈希
+func TestInspectImageFile(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 32))))
+	pngPath := filepath.Join(dir, "image.png")
+	require.NoError(t, os.WriteFile(pngPath, buf.Bytes(), 0644))
+
+	info, err := utils.InspectImageFile(pngPath)
+	require.NoError(t, err)
+	sum := sha256.Sum256(buf.Bytes())
+	assert.Equal(t, hex.EncodeToString(sum[:]), info.Hash)
+	assert.Equal(t, 64, info.Width)
+	assert.Equal(t, 32, info.Height)
+
+	// 无法识别的格式只返回哈希
+	otherPath := filepath.Join(dir, "image.webp")
+	require.NoError(t, os.WriteFile(otherPath, []byte("not an image"), 0644))
+	info, err = utils.InspectImageFile(otherPath)
+	require.NoError(t, err)
+	assert.NotEmpty(t, info.Hash)
+	assert.Zero(t, info.Width)
+
+	_, err = utils.InspectImageFile(filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+}
+
+// TestDuplicateUploadedFile 测试复制上传文件
+func TestDuplicateUploadedFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "source.png"), []byte("image data"), 0644))
+
+	newURL, copied, err := utils.DuplicateUploadedFile("/uploads/source.png", dir)
+	require.NoError(t, err)
+	assert.True(t, copied)
+	assert.NotEqual(t, "/uploads/source.png", newURL)
+	data, err := os.ReadFile(filepath.Join(dir, filepath.Base(newURL)))
+	require.NoError(t, err)
+	assert.Equal(t, "image data", string(data))
+
+	// 外部链接原样返回
+	newURL, copied, err = utils.DuplicateUploadedFile("https://example.com/a.png", dir)
+	require.NoError(t, err)
+	assert.False(t, copied)
+	assert.Equal(t, "https://example.com/a.png", newURL)
+
+	// 复制失败时不留下不完整的文件
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "broken.png"), 0755))
+	before, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	_, _, err = utils.DuplicateUploadedFile("/uploads/broken.png", dir)
+	assert.Error(t, err)
+	after, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, after, len(before))
+}
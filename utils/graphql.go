@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GraphQL 的解析、校验和执行使用 github.com/graphql-go/graphql，
+// 本文件提供请求结构、带错误码的字段错误、操作选择和 SDL 导出，查询的规模限制见 graphql_limits.go。
+
+// GraphQLRequest GraphQL 请求（POST 的 JSON 请求体或 GET 的查询参数）
+type GraphQLRequest struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables" form:"-"`
+}
+
+// GraphQLThunk 延迟求值的解析结果，graphql-go 在同一层的字段都解析后才对其求值
+type GraphQLThunk = func() (interface{}, error)
+
+// GraphQLError 带错误码的字段错误，错误码输出为 errors[].extensions.code，供客户端区分错误类型
+type GraphQLError struct {
+	Code    string
+	Message string
+}
+
+// NewGraphQLError 创建带错误码的字段错误，执行器补充位置和路径
+func NewGraphQLError(code, message string) *GraphQLError {
+	return &GraphQLError{Code: code, Message: message}
+}
+
+// Error 实现 error 接口
+func (e *GraphQLError) Error() string {
+	return e.Message
+}
+
+// Extensions 实现 gqlerrors.ExtendedError
+func (e *GraphQLError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// GraphQLOperation 按 operationName 选择文档中要执行的操作，规则与 graphql-go 执行时一致
+func GraphQLOperation(doc *ast.Document, operationName string) (*ast.OperationDefinition, error) {
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		op, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" {
+			if operation != nil {
+				return nil, fmt.Errorf("文档中有多个操作，请指定 operationName")
+			}
+			operation = op
+		} else if op.Name != nil && op.Name.Value == operationName {
+			return op, nil
+		}
+	}
+	if operation == nil {
+		if operationName != "" {
+			return nil, fmt.Errorf("操作 %s 不存在", operationName)
+		}
+		return nil, fmt.Errorf("文档中没有操作")
+	}
+	return operation, nil
+}
+
+// GraphQLSDL 以 SDL 格式导出 schema，类型和字段按名称排序，不包含内置标量和内省类型
+func GraphQLSDL(schema *graphql.Schema) string {
+	var b strings.Builder
+	writeDescription := func(description, indent string) {
+		if description == "" {
+			return
+		}
+		if strings.Contains(description, "\n") {
+			b.WriteString(indent + `"""` + "\n")
+			for _, line := range strings.Split(description, "\n") {
+				b.WriteString(indent + strings.ReplaceAll(line, `"""`, `\"""`) + "\n")
+			}
+			b.WriteString(indent + `"""` + "\n")
+			return
+		}
+		b.WriteString(indent + strconv.Quote(description) + "\n")
+	}
+	writeValue := func(name string, typ graphql.Type, defaultValue interface{}) {
+		b.WriteString(name + ": " + typ.String())
+		if defaultValue != nil {
+			value, _ := json.Marshal(defaultValue)
+			b.WriteString(" = " + string(value))
+		}
+	}
+
+	if mutation := schema.MutationType(); mutation != nil {
+		fmt.Fprintf(&b, "schema {\n  query: %s\n  mutation: %s\n}\n", schema.QueryType().Name(), mutation.Name())
+	}
+
+	names := make([]string, 0, len(schema.TypeMap()))
+	for name := range schema.TypeMap() {
+		if !strings.HasPrefix(name, "__") && !isBuiltinGraphQLScalar(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		switch t := schema.Type(name).(type) {
+		case *graphql.Scalar:
+			writeDescription(t.Description(), "")
+			b.WriteString("scalar " + t.Name() + "\n")
+		case *graphql.Object:
+			writeDescription(t.Description(), "")
+			b.WriteString("type " + t.Name() + " {\n")
+			fields := t.Fields()
+			for _, fieldName := range sortedKeys(fields) {
+				field := fields[fieldName]
+				writeDescription(field.Description, "  ")
+				b.WriteString("  " + field.Name)
+				if len(field.Args) > 0 {
+					args := append([]*graphql.Argument{}, field.Args...)
+					sort.Slice(args, func(i, j int) bool { return args[i].Name() < args[j].Name() })
+					b.WriteString("(")
+					for i, arg := range args {
+						if i > 0 {
+							b.WriteString(", ")
+						}
+						writeValue(arg.Name(), arg.Type, arg.DefaultValue)
+					}
+					b.WriteString(")")
+				}
+				b.WriteString(": " + field.Type.String() + "\n")
+			}
+			b.WriteString("}\n")
+		case *graphql.InputObject:
+			writeDescription(t.Description(), "")
+			b.WriteString("input " + t.Name() + " {\n")
+			fields := t.Fields()
+			for _, fieldName := range sortedKeys(fields) {
+				field := fields[fieldName]
+				writeDescription(field.Description(), "  ")
+				b.WriteString("  ")
+				writeValue(field.Name(), field.Type, field.DefaultValue)
+				b.WriteString("\n")
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+// isBuiltinGraphQLScalar 是否为规范内置的标量类型
+func isBuiltinGraphQLScalar(name string) bool {
+	switch name {
+	case "String", "Int", "Float", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+// sortedKeys 按字母顺序返回映射的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// GraphQLLimits 单个 GraphQL 操作的规模限制，在执行前检查，超出时整个请求被拒绝
+type GraphQLLimits struct {
+	MaxDepth      int // 选择集的最大嵌套层数，防止通过 Prompt.parent 等循环引用构造过深的查询
+	MaxAliases    int // 别名的最大数量，防止用别名把同一个根字段重复成千上万次
+	MaxFields     int // 展开片段后字段的最大数量
+	MaxComplexity int // 最大复杂度，见 CheckGraphQLLimits
+	ListSize      int // 无法从参数得知列表长度时估计的长度
+}
+
+// DefaultGraphQLLimits 默认的规模限制
+// 20 条的提示词列表连同标签、收藏集和分叉来源的复杂度约为 1000
+var DefaultGraphQLLimits = GraphQLLimits{
+	MaxDepth:      10,
+	MaxAliases:    30,
+	MaxFields:     500,
+	MaxComplexity: 10000,
+	ListSize:      10,
+}
+
+// CheckGraphQLLimits 检查已通过校验的操作是否超出规模限制
+// 每个字段的复杂度为 1 加上子字段复杂度之和乘以列表长度：返回列表的字段按自身或父字段（如 prompts 之于 items）
+// 的 page_size 参数（字面量、变量或默认值）计算长度，没有 page_size 时按 ListSize 估计；片段在使用处展开计算
+func CheckGraphQLLimits(schema *graphql.Schema, doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}, limits GraphQLLimits) error {
+	checker := &graphQLLimitChecker{
+		limits:    limits,
+		variables: variables,
+		fragments: make(map[string]*ast.FragmentDefinition),
+	}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			checker.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	var root graphql.Type = schema.QueryType()
+	if mutation := schema.MutationType(); mutation != nil && operation.Operation == ast.OperationTypeMutation {
+		root = mutation
+	}
+	complexity, err := checker.selectionSet(schema, root, operation.SelectionSet, 1, 0)
+	if err != nil {
+		return err
+	}
+	if complexity > limits.MaxComplexity {
+		return fmt.Errorf("查询复杂度超过 %d", limits.MaxComplexity)
+	}
+	return nil
+}
+
+// graphQLLimitChecker 遍历一个操作时的计数
+type graphQLLimitChecker struct {
+	limits    GraphQLLimits
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	aliases   int
+	fields    int
+}
+
+// selectionSet 返回选择集的复杂度；parent 为选择集所属的类型，未知时（如内省字段）为 nil，
+// pageSize 为父字段的 page_size 参数，0 表示没有
+func (c *graphQLLimitChecker) selectionSet(schema *graphql.Schema, parent graphql.Type, set *ast.SelectionSet, depth, pageSize int) (int, error) {
+	if set == nil {
+		return 0, nil
+	}
+	if depth > c.limits.MaxDepth {
+		return 0, fmt.Errorf("查询嵌套超过 %d 层", c.limits.MaxDepth)
+	}
+
+	total := 0
+	for _, selection := range set.Selections {
+		var cost int
+		var err error
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost, err = c.field(schema, parent, selection, depth, pageSize)
+		case *ast.InlineFragment:
+			typ := parent
+			if selection.TypeCondition != nil {
+				typ = schema.Type(selection.TypeCondition.Name.Value)
+			}
+			cost, err = c.selectionSet(schema, typ, selection.SelectionSet, depth, pageSize)
+		case *ast.FragmentSpread:
+			fragment, ok := c.fragments[selection.Name.Value]
+			if !ok {
+				continue
+			}
+			cost, err = c.selectionSet(schema, schema.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet, depth, pageSize)
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += cost
+		if total > c.limits.MaxComplexity {
+			return 0, fmt.Errorf("查询复杂度超过 %d", c.limits.MaxComplexity)
+		}
+	}
+	return total, nil
+}
+
+// field 返回字段及其子字段的复杂度
+func (c *graphQLLimitChecker) field(schema *graphql.Schema, parent graphql.Type, field *ast.Field, depth, pageSize int) (int, error) {
+	c.fields++
+	if c.fields > c.limits.MaxFields {
+		return 0, fmt.Errorf("查询的字段超过 %d 个", c.limits.MaxFields)
+	}
+	if field.Alias != nil {
+		c.aliases++
+		if c.aliases > c.limits.MaxAliases {
+			return 0, fmt.Errorf("查询的别名超过 %d 个", c.limits.MaxAliases)
+		}
+	}
+
+	var definition *graphql.FieldDefinition
+	if object, ok := parent.(*graphql.Object); ok {
+		definition = object.Fields()[field.Name.Value]
+	}
+	if definition == nil {
+		// 内省字段等没有定义的字段只检查层数和数量
+		cost, err := c.selectionSet(schema, nil, field.SelectionSet, depth+1, 0)
+		return cost + 1, err
+	}
+
+	size := c.pageSize(definition, field)
+	multiplier := 1
+	if isGraphQLList(definition.Type) {
+		switch {
+		case size > 0:
+			// 列表字段自身的 page_size 不再传给子字段
+			multiplier, size = size, 0
+		case pageSize > 0:
+			multiplier = pageSize
+		default:
+			multiplier = c.limits.ListSize
+		}
+	}
+	childCost, err := c.selectionSet(schema, namedGraphQLType(definition.Type), field.SelectionSet, depth+1, size)
+	if err != nil {
+		return 0, err
+	}
+	if childCost > 0 && multiplier > c.limits.MaxComplexity/childCost {
+		return 0, fmt.Errorf("查询复杂度超过 %d", c.limits.MaxComplexity)
+	}
+	return 1 + multiplier*childCost, nil
+}
+
+// pageSize 字段的 page_size 参数，字段没有该参数时为 0
+func (c *graphQLLimitChecker) pageSize(definition *graphql.FieldDefinition, field *ast.Field) int {
+	var argument *graphql.Argument
+	for _, arg := range definition.Args {
+		if arg.Name() == "page_size" {
+			argument = arg
+		}
+	}
+	if argument == nil {
+		return 0
+	}
+
+	size, _ := argument.DefaultValue.(int)
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "page_size" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				size = n
+			}
+		case *ast.Variable:
+			switch n := c.variables[value.Name.Value].(type) {
+			case float64:
+				size = int(n)
+			case int:
+				size = n
+			}
+		}
+	}
+	if size < 1 {
+		return c.limits.ListSize
+	}
+	return size
+}
+
+// namedGraphQLType 去掉列表和非空修饰后的类型
+func namedGraphQLType(t graphql.Type) graphql.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
+
+// isGraphQLList 类型（去掉非空修饰后）是否为列表
+func isGraphQLList(t graphql.Type) bool {
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType
+	}
+	_, ok := t.(*graphql.List)
+	return ok
+}
@@ -0,0 +1,90 @@
+package utils
+
+import "sync"
+
+// GraphQLLoader 按请求缓存的批量加载器（DataLoader）
+// 解析函数通过 Load 登记键并返回 GraphQLThunk，执行器在同一层的字段都解析后才对 thunk 求值，
+// 第一个 thunk 求值时用一次 fetch 加载所有已登记的键，避免列表中每一项各查询一次（N+1）
+type GraphQLLoader[K comparable, V any] struct {
+	fetch   func(keys []K) (map[K]V, error)
+	mu      sync.Mutex
+	pending []K
+	cache   map[K]V
+	errs    map[K]error
+}
+
+// NewGraphQLLoader 创建加载器，fetch 返回的映射中缺少的键按零值处理
+func NewGraphQLLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *GraphQLLoader[K, V] {
+	return &GraphQLLoader[K, V]{fetch: fetch, cache: make(map[K]V), errs: make(map[K]error)}
+}
+
+// Load 登记键，返回的 thunk 求值时得到该键的值
+func (l *GraphQLLoader[K, V]) Load(key K) GraphQLThunk {
+	l.mu.Lock()
+	if !l.loaded(key) && !containsGraphQLKey(l.pending, key) {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		value, err := l.LoadNow(key)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// LoadNow 立即加载键的值，同时加载所有已登记的键
+func (l *GraphQLLoader[K, V]) LoadNow(key K) (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded(key) {
+		keys := l.pending
+		if !containsGraphQLKey(keys, key) {
+			keys = append(keys, key)
+		}
+		l.pending = nil
+
+		values, err := l.fetch(keys)
+		for _, k := range keys {
+			if err != nil {
+				l.errs[k] = err
+				continue
+			}
+			l.cache[k] = values[k]
+		}
+	}
+	if err := l.errs[key]; err != nil {
+		var zero V
+		return zero, err
+	}
+	return l.cache[key], nil
+}
+
+// Prime 写入已知的值（如变更操作的结果），之后的 Load 不再查询
+func (l *GraphQLLoader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.errs, key)
+	l.cache[key] = value
+}
+
+// loaded 键是否已加载（成功或失败）
+func (l *GraphQLLoader[K, V]) loaded(key K) bool {
+	if _, ok := l.cache[key]; ok {
+		return true
+	}
+	_, ok := l.errs[key]
+	return ok
+}
+
+func containsGraphQLKey[K comparable](keys []K, key K) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package utils_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/utils"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBook struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	AuthorID uint   `json:"author_id"`
+}
+
+type testAuthor struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// testLoaderKey 作者加载器在 context 中的键
+type testLoaderKey struct{}
+
+// newTestGraphQLSchema 测试用的 schema：books 有 page_size 参数，Book.related 引用 Book 自身
+func newTestGraphQLSchema(t *testing.T) *graphql.Schema {
+	books := []*testBook{{ID: 1, Title: "A", AuthorID: 10}, {ID: 2, Title: "B", AuthorID: 20}, {ID: 3, Title: "C", AuthorID: 10}}
+
+	author := graphql.NewObject(graphql.ObjectConfig{Name: "Author", Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	}})
+	var book *graphql.Object
+	book = graphql.NewObject(graphql.ObjectConfig{Name: "Book", Description: "书", Fields: graphql.FieldsThunk(func() graphql.Fields {
+		return graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "标题"},
+			"author": &graphql.Field{Type: author, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				loader := p.Context.Value(testLoaderKey{}).(*utils.GraphQLLoader[uint, *testAuthor])
+				return loader.Load(p.Source.(*testBook).AuthorID), nil
+			}},
+			"related": &graphql.Field{Type: graphql.NewList(book), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return books, nil
+			}},
+		}
+	})})
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"books": &graphql.Field{Type: graphql.NewList(book), Args: graphql.FieldConfigArgument{
+			"page_size": {Type: graphql.Int, DefaultValue: 20},
+		}, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return books, nil
+		}},
+	}})
+	mutation := graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: graphql.Fields{
+		"add": &graphql.Field{Type: graphql.NewNonNull(book), Args: graphql.FieldConfigArgument{
+			"title": {Type: graphql.NewNonNull(graphql.String)},
+		}, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return &testBook{ID: 4, Title: p.Args["title"].(string)}, nil
+		}},
+	}})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	require.NoError(t, err)
+	return &schema
+}
+
+// checkLimits 解析查询并按 limits 检查
+func checkLimits(t *testing.T, schema *graphql.Schema, query string, variables map[string]interface{}, limits utils.GraphQLLimits) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	require.NoError(t, err)
+	require.True(t, graphql.ValidateDocument(schema, doc, nil).IsValid, query)
+	operation, err := utils.GraphQLOperation(doc, "")
+	require.NoError(t, err)
+	return utils.CheckGraphQLLimits(schema, doc, operation, variables, limits)
+}
+
+// TestGraphQLLimits 测试层数、别名、字段数量和复杂度限制
+func TestGraphQLLimits(t *testing.T) {
+	schema := newTestGraphQLSchema(t)
+	limits := utils.GraphQLLimits{MaxDepth: 3, MaxAliases: 2, MaxFields: 10, MaxComplexity: 100, ListSize: 5}
+
+	assert.NoError(t, checkLimits(t, schema, `{ books(page_size: 2) { id author { name } } }`, nil, limits))
+
+	err := checkLimits(t, schema, `{ books(page_size: 1) { related { related { id } } } }`, nil, limits)
+	assert.ErrorContains(t, err, "嵌套超过 3 层")
+
+	err = checkLimits(t, schema, `{ a: books(page_size: 1) { id } b: books(page_size: 1) { id } c: books(page_size: 1) { id } }`, nil, limits)
+	assert.ErrorContains(t, err, "别名超过 2 个")
+
+	err = checkLimits(t, schema, `{ books(page_size: 1) { ...f ...f ...f ...f ...f ...f } } fragment f on Book { id title }`, nil, limits)
+	assert.ErrorContains(t, err, "字段超过 10 个", "片段在使用处展开计算")
+
+	// books 的复杂度为 1 + page_size * (id + title)
+	assert.NoError(t, checkLimits(t, schema, `{ books(page_size: 49) { id title } }`, nil, limits))
+	err = checkLimits(t, schema, `{ books(page_size: 50) { id title } }`, nil, limits)
+	assert.ErrorContains(t, err, "复杂度超过 100")
+	err = checkLimits(t, schema, `query($n: Int) { books(page_size: $n) { id title } }`, map[string]interface{}{"n": float64(1e9)}, limits)
+	assert.ErrorContains(t, err, "复杂度超过 100", "变量中的 page_size 同样计入")
+	err = checkLimits(t, schema, `{ books { id title author { id name } } }`, nil, limits)
+	assert.ErrorContains(t, err, "复杂度超过 100", "未指定时按默认的 page_size 计算")
+	err = checkLimits(t, schema, `{ books(page_size: 1) { related { id title author { id name } } } }`, nil, utils.GraphQLLimits{MaxDepth: 5, MaxAliases: 2, MaxFields: 10, MaxComplexity: 20, ListSize: 5})
+	assert.ErrorContains(t, err, "复杂度超过 20", "没有 page_size 的列表按 ListSize 估计")
+}
+
+// TestGraphQLOperation 测试按 operationName 选择操作
+func TestGraphQLOperation(t *testing.T) {
+	doc, err := parser.Parse(parser.ParseParams{Source: `query A { books { id } } mutation B { add(title: "x") { id } }`})
+	require.NoError(t, err)
+
+	operation, err := utils.GraphQLOperation(doc, "B")
+	require.NoError(t, err)
+	assert.Equal(t, ast.OperationTypeMutation, operation.Operation)
+	_, err = utils.GraphQLOperation(doc, "")
+	assert.ErrorContains(t, err, "请指定 operationName")
+	_, err = utils.GraphQLOperation(doc, "C")
+	assert.ErrorContains(t, err, "操作 C 不存在")
+}
+
+// TestGraphQLLoader 测试同一层的字段通过加载器批量加载
+func TestGraphQLLoader(t *testing.T) {
+	schema := newTestGraphQLSchema(t)
+	var fetches [][]uint
+	loader := utils.NewGraphQLLoader(func(ids []uint) (map[uint]*testAuthor, error) {
+		fetches = append(fetches, append([]uint{}, ids...))
+		result := make(map[uint]*testAuthor)
+		for _, id := range ids {
+			result[id] = &testAuthor{ID: id, Name: fmt.Sprintf("作者%d", id)}
+		}
+		return result, nil
+	})
+
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `{ books { title author { name } } }`,
+		Context:       context.WithValue(context.Background(), testLoaderKey{}, loader),
+	})
+	require.Empty(t, result.Errors)
+	data, err := json.Marshal(result.Data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"books": [
+		{"title": "A", "author": {"name": "作者10"}},
+		{"title": "B", "author": {"name": "作者20"}},
+		{"title": "C", "author": {"name": "作者10"}}
+	]}`, string(data))
+	assert.Equal(t, [][]uint{{10, 20}}, fetches, "三本书的作者只加载一次")
+}
+
+// TestGraphQLErrorExtensions 测试字段错误的错误码
+func TestGraphQLErrorExtensions(t *testing.T) {
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"secret": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return nil, utils.NewGraphQLError("FORBIDDEN", "没有权限")
+		}},
+	}})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	require.NoError(t, err)
+
+	result := graphql.Do(graphql.Params{Schema: schema, RequestString: `{ secret }`})
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "没有权限", result.Errors[0].Message)
+	assert.Equal(t, map[string]interface{}{"code": "FORBIDDEN"}, result.Errors[0].Extensions)
+	assert.Equal(t, []interface{}{"secret"}, result.Errors[0].Path)
+}
+
+// TestGraphQLSDL 测试导出 schema
+func TestGraphQLSDL(t *testing.T) {
+	sdl := utils.GraphQLSDL(newTestGraphQLSchema(t))
+	assert.True(t, strings.HasPrefix(sdl, "schema {\n  query: Query\n  mutation: Mutation\n}\n"))
+	assert.Contains(t, sdl, "\"书\"\ntype Book {\n  author: Author\n  id: ID!\n  related: [Book]\n  \"标题\"\n  title: String!\n}\n")
+	assert.Contains(t, sdl, "  books(page_size: Int = 20): [Book]\n")
+	assert.NotContains(t, sdl, "__Schema", "不包含内省类型")
+	assert.NotContains(t, sdl, "scalar String", "不包含内置标量")
+}
@@ -0,0 +1,356 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 补丁请求的 Content-Type
+const (
+	MergePatchContentType = "application/merge-patch+json" // RFC 7396 JSON Merge Patch
+	JSONPatchContentType  = "application/json-patch+json"  // RFC 6902 JSON Patch
+)
+
+var (
+	// ErrInvalidPatch 补丁格式错误或路径不存在
+	ErrInvalidPatch = errors.New("无效的补丁")
+	// ErrPatchTestFailed JSON Patch 的 test 操作不成立
+	ErrPatchTestFailed = errors.New("补丁的 test 操作不成立")
+)
+
+// NormalizeJSON 通过一次JSON编解码将值转换为 map[string]interface{}、[]interface{}、float64 等通用类型
+func NormalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// ApplyMergePatch 按 RFC 7396 将合并补丁应用到文档：对象递归合并，null 删除字段，其他值（包括数组）整体替换
+// doc 必须是 NormalizeJSON 返回的通用类型，可能被原地修改
+func ApplyMergePatch(doc interface{}, patch []byte) (interface{}, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return mergePatch(doc, p), nil
+}
+
+// mergePatch RFC 7396 的 MergePatch(Target, Patch) 算法
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+// jsonPatchOperation RFC 6902 中的一个操作
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch 按 RFC 6902 依次执行 add、remove、replace、move、copy、test 操作
+// 任何一个操作失败时返回错误，调用方应放弃整个补丁；doc 可能被原地修改
+func ApplyJSONPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: 补丁必须是操作数组: %v", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		doc, err = applyJSONPatchOperation(doc, &operation)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个操作（%s）: %w", i+1, operation.Op, err)
+		}
+	}
+	return doc, nil
+}
+
+// applyJSONPatchOperation 执行单个 JSON Patch 操作
+func applyJSONPatchOperation(doc interface{}, operation *jsonPatchOperation) (interface{}, error) {
+	if operation.Path == nil {
+		return nil, fmt.Errorf("%w: 缺少 path", ErrInvalidPatch)
+	}
+	path, err := parseJSONPointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: 缺少 value", ErrInvalidPatch)
+		}
+		var v interface{}
+		if err := json.Unmarshal(operation.Value, &v); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		return v, nil
+	}
+	from := func() ([]string, error) {
+		if operation.From == nil {
+			return nil, fmt.Errorf("%w: 缺少 from", ErrInvalidPatch)
+		}
+		return parseJSONPointer(*operation.From)
+	}
+
+	switch operation.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPatchAdd(doc, path, v)
+
+	case "remove":
+		doc, _, err := jsonPatchRemove(doc, path)
+		return doc, err
+
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		doc, _, err = jsonPatchRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPatchAdd(doc, path, v)
+
+	case "move":
+		fromPath, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if isJSONPointerPrefix(fromPath, path) && len(fromPath) < len(path) {
+			return nil, fmt.Errorf("%w: 不能移动到自身的子路径", ErrInvalidPatch)
+		}
+		doc, v, err := jsonPatchRemove(doc, fromPath)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPatchAdd(doc, path, v)
+
+	case "copy":
+		fromPath, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := jsonPatchGet(doc, fromPath)
+		if err != nil {
+			return nil, err
+		}
+		// 深拷贝，避免两个位置共享同一个对象
+		if v, err = NormalizeJSON(v); err != nil {
+			return nil, err
+		}
+		return jsonPatchAdd(doc, path, v)
+
+	case "test":
+		expected, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := jsonPatchGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, ErrPatchTestFailed
+		}
+		return doc, nil
+
+	default:
+		return nil, fmt.Errorf("%w: 不支持的操作 %q", ErrInvalidPatch, operation.Op)
+	}
+}
+
+// parseJSONPointer 解析 RFC 6901 JSON Pointer，空字符串表示整个文档
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: 路径必须以 / 开头: %s", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// isJSONPointerPrefix 判断 prefix 是否为 path 的前缀
+func isJSONPointerPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonArrayIndex 解析数组下标，下标必须小于 limit
+func jsonArrayIndex(token string, limit int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: 无效的数组下标 %q", ErrInvalidPatch, token)
+	}
+	if index >= limit {
+		return 0, fmt.Errorf("%w: 数组下标 %d 越界", ErrInvalidPatch, index)
+	}
+	return index, nil
+}
+
+// jsonPatchGet 读取路径上的值
+func jsonPatchGet(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: 路径不存在: %s", ErrInvalidPatch, token)
+			}
+			node = child
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(n))
+			if err != nil {
+				return nil, err
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("%w: 路径不存在: %s", ErrInvalidPatch, token)
+		}
+	}
+	return node, nil
+}
+
+// jsonPatchAdd 在路径上添加值：对象中新增或覆盖字段，数组中在下标处插入（"-" 表示末尾）
+// 返回修改后的节点（数组插入会产生新的切片）
+func jsonPatchAdd(node interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	token, rest := path[0], path[1:]
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(rest) == 0 {
+			n[token] = value
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: 路径不存在: %s", ErrInvalidPatch, token)
+		}
+		updated, err := jsonPatchAdd(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = updated
+		return n, nil
+
+	case []interface{}:
+		if len(rest) == 0 {
+			if token == "-" {
+				return append(n, value), nil
+			}
+			index, err := jsonArrayIndex(token, len(n)+1)
+			if err != nil {
+				return nil, err
+			}
+			n = append(n, nil)
+			copy(n[index+1:], n[index:])
+			n[index] = value
+			return n, nil
+		}
+		index, err := jsonArrayIndex(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		updated, err := jsonPatchAdd(n[index], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = updated
+		return n, nil
+
+	default:
+		return nil, fmt.Errorf("%w: 路径不存在: %s", ErrInvalidPatch, token)
+	}
+}
+
+// jsonPatchRemove 删除路径上的值，返回修改后的节点和被删除的值
+func jsonPatchRemove(node interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: 不能删除整个文档", ErrInvalidPatch)
+	}
+	token, rest := path[0], path[1:]
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: 路径不存在: %s", ErrInvalidPatch, token)
+		}
+		if len(rest) == 0 {
+			delete(n, token)
+			return n, child, nil
+		}
+		updated, removed, err := jsonPatchRemove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[token] = updated
+		return n, removed, nil
+
+	case []interface{}:
+		index, err := jsonArrayIndex(token, len(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := n[index]
+			return append(n[:index], n[index+1:]...), removed, nil
+		}
+		updated, removed, err := jsonPatchRemove(n[index], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		n[index] = updated
+		return n, removed, nil
+
+	default:
+		return nil, nil, fmt.Errorf("%w: 路径不存在: %s", ErrInvalidPatch, token)
+	}
+}
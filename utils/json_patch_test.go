@@ -0,0 +1,73 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"imgGeneratePrompts/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patchDocument 将JSON文本解析为补丁函数使用的通用类型
+func patchDocument(t *testing.T, text string) interface{} {
+	var doc interface{}
+	require.NoError(t, json.Unmarshal([]byte(text), &doc))
+	return doc
+}
+
+// TestApplyMergePatch 测试 RFC 7396 合并补丁
+func TestApplyMergePatch(t *testing.T) {
+	doc := patchDocument(t, `{"a": "b", "c": {"d": "e", "f": "g"}, "list": [1, 2]}`)
+	result, err := utils.ApplyMergePatch(doc, []byte(`{"a": "z", "c": {"f": null}, "list": [3], "new": true}`))
+	require.NoError(t, err)
+	assert.Equal(t, patchDocument(t, `{"a": "z", "c": {"d": "e"}, "list": [3], "new": true}`), result)
+
+	// 非对象补丁整体替换文档
+	result, err = utils.ApplyMergePatch(patchDocument(t, `{"a": 1}`), []byte(`["x"]`))
+	require.NoError(t, err)
+	assert.Equal(t, patchDocument(t, `["x"]`), result)
+
+	_, err = utils.ApplyMergePatch(doc, []byte(`{`))
+	assert.ErrorIs(t, err, utils.ErrInvalidPatch)
+}
+
+// TestApplyJSONPatch 测试 RFC 6902 的各种操作
+func TestApplyJSONPatch(t *testing.T) {
+	doc := patchDocument(t, `{"tags": ["a", "b"], "obj": {"x": 1}, "a/b": "slash"}`)
+	result, err := utils.ApplyJSONPatch(doc, []byte(`[
+		{"op": "add", "path": "/tags/1", "value": "inserted"},
+		{"op": "add", "path": "/tags/-", "value": "last"},
+		{"op": "remove", "path": "/tags/0"},
+		{"op": "replace", "path": "/obj/x", "value": 2},
+		{"op": "copy", "from": "/obj", "path": "/copied"},
+		{"op": "move", "from": "/a~1b", "path": "/moved"},
+		{"op": "test", "path": "/copied/x", "value": 2}
+	]`))
+	require.NoError(t, err)
+	assert.Equal(t, patchDocument(t, `{"tags": ["inserted", "b", "last"], "obj": {"x": 2}, "copied": {"x": 2}, "moved": "slash"}`), result)
+}
+
+// TestApplyJSONPatchErrors 测试无效补丁和 test 失败
+func TestApplyJSONPatchErrors(t *testing.T) {
+	invalid := []string{
+		`{"op": "add"}`,
+		`[{"op": "add", "path": "/tags/-"}]`,
+		`[{"op": "remove", "path": "/tags/2"}]`,
+		`[{"op": "remove", "path": "/tags/01"}]`,
+		`[{"op": "replace", "path": "/missing", "value": 1}]`,
+		`[{"op": "add", "path": "/missing/child", "value": 1}]`,
+		`[{"op": "move", "from": "/obj", "path": "/obj/inner"}]`,
+		`[{"op": "add", "path": "tags", "value": 1}]`,
+		`[{"op": "increment", "path": "/tags"}]`,
+	}
+	for _, patch := range invalid {
+		doc := patchDocument(t, `{"tags": ["a", "b"], "obj": {}}`)
+		_, err := utils.ApplyJSONPatch(doc, []byte(patch))
+		assert.ErrorIs(t, err, utils.ErrInvalidPatch, patch)
+	}
+
+	doc := patchDocument(t, `{"tags": ["a", "b"]}`)
+	_, err := utils.ApplyJSONPatch(doc, []byte(`[{"op": "test", "path": "/tags", "value": ["b", "a"]}]`))
+	assert.ErrorIs(t, err, utils.ErrPatchTestFailed)
+}
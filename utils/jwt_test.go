@@ -0,0 +1,57 @@
+package utils_test
+
+import (
+	"imgGeneratePrompts/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateAndParseJWT 测试JWT的签发与校验
+func TestGenerateAndParseJWT(t *testing.T) {
+	claims := utils.JWTClaims{
+		Subject:   "42",
+		Username:  "alice",
+		TokenType: utils.TokenTypeAccess,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	}
+
+	token, err := utils.GenerateJWT(claims, "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(token, "."), "JWT应由三段组成")
+
+	t.Run("有效令牌", func(t *testing.T) {
+		parsed, err := utils.ParseJWT(token, "secret")
+		assert.NoError(t, err)
+		assert.Equal(t, claims, *parsed)
+	})
+
+	t.Run("错误的密钥", func(t *testing.T) {
+		_, err := utils.ParseJWT(token, "other-secret")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("篡改载荷", func(t *testing.T) {
+		parts := strings.Split(token, ".")
+		tampered, _ := utils.GenerateJWT(utils.JWTClaims{Subject: "1", ExpiresAt: claims.ExpiresAt}, "secret")
+		parts[1] = strings.Split(tampered, ".")[1]
+		_, err := utils.ParseJWT(strings.Join(parts, "."), "secret")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("格式错误", func(t *testing.T) {
+		_, err := utils.ParseJWT("not-a-jwt", "secret")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("已过期", func(t *testing.T) {
+		expired := claims
+		expired.ExpiresAt = time.Now().Add(-time.Second).Unix()
+		expiredToken, _ := utils.GenerateJWT(expired, "secret")
+		_, err := utils.ParseJWT(expiredToken, "secret")
+		assert.ErrorIs(t, err, utils.ErrExpiredToken)
+	})
+}
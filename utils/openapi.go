@@ -0,0 +1,511 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPIOperation 一个接口的文档描述，由 OpenAPIBuilder 转换为 OpenAPI 3.1 的 operation
+type OpenAPIOperation struct {
+	Method  string // HTTP方法
+	Path    string // Gin 路由路径，如 /api/v1/prompts/:id
+	Tag     string
+	Summary string
+
+	Query interface{} // 查询参数结构体（按 form 标签）
+	Body  interface{} // JSON 请求体
+	// Bodies 其他类型的请求体，键为 Content-Type，如 application/json-patch+json
+	Bodies map[string]interface{}
+	Form   interface{} // 表单请求体（multipart/form-data 或 application/x-www-form-urlencoded）
+	Files  []string    // multipart 文件字段，字段名以 [] 结尾表示可上传多个文件
+	// Headers 额外的请求头，如 If-Match
+	Headers []string
+
+	// Data 成功响应 ResponseData.data 的内容；为 nil 时 data 为空
+	Data interface{}
+	// Paginated 为 true 时 data 为 PaginationData，items 为 Data 类型的数组
+	Paginated bool
+	// Status 成功响应的状态码，默认200
+	Status int
+	// ContentType 不使用 ResponseData 封装的响应类型，如 text/event-stream、application/octet-stream；
+	// 为 application/json 时响应内容为 Data
+	ContentType string
+
+	Public       bool // 不需要认证
+	AuthRequired bool // 必须登录（匿名请求返回401）
+}
+
+// OpenAPIBuilder 根据接口描述和 Go 结构体生成 OpenAPI 3.1 文档
+// 结构体按 json / form 标签生成 schema，binding 标签中的 required、oneof、min、max 等转换为约束
+type OpenAPIBuilder struct {
+	title   string
+	version string
+	paths   map[string]map[string]interface{}
+	schemas map[string]interface{}
+	tags    []string
+}
+
+// NewOpenAPIBuilder 创建文档生成器
+func NewOpenAPIBuilder(title, version string) *OpenAPIBuilder {
+	b := &OpenAPIBuilder{
+		title:   title,
+		version: version,
+		paths:   make(map[string]map[string]interface{}),
+		schemas: make(map[string]interface{}),
+	}
+	b.Schema(ResponseData{})
+	b.Schema(PaginationData{})
+	return b
+}
+
+// Add 添加接口
+func (b *OpenAPIBuilder) Add(ops ...OpenAPIOperation) {
+	for _, op := range ops {
+		b.add(op)
+	}
+}
+
+// Document 返回完整的文档
+func (b *OpenAPIBuilder) Document() map[string]interface{} {
+	tags := make([]map[string]interface{}, len(b.tags))
+	for i, tag := range b.tags {
+		tags[i] = map[string]interface{}{"name": tag}
+	}
+	errorResponse := map[string]interface{}{
+		"description": "错误，message 为错误信息",
+		"content":     jsonContent(schemaRef("ResponseData")),
+	}
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":   b.title,
+			"version": b.version,
+		},
+		"tags":  tags,
+		"paths": b.paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"responses": map[string]interface{}{
+				"Error": errorResponse,
+			},
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "JWT 访问令牌，或以 igp_ 开头的个人API密钥",
+				},
+			},
+		},
+	}
+}
+
+// OpenAPIPath 将 Gin 路由路径转换为 OpenAPI 路径，如 /prompts/:id -> /prompts/{id}
+func OpenAPIPath(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// add 将接口描述转换为 operation
+func (b *OpenAPIBuilder) add(op OpenAPIOperation) {
+	path := OpenAPIPath(op.Path)
+	if b.paths[path] == nil {
+		b.paths[path] = make(map[string]interface{})
+	}
+	b.addTag(op.Tag)
+
+	operation := map[string]interface{}{
+		"tags":        []string{op.Tag},
+		"summary":     op.Summary,
+		"operationId": operationID(op.Method, path),
+	}
+	switch {
+	case op.Public:
+		operation["security"] = []interface{}{}
+	case op.AuthRequired:
+		operation["security"] = []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+	default:
+		// 匿名请求按配置的匿名角色处理，因此认证是可选的
+		operation["security"] = []interface{}{map[string]interface{}{}, map[string]interface{}{"bearerAuth": []string{}}}
+	}
+
+	parameters := pathParameters(op.Path)
+	if op.Query != nil {
+		parameters = append(parameters, b.queryParameters(op.Query)...)
+	}
+	for _, header := range op.Headers {
+		parameters = append(parameters, map[string]interface{}{
+			"name": header, "in": "header", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if body := b.requestBody(op); body != nil {
+		operation["requestBody"] = body
+	}
+	operation["responses"] = b.responses(op)
+	b.paths[path][strings.ToLower(op.Method)] = operation
+}
+
+// addTag 按首次出现的顺序记录分组
+func (b *OpenAPIBuilder) addTag(tag string) {
+	for _, existing := range b.tags {
+		if existing == tag {
+			return
+		}
+	}
+	b.tags = append(b.tags, tag)
+}
+
+// requestBody 生成请求体：JSON 和表单可以同时存在
+func (b *OpenAPIBuilder) requestBody(op OpenAPIOperation) map[string]interface{} {
+	content := make(map[string]interface{})
+	if op.Body != nil {
+		content["application/json"] = map[string]interface{}{"schema": b.Schema(op.Body)}
+	}
+	for contentType, body := range op.Bodies {
+		content[contentType] = map[string]interface{}{"schema": b.Schema(body)}
+	}
+	if op.Form != nil || len(op.Files) > 0 {
+		form := map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		if op.Form != nil {
+			form = b.inlineSchema(reflect.TypeOf(op.Form), "form")
+		}
+		properties := form["properties"].(map[string]interface{})
+		for _, file := range op.Files {
+			binary := map[string]interface{}{"type": "string", "contentMediaType": "application/octet-stream"}
+			if name, ok := strings.CutSuffix(file, "[]"); ok {
+				properties[name] = map[string]interface{}{"type": "array", "items": binary}
+			} else {
+				properties[file] = binary
+			}
+		}
+		content["multipart/form-data"] = map[string]interface{}{"schema": form}
+		if len(op.Files) == 0 {
+			content["application/x-www-form-urlencoded"] = map[string]interface{}{"schema": form}
+		}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"required": true, "content": content}
+}
+
+// responses 生成成功响应和通用的错误响应
+func (b *OpenAPIBuilder) responses(op OpenAPIOperation) map[string]interface{} {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case op.ContentType != "":
+		// 不封装的响应：JSON 使用 Data 的 schema，其他类型为字符串或二进制内容
+		var schema map[string]interface{}
+		switch {
+		case op.Data != nil:
+			schema = b.Schema(op.Data)
+		case op.ContentType == "application/json":
+			schema = map[string]interface{}{"type": "object"}
+		case strings.HasPrefix(op.ContentType, "text/"):
+			schema = map[string]interface{}{"type": "string"}
+		default:
+			schema = map[string]interface{}{"type": "string", "contentMediaType": op.ContentType}
+		}
+		success["content"] = map[string]interface{}{op.ContentType: map[string]interface{}{"schema": schema}}
+	case op.Paginated:
+		page := map[string]interface{}{
+			"allOf": []interface{}{
+				schemaRef("PaginationData"),
+				map[string]interface{}{"properties": map[string]interface{}{
+					"items": map[string]interface{}{"type": "array", "items": b.Schema(op.Data)},
+				}},
+			},
+		}
+		success["content"] = jsonContent(envelope(page))
+	case op.Data != nil:
+		success["content"] = jsonContent(envelope(b.Schema(op.Data)))
+	default:
+		success["content"] = jsonContent(schemaRef("ResponseData"))
+	}
+
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	if op.ContentType == "" {
+		responses["default"] = map[string]interface{}{"$ref": "#/components/responses/Error"}
+	}
+	return responses
+}
+
+// Schema 返回类型对应的 schema，命名的结构体注册到 components 中并返回引用
+func (b *OpenAPIBuilder) Schema(v interface{}) map[string]interface{} {
+	if schema, ok := v.(map[string]interface{}); ok {
+		return schema // 已经是 schema（如 gin.H 形式的响应）
+	}
+	return b.typeSchema(reflect.TypeOf(v))
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// typeSchema 按类型生成 schema
+func (b *OpenAPIBuilder) typeSchema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case t == timeType:
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType || t.Kind() == reflect.Interface:
+		return map[string]interface{}{} // 任意JSON
+	case t.Kind() == reflect.Struct && t.Name() == "DeletedAt":
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		if t.Name() == "" {
+			return b.inlineSchema(t, "json")
+		}
+		name := schemaName(t)
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = map[string]interface{}{} // 先占位，处理自引用的类型
+			b.schemas[name] = b.inlineSchema(t, "json")
+		}
+		return schemaRef(name)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		schema = map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = map[string]interface{}{"type": "array", "items": b.typeSchema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		schema = map[string]interface{}{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	default:
+		schema = map[string]interface{}{"type": primitiveType(t)}
+	}
+	if nullable {
+		schema["type"] = []string{schema["type"].(string), "null"}
+	}
+	return schema
+}
+
+// inlineSchema 生成结构体的 object schema，tagKey 为字段名使用的标签（json 或 form）
+func (b *OpenAPIBuilder) inlineSchema(t reflect.Type, tagKey string) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	properties := make(map[string]interface{})
+	var required []string
+	b.collectFields(t, tagKey, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields 收集结构体字段，匿名嵌入的结构体字段展开到外层
+func (b *OpenAPIBuilder) collectFields(t reflect.Type, tagKey string, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			b.collectFields(field.Type, tagKey, properties, required)
+			continue
+		}
+		name, ok := fieldName(field, tagKey)
+		if !ok {
+			continue
+		}
+
+		schema := b.typeSchema(field.Type)
+		if _, isRef := schema["$ref"]; !isRef {
+			if applyBinding(schema, field.Tag.Get("binding")) {
+				*required = append(*required, name)
+			}
+		} else if strings.Contains(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// queryParameters 按 form 标签生成查询参数
+func (b *OpenAPIBuilder) queryParameters(query interface{}) []map[string]interface{} {
+	t := reflect.TypeOf(query)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var parameters []map[string]interface{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := fieldName(field, "form")
+		if !ok {
+			continue
+		}
+		schema := b.typeSchema(field.Type)
+		if types, ok := schema["type"].([]string); ok {
+			schema["type"] = types[0] // 查询参数省略即为空
+		}
+		parameter := map[string]interface{}{"name": name, "in": "query", "schema": schema}
+		if applyBinding(schema, field.Tag.Get("binding")) {
+			parameter["required"] = true
+		}
+		if schema["type"] == "array" {
+			parameter["explode"] = true
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// fieldName 获取字段在 JSON 或表单中的名称，没有对应标签时使用 json 标签，都没有时使用字段名
+func fieldName(field reflect.StructField, tagKey string) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get(tagKey)
+	if tag == "" && tagKey != "json" {
+		tag = field.Tag.Get("json")
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// applyBinding 将 binding 标签中的校验规则转换为 schema 约束，返回字段是否必填
+// dive 之后的规则作用于数组元素
+func applyBinding(schema map[string]interface{}, binding string) bool {
+	required := false
+	target := schema
+	for _, rule := range strings.Split(binding, ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			if items, ok := target["items"].(map[string]interface{}); ok {
+				target = items
+			}
+		case "oneof":
+			target["enum"] = strings.Fields(value)
+		case "url":
+			target["format"] = "uri"
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			keyword := map[string]map[string]string{
+				"min": {"string": "minLength", "array": "minItems", "integer": "minimum", "number": "minimum"},
+				"max": {"string": "maxLength", "array": "maxItems", "integer": "maximum", "number": "maximum"},
+			}[name][baseType(target)]
+			if keyword != "" {
+				target[keyword] = n
+			}
+		}
+	}
+	return required
+}
+
+// baseType 获取 schema 的类型，可为空的类型返回非 null 的部分
+func baseType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []string:
+		return t[0]
+	}
+	return ""
+}
+
+// pathParameters 生成路径参数，名称为 id 或以 _id 结尾的参数为整数
+func pathParameters(ginPath string) []map[string]interface{} {
+	var parameters []map[string]interface{}
+	for _, segment := range strings.Split(ginPath, "/") {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		schema := map[string]interface{}{"type": "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = map[string]interface{}{"type": "integer", "minimum": 1}
+		}
+		parameters = append(parameters, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "schema": schema,
+		})
+	}
+	return parameters
+}
+
+// primitiveType 基本类型对应的 JSON Schema 类型
+func primitiveType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// schemaName 结构体在 components 中的名称，不同包的同名类型加包名前缀
+func schemaName(t reflect.Type) string {
+	if strings.HasSuffix(t.PkgPath(), "/utils") || strings.HasSuffix(t.PkgPath(), "/models") {
+		return t.Name()
+	}
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	return pkg + "." + t.Name()
+}
+
+// schemaRef 引用 components 中的 schema
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// envelope 将 data 的 schema 包装为 ResponseData
+func envelope(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"allOf": []interface{}{
+			schemaRef("ResponseData"),
+			map[string]interface{}{"properties": map[string]interface{}{"data": data}},
+		},
+	}
+}
+
+// jsonContent 生成 application/json 内容
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// operationID 根据方法和路径生成唯一的 operationId，如 get_api_v1_prompts_id
+func operationID(method, path string) string {
+	replacer := strings.NewReplacer("/", "_", "{", "", "}", "", "-", "_", ".", "_")
+	return strings.ToLower(method) + strings.TrimRight(replacer.Replace(path), "_")
+}
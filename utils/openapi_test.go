@@ -0,0 +1,60 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"imgGeneratePrompts/utils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenAPIPath 测试 Gin 路由路径转换
+func TestOpenAPIPath(t *testing.T) {
+	assert.Equal(t, "/api/v1/prompts/", utils.OpenAPIPath("/api/v1/prompts/"))
+	assert.Equal(t, "/api/v1/prompts/{id}/shares/{share_id}", utils.OpenAPIPath("/api/v1/prompts/:id/shares/:share_id"))
+	assert.Equal(t, "/uploads/{filepath}", utils.OpenAPIPath("/uploads/*filepath"))
+}
+
+type openAPITestItem struct {
+	Name     string     `json:"name" binding:"required,max=50"`
+	Kind     string     `json:"kind" binding:"omitempty,oneof=a b"`
+	Tags     []string   `json:"tags" binding:"required,min=1,dive,max=10"`
+	Score    *float64   `json:"score"`
+	Deleted  *time.Time `json:"deleted"`
+	Secret   string     `json:"-"`
+	internal string
+}
+
+// TestOpenAPISchema 测试结构体 schema 和 binding 约束
+func TestOpenAPISchema(t *testing.T) {
+	builder := utils.NewOpenAPIBuilder("test", "v1")
+	builder.Add(utils.OpenAPIOperation{Method: http.MethodPost, Path: "/items/:id", Tag: "items", Body: openAPITestItem{}, Data: openAPITestItem{}})
+
+	data, err := json.Marshal(builder.Document())
+	require.NoError(t, err)
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string                          `json:"required"`
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	require.Contains(t, doc.Paths, "/items/{id}")
+	assert.Contains(t, string(doc.Paths["/items/{id}"]["post"]), `"$ref":"#/components/schemas/utils_test.openAPITestItem"`)
+
+	schema := doc.Components.Schemas["utils_test.openAPITestItem"]
+	assert.Equal(t, []string{"name", "tags"}, schema.Required)
+	assert.Len(t, schema.Properties, 5, "忽略 json:\"-\" 和未导出的字段")
+	assert.Equal(t, float64(50), schema.Properties["name"]["maxLength"])
+	assert.Equal(t, []interface{}{"a", "b"}, schema.Properties["kind"]["enum"])
+	assert.Equal(t, float64(1), schema.Properties["tags"]["minItems"])
+	assert.Equal(t, float64(10), schema.Properties["tags"]["items"].(map[string]interface{})["maxLength"])
+	assert.Equal(t, []interface{}{"number", "null"}, schema.Properties["score"]["type"])
+	assert.Equal(t, "date-time", schema.Properties["deleted"]["format"])
+}
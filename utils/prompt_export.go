@@ -0,0 +1,358 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exportCSVHeader CSV导出的列，与导入时的字段名一致，导出的文件可以直接重新导入
+var exportCSVHeader = []string{
+	"id", "created_at", "prompt_text", "negative_prompt", "model_name", "is_public",
+	"style_description", "usage_scenario", "atmosphere_description", "expressive_intent",
+	"structure_analysis", "generation_params", "input_image_urls", "output_image_url", "tag_names",
+}
+
+// ExportFileExtension 返回导出格式对应的文件扩展名
+func ExportFileExtension(format string) string {
+	switch format {
+	case models.ExportFormatCSV:
+		return ".csv"
+	case models.ExportFormatMarkdown:
+		return ".md"
+	case models.ExportFormatText:
+		return ".txt"
+	case models.ExportFormatCivitai:
+		return ".json"
+	default:
+		return ".jsonl"
+	}
+}
+
+// ExportContentType 返回导出格式对应的 Content-Type
+func ExportContentType(format string) string {
+	switch format {
+	case models.ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case models.ExportFormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case models.ExportFormatText:
+		return "text/plain; charset=utf-8"
+	case models.ExportFormatCivitai:
+		return "application/json; charset=utf-8"
+	default:
+		return "application/x-ndjson; charset=utf-8"
+	}
+}
+
+// PromptExporter 将提示词逐条写入指定格式的输出流
+type PromptExporter struct {
+	w       io.Writer
+	format  string
+	csv     *csv.Writer
+	started bool
+	count   int
+
+	// ImageURL 写出前对图片URL的转换（如打包zip时改写为相对路径），为空时原样输出
+	ImageURL func(url string) string
+}
+
+// NewPromptExporter 创建提示词导出器
+func NewPromptExporter(w io.Writer, format string) (*PromptExporter, error) {
+	switch format {
+	case models.ExportFormatJSONL, models.ExportFormatCSV, models.ExportFormatMarkdown, models.ExportFormatText, models.ExportFormatCivitai:
+	default:
+		return nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	e := &PromptExporter{w: w, format: format}
+	if format == models.ExportFormatCSV {
+		e.csv = csv.NewWriter(w)
+	}
+	return e, nil
+}
+
+// Write 写入一条提示词
+func (e *PromptExporter) Write(prompt *models.Prompt) error {
+	if !e.started {
+		e.started = true
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	response := prompt.ToResponse()
+	response.MapImageURLs(e.imageURL)
+
+	e.count++
+	switch e.format {
+	case models.ExportFormatCSV:
+		return e.writeCSV(&response)
+	case models.ExportFormatCivitai:
+		return e.writeCivitai(&response)
+	case models.ExportFormatMarkdown:
+		return e.writeMarkdown(&response)
+	case models.ExportFormatText:
+		_, err := io.WriteString(e.w, A1111PromptLine(response.PromptText, response.NegativePrompt)+"\n")
+		return err
+	default:
+		data, err := json.Marshal(response)
+		if err != nil {
+			return err
+		}
+		_, err = e.w.Write(append(data, '\n'))
+		return err
+	}
+}
+
+// Close 写出剩余内容和结尾（Civitai 格式需要闭合JSON），导出结束时调用
+func (e *PromptExporter) Close() error {
+	if err := e.Flush(); err != nil {
+		return err
+	}
+	if e.format == models.ExportFormatCivitai {
+		_, err := io.WriteString(e.w, "\n]}\n")
+		return err
+	}
+	return nil
+}
+
+// Flush 将缓冲的内容写出，没有任何提示词时也会写出表头
+func (e *PromptExporter) Flush() error {
+	if !e.started {
+		e.started = true
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+// writeHeader 写入CSV表头或Markdown标题
+func (e *PromptExporter) writeHeader() error {
+	switch e.format {
+	case models.ExportFormatCSV:
+		return e.csv.Write(exportCSVHeader)
+	case models.ExportFormatMarkdown:
+		_, err := io.WriteString(e.w, "# 提示词画廊\n\n")
+		return err
+	case models.ExportFormatCivitai:
+		_, err := io.WriteString(e.w, `{"items":[`)
+		return err
+	}
+	return nil
+}
+
+// writeCivitai 写入一个 Civitai 图片条目
+func (e *PromptExporter) writeCivitai(p *models.PromptResponse) error {
+	image, err := CivitaiFromPrompt(p)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(image)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if e.count == 1 {
+		separator = "\n"
+	}
+	_, err = io.WriteString(e.w, separator+string(data))
+	return err
+}
+
+// writeCSV 写入一行CSV
+func (e *PromptExporter) writeCSV(p *models.PromptResponse) error {
+	tagNames := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		tagNames[i] = tag.Name
+	}
+	return e.csv.Write([]string{
+		fmt.Sprint(p.ID),
+		p.CreatedAt.Format("2006-01-02 15:04:05"),
+		p.PromptText,
+		p.NegativePrompt,
+		p.ModelName,
+		fmt.Sprint(p.IsPublic),
+		p.StyleDescription,
+		p.UsageScenario,
+		p.AtmosphereDescription,
+		p.ExpressiveIntent,
+		string(p.StructureAnalysis),
+		string(p.GenerationParams),
+		strings.Join(p.InputImageURLs, ","),
+		p.OutputImageURL,
+		strings.Join(tagNames, ","),
+	})
+}
+
+// writeMarkdown 写入一个Markdown画廊条目
+func (e *PromptExporter) writeMarkdown(p *models.PromptResponse) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## #%d", p.ID)
+	if p.ModelName != "" {
+		fmt.Fprintf(&b, " · %s", p.ModelName)
+	}
+	b.WriteString("\n\n")
+
+	if p.OutputImageURL != "" {
+		fmt.Fprintf(&b, "![输出图片](%s)\n\n", markdownURL(p.OutputImageURL))
+	}
+	if len(p.InputImageURLs) > 0 {
+		b.WriteString("参考图片：")
+		for i, url := range p.InputImageURLs {
+			fmt.Fprintf(&b, " ![参考图片%d](%s)", i+1, markdownURL(url))
+		}
+		b.WriteString("\n\n")
+	}
+
+	fmt.Fprintf(&b, "**提示词**\n\n```\n%s\n```\n\n", markdownCode(p.PromptText))
+	if p.NegativePrompt != "" {
+		fmt.Fprintf(&b, "**负面提示词**\n\n```\n%s\n```\n\n", markdownCode(p.NegativePrompt))
+	}
+	if p.StyleDescription != "" {
+		fmt.Fprintf(&b, "- 风格：%s\n", singleLine(p.StyleDescription))
+	}
+	if p.UsageScenario != "" {
+		fmt.Fprintf(&b, "- 适用场景：%s\n", singleLine(p.UsageScenario))
+	}
+	if len(p.Tags) > 0 {
+		names := make([]string, len(p.Tags))
+		for i, tag := range p.Tags {
+			names[i] = "`" + strings.ReplaceAll(tag.Name, "`", "'") + "`"
+		}
+		fmt.Fprintf(&b, "- 标签：%s\n", strings.Join(names, " "))
+	}
+	b.WriteString("\n---\n\n")
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+// imageURL 应用图片URL转换
+func (e *PromptExporter) imageURL(url string) string {
+	if url == "" || e.ImageURL == nil {
+		return url
+	}
+	return e.ImageURL(url)
+}
+
+// A1111PromptLine 生成 A1111 "Prompts from file or textbox" 脚本可读取的一行
+// 没有负面提示词时直接输出提示词；否则使用脚本支持的 --prompt/--negative_prompt 参数形式
+func A1111PromptLine(prompt, negative string) string {
+	prompt = singleLine(prompt)
+	negative = singleLine(negative)
+	if negative == "" && !strings.HasPrefix(prompt, "--") {
+		return prompt
+	}
+	line := "--prompt " + shellQuote(prompt)
+	if negative != "" {
+		line += " --negative_prompt " + shellQuote(negative)
+	}
+	return line
+}
+
+// singleLine 将多行文本合并为一行
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// shellQuote 按 shlex 规则用双引号包裹参数
+func shellQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// markdownURL 转义Markdown链接中的空格和括号
+func markdownURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+}
+
+// markdownCode 避免内容中的 ``` 提前结束代码块
+func markdownCode(s string) string {
+	return strings.ReplaceAll(s, "```", "'''")
+}
+
+// ExportBundle 将导出文件和引用的本地图片打包为zip
+// 导出文件中引用的 /uploads/ 图片被改写为 images/ 下的相对路径，打开zip中的Markdown即可离线浏览
+type ExportBundle struct {
+	zip        *zip.Writer
+	uploadPath string
+	images     map[string]bool
+}
+
+// NewExportBundle 创建zip导出包
+func NewExportBundle(w io.Writer, uploadPath string) *ExportBundle {
+	return &ExportBundle{
+		zip:        zip.NewWriter(w),
+		uploadPath: uploadPath,
+		images:     make(map[string]bool),
+	}
+}
+
+// Create 在zip中创建导出文件，并返回改写图片URL的函数
+func (b *ExportBundle) Create(name string) (io.Writer, func(url string) string, error) {
+	w, err := b.zip.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return w, b.rewriteImageURL, nil
+}
+
+// rewriteImageURL 将本地上传的图片改写为 images/<文件名> 并记录下来
+func (b *ExportBundle) rewriteImageURL(url string) string {
+	name, ok := uploadedFileName(url)
+	if !ok {
+		return url
+	}
+	b.images[name] = true
+	return "images/" + name
+}
+
+// Close 写入所有引用的图片并结束zip，返回缺失的图片
+func (b *ExportBundle) Close() ([]string, error) {
+	names := make([]string, 0, len(b.images))
+	for name := range b.images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var missing []string
+	for _, name := range names {
+		file, err := os.Open(filepath.Join(b.uploadPath, name))
+		if err != nil {
+			missing = append(missing, "/uploads/"+name)
+			continue
+		}
+		w, err := b.zip.CreateHeader(&zip.FileHeader{Name: "images/" + name, Method: zip.Store})
+		if err == nil {
+			_, err = io.Copy(w, file)
+		}
+		file.Close()
+		if err != nil {
+			return missing, err
+		}
+	}
+
+	if len(missing) > 0 {
+		w, err := b.zip.Create("missing_images.txt")
+		if err != nil {
+			return missing, err
+		}
+		if _, err := io.WriteString(w, strings.Join(missing, "\n")+"\n"); err != nil {
+			return missing, err
+		}
+	}
+	return missing, b.zip.Close()
+}
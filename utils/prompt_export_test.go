@@ -0,0 +1,124 @@
+package utils_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportTestPrompts 测试用的提示词数据
+func exportTestPrompts() []*models.Prompt {
+	first := &models.Prompt{
+		ID:                1,
+		PromptText:        "a cat,\nsitting on a \"chair\"",
+		NegativePrompt:    "blurry",
+		ModelName:         "sdxl",
+		StructureAnalysis: json.RawMessage(`{"subject":"cat"}`),
+		Tags:              []*models.Tag{{ID: 1, Name: "animal"}, {ID: 2, Name: "cute"}},
+	}
+	first.SetInputImageURLs([]string{"/uploads/ref.png", "https://example.com/remote.png"})
+	first.SetOutputImageURL("/uploads/cat.png")
+	second := &models.Prompt{ID: 2, PromptText: "a dog"}
+	return []*models.Prompt{first, second}
+}
+
+// exportToString 将测试数据导出为字符串
+func exportToString(t *testing.T, format string) string {
+	var buf bytes.Buffer
+	exporter, err := utils.NewPromptExporter(&buf, format)
+	require.NoError(t, err)
+	for _, prompt := range exportTestPrompts() {
+		require.NoError(t, exporter.Write(prompt))
+	}
+	require.NoError(t, exporter.Close())
+	return buf.String()
+}
+
+// TestPromptExporterFormats 测试各导出格式的输出
+func TestPromptExporterFormats(t *testing.T) {
+	// JSONL：每行一个 PromptResponse
+	lines := strings.Split(strings.TrimSpace(exportToString(t, models.ExportFormatJSONL)), "\n")
+	require.Len(t, lines, 2)
+	var first models.PromptResponse
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, []string{"/uploads/ref.png", "https://example.com/remote.png"}, first.InputImageURLs)
+	assert.Len(t, first.Tags, 2)
+
+	// CSV：导出的文件可以按默认列名重新导入
+	csvOutput := exportToString(t, models.ExportFormatCSV)
+	reader, err := utils.NewImportReader(strings.NewReader(csvOutput), models.ImportFormatCSV, nil)
+	require.NoError(t, err)
+	row, err := reader.Next()
+	require.NoError(t, err)
+	require.NoError(t, row.Err)
+	assert.Equal(t, "a cat,\nsitting on a \"chair\"", row.Request.PromptText)
+	assert.Equal(t, []string{"animal", "cute"}, row.Request.TagNames)
+	assert.JSONEq(t, `{"subject":"cat"}`, row.Request.StructureAnalysis)
+
+	// Markdown 画廊
+	markdown := exportToString(t, models.ExportFormatMarkdown)
+	assert.True(t, strings.HasPrefix(markdown, "# 提示词画廊"))
+	assert.Contains(t, markdown, "![输出图片](/uploads/cat.png)")
+	assert.Contains(t, markdown, "`animal` `cute`")
+
+	// A1111 文本：每行一个提示词
+	text := exportToString(t, models.ExportFormatText)
+	assert.Equal(t, "--prompt \"a cat, sitting on a \\\"chair\\\"\" --negative_prompt \"blurry\"\na dog\n", text)
+
+	_, err = utils.NewPromptExporter(io.Discard, "xml")
+	assert.Error(t, err)
+}
+
+// TestExportBundle 测试zip打包：本地图片改写为相对路径并打包，远程图片保持不变
+func TestExportBundle(t *testing.T) {
+	uploadPath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(uploadPath, "cat.png"), []byte("cat image"), 0644))
+
+	var buf bytes.Buffer
+	bundle := utils.NewExportBundle(&buf, uploadPath)
+	w, rewrite, err := bundle.Create("prompts.md")
+	require.NoError(t, err)
+	exporter, err := utils.NewPromptExporter(w, models.ExportFormatMarkdown)
+	require.NoError(t, err)
+	exporter.ImageURL = rewrite
+	for _, prompt := range exportTestPrompts() {
+		require.NoError(t, exporter.Write(prompt))
+	}
+	require.NoError(t, exporter.Close())
+	missing, err := bundle.Close()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/uploads/ref.png"}, missing)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+	assert.Equal(t, "cat image", files["images/cat.png"])
+	assert.Contains(t, files["prompts.md"], "![输出图片](images/cat.png)")
+	assert.Contains(t, files["prompts.md"], "https://example.com/remote.png")
+	assert.Contains(t, files["missing_images.txt"], "/uploads/ref.png")
+}
+
+// TestA1111PromptLine 测试 A1111 提示词行的生成
+func TestA1111PromptLine(t *testing.T) {
+	assert.Equal(t, "a cat", utils.A1111PromptLine("a cat", ""))
+	assert.Equal(t, "a cat on a mat", utils.A1111PromptLine("a cat\n on a mat ", " "))
+	assert.Equal(t, `--prompt "--weird"`, utils.A1111PromptLine("--weird", ""))
+	assert.Equal(t, `--prompt "a \\ b" --negative_prompt "ugly"`, utils.A1111PromptLine(`a \ b`, "ugly"))
+}
@@ -0,0 +1,428 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"imgGeneratePrompts/models"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// importFields 可作为导入目标的 CreatePromptRequest 字段（JSON字段名）
+var importFields = map[string]bool{
+	"prompt_text":            true,
+	"negative_prompt":        true,
+	"model_name":             true,
+	"is_public":              true,
+	"style_description":      true,
+	"usage_scenario":         true,
+	"atmosphere_description": true,
+	"expressive_intent":      true,
+	"structure_analysis":     true,
+	"generation_params":      true,
+	"input_image_urls":       true,
+	"output_image_url":       true,
+	"tag_names":              true,
+}
+
+// defaultImportAliases 未配置映射时自动识别的常见列名
+var defaultImportAliases = map[string]string{
+	"prompt":   "prompt_text",
+	"negative": "negative_prompt",
+	"model":    "model_name",
+	"tags":     "tag_names",
+}
+
+// ImportFields 返回所有可作为导入目标的字段名
+func ImportFields() []string {
+	fields := make([]string, 0, len(importFields))
+	for field := range importFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// DetectImportFormat 根据文件扩展名判断导入格式，无法识别时返回空字符串
+func DetectImportFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jsonl", ".ndjson":
+		return models.ImportFormatJSONL
+	case ".csv":
+		return models.ImportFormatCSV
+	case ".json":
+		return models.ImportFormatCivitai
+	default:
+		return ""
+	}
+}
+
+// ParseImportMapping 解析列映射配置
+// 支持JSON对象（{"prompt":"prompt_text"}）或逗号分隔的 源列=目标字段（prompt=prompt_text,tags=tag_names）
+// 目标字段为 "-" 表示忽略该列
+func ParseImportMapping(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	mapping := make(map[string]string)
+	if strings.HasPrefix(s, "{") {
+		if err := json.Unmarshal([]byte(s), &mapping); err != nil {
+			return nil, fmt.Errorf("列映射不是有效的JSON对象: %v", err)
+		}
+	} else {
+		for _, pair := range strings.Split(s, ",") {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return nil, fmt.Errorf("无效的列映射: %s（格式应为 源列=目标字段）", pair)
+			}
+			mapping[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	for source, target := range mapping {
+		if target != "-" && !importFields[target] {
+			return nil, fmt.Errorf("列 %s 映射到了未知字段 %s（可用字段: %s）", source, target, strings.Join(ImportFields(), ", "))
+		}
+	}
+	return mapping, nil
+}
+
+// ImportRow 导入文件中解析出的一行
+type ImportRow struct {
+	Line    int                         // 文件中的行号
+	Request *models.CreatePromptRequest // 映射后的创建请求
+	Fields  []string                    // 该行实际出现的目标字段，upsert 时只更新这些字段
+	Err     error                       // 该行的解析或校验错误
+}
+
+// HasField 判断该行是否包含指定的目标字段
+func (r *ImportRow) HasField(field string) bool {
+	for _, f := range r.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportReader 逐行读取 JSONL、CSV 或 Civitai JSON 导入文件并映射为 CreatePromptRequest
+type ImportReader struct {
+	format  string
+	mapping map[string]string
+
+	// JSONL
+	lines *bufio.Reader
+	line  int
+
+	// CSV
+	csv    *csv.Reader
+	header []string
+
+	// Civitai：顶层JSON值可能包含多个条目，行号为条目序号
+	decoder *json.Decoder
+	pending []json.RawMessage
+	item    int
+}
+
+// NewImportReader 创建导入读取器，CSV 格式会立即读取表头
+func NewImportReader(r io.Reader, format string, mapping map[string]string) (*ImportReader, error) {
+	for source, target := range mapping {
+		if target != "-" && !importFields[target] {
+			return nil, fmt.Errorf("列 %s 映射到了未知字段 %s", source, target)
+		}
+	}
+
+	ir := &ImportReader{format: format, mapping: mapping}
+	switch format {
+	case models.ImportFormatJSONL:
+		ir.lines = bufio.NewReader(r)
+	case models.ImportFormatCSV:
+		ir.csv = csv.NewReader(r)
+		ir.csv.FieldsPerRecord = -1
+		ir.csv.LazyQuotes = true
+		header, err := ir.csv.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("CSV文件为空")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取CSV表头失败: %v", err)
+		}
+		if len(header) > 0 {
+			// 去掉Excel导出时附带的UTF-8 BOM
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+		ir.header = make([]string, len(header))
+		mapped := false
+		for i, column := range header {
+			ir.header[i] = ir.targetField(strings.TrimSpace(column))
+			if ir.header[i] == "prompt_text" {
+				mapped = true
+			}
+		}
+		if !mapped {
+			return nil, fmt.Errorf("CSV表头中没有映射到 prompt_text 的列")
+		}
+	case models.ImportFormatCivitai:
+		ir.decoder = json.NewDecoder(r)
+	default:
+		return nil, fmt.Errorf("不支持的导入格式: %s", format)
+	}
+	return ir, nil
+}
+
+// Next 读取下一行，文件结束时返回 io.EOF
+// 单行的解析错误放在 ImportRow.Err 中返回，只有读取文件本身失败时才返回 error
+func (ir *ImportReader) Next() (*ImportRow, error) {
+	switch ir.format {
+	case models.ImportFormatCSV:
+		return ir.nextCSV()
+	case models.ImportFormatCivitai:
+		return ir.nextCivitai()
+	default:
+		return ir.nextJSONL()
+	}
+}
+
+// nextJSONL 读取下一个非空的JSON行
+func (ir *ImportReader) nextJSONL() (*ImportRow, error) {
+	for {
+		data, err := ir.lines.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(data) == 0 && err == io.EOF {
+			return nil, io.EOF
+		}
+		ir.line++
+		if ir.line == 1 {
+			data = bytes.TrimPrefix(data, []byte("\ufeff"))
+		}
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			continue
+		}
+
+		row := &ImportRow{Line: ir.line, Request: &models.CreatePromptRequest{}}
+		var object map[string]json.RawMessage
+		if jsonErr := json.Unmarshal(data, &object); jsonErr != nil {
+			row.Err = fmt.Errorf("不是有效的JSON对象: %v", jsonErr)
+			return row, nil
+		}
+		for key, raw := range object {
+			field := ir.targetField(key)
+			if field == "" || string(raw) == "null" {
+				continue
+			}
+			text, list := jsonImportValue(raw)
+			if setErr := ir.setField(row, field, text, list); setErr != nil {
+				row.Err = setErr
+				return row, nil
+			}
+		}
+		row.Err = validateImportRow(row)
+		return row, nil
+	}
+}
+
+// nextCSV 读取下一条CSV记录
+func (ir *ImportReader) nextCSV() (*ImportRow, error) {
+	record, err := ir.csv.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	row := &ImportRow{Request: &models.CreatePromptRequest{}}
+	if err != nil {
+		if parseErr, ok := err.(*csv.ParseError); ok {
+			row.Line = parseErr.StartLine
+			row.Err = fmt.Errorf("CSV解析失败: %v", parseErr.Err)
+			return row, nil
+		}
+		return nil, err
+	}
+	if len(record) > 0 {
+		row.Line, _ = ir.csv.FieldPos(0)
+	}
+
+	for i, value := range record {
+		if i >= len(ir.header) || ir.header[i] == "" {
+			continue
+		}
+		if setErr := ir.setField(row, ir.header[i], value, splitImportList(value)); setErr != nil {
+			row.Err = setErr
+			return row, nil
+		}
+	}
+	row.Err = validateImportRow(row)
+	return row, nil
+}
+
+// nextCivitai 读取下一个 Civitai 图片条目，文件可以包含一个或多个顶层JSON值（如逐行保存的接口响应）
+// Civitai 格式的字段固定，不使用列映射
+func (ir *ImportReader) nextCivitai() (*ImportRow, error) {
+	for len(ir.pending) == 0 {
+		var raw json.RawMessage
+		if err := ir.decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			if _, ok := err.(*json.SyntaxError); ok || err == io.ErrUnexpectedEOF {
+				// 语法错误后无法继续解析，记为一行错误并结束读取
+				ir.item++
+				ir.pending = nil
+				ir.decoder = json.NewDecoder(strings.NewReader(""))
+				return &ImportRow{Line: ir.item, Err: fmt.Errorf("JSON格式错误，之后的内容未导入: %v", err), Request: &models.CreatePromptRequest{}}, nil
+			}
+			return nil, err
+		}
+		items, err := expandCivitaiValue(raw)
+		if err != nil {
+			ir.item++
+			return &ImportRow{Line: ir.item, Err: err, Request: &models.CreatePromptRequest{}}, nil
+		}
+		ir.pending = items
+	}
+
+	raw := ir.pending[0]
+	ir.pending = ir.pending[1:]
+	ir.item++
+
+	req, fields, err := CivitaiToRequest(raw)
+	if err != nil {
+		return &ImportRow{Line: ir.item, Err: err, Request: &models.CreatePromptRequest{}}, nil
+	}
+	return &ImportRow{Line: ir.item, Request: req, Fields: fields}, nil
+}
+
+// targetField 返回源列对应的目标字段，忽略的列返回空字符串
+func (ir *ImportReader) targetField(column string) string {
+	if target, ok := ir.mapping[column]; ok {
+		if target == "-" {
+			return ""
+		}
+		return target
+	}
+	key := strings.ToLower(column)
+	if importFields[key] {
+		return key
+	}
+	return defaultImportAliases[key]
+}
+
+// setField 将一个值写入请求的目标字段
+// text 为原始文本，list 为按列表解析的结果（用于标签和输入图片）
+func (ir *ImportReader) setField(row *ImportRow, field, text string, list []string) error {
+	req := row.Request
+	switch field {
+	case "prompt_text":
+		req.PromptText = strings.TrimSpace(text)
+	case "negative_prompt":
+		req.NegativePrompt = text
+	case "model_name":
+		req.ModelName = strings.TrimSpace(text)
+	case "is_public":
+		if strings.TrimSpace(text) == "" {
+			req.IsPublic = false
+			break
+		}
+		value, err := parseImportBool(text)
+		if err != nil {
+			return err
+		}
+		req.IsPublic = value
+	case "style_description":
+		req.StyleDescription = text
+	case "usage_scenario":
+		req.UsageScenario = text
+	case "atmosphere_description":
+		req.AtmosphereDescription = text
+	case "expressive_intent":
+		req.ExpressiveIntent = text
+	case "structure_analysis":
+		req.StructureAnalysis = text
+	case "generation_params":
+		req.GenerationParams = text
+	case "input_image_urls":
+		req.InputImageURLs = list
+	case "output_image_url":
+		req.OutputImageURL = strings.TrimSpace(text)
+	case "tag_names":
+		req.TagNames = list
+	default:
+		return nil
+	}
+	if !row.HasField(field) {
+		row.Fields = append(row.Fields, field)
+	}
+	return nil
+}
+
+// validateImportRow 校验映射后的请求
+func validateImportRow(row *ImportRow) error {
+	if row.Request.PromptText == "" {
+		return fmt.Errorf("prompt_text 不能为空")
+	}
+	return nil
+}
+
+// parseImportBool 解析布尔值，除 strconv.ParseBool 支持的格式外，还支持表格中常见的 yes/no、是/否
+func parseImportBool(text string) (bool, error) {
+	value := strings.ToLower(strings.TrimSpace(text))
+	switch value {
+	case "yes", "y", "是":
+		return true, nil
+	case "no", "n", "否":
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("is_public 的值无效: %s", text)
+	}
+	return parsed, nil
+}
+
+// jsonImportValue 将JSON值转换为文本和列表两种形式
+// 字符串按逗号拆分为列表，数组转换为字符串列表，对象（如 structure_analysis）保留原始JSON
+func jsonImportValue(raw json.RawMessage) (string, []string) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, splitImportList(s)
+	}
+
+	var items []interface{}
+	if err := json.Unmarshal(raw, &items); err == nil {
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			value := strings.TrimSpace(fmt.Sprint(item))
+			if item != nil && value != "" {
+				list = append(list, value)
+			}
+		}
+		return strings.Join(list, ","), list
+	}
+
+	text := string(raw)
+	return text, []string{text}
+}
+
+// splitImportList 按逗号拆分列表值并去掉空项
+func splitImportList(s string) []string {
+	parts := strings.Split(s, ",")
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			list = append(list, trimmed)
+		}
+	}
+	return list
+}
@@ -0,0 +1,109 @@
+package utils_test
+
+import (
+	"imgGeneratePrompts/models"
+	"imgGeneratePrompts/utils"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readAllImportRows 读取导入文件中的所有行
+func readAllImportRows(t *testing.T, reader *utils.ImportReader) []*utils.ImportRow {
+	var rows []*utils.ImportRow
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows
+		}
+		require.NoError(t, err)
+		rows = append(rows, row)
+	}
+}
+
+// TestImportReaderJSONL 测试JSONL导入的解析、列映射和逐行错误
+func TestImportReaderJSONL(t *testing.T) {
+	input := `{"prompt": "a cat", "tags": ["animal", " cute "], "is_public": true, "structure_analysis": {"subject": "cat"}}
+
+{"text": "a dog", "negative_prompt": "blurry", "tag_names": "animal,dog", "model": "sdxl"}
+not json
+{"prompt_text": "", "tags": "empty"}
+{"prompt": "ignored column", "source": "web"}`
+
+	mapping, err := utils.ParseImportMapping("text=prompt_text,source=-")
+	require.NoError(t, err)
+	reader, err := utils.NewImportReader(strings.NewReader(input), models.ImportFormatJSONL, mapping)
+	require.NoError(t, err)
+	rows := readAllImportRows(t, reader)
+	require.Len(t, rows, 5)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "a cat", rows[0].Request.PromptText)
+	assert.Equal(t, []string{"animal", "cute"}, rows[0].Request.TagNames)
+	assert.True(t, rows[0].Request.IsPublic)
+	assert.JSONEq(t, `{"subject": "cat"}`, rows[0].Request.StructureAnalysis)
+
+	assert.NoError(t, rows[1].Err)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Equal(t, "a dog", rows[1].Request.PromptText)
+	assert.Equal(t, "blurry", rows[1].Request.NegativePrompt)
+	assert.Equal(t, "sdxl", rows[1].Request.ModelName)
+	assert.Equal(t, []string{"animal", "dog"}, rows[1].Request.TagNames)
+	assert.True(t, rows[1].HasField("negative_prompt"))
+	assert.False(t, rows[1].HasField("is_public"))
+
+	assert.Error(t, rows[2].Err)
+	assert.Equal(t, 4, rows[2].Line)
+	assert.Error(t, rows[3].Err, "prompt_text 为空的行应报错")
+
+	assert.NoError(t, rows[4].Err)
+	assert.ElementsMatch(t, []string{"prompt_text"}, rows[4].Fields)
+}
+
+// TestImportReaderCSV 测试CSV导入的表头映射、BOM和逐行错误
+func TestImportReaderCSV(t *testing.T) {
+	input := "\ufeffPrompt,Negative,Tags,Public,Notes\n" +
+		"\"a cat, sitting\",blurry,\"animal,cute\",yes,x\n" +
+		"a dog,,dog,false,y\n" +
+		"a bird,,,maybe,z\n"
+
+	mapping, err := utils.ParseImportMapping(`{"Prompt": "prompt_text", "Negative": "negative_prompt", "Tags": "tag_names", "Public": "is_public"}`)
+	require.NoError(t, err)
+	reader, err := utils.NewImportReader(strings.NewReader(input), models.ImportFormatCSV, mapping)
+	require.NoError(t, err)
+	rows := readAllImportRows(t, reader)
+	require.Len(t, rows, 3)
+
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "a cat, sitting", rows[0].Request.PromptText)
+	assert.Equal(t, []string{"animal", "cute"}, rows[0].Request.TagNames)
+	assert.Equal(t, "blurry", rows[0].Request.NegativePrompt)
+
+	assert.NoError(t, rows[1].Err)
+	assert.False(t, rows[1].Request.IsPublic)
+
+	assert.Error(t, rows[2].Err, "无效的 is_public 应报错")
+	assert.Equal(t, 4, rows[2].Line)
+}
+
+// TestImportMappingErrors 测试无效的映射和表头
+func TestImportMappingErrors(t *testing.T) {
+	_, err := utils.ParseImportMapping("prompt=unknown_field")
+	assert.Error(t, err)
+	_, err = utils.ParseImportMapping("prompt")
+	assert.Error(t, err)
+
+	_, err = utils.NewImportReader(strings.NewReader("a,b\n1,2\n"), models.ImportFormatCSV, nil)
+	assert.Error(t, err, "没有映射到 prompt_text 的列时应报错")
+	_, err = utils.NewImportReader(strings.NewReader(""), "xml", nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, models.ImportFormatJSONL, utils.DetectImportFormat("dump.NDJSON"))
+	assert.Equal(t, models.ImportFormatCSV, utils.DetectImportFormat("sheet.csv"))
+	assert.Equal(t, "", utils.DetectImportFormat("sheet.xlsx"))
+}
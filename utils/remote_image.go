@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrRemoteImage 远程图片无法下载或不符合限制
+	ErrRemoteImage = errors.New("无法获取远程图片")
+	// ErrBlockedAddress 远程地址指向内网、本机等禁止访问的网段
+	ErrBlockedAddress = errors.New("禁止访问内网地址")
+)
+
+// remoteImageTypes 允许下载的图片类型（按内容识别）及保存时使用的扩展名
+var remoteImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// blockedNetworks 除 net.IP 自带判断（回环、私有、链路本地、组播、未指定）外需要禁止的网段
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // 本网络
+	"100.64.0.0/10",  // 运营商级NAT
+	"192.0.0.0/24",   // IETF 协议分配
+	"198.18.0.0/15",  // 基准测试
+	"240.0.0.0/4",    // 保留地址（含广播地址）
+	"64:ff9b::/96",   // NAT64，可映射到任意IPv4地址
+	"64:ff9b:1::/48", // 本地使用的NAT64
+	"2002::/16",      // 6to4，地址中嵌入了IPv4地址
+	"2001::/32",      // Teredo，地址中嵌入了（取反的）IPv4地址
+)
+
+// RemoteImageFetcher 将远程图片下载到上传目录
+// 只允许 http/https，连接前校验实际解析到的IP（防止DNS重绑定），并限制大小、类型、超时和重定向次数
+type RemoteImageFetcher struct {
+	MaxSize      int64         // 最大字节数
+	Timeout      time.Duration // 单张图片的总超时（包括连接、重定向和读取）
+	MaxRedirects int           // 最大重定向次数
+	// AllowPrivateNetworks 允许访问内网地址，仅用于测试或完全可信的部署环境
+	AllowPrivateNetworks bool
+}
+
+// NewRemoteImageFetcher 创建远程图片下载器
+func NewRemoteImageFetcher(maxSize int64, timeout time.Duration, maxRedirects int) *RemoteImageFetcher {
+	return &RemoteImageFetcher{
+		MaxSize:      maxSize,
+		Timeout:      timeout,
+		MaxRedirects: maxRedirects,
+	}
+}
+
+// IsRemoteURL 判断是否为需要下载的 http/https 地址
+func IsRemoteURL(rawURL string) bool {
+	lower := strings.ToLower(strings.TrimSpace(rawURL))
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// Fetch 下载远程图片并保存到上传目录，返回保存的文件名
+func (f *RemoteImageFetcher) Fetch(ctx context.Context, rawURL, uploadPath string) (string, error) {
+	target, err := parseRemoteURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRemoteImage, err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return "", fmt.Errorf("%w: %w: %s", ErrRemoteImage, ErrBlockedAddress, target.Host)
+		}
+		return "", fmt.Errorf("%w: %v", ErrRemoteImage, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s 返回状态码 %d", ErrRemoteImage, target.Host, resp.StatusCode)
+	}
+	if resp.ContentLength > f.MaxSize {
+		return "", fmt.Errorf("%w: 图片大小超出限制（%d 字节）", ErrRemoteImage, f.MaxSize)
+	}
+
+	// 按内容识别类型，不信任响应头中的 Content-Type
+	body := io.LimitReader(resp.Body, f.MaxSize+1)
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("%w: 读取图片失败: %v", ErrRemoteImage, err)
+	}
+	head = head[:n]
+	ext, ok := remoteImageTypes[http.DetectContentType(head)]
+	if !ok {
+		return "", fmt.Errorf("%w: 不支持的图片类型 %s", ErrRemoteImage, http.DetectContentType(head))
+	}
+
+	if err := os.MkdirAll(uploadPath, os.ModePerm); err != nil {
+		return "", fmt.Errorf("创建上传目录失败: %v", err)
+	}
+	name := strings.TrimSuffix(path.Base(target.Path), path.Ext(target.Path))
+	if name == "" || name == "." || name == "/" {
+		name = "remote"
+	}
+	filename := GenerateUniqueFilename(name + ext)
+	file, err := createUniqueFile(uploadPath, filename)
+	if err != nil {
+		return "", err
+	}
+
+	written, err := io.Copy(file, io.MultiReader(bytes.NewReader(head), body))
+	closeErr := file.Close()
+	if err == nil && written > f.MaxSize {
+		err = fmt.Errorf("%w: 图片大小超出限制（%d 字节）", ErrRemoteImage, f.MaxSize)
+	} else if err != nil {
+		err = fmt.Errorf("%w: 读取图片失败: %v", ErrRemoteImage, err)
+	} else if closeErr != nil {
+		err = fmt.Errorf("保存文件失败: %v", closeErr)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return filepath.Base(file.Name()), nil
+}
+
+// client 创建下载图片使用的 HTTP 客户端，每次重定向都重新校验地址
+func (f *RemoteImageFetcher) client() *http.Client {
+	client := NewSafeHTTPClient(f.Timeout, f.AllowPrivateNetworks)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > f.MaxRedirects {
+			return fmt.Errorf("重定向次数超过 %d 次", f.MaxRedirects)
+		}
+		_, err := parseRemoteURL(req.URL.String())
+		return err
+	}
+	return client
+}
+
+// NewSafeHTTPClient 创建只连接公网地址、不使用代理的 HTTP 客户端，连接前校验实际解析到的IP
+// allowPrivate 为 true 时不限制目标地址；重定向策略由调用方设置 CheckRedirect
+func NewSafeHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsBlockedIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 nil, // 使用代理时无法校验目标地址
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			DisableKeepAlives:     true,
+		},
+	}
+}
+
+// parseRemoteURL 解析并校验远程地址，只允许 http/https 且不允许携带用户信息
+func parseRemoteURL(rawURL string) (*url.URL, error) {
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无效的URL: %v", ErrRemoteImage, err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("%w: 只支持 http/https 地址", ErrRemoteImage)
+	}
+	if target.Hostname() == "" || target.User != nil {
+		return nil, fmt.Errorf("%w: 无效的URL", ErrRemoteImage)
+	}
+	return target, nil
+}
+
+// IsBlockedIP 判断IP是否属于禁止访问的网段（回环、私有、链路本地、组播、保留地址等）
+func IsBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// createUniqueFile 在目录中创建文件，同名文件已存在时在文件名后追加序号
+func createUniqueFile(dir, filename string) (*os.File, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for i := 0; ; i++ {
+		name := filename
+		if i > 0 {
+			name = fmt.Sprintf("%s_%d%s", base, i, ext)
+		}
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("创建目标文件失败: %v", err)
+		}
+	}
+}
+
+// mustParseCIDRs 解析网段列表
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
@@ -41,6 +41,15 @@ func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 	})
 }
 
+// AcceptedResponse 请求已接受、将在后台处理的响应（202）
+func AcceptedResponse(c *gin.Context, message string, data interface{}) {
+	c.JSON(http.StatusAccepted, ResponseData{
+		Code:    http.StatusAccepted,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // ErrorResponse 错误响应
 func ErrorResponse(c *gin.Context, code int, message string) {
 	c.JSON(code, ResponseData{
@@ -69,6 +78,11 @@ func NotFoundResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusNotFound, message)
 }
 
+// PreconditionFailedResponse 412错误响应（If-Match 与当前版本不一致）
+func PreconditionFailedResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusPreconditionFailed, message)
+}
+
 // InternalServerErrorResponse 500错误响应
 func InternalServerErrorResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusInternalServerError, message)
@@ -89,6 +103,17 @@ func PaginationResponse(c *gin.Context, items interface{}, page, pageSize int, t
 	SuccessResponse(c, data)
 }
 
+// NotModified 设置 ETag 响应头，If-None-Match 匹配时返回 304 并返回 true，调用方不应再写响应体
+func NotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	if ETagMatchesNoneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return true
+	}
+	return false
+}
+
 // ValidationErrorResponse 参数验证错误响应
 func ValidationErrorResponse(c *gin.Context, err error) {
 	if err == nil {
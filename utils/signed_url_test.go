@@ -0,0 +1,35 @@
+package utils_test
+
+import (
+	"imgGeneratePrompts/utils"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignedPath 测试签名URL的生成与校验
+func TestSignedPath(t *testing.T) {
+	const secret = "test-secret"
+	const path = "/api/v1/shared/abc/images/1"
+
+	signed := utils.SignPath(path, time.Now().Add(time.Minute), secret)
+	assert.True(t, strings.HasPrefix(signed, path+"?"))
+
+	parsed, err := url.Parse(signed)
+	assert.NoError(t, err)
+	expires := parsed.Query().Get("expires")
+	signature := parsed.Query().Get("signature")
+
+	assert.NoError(t, utils.VerifySignedPath(path, expires, signature, secret))
+	assert.ErrorIs(t, utils.VerifySignedPath(path, expires, signature, "other-secret"), utils.ErrInvalidSignature)
+	assert.ErrorIs(t, utils.VerifySignedPath("/api/v1/shared/abc/images/2", expires, signature, secret), utils.ErrInvalidSignature)
+	assert.ErrorIs(t, utils.VerifySignedPath(path, expires+"0", signature, secret), utils.ErrInvalidSignature)
+
+	expired := utils.SignPath(path, time.Now().Add(-time.Minute), secret)
+	parsed, _ = url.Parse(expired)
+	err = utils.VerifySignedPath(path, parsed.Query().Get("expires"), parsed.Query().Get("signature"), secret)
+	assert.ErrorIs(t, err, utils.ErrExpiredSignature)
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteSSE 按 Server-Sent Events 格式写出一条消息，data 序列化为 JSON
+// id 和 event 为空时省略对应字段
+func WriteSSE(w io.Writer, id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %v", err)
+	}
+
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + sseField(id) + "\n")
+	}
+	if event != "" {
+		b.WriteString("event: " + sseField(event) + "\n")
+	}
+	// JSON 编码后不含换行，作为单行 data 写出
+	b.WriteString("data: ")
+	b.Write(payload)
+	b.WriteString("\n\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteSSEComment 写出注释行，用于保持连接（客户端会忽略）
+func WriteSSEComment(w io.Writer, comment string) error {
+	_, err := io.WriteString(w, ": "+sseField(comment)+"\n\n")
+	return err
+}
+
+// WriteSSERetry 设置客户端断线后的重连间隔（毫秒）
+func WriteSSERetry(w io.Writer, milliseconds int) error {
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", milliseconds)
+	return err
+}
+
+// sseField 去掉字段值中的换行，避免破坏消息格式
+func sseField(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,37 @@
+package utils_test
+
+import (
+	"bytes"
+	"imgGeneratePrompts/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWriteSSE 测试 Server-Sent Events 消息格式
+func TestWriteSSE(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, utils.WriteSSE(&buf, "1-2", "prompt.created", map[string]interface{}{"text": "a\nb"}))
+	assert.Equal(t, "id: 1-2\nevent: prompt.created\ndata: {\"text\":\"a\\nb\"}\n\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, utils.WriteSSE(&buf, "", "", 1))
+	assert.Equal(t, "data: 1\n\n", buf.String(), "id 和 event 为空时省略")
+
+	buf.Reset()
+	require.NoError(t, utils.WriteSSE(&buf, "x\ny", "a\r\nb", nil))
+	assert.Equal(t, "id: xy\nevent: ab\ndata: null\n\n", buf.String(), "字段中的换行应被去掉")
+
+	buf.Reset()
+	assert.Error(t, utils.WriteSSE(&buf, "", "", make(chan int)))
+	assert.Empty(t, buf.String())
+}
+
+// TestWriteSSEComment 测试注释和重连间隔
+func TestWriteSSEComment(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, utils.WriteSSERetry(&buf, 3000))
+	require.NoError(t, utils.WriteSSEComment(&buf, "ping"))
+	assert.Equal(t, "retry: 3000\n\n: ping\n\n", buf.String())
+}
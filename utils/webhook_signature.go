@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 事件推送请求头
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // 签名，格式为 sha256=<hex>
+	WebhookTimestampHeader = "X-Webhook-Timestamp" // 签名时间（Unix秒），参与签名，用于拒绝重放
+	WebhookEventHeader     = "X-Webhook-Event"     // 事件类型
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"  // 推送记录ID，重试时不变，可用于去重
+)
+
+// ErrInvalidWebhookSignature 推送签名无效或已过期
+var ErrInvalidWebhookSignature = errors.New("推送签名无效")
+
+// SignWebhookPayload 计算推送签名：HMAC-SHA256(secret, "<timestamp>.<body>")
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature 校验推送签名，供接收方使用
+// tolerance 大于0时，签名时间与 now 相差超过 tolerance 视为无效
+func VerifyWebhookSignature(secret, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+	if tolerance > 0 {
+		if diff := now.Sub(time.Unix(ts, 0)); diff > tolerance || diff < -tolerance {
+			return ErrInvalidWebhookSignature
+		}
+	}
+	expected := SignWebhookPayload(secret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
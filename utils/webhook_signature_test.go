@@ -0,0 +1,41 @@
+package utils_test
+
+import (
+	"imgGeneratePrompts/utils"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSignWebhookPayload 测试签名格式与已知结果一致
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	signature := utils.SignWebhookPayload("secret", 1700000000, []byte(`{"a":1}`))
+	assert.Equal(t, "sha256=49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686", signature)
+}
+
+// TestVerifyWebhookSignature 测试签名校验：内容、密钥、时间任一不符都无效
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"prompt.created"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := utils.SignWebhookPayload("secret", now.Unix(), body)
+
+	assert.NoError(t, utils.VerifyWebhookSignature("secret", signature, timestamp, body, 5*time.Minute, now))
+	assert.NoError(t, utils.VerifyWebhookSignature("secret", signature, timestamp, body, 5*time.Minute, now.Add(4*time.Minute)))
+	assert.NoError(t, utils.VerifyWebhookSignature("secret", signature, timestamp, body, 0, now.Add(time.Hour)), "tolerance 为0时不检查时间")
+
+	cases := map[string]error{
+		"密钥不同":  utils.VerifyWebhookSignature("other", signature, timestamp, body, 0, now),
+		"内容被修改": utils.VerifyWebhookSignature("secret", signature, timestamp, []byte(`{}`), 0, now),
+		"时间被修改": utils.VerifyWebhookSignature("secret", signature, "1700000001", body, 0, now),
+		"时间无效":  utils.VerifyWebhookSignature("secret", signature, "abc", body, 0, now),
+		"签名已过期": utils.VerifyWebhookSignature("secret", signature, timestamp, body, 5*time.Minute, now.Add(6*time.Minute)),
+		"签名为空":  utils.VerifyWebhookSignature("secret", "", timestamp, body, 0, now),
+	}
+	for name, err := range cases {
+		assert.ErrorIs(t, err, utils.ErrInvalidWebhookSignature, name)
+	}
+}